@@ -0,0 +1,201 @@
+// Package certpin helps establishing QUIC connections between endpoints that use self-signed certificates.
+// Instead of verifying a certificate chain, the endpoints verify that the peer presents a certificate
+// whose fingerprint is known in advance (certificate pinning).
+// This is useful for peer-to-peer applications and internal meshes, where the fingerprints can be exchanged out of band.
+package certpin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// A Fingerprint is the SHA-256 hash of a certificate, or of the certificate's public key.
+type Fingerprint [sha256.Size]byte
+
+// String returns the fingerprint in hex encoding.
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// ParseFingerprint parses a hex-encoded fingerprint.
+// The bytes may be separated by colons, as commonly used by OpenSSL.
+func ParseFingerprint(s string) (Fingerprint, error) {
+	var f Fingerprint
+	b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil {
+		return f, err
+	}
+	if len(b) != len(f) {
+		return f, fmt.Errorf("invalid fingerprint length: %d bytes", len(b))
+	}
+	copy(f[:], b)
+	return f, nil
+}
+
+// A PinType defines what is hashed to calculate the fingerprint.
+type PinType uint8
+
+const (
+	// PinCertificate pins the DER encoding of the certificate.
+	PinCertificate PinType = iota
+	// PinPublicKey pins the DER encoding of the SubjectPublicKeyInfo of the certificate.
+	// This allows renewing the certificate without changing the key.
+	PinPublicKey
+)
+
+// CertificateFingerprint calculates the fingerprint of the certificate.
+func CertificateFingerprint(cert *x509.Certificate) Fingerprint {
+	return sha256.Sum256(cert.Raw)
+}
+
+// PublicKeyFingerprint calculates the fingerprint of the SubjectPublicKeyInfo of the certificate.
+func PublicKeyFingerprint(cert *x509.Certificate) Fingerprint {
+	return sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+}
+
+// GetFingerprint calculates the fingerprint of the certificate for the pin type.
+func GetFingerprint(cert *x509.Certificate, pinType PinType) Fingerprint {
+	if pinType == PinPublicKey {
+		return PublicKeyFingerprint(cert)
+	}
+	return CertificateFingerprint(cert)
+}
+
+// GenerateCertificate generates a new ECDSA P-256 key and a self-signed certificate.
+// The certificate is valid for the given duration.
+// The Leaf of the tls.Certificate is set, so the fingerprint can be calculated right away.
+func GenerateCertificate(validity time.Duration) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "quic-go"},
+		NotBefore:             now.Add(-time.Minute), // allow for some clock skew
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{certDER},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// ErrFingerprintMismatch is returned when the peer's certificate doesn't match any of the pinned fingerprints.
+var ErrFingerprintMismatch = errors.New("certpin: certificate doesn't match any pinned fingerprint")
+
+// VerifyPeerCertificate returns a function that can be used as the VerifyPeerCertificate callback of a tls.Config.
+// It accepts the peer's certificate if its fingerprint matches one of the pins, and if it is currently valid.
+// No certificate chain is verified, so the tls.Config needs to set InsecureSkipVerify on the client side,
+// and it must not set ClientCAs on the server side.
+func VerifyPeerCertificate(pinType PinType, pins ...Fingerprint) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("certpin: peer didn't present a certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			return fmt.Errorf("certpin: certificate is only valid from %s to %s", cert.NotBefore, cert.NotAfter)
+		}
+		fp := GetFingerprint(cert, pinType)
+		var matched int
+		for _, pin := range pins {
+			matched |= subtle.ConstantTimeCompare(fp[:], pin[:])
+		}
+		if matched != 1 {
+			return ErrFingerprintMismatch
+		}
+		return nil
+	}
+}
+
+// A Config configures mutual fingerprint pinning.
+type Config struct {
+	// Certificate is presented to the peer.
+	// It can be generated using GenerateCertificate.
+	Certificate tls.Certificate
+	// PinType defines how the fingerprints of the peer are calculated.
+	PinType PinType
+	// Pins are the accepted fingerprints of the peer.
+	Pins []Fingerprint
+	// NextProtos are the application protocols offered (on the client side) or accepted (on the server side).
+	// QUIC requires the use of ALPN, so at least one protocol must be set.
+	NextProtos []string
+}
+
+func (c *Config) validate() error {
+	if len(c.Certificate.Certificate) == 0 {
+		return errors.New("certpin: no certificate")
+	}
+	if len(c.Pins) == 0 {
+		return errors.New("certpin: no pins")
+	}
+	if len(c.NextProtos) == 0 {
+		return errors.New("certpin: QUIC requires an application protocol (NextProtos)")
+	}
+	return nil
+}
+
+// ServerTLSConfig returns a tls.Config for a QUIC server.
+// The server requires clients to present a certificate matching one of the pins.
+func (c *Config) ServerTLSConfig() (*tls.Config, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:            tls.VersionTLS13,
+		Certificates:          []tls.Certificate{c.Certificate},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: VerifyPeerCertificate(c.PinType, c.Pins...),
+		NextProtos:            append([]string{}, c.NextProtos...),
+	}, nil
+}
+
+// ClientTLSConfig returns a tls.Config for a QUIC client.
+// The client only accepts server certificates matching one of the pins.
+func (c *Config) ClientTLSConfig() (*tls.Config, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{c.Certificate},
+		// The certificate chain isn't verified. Instead, VerifyPeerCertificate checks the fingerprint.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: VerifyPeerCertificate(c.PinType, c.Pins...),
+		NextProtos:            append([]string{}, c.NextProtos...),
+	}, nil
+}
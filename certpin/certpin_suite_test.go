@@ -0,0 +1,13 @@
+package certpin
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCertpin(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Certpin Suite")
+}
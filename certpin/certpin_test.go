@@ -0,0 +1,206 @@
+package certpin
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificate Pinning", func() {
+	Context("fingerprints", func() {
+		It("encodes and parses fingerprints", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			fp := CertificateFingerprint(cert.Leaf)
+			Expect(fp.String()).To(HaveLen(64))
+			parsed, err := ParseFingerprint(fp.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(fp))
+		})
+
+		It("parses colon-separated fingerprints", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			fp := PublicKeyFingerprint(cert.Leaf)
+			s := fp.String()
+			var parts []string
+			for i := 0; i < len(s); i += 2 {
+				parts = append(parts, strings.ToUpper(s[i:i+2]))
+			}
+			parsed, err := ParseFingerprint(strings.Join(parts, ":"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(fp))
+		})
+
+		It("rejects fingerprints with the wrong length", func() {
+			_, err := ParseFingerprint("deadbeef")
+			Expect(err).To(MatchError("invalid fingerprint length: 4 bytes"))
+		})
+
+		It("rejects invalid hex", func() {
+			_, err := ParseFingerprint("foobar")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("calculates the fingerprint for the pin type", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(GetFingerprint(cert.Leaf, PinCertificate)).To(Equal(CertificateFingerprint(cert.Leaf)))
+			Expect(GetFingerprint(cert.Leaf, PinPublicKey)).To(Equal(PublicKeyFingerprint(cert.Leaf)))
+			Expect(CertificateFingerprint(cert.Leaf)).ToNot(Equal(PublicKeyFingerprint(cert.Leaf)))
+		})
+	})
+
+	Context("verifying certificates", func() {
+		It("accepts a pinned certificate", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			verify := VerifyPeerCertificate(PinCertificate, CertificateFingerprint(other.Leaf), CertificateFingerprint(cert.Leaf))
+			Expect(verify(cert.Certificate, nil)).To(Succeed())
+		})
+
+		It("accepts a pinned public key", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			verify := VerifyPeerCertificate(PinPublicKey, PublicKeyFingerprint(cert.Leaf))
+			Expect(verify(cert.Certificate, nil)).To(Succeed())
+			// a certificate fingerprint doesn't match a public key pin
+			verify = VerifyPeerCertificate(PinPublicKey, CertificateFingerprint(cert.Leaf))
+			Expect(verify(cert.Certificate, nil)).To(MatchError(ErrFingerprintMismatch))
+		})
+
+		It("rejects certificates that are not pinned", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			verify := VerifyPeerCertificate(PinCertificate, CertificateFingerprint(other.Leaf))
+			Expect(verify(cert.Certificate, nil)).To(MatchError(ErrFingerprintMismatch))
+		})
+
+		It("rejects expired certificates", func() {
+			cert, err := GenerateCertificate(-time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			verify := VerifyPeerCertificate(PinCertificate, CertificateFingerprint(cert.Leaf))
+			err = verify(cert.Certificate, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("certificate is only valid"))
+		})
+
+		It("rejects peers that don't present a certificate", func() {
+			verify := VerifyPeerCertificate(PinCertificate, Fingerprint{})
+			Expect(verify(nil, nil)).To(MatchError("certpin: peer didn't present a certificate"))
+		})
+	})
+
+	Context("tls.Configs", func() {
+		It("requires an application protocol", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			conf := &Config{Certificate: cert, Pins: []Fingerprint{{}}}
+			_, err = conf.ServerTLSConfig()
+			Expect(err).To(MatchError("certpin: QUIC requires an application protocol (NextProtos)"))
+			_, err = conf.ClientTLSConfig()
+			Expect(err).To(MatchError("certpin: QUIC requires an application protocol (NextProtos)"))
+		})
+
+		It("requires pins", func() {
+			cert, err := GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = (&Config{Certificate: cert, NextProtos: []string{"foo"}}).ServerTLSConfig()
+			Expect(err).To(MatchError("certpin: no pins"))
+		})
+
+		It("requires a certificate", func() {
+			_, err := (&Config{Pins: []Fingerprint{{}}, NextProtos: []string{"foo"}}).ClientTLSConfig()
+			Expect(err).To(MatchError("certpin: no certificate"))
+		})
+	})
+
+	Context("QUIC handshakes", func() {
+		var serverCert, clientCert tls.Certificate
+
+		BeforeEach(func() {
+			var err error
+			serverCert, err = GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			clientCert, err = GenerateCertificate(time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		runServer := func(pins ...Fingerprint) (quic.Listener, <-chan quic.Connection) {
+			tlsConf, err := (&Config{
+				Certificate: serverCert,
+				PinType:     PinPublicKey,
+				Pins:        pins,
+				NextProtos:  []string{"certpin"},
+			}).ServerTLSConfig()
+			Expect(err).ToNot(HaveOccurred())
+			ln, err := quic.ListenAddr("localhost:0", tlsConf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			connChan := make(chan quic.Connection, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				connChan <- conn
+			}()
+			return ln, connChan
+		}
+
+		dial := func(ln quic.Listener, pins ...Fingerprint) (quic.Connection, error) {
+			tlsConf, err := (&Config{
+				Certificate: clientCert,
+				PinType:     PinPublicKey,
+				Pins:        pins,
+				NextProtos:  []string{"certpin"},
+			}).ClientTLSConfig()
+			Expect(err).ToNot(HaveOccurred())
+			return quic.DialAddr(ln.Addr().String(), tlsConf, &quic.Config{HandshakeIdleTimeout: time.Second})
+		}
+
+		It("establishes a connection when both peers are pinned", func() {
+			ln, connChan := runServer(PublicKeyFingerprint(clientCert.Leaf))
+			defer ln.Close()
+			conn, err := dial(ln, PublicKeyFingerprint(serverCert.Leaf))
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			Expect(conn.ConnectionState().TLS.NegotiatedProtocol).To(Equal("certpin"))
+			var serverConn quic.Connection
+			Eventually(connChan).Should(Receive(&serverConn))
+			peerCerts := serverConn.ConnectionState().TLS.PeerCertificates
+			Expect(peerCerts).To(HaveLen(1))
+			Expect(PublicKeyFingerprint(peerCerts[0])).To(Equal(PublicKeyFingerprint(clientCert.Leaf)))
+		})
+
+		It("rejects a server that is not pinned", func() {
+			ln, connChan := runServer(PublicKeyFingerprint(clientCert.Leaf))
+			defer ln.Close()
+			_, err := dial(ln, PublicKeyFingerprint(clientCert.Leaf))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(ErrFingerprintMismatch.Error()))
+			Consistently(connChan).ShouldNot(Receive())
+		})
+
+		It("rejects a client that is not pinned", func() {
+			ln, connChan := runServer(PublicKeyFingerprint(serverCert.Leaf))
+			defer ln.Close()
+			conn, err := dial(ln, PublicKeyFingerprint(serverCert.Leaf))
+			if err == nil {
+				// The client might consider the handshake complete before the server verified its certificate.
+				Eventually(conn.Context().Done()).Should(BeClosed())
+			}
+			Consistently(connChan).ShouldNot(Receive())
+		})
+	})
+})
@@ -51,7 +51,8 @@ var (
 // DialAddr establishes a new QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC connection is closed.
 // The hostname for SNI is taken from the given address.
-// The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
+// The tls.Config.CipherSuites allows restricting and ordering the TLS 1.3 cipher suites,
+// in order of the client's preference. It must contain at least one TLS 1.3 cipher suite.
 func DialAddr(
 	addr string,
 	tlsConf *tls.Config,
@@ -63,7 +64,8 @@ func DialAddr(
 // DialAddrEarly establishes a new 0-RTT QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC connection is closed.
 // The hostname for SNI is taken from the given address.
-// The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
+// The tls.Config.CipherSuites allows restricting and ordering the TLS 1.3 cipher suites,
+// in order of the client's preference. It must contain at least one TLS 1.3 cipher suite.
 func DialAddrEarly(
 	addr string,
 	tlsConf *tls.Config,
@@ -186,8 +188,8 @@ func dialContext(
 	use0RTT bool,
 	createdPacketConn bool,
 ) (quicConn, error) {
-	if tlsConf == nil {
-		return nil, errors.New("quic: tls.Config not set")
+	if err := validateTLSConfig(tlsConf); err != nil {
+		return nil, err
 	}
 	if err := validateConfig(config); err != nil {
 		return nil, err
@@ -0,0 +1,154 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The maximum number of fragments that the ClientHello can be split into.
+const maxClientHelloFragments = 32
+
+// A ClientHelloFiller is inserted between the fragments of a split ClientHello.
+type ClientHelloFiller uint8
+
+const (
+	// ClientHelloFillerNone doesn't insert anything between the fragments.
+	ClientHelloFillerNone ClientHelloFiller = iota
+	// ClientHelloFillerPadding inserts an Initial packet that only contains PADDING frames.
+	ClientHelloFillerPadding
+	// ClientHelloFillerPing inserts an Initial packet that contains a PING frame.
+	ClientHelloFillerPing
+)
+
+// ClientHelloSplitting configures how a client sends its ClientHello.
+// Middleboxes that inspect QUIC traffic commonly read the SNI from the first Initial packet sent by the client.
+// Splitting the ClientHello across multiple Initial packets, and sending them out of order, makes this harder.
+// The server reassembles the ClientHello, as required by RFC 9000.
+// It is only valid for the client.
+type ClientHelloSplitting struct {
+	// Fragments is the number of CRYPTO frames that the ClientHello is split into.
+	// Every CRYPTO frame is sent in its own Initial packet.
+	// Values below 2 and above 32 are invalid.
+	Fragments int
+	// Reorder sends the CRYPTO frames in reverse order, starting with the one that has the highest offset.
+	Reorder bool
+	// Filler is sent in a separate Initial packet between every two CRYPTO frames.
+	Filler ClientHelloFiller
+}
+
+func (s *ClientHelloSplitting) valid() bool {
+	if s.Fragments < 2 || s.Fragments > maxClientHelloFragments {
+		return false
+	}
+	return s.Filler <= ClientHelloFillerPing
+}
+
+// A framePopper is a cryptoStream that determines which frames are sent in a packet.
+// This allows it to send packets that don't (only) contain CRYPTO frames.
+type framePopper interface {
+	// PopFrames returns the frames for the next packet.
+	// The returned slice might be empty, if the packet should only contain PADDING frames.
+	PopFrames(maxLen protocol.ByteCount) []ackhandler.Frame
+}
+
+// The clientHelloSplitter splits the data written to the client's Initial crypto stream.
+// Every time data is written, it is split into the configured number of CRYPTO frames,
+// which are then sent in separate Initial packets.
+// Lost CRYPTO frames are retransmitted by the retransmissionQueue, and might be sent in the same packet.
+type clientHelloSplitter struct {
+	cryptoStream
+
+	conf    ClientHelloSplitting
+	version protocol.VersionNumber
+
+	// The CRYPTO frames that still need to be sent.
+	// A nil entry stands for a filler packet.
+	queue []*wire.CryptoFrame
+}
+
+var _ framePopper = &clientHelloSplitter{}
+
+func newClientHelloSplitter(str cryptoStream, conf ClientHelloSplitting, version protocol.VersionNumber) *clientHelloSplitter {
+	return &clientHelloSplitter{
+		cryptoStream: str,
+		conf:         conf,
+		version:      version,
+	}
+}
+
+func (s *clientHelloSplitter) HasData() bool {
+	return len(s.queue) > 0 || s.cryptoStream.HasData()
+}
+
+func (s *clientHelloSplitter) PopFrames(maxLen protocol.ByteCount) []ackhandler.Frame {
+	s.maybeSplit()
+	if len(s.queue) == 0 {
+		return nil
+	}
+	if s.queue[0] == nil {
+		s.queue = s.queue[1:]
+		if s.conf.Filler == ClientHelloFillerPing {
+			return []ackhandler.Frame{{Frame: &wire.PingFrame{}}}
+		}
+		return nil
+	}
+	if cf := s.popCryptoFrame(maxLen); cf != nil {
+		return []ackhandler.Frame{{Frame: cf}}
+	}
+	return nil
+}
+
+// PopCryptoFrame returns the next CRYPTO frame, skipping all fillers.
+func (s *clientHelloSplitter) PopCryptoFrame(maxLen protocol.ByteCount) *wire.CryptoFrame {
+	s.maybeSplit()
+	for len(s.queue) > 0 && s.queue[0] == nil {
+		s.queue = s.queue[1:]
+	}
+	if len(s.queue) == 0 {
+		return nil
+	}
+	return s.popCryptoFrame(maxLen)
+}
+
+func (s *clientHelloSplitter) popCryptoFrame(maxLen protocol.ByteCount) *wire.CryptoFrame {
+	cf := s.queue[0]
+	newFrame, needsSplit := cf.MaybeSplitOffFrame(maxLen, s.version)
+	if !needsSplit {
+		s.queue = s.queue[1:]
+		return cf
+	}
+	// If the frame was split, the rest of the data is sent in the next packet.
+	return newFrame
+}
+
+func (s *clientHelloSplitter) maybeSplit() {
+	if len(s.queue) > 0 || !s.cryptoStream.HasData() {
+		return
+	}
+	f := s.cryptoStream.PopCryptoFrame(protocol.MaxByteCount)
+	numFragments := s.conf.Fragments
+	if len(f.Data) < numFragments {
+		numFragments = len(f.Data)
+	}
+	fragments := make([]*wire.CryptoFrame, 0, numFragments)
+	for i := 0; i < numFragments; i++ {
+		start := i * len(f.Data) / numFragments
+		end := (i + 1) * len(f.Data) / numFragments
+		fragments = append(fragments, &wire.CryptoFrame{
+			Offset: f.Offset + protocol.ByteCount(start),
+			Data:   f.Data[start:end],
+		})
+	}
+	if s.conf.Reorder {
+		for i, j := 0, len(fragments)-1; i < j; i, j = i+1, j-1 {
+			fragments[i], fragments[j] = fragments[j], fragments[i]
+		}
+	}
+	for i, cf := range fragments {
+		if i > 0 && s.conf.Filler != ClientHelloFillerNone {
+			s.queue = append(s.queue, nil)
+		}
+		s.queue = append(s.queue, cf)
+	}
+}
@@ -0,0 +1,108 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello Splitter", func() {
+	const version = protocol.Version1
+	data := []byte("Lorem ipsum dolor sit amet.") // 27 bytes
+
+	newSplitter := func(conf ClientHelloSplitting) *clientHelloSplitter {
+		s := newClientHelloSplitter(newCryptoStream(), conf, version)
+		_, err := s.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	popAll := func(s *clientHelloSplitter) [][]ackhandler.Frame {
+		var packets [][]ackhandler.Frame
+		for s.HasData() {
+			packets = append(packets, s.PopFrames(protocol.MaxByteCount))
+		}
+		return packets
+	}
+
+	It("validates the configuration", func() {
+		Expect((&ClientHelloSplitting{Fragments: 2}).valid()).To(BeTrue())
+		Expect((&ClientHelloSplitting{Fragments: 32, Filler: ClientHelloFillerPing}).valid()).To(BeTrue())
+		Expect((&ClientHelloSplitting{Fragments: 1}).valid()).To(BeFalse())
+		Expect((&ClientHelloSplitting{Fragments: 33}).valid()).To(BeFalse())
+		Expect((&ClientHelloSplitting{Fragments: 2, Filler: ClientHelloFillerPing + 1}).valid()).To(BeFalse())
+	})
+
+	It("splits the data", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 3})
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 0, Data: data[:9]}}},
+			{{Frame: &wire.CryptoFrame{Offset: 9, Data: data[9:18]}}},
+			{{Frame: &wire.CryptoFrame{Offset: 18, Data: data[18:]}}},
+		}))
+	})
+
+	It("reorders the fragments", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2, Reorder: true})
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 13, Data: data[13:]}}},
+			{{Frame: &wire.CryptoFrame{Offset: 0, Data: data[:13]}}},
+		}))
+	})
+
+	It("inserts PADDING packets", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2, Filler: ClientHelloFillerPadding})
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 0, Data: data[:13]}}},
+			nil,
+			{{Frame: &wire.CryptoFrame{Offset: 13, Data: data[13:]}}},
+		}))
+	})
+
+	It("inserts PING packets", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2, Filler: ClientHelloFillerPing})
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 0, Data: data[:13]}}},
+			{{Frame: &wire.PingFrame{}}},
+			{{Frame: &wire.CryptoFrame{Offset: 13, Data: data[13:]}}},
+		}))
+	})
+
+	It("doesn't create more fragments than there are bytes", func() {
+		s := newClientHelloSplitter(newCryptoStream(), ClientHelloSplitting{Fragments: 5}, version)
+		_, err := s.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(popAll(s)).To(HaveLen(3))
+	})
+
+	It("splits fragments that don't fit into the packet", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2})
+		frames := s.PopFrames((&wire.CryptoFrame{Data: data[:5]}).Length(version))
+		Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.CryptoFrame{Offset: 0, Data: data[:5]}}}))
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 5, Data: data[5:13]}}},
+			{{Frame: &wire.CryptoFrame{Offset: 13, Data: data[13:]}}},
+		}))
+	})
+
+	It("splits data that is written later", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2})
+		Expect(popAll(s)).To(HaveLen(2))
+		_, err := s.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(popAll(s)).To(Equal([][]ackhandler.Frame{
+			{{Frame: &wire.CryptoFrame{Offset: 27, Data: []byte("foo")}}},
+			{{Frame: &wire.CryptoFrame{Offset: 30, Data: []byte("bar")}}},
+		}))
+	})
+
+	It("pops CRYPTO frames, skipping fillers", func() {
+		s := newSplitter(ClientHelloSplitting{Fragments: 2, Filler: ClientHelloFillerPing})
+		Expect(s.PopCryptoFrame(protocol.MaxByteCount)).To(Equal(&wire.CryptoFrame{Offset: 0, Data: data[:13]}))
+		Expect(s.PopCryptoFrame(protocol.MaxByteCount)).To(Equal(&wire.CryptoFrame{Offset: 13, Data: data[13:]}))
+		Expect(s.HasData()).To(BeFalse())
+	})
+})
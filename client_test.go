@@ -486,6 +486,12 @@ var _ = Describe("Client", func() {
 				Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 			})
 
+			It("errors when the tls.Config doesn't contain any TLS 1.3 cipher suite", func() {
+				tlsConf.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, nil)
+				Expect(err).To(MatchError("quic: tls.Config.CipherSuites doesn't contain any TLS 1.3 cipher suite"))
+			})
+
 			It("disables bidirectional streams", func() {
 				config := &Config{
 					MaxIncomingStreams:    -1,
@@ -1,9 +1,13 @@
 package quic
 
 import (
+	"crypto/tls"
 	"errors"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/qtls"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -15,6 +19,29 @@ func (c *Config) Clone() *Config {
 	return &copy
 }
 
+// ackPolicy returns the ACK policy for application data packets.
+// The Config must have been populated.
+func (c *Config) ackPolicy() ackhandler.AckPolicy {
+	return ackhandler.AckPolicy{
+		MaxAckDelay:              c.MaxAckDelay,
+		AckDelayExponent:         c.AckDelayExponent,
+		PacketsBeforeAck:         c.AckElicitingThreshold,
+		ImmediateAckOnReordering: !c.DisableImmediateAckOnReordering,
+		MaxAckRanges:             c.MaxAckRanges,
+	}
+}
+
+// congestionConfig returns the configuration of the congestion controller.
+// The Config must have been populated.
+func (c *Config) congestionConfig() congestion.Config {
+	return congestion.Config{
+		InitialWindowPackets: c.InitialCongestionWindow,
+		MinWindowPackets:     c.MinCongestionWindow,
+		MaxWindowPackets:     c.MaxCongestionWindow,
+		LossReductionFactor:  c.CongestionLossReductionFactor,
+	}
+}
+
 func (c *Config) handshakeTimeout() time.Duration {
 	return utils.MaxDuration(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
@@ -29,6 +56,54 @@ func validateConfig(config *Config) error {
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.MaxAckDelay < 0 || config.MaxAckDelay+protocol.TimerGranularity > protocol.MaxMaxAckDelay {
+		return errors.New("invalid value for Config.MaxAckDelay")
+	}
+	if config.AckDelayExponent > protocol.MaxAckDelayExponent {
+		return errors.New("invalid value for Config.AckDelayExponent")
+	}
+	if config.AckElicitingThreshold < 0 {
+		return errors.New("invalid value for Config.AckElicitingThreshold")
+	}
+	if config.MaxAckRanges < 0 {
+		return errors.New("invalid value for Config.MaxAckRanges")
+	}
+	if config.CorkDelay < 0 || config.CorkDelay > protocol.MaxCorkDelay {
+		return errors.New("invalid value for Config.CorkDelay")
+	}
+	if config.ClientHelloSplitting != nil && !config.ClientHelloSplitting.valid() {
+		return errors.New("invalid value for Config.ClientHelloSplitting")
+	}
+	if config.DecryptionWorkers < 0 {
+		return errors.New("invalid value for Config.DecryptionWorkers")
+	}
+	if config.CongestionLossReductionFactor < 0 || config.CongestionLossReductionFactor >= 1 {
+		return errors.New("invalid value for Config.CongestionLossReductionFactor")
+	}
+	// The congestion windows depend on each other, so they are validated after applying the defaults.
+	cc := populateConfig(config).congestionConfig()
+	if cc.MinWindowPackets < 2 {
+		return errors.New("invalid value for Config.MinCongestionWindow")
+	}
+	if cc.MaxWindowPackets > protocol.MaxCongestionWindowPackets || cc.MaxWindowPackets < cc.MinWindowPackets {
+		return errors.New("invalid value for Config.MaxCongestionWindow")
+	}
+	if cc.InitialWindowPackets < cc.MinWindowPackets || cc.InitialWindowPackets > cc.MaxWindowPackets {
+		return errors.New("invalid value for Config.InitialCongestionWindow")
+	}
+	return nil
+}
+
+// validateTLSConfig checks that the tls.Config can be used for QUIC.
+// QUIC always uses TLS 1.3, so if CipherSuites is set, it must contain at least one TLS 1.3 cipher suite.
+// Otherwise, the restriction would be silently ignored, and the default cipher suites would be used.
+func validateTLSConfig(tlsConf *tls.Config) error {
+	if tlsConf == nil {
+		return errors.New("quic: tls.Config not set")
+	}
+	if len(tlsConf.CipherSuites) > 0 && len(qtls.FilterCipherSuitesTLS13(tlsConf.CipherSuites)) == 0 {
+		return errors.New("quic: tls.Config.CipherSuites doesn't contain any TLS 1.3 cipher suite")
+	}
 	return nil
 }
 
@@ -99,6 +174,40 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckDelay := config.MaxAckDelay
+	if maxAckDelay == 0 {
+		maxAckDelay = protocol.MaxAckDelay
+	}
+	ackDelayExponent := config.AckDelayExponent
+	if ackDelayExponent == 0 {
+		ackDelayExponent = protocol.AckDelayExponent
+	}
+	ackElicitingThreshold := config.AckElicitingThreshold
+	if ackElicitingThreshold == 0 {
+		ackElicitingThreshold = ackhandler.DefaultAckPolicy.PacketsBeforeAck
+	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges == 0 {
+		maxAckRanges = protocol.MaxNumAckRanges
+	} else if maxAckRanges > protocol.MaxMaxAckRanges {
+		maxAckRanges = protocol.MaxMaxAckRanges
+	}
+	initialCongestionWindow := config.InitialCongestionWindow
+	if initialCongestionWindow == 0 {
+		initialCongestionWindow = congestion.DefaultConfig.InitialWindowPackets
+	}
+	minCongestionWindow := config.MinCongestionWindow
+	if minCongestionWindow == 0 {
+		minCongestionWindow = congestion.DefaultConfig.MinWindowPackets
+	}
+	maxCongestionWindow := config.MaxCongestionWindow
+	if maxCongestionWindow == 0 {
+		maxCongestionWindow = congestion.DefaultConfig.MaxWindowPackets
+	}
+	lossReductionFactor := config.CongestionLossReductionFactor
+	if lossReductionFactor == 0 {
+		lossReductionFactor = congestion.DefaultConfig.LossReductionFactor
+	}
 
 	return &Config{
 		Versions:                         versions,
@@ -113,12 +222,33 @@ func populateConfig(config *Config) *Config {
 		AllowConnectionWindowIncrease:    config.AllowConnectionWindowIncrease,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
+		MaxAckDelay:                      maxAckDelay,
+		AckDelayExponent:                 ackDelayExponent,
+		AckElicitingThreshold:            ackElicitingThreshold,
+		DisableImmediateAckOnReordering:  config.DisableImmediateAckOnReordering,
+		MaxAckRanges:                     maxAckRanges,
+		InitialCongestionWindow:          initialCongestionWindow,
+		MinCongestionWindow:              minCongestionWindow,
+		MaxCongestionWindow:              maxCongestionWindow,
+		CongestionLossReductionFactor:    lossReductionFactor,
+		EnableL4S:                        config.EnableL4S,
+		EnableFlowLabels:                 config.EnableFlowLabels,
+		DecryptionWorkers:                config.DecryptionWorkers,
+		KeyUpdateInterval:                config.KeyUpdateInterval,
+		ClientHelloSplitting:             config.ClientHelloSplitting,
+		CorkDelay:                        config.CorkDelay,
 		ConnectionIDLength:               config.ConnectionIDLength,
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
+		EnableReliableStreamReset:        config.EnableReliableStreamReset,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
 		Tracer:                           config.Tracer,
+		ConnContext:                      config.ConnContext,
+		FaultInjector:                    config.FaultInjector,
+		StreamLeakDetector:               config.StreamLeakDetector,
+		Recorder:                         config.Recorder,
+		Clock:                            config.Clock,
 	}
 }
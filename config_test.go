@@ -1,11 +1,15 @@
 package quic
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"reflect"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 
@@ -30,6 +34,77 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on invalid values for MaxAckDelay", func() {
+			Expect(validateConfig(&Config{MaxAckDelay: -time.Millisecond})).To(MatchError("invalid value for Config.MaxAckDelay"))
+			Expect(validateConfig(&Config{MaxAckDelay: protocol.MaxMaxAckDelay})).To(MatchError("invalid value for Config.MaxAckDelay"))
+			Expect(validateConfig(&Config{MaxAckDelay: protocol.MaxMaxAckDelay - protocol.TimerGranularity})).To(Succeed())
+		})
+
+		It("errors on too large values for AckDelayExponent", func() {
+			Expect(validateConfig(&Config{AckDelayExponent: protocol.MaxAckDelayExponent})).To(Succeed())
+			Expect(validateConfig(&Config{AckDelayExponent: protocol.MaxAckDelayExponent + 1})).To(MatchError("invalid value for Config.AckDelayExponent"))
+		})
+
+		It("errors on negative values for DecryptionWorkers", func() {
+			Expect(validateConfig(&Config{DecryptionWorkers: 4})).To(Succeed())
+			Expect(validateConfig(&Config{DecryptionWorkers: -1})).To(MatchError("invalid value for Config.DecryptionWorkers"))
+		})
+
+		It("errors on invalid values for CorkDelay", func() {
+			Expect(validateConfig(&Config{CorkDelay: protocol.MaxCorkDelay})).To(Succeed())
+			Expect(validateConfig(&Config{CorkDelay: -time.Millisecond})).To(MatchError("invalid value for Config.CorkDelay"))
+			Expect(validateConfig(&Config{CorkDelay: protocol.MaxCorkDelay + 1})).To(MatchError("invalid value for Config.CorkDelay"))
+		})
+
+		It("errors on invalid values for ClientHelloSplitting", func() {
+			Expect(validateConfig(&Config{ClientHelloSplitting: &ClientHelloSplitting{Fragments: 2}})).To(Succeed())
+			Expect(validateConfig(&Config{ClientHelloSplitting: &ClientHelloSplitting{Fragments: 1}})).To(MatchError("invalid value for Config.ClientHelloSplitting"))
+			Expect(validateConfig(&Config{ClientHelloSplitting: &ClientHelloSplitting{Fragments: 33}})).To(MatchError("invalid value for Config.ClientHelloSplitting"))
+			Expect(validateConfig(&Config{ClientHelloSplitting: &ClientHelloSplitting{Fragments: 2, Filler: 42}})).To(MatchError("invalid value for Config.ClientHelloSplitting"))
+		})
+
+		It("errors on negative values for AckElicitingThreshold and MaxAckRanges", func() {
+			Expect(validateConfig(&Config{AckElicitingThreshold: -1})).To(MatchError("invalid value for Config.AckElicitingThreshold"))
+			Expect(validateConfig(&Config{MaxAckRanges: -1})).To(MatchError("invalid value for Config.MaxAckRanges"))
+		})
+
+		It("errors on invalid values for CongestionLossReductionFactor", func() {
+			Expect(validateConfig(&Config{CongestionLossReductionFactor: 0.5})).To(Succeed())
+			Expect(validateConfig(&Config{CongestionLossReductionFactor: -0.5})).To(MatchError("invalid value for Config.CongestionLossReductionFactor"))
+			Expect(validateConfig(&Config{CongestionLossReductionFactor: 1})).To(MatchError("invalid value for Config.CongestionLossReductionFactor"))
+		})
+
+		It("validates the congestion windows", func() {
+			Expect(validateConfig(&Config{InitialCongestionWindow: 100, MinCongestionWindow: 10, MaxCongestionWindow: 1000})).To(Succeed())
+			Expect(validateConfig(&Config{InitialCongestionWindow: 2})).To(Succeed())
+			Expect(validateConfig(&Config{MinCongestionWindow: 1})).To(MatchError("invalid value for Config.MinCongestionWindow"))
+			Expect(validateConfig(&Config{MaxCongestionWindow: protocol.MaxCongestionWindowPackets + 1})).To(MatchError("invalid value for Config.MaxCongestionWindow"))
+			Expect(validateConfig(&Config{MinCongestionWindow: 10, MaxCongestionWindow: 5})).To(MatchError("invalid value for Config.MaxCongestionWindow"))
+			// the default initial congestion window is larger than the configured maximum
+			Expect(validateConfig(&Config{MaxCongestionWindow: 10})).To(MatchError("invalid value for Config.InitialCongestionWindow"))
+			Expect(validateConfig(&Config{InitialCongestionWindow: 1})).To(MatchError("invalid value for Config.InitialCongestionWindow"))
+		})
+
+		It("errors when no tls.Config is given", func() {
+			Expect(validateTLSConfig(nil)).To(MatchError("quic: tls.Config not set"))
+		})
+
+		It("accepts a tls.Config without CipherSuites", func() {
+			Expect(validateTLSConfig(&tls.Config{})).To(Succeed())
+		})
+
+		It("accepts CipherSuites that contain a TLS 1.3 cipher suite", func() {
+			Expect(validateTLSConfig(&tls.Config{
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_CHACHA20_POLY1305_SHA256},
+			})).To(Succeed())
+		})
+
+		It("errors when CipherSuites doesn't contain any TLS 1.3 cipher suite", func() {
+			Expect(validateTLSConfig(&tls.Config{
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			})).To(MatchError("quic: tls.Config.CipherSuites doesn't contain any TLS 1.3 cipher suite"))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,7 +120,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter", "AllowConnectionWindowIncrease":
+			case "AcceptToken", "GetLogWriter", "AllowConnectionWindowIncrease", "Recorder", "ConnContext":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -75,12 +150,50 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(time.Second))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
+			case "EnableReliableStreamReset":
+				f.Set(reflect.ValueOf(true))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "MaxAckDelay":
+				f.Set(reflect.ValueOf(5 * time.Millisecond))
+			case "AckDelayExponent":
+				f.Set(reflect.ValueOf(uint8(7)))
+			case "AckElicitingThreshold":
+				f.Set(reflect.ValueOf(10))
+			case "DisableImmediateAckOnReordering":
+				f.Set(reflect.ValueOf(true))
+			case "MaxAckRanges":
+				f.Set(reflect.ValueOf(64))
+			case "InitialCongestionWindow":
+				f.Set(reflect.ValueOf(100))
+			case "MinCongestionWindow":
+				f.Set(reflect.ValueOf(4))
+			case "MaxCongestionWindow":
+				f.Set(reflect.ValueOf(1000))
+			case "CongestionLossReductionFactor":
+				f.Set(reflect.ValueOf(0.5))
+			case "EnableL4S":
+				f.Set(reflect.ValueOf(true))
+			case "EnableFlowLabels":
+				f.Set(reflect.ValueOf(true))
+			case "DecryptionWorkers":
+				f.Set(reflect.ValueOf(4))
+			case "KeyUpdateInterval":
+				f.Set(reflect.ValueOf(uint64(1000)))
+			case "ClientHelloSplitting":
+				f.Set(reflect.ValueOf(&ClientHelloSplitting{Fragments: 3, Reorder: true}))
+			case "CorkDelay":
+				f.Set(reflect.ValueOf(10 * time.Millisecond))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
+			case "FaultInjector":
+				f.Set(reflect.ValueOf(NewFaultInjector()))
+			case "StreamLeakDetector":
+				f.Set(reflect.ValueOf(&StreamLeakDetector{IdleTimeout: time.Minute}))
+			case "Clock":
+				f.Set(reflect.ValueOf(NewVirtualClock(time.Now())))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -100,16 +213,22 @@ var _ = Describe("Config", func() {
 
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAcceptToken, calledAllowConnectionWindowIncrease bool
+			var calledAcceptToken, calledAllowConnectionWindowIncrease, calledConnContext bool
 			c1 := &Config{
 				AcceptToken:                   func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
 				AllowConnectionWindowIncrease: func(Connection, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
+				ConnContext: func(ctx context.Context, _ *HandshakeInfo) context.Context {
+					calledConnContext = true
+					return ctx
+				},
 			}
 			c2 := c1.Clone()
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
 			Expect(calledAcceptToken).To(BeTrue())
 			c2.AllowConnectionWindowIncrease(nil, 1234)
 			Expect(calledAllowConnectionWindowIncrease).To(BeTrue())
+			c2.ConnContext(context.Background(), &HandshakeInfo{})
+			Expect(calledConnContext).To(BeTrue())
 		})
 
 		It("clones non-function fields", func() {
@@ -159,6 +278,51 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.MaxAckDelay).To(Equal(protocol.MaxAckDelay))
+			Expect(c.AckDelayExponent).To(BeEquivalentTo(protocol.AckDelayExponent))
+			Expect(c.AckElicitingThreshold).To(Equal(2))
+			Expect(c.DisableImmediateAckOnReordering).To(BeFalse())
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+			Expect(c.ackPolicy()).To(Equal(ackhandler.DefaultAckPolicy))
+			Expect(c.congestionConfig()).To(Equal(congestion.DefaultConfig))
+		})
+
+		It("derives the congestion controller configuration", func() {
+			c := populateConfig(&Config{
+				InitialCongestionWindow:       100,
+				MinCongestionWindow:           4,
+				MaxCongestionWindow:           1000,
+				CongestionLossReductionFactor: 0.5,
+			})
+			Expect(c.congestionConfig()).To(Equal(congestion.Config{
+				InitialWindowPackets: 100,
+				MinWindowPackets:     4,
+				MaxWindowPackets:     1000,
+				LossReductionFactor:  0.5,
+			}))
+		})
+
+		It("derives the ACK policy", func() {
+			c := populateConfig(&Config{
+				MaxAckDelay:                     time.Millisecond,
+				AckDelayExponent:                5,
+				AckElicitingThreshold:           10,
+				DisableImmediateAckOnReordering: true,
+				MaxAckRanges:                    100,
+			})
+			Expect(c.ackPolicy()).To(Equal(ackhandler.AckPolicy{
+				MaxAckDelay:              time.Millisecond,
+				AckDelayExponent:         5,
+				PacketsBeforeAck:         10,
+				ImmediateAckOnReordering: false,
+				MaxAckRanges:             100,
+			}))
+		})
+
+		It("limits the number of ACK ranges", func() {
+			c := populateConfig(&Config{MaxAckRanges: 100000})
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxMaxAckRanges))
+			Expect(c.ackPolicy().MaxAckRanges).To(Equal(protocol.MaxMaxAckRanges))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
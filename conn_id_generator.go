@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -23,6 +24,8 @@ type connIDGenerator struct {
 	replaceWithClosed      func(protocol.ConnectionID, packetHandler)
 	queueControlFrame      func(wire.Frame)
 
+	rand io.Reader // if nil, crypto/rand is used
+
 	version protocol.VersionNumber
 }
 
@@ -35,6 +38,7 @@ func newConnIDGenerator(
 	retireConnectionID func(protocol.ConnectionID),
 	replaceWithClosed func(protocol.ConnectionID, packetHandler),
 	queueControlFrame func(wire.Frame),
+	rand io.Reader,
 	version protocol.VersionNumber,
 ) *connIDGenerator {
 	m := &connIDGenerator{
@@ -46,6 +50,7 @@ func newConnIDGenerator(
 		retireConnectionID:     retireConnectionID,
 		replaceWithClosed:      replaceWithClosed,
 		queueControlFrame:      queueControlFrame,
+		rand:                   rand,
 		version:                version,
 	}
 	m.activeSrcConnIDs[0] = initialConnectionID
@@ -99,7 +104,13 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	var connID protocol.ConnectionID
+	var err error
+	if m.rand != nil {
+		connID, err = protocol.ReadConnectionID(m.rand, m.connIDLen)
+	} else {
+		connID, err = protocol.GenerateConnectionID(m.connIDLen)
+	}
 	if err != nil {
 		return err
 	}
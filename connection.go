@@ -75,6 +75,8 @@ type receivedPacket struct {
 	ecn protocol.ECN
 
 	info *packetInfo
+
+	decryption *decryptionJob // only set if the payload is decrypted by the parallelDecrypter
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
@@ -101,10 +103,11 @@ type connRunner interface {
 }
 
 type handshakeRunner struct {
-	onReceivedParams    func(*wire.TransportParameters)
-	onError             func(error)
-	dropKeys            func(protocol.EncryptionLevel)
-	onHandshakeComplete func()
+	onReceivedClientHello func(serverName string, supportedProtocols []string)
+	onReceivedParams      func(*wire.TransportParameters)
+	onError               func(error)
+	dropKeys              func(protocol.EncryptionLevel)
+	onHandshakeComplete   func()
 }
 
 func (r *handshakeRunner) OnReceivedParams(tp *wire.TransportParameters) { r.onReceivedParams(tp) }
@@ -112,6 +115,10 @@ func (r *handshakeRunner) OnError(e error)                               { r.onE
 func (r *handshakeRunner) DropKeys(el protocol.EncryptionLevel)          { r.dropKeys(el) }
 func (r *handshakeRunner) OnHandshakeComplete()                          { r.onHandshakeComplete() }
 
+func (r *handshakeRunner) OnReceivedClientHello(serverName string, supportedProtocols []string) {
+	r.onReceivedClientHello(serverName, supportedProtocols)
+}
+
 type closeError struct {
 	err       error
 	remote    bool
@@ -165,6 +172,7 @@ type connection struct {
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
 	unpacker      unpacker
+	decrypter     *parallelDecrypter // only set if Config.DecryptionWorkers is set
 	frameParser   wire.FrameParser
 	packer        packer
 	mtuDiscoverer mtuDiscoverer // initialized when the handshake completes
@@ -174,6 +182,8 @@ type connection struct {
 
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
+	// used to dequeue multiple packets from the receivedPackets channel, without allocating
+	receivedPacketsBatch []*receivedPacket
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
@@ -184,6 +194,10 @@ type connection struct {
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
 
+	// connCtx is the context returned by Context.
+	// It is set when the connection is created, and derived from ctx.
+	connCtx context.Context
+
 	undecryptablePackets          []*receivedPacket // undecryptable packets, waiting for a change in encryption level
 	undecryptablePacketsToProcess []*receivedPacket
 
@@ -217,6 +231,25 @@ type connection struct {
 
 	datagramQueue *datagramQueue
 
+	// set to 1 if both endpoints support RESET_STREAM_AT, to be accessed atomically
+	reliableResetSupported uint32
+
+	faultInjector faultInjector       // only set if a FaultInjector is configured
+	leakDetector  *streamLeakDetector // only set if a StreamLeakDetector is configured
+
+	clock utils.Clock
+	// appClock is the clock configured in the Config, or the system clock.
+	// Unlike clock, it is neither recorded nor replayed, and can be read outside of the run loop.
+	// It is used for the timers set by the application, e.g. for expiring streams and DATAGRAM frames.
+	appClock utils.Clock
+	rand     io.Reader // the source of randomness, if nil, crypto/rand is used
+
+	recorder *connRecorder // only set if the connection is recorded
+	replayer *connReplayer // only set if a recorded connection is replayed
+
+	ecn       protocol.ECN // the ECN codepoint that outgoing packets are marked with
+	flowLabel uint32       // the IPv6 flow label of outgoing packets, 0 if no flow label is set
+
 	logID  string
 	tracer logging.ConnectionTracer
 	logger utils.Logger
@@ -265,11 +298,26 @@ var newConnection = func(
 	} else {
 		s.logID = destConnID.String()
 	}
+	runner, tlsConf = s.setupRecording(runner, tlsConf, &recordingHeader{
+		perspective:         protocol.PerspectiveServer,
+		version:             v,
+		localAddr:           conn.LocalAddr(),
+		remoteAddr:          conn.RemoteAddr(),
+		origDestConnID:      origDestConnID,
+		retrySrcConnID:      retrySrcConnID,
+		clientDestConnID:    clientDestConnID,
+		destConnID:          destConnID,
+		srcConnID:           srcConnID,
+		statelessResetToken: statelessResetToken,
+		enable0RTT:          enable0RTT,
+		tracingID:           tracingID,
+	})
 	s.connIDManager = newConnIDManager(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
+		s.rand,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -280,15 +328,27 @@ var newConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		s.rand,
 		s.version,
 	)
 	s.preSetup()
 	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
+	// The ClientHello hasn't been processed yet, so only the addresses are known.
+	s.setConnContext(&HandshakeInfo{LocalAddr: conn.LocalAddr(), RemoteAddr: conn.RemoteAddr()})
+	s.faultInjector = s.newFaultInjector(tracingID)
+	if s.config.StreamLeakDetector != nil {
+		s.leakDetector = newStreamLeakDetector(s.config.StreamLeakDetector, tracingID)
+	}
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.clock,
+		s.rand,
+		s.config.ackPolicy(),
+		s.config.congestionConfig(),
+		s.ecn,
 		s.tracer,
 		s.logger,
 		s.version,
@@ -303,8 +363,8 @@ var newConnection = func(
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:                protocol.AckDelayExponent,
+		MaxAckDelay:                     s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:                s.config.AckDelayExponent,
 		DisableActiveMigration:          true,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
@@ -315,6 +375,7 @@ var newConnection = func(
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	params.EnableResetStreamAt = s.config.EnableReliableStreamReset
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -326,6 +387,14 @@ var newConnection = func(
 		conn.RemoteAddr(),
 		params,
 		&handshakeRunner{
+			onReceivedClientHello: func(serverName string, supportedProtocols []string) {
+				s.startedHandshake(&HandshakeInfo{
+					LocalAddr:          s.conn.LocalAddr(),
+					RemoteAddr:         s.conn.RemoteAddr(),
+					ServerName:         serverName,
+					SupportedProtocols: supportedProtocols,
+				})
+			},
 			onReceivedParams: s.handleTransportParameters,
 			onError:          s.closeLocal,
 			dropKeys:         s.dropEncryptionLevel,
@@ -337,6 +406,7 @@ var newConnection = func(
 		tlsConf,
 		enable0RTT,
 		s.rttStats,
+		s.config.KeyUpdateInterval,
 		tracer,
 		logger,
 		s.version,
@@ -358,6 +428,9 @@ var newConnection = func(
 		s.version,
 	)
 	s.unpacker = newPacketUnpacker(cs, s.version)
+	if s.config.DecryptionWorkers > 0 && s.recorder == nil && s.replayer == nil {
+		s.decrypter = newParallelDecrypter(cs, s.config.DecryptionWorkers, s.srcConnIDLen, s.version)
+	}
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, s.oneRTTStream)
 	return s
 }
@@ -392,11 +465,25 @@ var newClientConnection = func(
 		versionNegotiated:     hasNegotiatedVersion,
 		version:               v,
 	}
+	runner, tlsConf = s.setupRecording(runner, tlsConf, &recordingHeader{
+		perspective:          protocol.PerspectiveClient,
+		version:              v,
+		localAddr:            conn.LocalAddr(),
+		remoteAddr:           conn.RemoteAddr(),
+		destConnID:           destConnID,
+		srcConnID:            srcConnID,
+		initialPacketNumber:  initialPacketNumber,
+		enable0RTT:           enable0RTT,
+		hasNegotiatedVersion: hasNegotiatedVersion,
+		serverName:           tlsConf.ServerName,
+		tracingID:            tracingID,
+	})
 	s.connIDManager = newConnIDManager(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
+		s.rand,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -407,20 +494,33 @@ var newClientConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		s.rand,
 		s.version,
 	)
 	s.preSetup()
 	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
+	s.faultInjector = s.newFaultInjector(tracingID)
+	if s.config.StreamLeakDetector != nil {
+		s.leakDetector = newStreamLeakDetector(s.config.StreamLeakDetector, tracingID)
+	}
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.clock,
+		s.rand,
+		s.config.ackPolicy(),
+		s.config.congestionConfig(),
+		s.ecn,
 		s.tracer,
 		s.logger,
 		s.version,
 	)
-	initialStream := newCryptoStream()
+	var initialStream cryptoStream = newCryptoStream()
+	if s.config.ClientHelloSplitting != nil {
+		initialStream = newClientHelloSplitter(initialStream, *s.config.ClientHelloSplitting, s.version)
+	}
 	handshakeStream := newCryptoStream()
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -430,8 +530,8 @@ var newClientConnection = func(
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:               protocol.AckDelayExponent,
+		MaxAckDelay:                    s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:               s.config.AckDelayExponent,
 		DisableActiveMigration:         true,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:      srcConnID,
@@ -439,6 +539,7 @@ var newClientConnection = func(
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	params.EnableResetStreamAt = s.config.EnableReliableStreamReset
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -458,6 +559,7 @@ var newClientConnection = func(
 		tlsConf,
 		enable0RTT,
 		s.rttStats,
+		s.config.KeyUpdateInterval,
 		tracer,
 		logger,
 		s.version,
@@ -466,6 +568,9 @@ var newClientConnection = func(
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, newCryptoStream())
 	s.unpacker = newPacketUnpacker(cs, s.version)
+	if s.config.DecryptionWorkers > 0 && s.recorder == nil && s.replayer == nil {
+		s.decrypter = newParallelDecrypter(cs, s.config.DecryptionWorkers, s.srcConnIDLen, s.version)
+	}
 	s.packer = newPacketPacker(
 		srcConnID,
 		s.connIDManager.Get,
@@ -486,18 +591,39 @@ var newClientConnection = func(
 	} else {
 		s.tokenStoreKey = conn.RemoteAddr().String()
 	}
-	if s.config.TokenStore != nil {
-		if token := s.config.TokenStore.Pop(s.tokenStoreKey); token != nil {
-			s.packer.SetToken(token.data)
-		}
+	if token := s.popToken(); token != nil {
+		s.packer.SetToken(token)
+	}
+	info := &HandshakeInfo{
+		LocalAddr:          s.conn.LocalAddr(),
+		RemoteAddr:         s.conn.RemoteAddr(),
+		ServerName:         tlsConf.ServerName,
+		SupportedProtocols: tlsConf.NextProtos,
 	}
+	s.setConnContext(info)
+	s.startedHandshake(info)
 	return s
 }
 
 func (s *connection) preSetup() {
 	s.sendQueue = newSendQueue(s.conn)
+	if s.recorder != nil {
+		s.sendQueue = &recordingSender{sender: s.sendQueue, recorder: s.recorder}
+	} else if s.replayer != nil {
+		s.sendQueue = newReplaySender(s.replayer)
+	}
+	if s.config.EnableL4S && s.replayer == nil {
+		if c, ok := s.conn.(ecnSetter); ok && c.SetECN(protocol.ECT1) {
+			s.ecn = protocol.ECT1
+		} else {
+			s.logger.Debugf("Not using L4S, since the ECN codepoint of outgoing packets can't be set.")
+		}
+	}
+	if s.config.EnableFlowLabels && s.replayer == nil {
+		s.rotateFlowLabel(logging.FlowLabelTriggerInitial)
+	}
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.config.EnableReliableStreamReset, s.version)
 	s.rttStats = &utils.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
@@ -518,6 +644,8 @@ func (s *connection) preSetup() {
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.CorkDelay,
+		s.appClock,
 		s.perspective,
 		s.version,
 	)
@@ -527,13 +655,13 @@ func (s *connection) preSetup() {
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
-	now := time.Now()
+	now := s.clock.Now()
 	s.lastPacketReceivedTime = now
 	s.creationTime = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 	if s.config.EnableDatagrams {
-		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
+		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.appClock, s.tracer, s.logger)
 	}
 }
 
@@ -541,28 +669,7 @@ func (s *connection) preSetup() {
 func (s *connection) run() error {
 	defer s.ctxCancel()
 
-	s.timer = utils.NewTimer()
-
-	go s.cryptoStreamHandler.RunHandshake()
-	go func() {
-		if err := s.sendQueue.Run(); err != nil {
-			s.destroyImpl(err)
-		}
-	}()
-
-	if s.perspective == protocol.PerspectiveClient {
-		select {
-		case zeroRTTParams := <-s.clientHelloWritten:
-			s.scheduleSending()
-			if zeroRTTParams != nil {
-				s.restoreTransportParameters(zeroRTTParams)
-				close(s.earlyConnReadyChan)
-			}
-		case closeErr := <-s.closeChan:
-			// put the close error back into the channel, so that the run loop can receive it
-			s.closeChan <- closeErr
-		}
-	}
+	s.startRunLoop()
 
 	var (
 		closeErr           closeError
@@ -576,6 +683,9 @@ runLoop:
 		case closeErr = <-s.closeChan:
 			break runLoop
 		case <-s.handshakeCompleteChan:
+			if s.recorder != nil {
+				s.recorder.RecordHandshakeComplete()
+			}
 			s.handleHandshakeComplete()
 		default:
 		}
@@ -584,6 +694,9 @@ runLoop:
 
 		var processedUndecryptablePacket bool
 		if len(s.undecryptablePacketsToProcess) > 0 {
+			if s.recorder != nil {
+				s.recorder.RecordUndecryptablePackets()
+			}
 			queue := s.undecryptablePacketsToProcess
 			s.undecryptablePacketsToProcess = nil
 			for _, p := range queue {
@@ -605,13 +718,25 @@ runLoop:
 				break runLoop
 			case <-s.timer.Chan():
 				s.timer.SetRead()
+				if s.recorder != nil {
+					s.recorder.RecordWakeup(wakeupTimer)
+				}
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-s.sendingScheduled:
+				if s.recorder != nil {
+					s.recorder.RecordWakeup(wakeupSendingScheduled)
+				}
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-sendQueueAvailable:
+				if s.recorder != nil {
+					s.recorder.RecordWakeup(wakeupSendQueueAvailable)
+				}
 			case firstPacket := <-s.receivedPackets:
+				if s.recorder != nil {
+					s.recorder.RecordPacket(firstPacket)
+				}
 				wasProcessed := s.handlePacketImpl(firstPacket)
 				// Don't set timers and send packets if the packet made us close the connection.
 				select {
@@ -623,21 +748,19 @@ runLoop:
 					// Now process all packets in the receivedPackets channel.
 					// Limit the number of packets to the length of the receivedPackets channel,
 					// so we eventually get a chance to send out an ACK when receiving a lot of packets.
-					numPackets := len(s.receivedPackets)
-				receiveLoop:
-					for i := 0; i < numPackets; i++ {
+					packets := s.dequeueReceivedPackets(len(s.receivedPackets))
+					for i, p := range packets {
+						if s.recorder != nil {
+							s.recorder.RecordPacket(p)
+						}
+						if processed := s.handlePacketImpl(p); processed {
+							wasProcessed = true
+						}
 						select {
-						case p := <-s.receivedPackets:
-							if processed := s.handlePacketImpl(p); processed {
-								wasProcessed = true
-							}
-							select {
-							case closeErr = <-s.closeChan:
-								break runLoop
-							default:
-							}
+						case closeErr = <-s.closeChan:
+							s.discardReceivedPackets(packets[i+1:])
+							break runLoop
 						default:
-							break receiveLoop
 						}
 					}
 				}
@@ -648,52 +771,142 @@ runLoop:
 					continue
 				}
 			case <-s.handshakeCompleteChan:
+				if s.recorder != nil {
+					s.recorder.RecordHandshakeComplete()
+				}
 				s.handleHandshakeComplete()
 			}
 		}
 
-		now := time.Now()
-		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
-			// This could cause packets to be retransmitted.
-			// Check it before trying to send packets.
-			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
-				s.closeLocal(err)
+		if s.recorder != nil {
+			s.recorder.RecordTimersAndSend()
+		}
+		if c, ok := s.handleTimersAndSendPackets(); ok {
+			sendQueueAvailable = c
+		}
+	}
+
+	if s.recorder != nil {
+		s.recorder.RecordClose(closeErr)
+	}
+	return s.finishRunLoop(closeErr)
+}
+
+// startRunLoop starts the Go routines needed by the run loop.
+// For the client, it blocks until the ClientHello has been written.
+func (s *connection) startRunLoop() {
+	if clock, ok := s.clock.(*utils.VirtualClock); ok {
+		s.timer = clock.NewTimer()
+	} else {
+		s.timer = utils.NewTimer()
+	}
+
+	go s.cryptoStreamHandler.RunHandshake()
+	go func() {
+		if err := s.sendQueue.Run(); err != nil {
+			s.destroyImpl(err)
+		}
+	}()
+
+	if s.perspective == protocol.PerspectiveClient {
+		select {
+		case zeroRTTParams := <-s.clientHelloWritten:
+			s.scheduleSending()
+			if zeroRTTParams != nil {
+				s.restoreTransportParameters(zeroRTTParams)
+				close(s.earlyConnReadyChan)
 			}
+		case closeErr := <-s.closeChan:
+			// put the close error back into the channel, so that the run loop can receive it
+			s.closeChan <- closeErr
 		}
+	}
+}
 
-		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
-			// send a PING frame since there is no activity in the connection
-			s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
-			s.framer.QueueControlFrame(&wire.PingFrame{})
-			s.keepAlivePingSent = true
-		} else if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
-			s.destroyImpl(qerr.ErrHandshakeTimeout)
-			continue
-		} else {
-			idleTimeoutStartTime := s.idleTimeoutStartTime()
-			if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
-				(s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.idleTimeout) {
-				s.destroyImpl(qerr.ErrIdleTimeout)
-				continue
+// dequeueReceivedPackets takes up to max packets from the receivedPackets channel.
+// If packets are decrypted in parallel, decryption of all packets is started before the first packet is processed.
+func (s *connection) dequeueReceivedPackets(max int) []*receivedPacket {
+	packets := s.receivedPacketsBatch[:0]
+loop:
+	for i := 0; i < max; i++ {
+		select {
+		case p := <-s.receivedPackets:
+			if s.decrypter != nil {
+				s.decrypter.Prepare(p)
 			}
+			packets = append(packets, p)
+		default:
+			break loop
 		}
+	}
+	s.receivedPacketsBatch = packets
+	return packets
+}
 
-		if s.sendQueue.WouldBlock() {
-			// The send queue is still busy sending out packets.
-			// Wait until there's space to enqueue new packets.
-			sendQueueAvailable = s.sendQueue.Available()
-			continue
+// discardReceivedPackets releases packets that were dequeued, but won't be handled since the connection is closing.
+func (s *connection) discardReceivedPackets(packets []*receivedPacket) {
+	for _, p := range packets {
+		if p.decryption != nil {
+			// The worker might still be accessing the packet.
+			p.decryption.Wait()
+			p.decryption.Release()
+			p.decryption = nil
 		}
-		if err := s.sendPackets(); err != nil {
+		p.buffer.Release()
+	}
+}
+
+// handleTimersAndSendPackets is called after every iteration of the run loop.
+// It handles the expiration of the timers, and sends packets.
+// If the send queue is blocked, it returns a channel that is notified when the send queue is available again.
+// It returns false if the connection was destroyed.
+func (s *connection) handleTimersAndSendPackets() (sendQueueAvailable <-chan struct{}, ok bool) {
+	now := s.clock.Now()
+	if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
+		// This could cause packets to be retransmitted.
+		// Check it before trying to send packets.
+		if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
 			s.closeLocal(err)
 		}
-		if s.sendQueue.WouldBlock() {
-			sendQueueAvailable = s.sendQueue.Available()
-		} else {
-			sendQueueAvailable = nil
+		s.maybeRepath()
+	}
+
+	if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
+		// send a PING frame since there is no activity in the connection
+		s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
+		s.framer.QueueControlFrame(&wire.PingFrame{})
+		s.keepAlivePingSent = true
+	} else if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
+		s.destroyImpl(qerr.ErrHandshakeTimeout)
+		return nil, false
+	} else {
+		idleTimeoutStartTime := s.idleTimeoutStartTime()
+		if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
+			(s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.idleTimeout) {
+			s.destroyImpl(qerr.ErrIdleTimeout)
+			return nil, false
 		}
 	}
 
+	if s.faultInjector != nil {
+		s.sendFaultInjectorPackets()
+	}
+	if s.sendQueue.WouldBlock() {
+		// The send queue is still busy sending out packets.
+		// Wait until there's space to enqueue new packets.
+		return s.sendQueue.Available(), true
+	}
+	if err := s.sendPackets(); err != nil {
+		s.closeLocal(err)
+	}
+	if s.sendQueue.WouldBlock() {
+		return s.sendQueue.Available(), true
+	}
+	return nil, true
+}
+
+// finishRunLoop is called when the run loop returns.
+func (s *connection) finishRunLoop(closeErr closeError) error {
 	s.handleCloseError(&closeErr)
 	if e := (&errCloseForRecreating{}); !errors.As(closeErr.err, &e) && s.tracer != nil {
 		s.tracer.Close()
@@ -701,7 +914,19 @@ runLoop:
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close()
+	if s.faultInjector != nil {
+		s.faultInjector.Close()
+	}
+	if s.decrypter != nil {
+		s.decrypter.Close()
+	}
 	s.timer.Stop()
+	if s.flowLabel != 0 {
+		s.conn.(flowLabelSetter).ReleaseFlowLabel()
+	}
+	if s.recorder != nil {
+		s.recorder.Close()
+	}
 	return closeErr.err
 }
 
@@ -715,7 +940,31 @@ func (s *connection) HandshakeComplete() context.Context {
 }
 
 func (s *connection) Context() context.Context {
-	return s.ctx
+	return s.connCtx
+}
+
+// setConnContext sets the context of the connection, using Config.ConnContext if set.
+// It must be called when the connection is created.
+// If ConnContext returns nil, the connection is closed.
+func (s *connection) setConnContext(info *HandshakeInfo) {
+	s.connCtx = s.ctx
+	if s.config.ConnContext == nil {
+		return
+	}
+	ctx := s.config.ConnContext(s.ctx, info)
+	if ctx == nil {
+		s.closeLocal(errors.New("ConnContext returned nil"))
+		return
+	}
+	s.connCtx = ctx
+}
+
+// startedHandshake is called once the handshake information is known.
+// For the client, this is the case when the connection is created, for the server when the ClientHello is received.
+func (s *connection) startedHandshake(info *HandshakeInfo) {
+	if s.tracer != nil {
+		s.tracer.StartedHandshake(s.connCtx, info)
+	}
 }
 
 func (s *connection) supportsDatagrams() bool {
@@ -798,7 +1047,7 @@ func (s *connection) handleHandshakeComplete() {
 			s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
 		}
 	}
-	token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+	token, err := s.newToken()
 	if err != nil {
 		s.closeLocal(err)
 	}
@@ -819,6 +1068,7 @@ func (s *connection) handleHandshakeConfirmed() {
 		maxPacketSize = utils.MinByteCount(maxPacketSize, protocol.MaxPacketBufferSize)
 		s.mtuDiscoverer = newMTUDiscoverer(
 			s.rttStats,
+			s.clock,
 			getMaxPacketSize(s.conn.RemoteAddr()),
 			maxPacketSize,
 			func(size protocol.ByteCount) {
@@ -832,6 +1082,15 @@ func (s *connection) handleHandshakeConfirmed() {
 func (s *connection) handlePacketImpl(rp *receivedPacket) bool {
 	s.sentPacketHandler.ReceivedBytes(rp.Size())
 
+	if rp.decryption != nil {
+		// The packet is being unpacked by a decryption worker.
+		// The decrypted payload is valid until the packet was handled.
+		rp.decryption.Wait()
+		defer func() {
+			rp.decryption.Release()
+			rp.decryption = nil
+		}()
+	}
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
 		return false
@@ -928,7 +1187,13 @@ func (s *connection) handleSinglePacket(p *receivedPacket, hdr *wire.Header) boo
 		return false
 	}
 
-	packet, err := s.unpacker.Unpack(hdr, p.rcvTime, p.data)
+	var packet *unpackedPacket
+	var err error
+	if p.decryption != nil {
+		packet, err = s.decrypter.Unpack(p.decryption, p.rcvTime, p.data)
+	} else {
+		packet, err = s.unpacker.Unpack(hdr, p.rcvTime, p.data)
+	}
 	if err != nil {
 		switch err {
 		case handshake.ErrKeysDropped:
@@ -1254,6 +1519,18 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 
 // handlePacket is called by the server with a new packet
 func (s *connection) handlePacket(p *receivedPacket) {
+	if clock, ok := s.clock.(*utils.VirtualClock); ok {
+		// the packet was timestamped using the system clock
+		p.rcvTime = clock.Now()
+	}
+	if s.faultInjector != nil {
+		s.faultInjector.HandleIncoming(p)
+		return
+	}
+	s.queueReceivedPacket(p)
+}
+
+func (s *connection) queueReceivedPacket(p *receivedPacket) {
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxConnUnprocessedPackets
 	select {
@@ -1397,6 +1674,11 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 	if err != nil {
 		return err
 	}
+	if s.ecn != protocol.ECNNon && s.sentPacketHandler.ECNMode() == protocol.ECNNon {
+		s.conn.(ecnSetter).SetECN(protocol.ECNNon)
+		s.ecn = protocol.ECNNon
+	}
+	s.maybeRepath()
 	if !acked1RTTPacket {
 		return nil
 	}
@@ -1406,6 +1688,35 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 	return s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
 }
 
+// maybeRepath changes the flow label if the sent packet handler detected that the path is congested or failing,
+// such that the network (hopefully) routes the packets of this connection via a different path.
+func (s *connection) maybeRepath() {
+	if s.flowLabel == 0 {
+		return
+	}
+	if repath, trigger := s.sentPacketHandler.PopRepath(); repath {
+		s.rotateFlowLabel(trigger)
+	}
+}
+
+func (s *connection) rotateFlowLabel(trigger logging.FlowLabelTrigger) {
+	c, ok := s.conn.(flowLabelSetter)
+	if !ok {
+		s.logger.Debugf("Not using flow labels, since the flow label of outgoing packets can't be set.")
+		return
+	}
+	label, err := c.RotateFlowLabel()
+	if err != nil {
+		s.logger.Debugf("Setting the flow label failed: %s", err)
+		return
+	}
+	s.logger.Debugf("Using flow label %#x.", label)
+	s.flowLabel = label
+	if s.tracer != nil {
+		s.tracer.UpdatedFlowLabel(label, trigger)
+	}
+}
+
 func (s *connection) handleDatagramFrame(f *wire.DatagramFrame) error {
 	if f.Length(s.version) > protocol.MaxDatagramFrameSize {
 		return &qerr.TransportError{
@@ -1502,6 +1813,9 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	}
 
 	s.streamsMap.CloseWithError(e)
+	if s.leakDetector != nil {
+		s.leakDetector.close()
+	}
 	s.connIDManager.Close()
 	if s.datagramQueue != nil {
 		s.datagramQueue.CloseWithError(e)
@@ -1620,6 +1934,9 @@ func (s *connection) applyTransportParameters() {
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
 	s.keepAliveInterval = utils.MinDuration(s.config.KeepAlivePeriod, utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval))
 	s.streamsMap.UpdateLimits(params)
+	if s.config.EnableReliableStreamReset && params.EnableResetStreamAt {
+		atomic.StoreUint32(&s.reliableResetSupported, 1)
+	}
 	s.packer.HandleTransportParameters(params)
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
@@ -1691,7 +2008,7 @@ func (s *connection) sendPackets() error {
 			return fmt.Errorf("BUG: invalid send mode %d", sendMode)
 		}
 		// Prioritize receiving of packets over sending out more packets.
-		if len(s.receivedPackets) > 0 {
+		if s.hasQueuedPackets() {
 			s.pacingDeadline = deadlineSendImmediately
 			return nil
 		}
@@ -1709,7 +2026,7 @@ func (s *connection) maybeSendAckOnlyPacket() error {
 	if packet == nil {
 		return nil
 	}
-	s.sendPackedPacket(packet, time.Now())
+	s.sendPackedPacket(packet, s.clock.Now())
 	return nil
 }
 
@@ -1751,7 +2068,7 @@ func (s *connection) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 	if packet == nil || packet.packetContents == nil {
 		return fmt.Errorf("connection BUG: couldn't pack %s probe packet", encLevel)
 	}
-	s.sendPackedPacket(packet, time.Now())
+	s.sendPackedPacket(packet, s.clock.Now())
 	return nil
 }
 
@@ -1761,7 +2078,7 @@ func (s *connection) sendPacket() (bool, error) {
 	}
 	s.windowUpdateQueue.QueueAll()
 
-	now := time.Now()
+	now := s.clock.Now()
 	if !s.handshakeConfirmed {
 		packet, err := s.packer.PackCoalescedPacket()
 		if err != nil || packet == nil {
@@ -1776,7 +2093,7 @@ func (s *connection) sendPacket() (bool, error) {
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
 		}
 		s.connIDManager.SentPacket()
-		s.sendQueue.Send(packet.buffer)
+		s.sendPacketBuffer(packet.buffer, packet.packets)
 		return true, nil
 	}
 	if !s.config.DisablePathMTUDiscovery && s.mtuDiscoverer.ShouldSendProbe(now) {
@@ -1802,7 +2119,28 @@ func (s *connection) sendPackedPacket(packet *packedPacket, now time.Time) {
 	s.logPacket(packet)
 	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
 	s.connIDManager.SentPacket()
-	s.sendQueue.Send(packet.buffer)
+	s.sendPacketBuffer(packet.buffer, []*packetContents{packet.packetContents})
+}
+
+func (s *connection) sendPacketBuffer(buf *packetBuffer, packets []*packetContents) {
+	if s.faultInjector != nil {
+		s.faultInjector.HandleOutgoing(buf, packets)
+		s.sendFaultInjectorPackets()
+		return
+	}
+	s.sendQueue.Send(buf)
+}
+
+// sendFaultInjectorPackets hands the packets released by the fault injector to the send queue,
+// until the send queue is full.
+func (s *connection) sendFaultInjectorPackets() {
+	for !s.sendQueue.WouldBlock() {
+		buf := s.faultInjector.NextOutgoing()
+		if buf == nil {
+			return
+		}
+		s.sendQueue.Send(buf)
+	}
 }
 
 func (s *connection) sendConnectionClose(e error) ([]byte, error) {
@@ -1872,28 +2210,52 @@ func (s *connection) logPacket(packet *packedPacket) {
 
 // AcceptStream returns the next stream openend by the peer
 func (s *connection) AcceptStream(ctx context.Context) (Stream, error) {
-	return s.streamsMap.AcceptStream(ctx)
+	str, err := s.streamsMap.AcceptStream(ctx)
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackStream(str, true), nil
 }
 
 func (s *connection) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
-	return s.streamsMap.AcceptUniStream(ctx)
+	str, err := s.streamsMap.AcceptUniStream(ctx)
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackReceiveStream(str), nil
 }
 
 // OpenStream opens a stream
 func (s *connection) OpenStream() (Stream, error) {
-	return s.streamsMap.OpenStream()
+	str, err := s.streamsMap.OpenStream()
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackStream(str, false), nil
 }
 
 func (s *connection) OpenStreamSync(ctx context.Context) (Stream, error) {
-	return s.streamsMap.OpenStreamSync(ctx)
+	str, err := s.streamsMap.OpenStreamSync(ctx)
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackStream(str, false), nil
 }
 
 func (s *connection) OpenUniStream() (SendStream, error) {
-	return s.streamsMap.OpenUniStream()
+	str, err := s.streamsMap.OpenUniStream()
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackSendStream(str), nil
 }
 
 func (s *connection) OpenUniStreamSync(ctx context.Context) (SendStream, error) {
-	return s.streamsMap.OpenUniStreamSync(ctx)
+	str, err := s.streamsMap.OpenUniStreamSync(ctx)
+	if err != nil || s.leakDetector == nil {
+		return str, err
+	}
+	return s.leakDetector.trackSendStream(str), nil
 }
 
 func (s *connection) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
@@ -1963,6 +2325,10 @@ func (s *connection) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *connection) supportsReliableReset() bool {
+	return atomic.LoadUint32(&s.reliableResetSupported) == 1
+}
+
 func (s *connection) onStreamCompleted(id protocol.StreamID) {
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
@@ -1970,13 +2336,17 @@ func (s *connection) onStreamCompleted(id protocol.StreamID) {
 }
 
 func (s *connection) SendMessage(p []byte) error {
+	return s.SendMessageWithExpiry(p, time.Time{})
+}
+
+func (s *connection) SendMessageWithExpiry(p []byte, expiry time.Time) error {
 	f := &wire.DatagramFrame{DataLenPresent: true}
 	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
 		return errors.New("message too large")
 	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
-	return s.datagramQueue.AddAndWait(f)
+	return s.datagramQueue.AddAndWait(f, expiry)
 }
 
 func (s *connection) ReceiveMessage() ([]byte, error) {
@@ -43,6 +43,30 @@ func areClosedConnsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*closedLocalConn).run")
 }
 
+type ecnSendConn struct {
+	*MockSendConn
+	ecn protocol.ECN
+}
+
+func (c *ecnSendConn) SetECN(ecn protocol.ECN) bool {
+	c.ecn = ecn
+	return true
+}
+
+type flowLabelSendConn struct {
+	*MockSendConn
+	labels   []uint32
+	released bool
+}
+
+func (c *flowLabelSendConn) RotateFlowLabel() (uint32, error) {
+	label := c.labels[0]
+	c.labels = c.labels[1:]
+	return label, nil
+}
+
+func (c *flowLabelSendConn) ReleaseFlowLabel() { c.released = true }
+
 var _ = Describe("Connection", func() {
 	var (
 		conn          *connection
@@ -94,6 +118,7 @@ var _ = Describe("Connection", func() {
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+		tracer.EXPECT().UpdatedCongestionParameters(gomock.Any())
 		conn = newConnection(
 			mconn,
 			connRunner,
@@ -169,6 +194,44 @@ var _ = Describe("Connection", func() {
 				err := conn.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("stops marking packets when ECN validation fails", func() {
+				c := &ecnSendConn{MockSendConn: mconn, ecn: protocol.ECT1}
+				conn.conn = c
+				conn.ecn = protocol.ECT1
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().ECNMode().Return(protocol.ECT1)
+				conn.sentPacketHandler = sph
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(c.ecn).To(Equal(protocol.ECT1))
+
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().ECNMode().Return(protocol.ECNNon)
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(c.ecn).To(Equal(protocol.ECNNon))
+				Expect(conn.ecn).To(Equal(protocol.ECNNon))
+			})
+
+			It("rotates the flow label when the path is congested", func() {
+				c := &flowLabelSendConn{MockSendConn: mconn, labels: []uint32{0x1234}}
+				conn.conn = c
+				conn.flowLabel = 0x42
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().PopRepath()
+				conn.sentPacketHandler = sph
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(conn.flowLabel).To(Equal(uint32(0x42)))
+
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().PopRepath().Return(true, logging.FlowLabelTriggerCongestion)
+				tracer.EXPECT().UpdatedFlowLabel(uint32(0x1234), logging.FlowLabelTriggerCongestion)
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(conn.flowLabel).To(Equal(uint32(0x1234)))
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -455,6 +518,26 @@ var _ = Describe("Connection", func() {
 			Expect(conn.Context().Done()).To(BeClosed())
 		})
 
+		It("releases the flow label", func() {
+			c := &flowLabelSendConn{MockSendConn: mconn}
+			conn.conn = c
+			conn.flowLabel = 0x42
+			conn.handshakeComplete = true
+			runConn()
+			streamManager.EXPECT().CloseWithError(&qerr.ApplicationError{})
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			buffer := getPacketBuffer()
+			buffer.Data = append(buffer.Data, []byte("connection close")...)
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: buffer}, nil)
+			mconn.EXPECT().Write([]byte("connection close"))
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			conn.shutdown()
+			Eventually(areConnsRunning).Should(BeFalse())
+			Expect(c.released).To(BeTrue())
+		})
+
 		It("only closes once", func() {
 			runConn()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -2194,6 +2277,31 @@ var _ = Describe("Connection", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("times out when the virtual clock is advanced", func() {
+			clock := NewVirtualClock(time.Now())
+			conn.clock = clock
+			conn.lastPacketReceivedTime = clock.Now()
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			done := make(chan struct{})
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+					Expect(e).To(MatchError(&qerr.IdleTimeoutError{}))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := conn.run()
+				Expect(err).To(MatchError(qerr.ErrIdleTimeout))
+				close(done)
+			}()
+			Consistently(done, scaleDuration(50*time.Millisecond)).ShouldNot(BeClosed())
+			clock.Advance(time.Hour)
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("times out due to non-completed handshake", func() {
 			conn.handshakeComplete = false
 			conn.creationTime = time.Now().Add(-protocol.DefaultHandshakeTimeout).Add(-time.Second)
@@ -2397,6 +2505,40 @@ var _ = Describe("Connection", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("tracks streams, if a StreamLeakDetector is configured", func() {
+			conn.leakDetector = newStreamLeakDetector(&StreamLeakDetector{}, 1234)
+			defer conn.leakDetector.close()
+			mstr := NewMockStreamI(mockCtrl)
+			mstr.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			streamManager.EXPECT().OpenStream().Return(mstr, nil)
+			str, err := conn.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeAssignableToTypeOf(&trackedStream{}))
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(4)))
+			msendStr := NewMockSendStreamI(mockCtrl)
+			msendStr.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
+			streamManager.EXPECT().OpenUniStream().Return(msendStr, nil)
+			sendStr, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sendStr).To(BeAssignableToTypeOf(&trackedSendStream{}))
+			mrecvStr := NewMockReceiveStreamI(mockCtrl)
+			mrecvStr.EXPECT().StreamID().Return(protocol.StreamID(3)).AnyTimes()
+			streamManager.EXPECT().AcceptUniStream(gomock.Any()).Return(mrecvStr, nil)
+			recvStr, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recvStr).To(BeAssignableToTypeOf(&trackedReceiveStream{}))
+		})
+
+		It("doesn't track streams, if opening fails", func() {
+			conn.leakDetector = newStreamLeakDetector(&StreamLeakDetector{}, 1234)
+			defer conn.leakDetector.close()
+			testErr := errors.New("test error")
+			streamManager.EXPECT().OpenStream().Return(nil, testErr)
+			str, err := conn.OpenStream()
+			Expect(err).To(MatchError(testErr))
+			Expect(str).To(BeNil())
+		})
 	})
 
 	It("returns the local address", func() {
@@ -2406,6 +2548,51 @@ var _ = Describe("Connection", func() {
 	It("returns the remote address", func() {
 		Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
 	})
+
+	It("uses the context returned by ConnContext", func() {
+		type ctxKey struct{}
+		var connCtxInfo *HandshakeInfo
+		conn.config.ConnContext = func(ctx context.Context, info *HandshakeInfo) context.Context {
+			Expect(ctx.Value(ConnectionTracingKey)).To(Equal(uint64(1234)))
+			connCtxInfo = info
+			return context.WithValue(ctx, ctxKey{}, "tenant")
+		}
+		conn.setConnContext(&HandshakeInfo{LocalAddr: localAddr, RemoteAddr: remoteAddr})
+		Expect(connCtxInfo).To(Equal(&HandshakeInfo{LocalAddr: localAddr, RemoteAddr: remoteAddr}))
+		ctx := conn.Context()
+		Expect(ctx.Value(ctxKey{})).To(Equal("tenant"))
+		Expect(ctx.Value(ConnectionTracingKey)).To(Equal(uint64(1234)))
+		// once the ClientHello is received, the tracer is passed the same context
+		info := &HandshakeInfo{
+			LocalAddr:          localAddr,
+			RemoteAddr:         remoteAddr,
+			ServerName:         "quic-go.net",
+			SupportedProtocols: []string{"proto"},
+		}
+		tracer.EXPECT().StartedHandshake(ctx, info)
+		conn.startedHandshake(info)
+		Expect(conn.Context()).To(BeIdenticalTo(ctx))
+		// the context is cancelled when the connection is closed
+		Expect(ctx.Done()).ToNot(BeClosed())
+		conn.ctxCancel()
+		Expect(ctx.Done()).To(BeClosed())
+	})
+
+	It("passes the handshake info to the tracer, if no ConnContext is set", func() {
+		info := &HandshakeInfo{ServerName: "quic-go.net"}
+		tracer.EXPECT().StartedHandshake(conn.ctx, info)
+		conn.startedHandshake(info)
+		Expect(conn.Context()).To(Equal(conn.ctx))
+	})
+
+	It("closes the connection if ConnContext returns nil", func() {
+		conn.config.ConnContext = func(context.Context, *HandshakeInfo) context.Context { return nil }
+		conn.setConnContext(&HandshakeInfo{})
+		Expect(conn.Context()).To(Equal(conn.ctx))
+		var closeErr closeError
+		Expect(conn.closeChan).To(Receive(&closeErr))
+		Expect(closeErr.err).To(MatchError("ConnContext returned nil"))
+	})
 })
 
 var _ = Describe("Client Connection", func() {
@@ -2458,6 +2645,8 @@ var _ = Describe("Client Connection", func() {
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+		tracer.EXPECT().UpdatedCongestionParameters(gomock.Any())
+		tracer.EXPECT().StartedHandshake(gomock.Any(), gomock.Any())
 		conn = newClientConnection(
 			mconn,
 			connRunner,
@@ -2588,6 +2777,27 @@ var _ = Describe("Client Connection", func() {
 		Eventually(areConnsRunning).Should(BeFalse())
 	})
 
+	Context("using a ConnContext", func() {
+		type ctxKey struct{}
+		var connCtxInfo *HandshakeInfo
+
+		BeforeEach(func() {
+			tlsConf = &tls.Config{ServerName: "quic-go.net", NextProtos: []string{"proto"}}
+			quicConf.ConnContext = func(ctx context.Context, info *HandshakeInfo) context.Context {
+				connCtxInfo = info
+				return context.WithValue(ctx, ctxKey{}, "tenant")
+			}
+		})
+
+		It("calls ConnContext when the connection is created", func() {
+			Expect(connCtxInfo).ToNot(BeNil())
+			Expect(connCtxInfo.ServerName).To(Equal("quic-go.net"))
+			Expect(connCtxInfo.SupportedProtocols).To(Equal([]string{"proto"}))
+			Expect(conn.Context().Value(ctxKey{})).To(Equal("tenant"))
+			Expect(conn.Context().Value(ConnectionTracingKey)).To(Equal(uint64(1234)))
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -1,62 +1,168 @@
 package quic
 
 import (
+	"sync"
+	"time"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
+type queuedDatagram struct {
+	frame  *wire.DatagramFrame
+	expiry time.Time  // zero if the datagram doesn't expire
+	done   chan error // cap: 1, receives the result once the datagram is dequeued
+}
+
 type datagramQueue struct {
-	sendQueue chan *wire.DatagramFrame
-	rcvQueue  chan []byte
+	// sendMutex makes dequeueing a datagram and signaling its waiter one atomic operation.
+	sendMutex sync.Mutex
+	sendQueue chan *queuedDatagram
+	// next is a datagram that was taken from the sendQueue, but didn't fit into the packet.
+	// It is sent in one of the next packets. Protected by the sendMutex.
+	next     *queuedDatagram
+	rcvQueue chan []byte
 
 	closeErr error
 	closed   chan struct{}
 
 	hasData func()
+	clock   utils.Clock // used for the expiry of DATAGRAM frames
 
-	dequeued chan struct{}
-
+	tracer logging.ConnectionTracer
 	logger utils.Logger
 }
 
-func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
+func newDatagramQueue(hasData func(), clock utils.Clock, tracer logging.ConnectionTracer, logger utils.Logger) *datagramQueue {
 	return &datagramQueue{
 		hasData:   hasData,
-		sendQueue: make(chan *wire.DatagramFrame, 1),
+		clock:     clock,
+		sendQueue: make(chan *queuedDatagram, 1),
 		rcvQueue:  make(chan []byte, protocol.DatagramRcvQueueLen),
-		dequeued:  make(chan struct{}),
 		closed:    make(chan struct{}),
+		tracer:    tracer,
 		logger:    logger,
 	}
 }
 
 // AddAndWait queues a new DATAGRAM frame for sending.
 // It blocks until the frame has been dequeued.
-func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
+// If expiry is non-zero, and the frame wasn't dequeued by then, it is dropped and ErrExpired is returned.
+func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame, expiry time.Time) error {
+	d := &queuedDatagram{frame: f, expiry: expiry, done: make(chan error, 1)}
+	var expired <-chan struct{}
+	if !expiry.IsZero() {
+		c := make(chan struct{})
+		timer := utils.AfterFunc(h.clock, expiry.Sub(h.clock.Now()), func() { close(c) })
+		defer timer.Stop()
+		expired = c
+	}
+
 	select {
-	case h.sendQueue <- f:
+	case h.sendQueue <- d:
+		if h.tracer != nil {
+			h.tracer.QueuedDatagram(protocol.ByteCount(len(f.Data)))
+		}
 		h.hasData()
+	case <-expired:
+		h.dropExpired(f)
+		return ErrExpired
 	case <-h.closed:
 		return h.closeErr
 	}
 
 	select {
-	case <-h.dequeued:
-		return nil
+	case err := <-d.done:
+		return err
+	case <-expired:
+		return h.remove(d)
 	case <-h.closed:
 		return h.closeErr
 	}
 }
 
-// Get dequeues a DATAGRAM frame for sending.
-func (h *datagramQueue) Get() *wire.DatagramFrame {
+// remove removes an expired datagram from the send queue,
+// unless it was dequeued in the meantime.
+func (h *datagramQueue) remove(d *queuedDatagram) error {
+	h.sendMutex.Lock()
+	defer h.sendMutex.Unlock()
+
 	select {
-	case f := <-h.sendQueue:
-		h.dequeued <- struct{}{}
-		return f
+	case err := <-d.done:
+		return err
 	default:
-		return nil
+	}
+	// Datagrams are only dequeued while holding the mutex, and their waiter is signaled right away.
+	// Since d wasn't signaled yet, it's either held back for the next packet,
+	// or it's still the (only) datagram in the send queue.
+	if h.next == d {
+		h.next = nil
+	} else {
+		<-h.sendQueue
+	}
+	h.dropExpired(d.frame)
+	return ErrExpired
+}
+
+// Get dequeues a DATAGRAM frame for sending.
+// A DATAGRAM frame that is larger than maxLen, but not larger than maxPacketLen,
+// the space available in a full-size packet, is kept in the queue and sent in one of the next packets.
+// A DATAGRAM frame that is larger than maxPacketLen is dropped.
+// Expired DATAGRAM frames are dropped as well.
+func (h *datagramQueue) Get(maxLen, maxPacketLen protocol.ByteCount, v protocol.VersionNumber) *wire.DatagramFrame {
+	h.sendMutex.Lock()
+	defer h.sendMutex.Unlock()
+
+	for {
+		d := h.next
+		h.next = nil
+		if d == nil {
+			select {
+			case d = <-h.sendQueue:
+			default:
+				return nil
+			}
+		}
+		f := d.frame
+		if !d.expiry.IsZero() && !h.clock.Now().Before(d.expiry) {
+			d.done <- ErrExpired
+			h.dropExpired(f)
+			continue
+		}
+		l := f.Length(v)
+		if l > maxLen && l <= maxPacketLen {
+			h.next = d
+			return nil
+		}
+		d.done <- nil
+		if l > maxPacketLen {
+			h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload), since it doesn't fit into the packet", len(f.Data))
+			if h.tracer != nil {
+				h.tracer.DroppedDatagram(protocol.ByteCount(len(f.Data)), logging.DatagramDropTooLarge)
+			}
+			return nil
+		}
+		if h.tracer != nil {
+			h.tracer.SentDatagram(protocol.ByteCount(len(f.Data)))
+		}
+		return f
+	}
+}
+
+func (h *datagramQueue) dropExpired(f *wire.DatagramFrame) {
+	h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload), since it expired", len(f.Data))
+	if h.tracer != nil {
+		h.tracer.DroppedDatagram(protocol.ByteCount(len(f.Data)), logging.DatagramDropExpired)
+	}
+}
+
+// OnLost is called when a packet containing a DATAGRAM frame is declared lost.
+// DATAGRAM frames are never retransmitted.
+func (h *datagramQueue) OnLost(f wire.Frame) {
+	if h.tracer != nil {
+		h.tracer.LostDatagram(protocol.ByteCount(len(f.(*wire.DatagramFrame).Data)))
 	}
 }
 
@@ -66,8 +172,14 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	copy(data, f.Data)
 	select {
 	case h.rcvQueue <- data:
+		if h.tracer != nil {
+			h.tracer.ReceivedDatagram(protocol.ByteCount(len(f.Data)))
+		}
 	default:
 		h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload)", len(f.Data))
+		if h.tracer != nil {
+			h.tracer.DroppedDatagram(protocol.ByteCount(len(f.Data)), logging.DatagramDropReceiveQueueFull)
+		}
 	}
 }
 
@@ -2,9 +2,14 @@ package quic
 
 import (
 	"errors"
+	"time"
 
+	"github.com/golang/mock/gomock"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,41 +18,163 @@ import (
 var _ = Describe("Datagram Queue", func() {
 	var queue *datagramQueue
 	var queued chan struct{}
+	var tracer *mocklogging.MockConnectionTracer
 
 	BeforeEach(func() {
 		queued = make(chan struct{}, 100)
+		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
 		queue = newDatagramQueue(func() {
 			queued <- struct{}{}
-		}, utils.DefaultLogger)
+		}, utils.DefaultClock{}, tracer, utils.DefaultLogger)
 	})
 
 	Context("sending", func() {
 		It("returns nil when there's no datagram to send", func() {
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
 		})
 
 		It("queues a datagram", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().SentDatagram(protocol.ByteCount(6))
 			done := make(chan struct{})
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})).To(Succeed())
+				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, time.Time{})).To(Succeed())
 			}()
 
 			Eventually(queued).Should(HaveLen(1))
 			Consistently(done).ShouldNot(BeClosed())
-			f := queue.Get()
+			f := queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)
 			Expect(f).ToNot(BeNil())
 			Expect(f.Data).To(Equal([]byte("foobar")))
 			Eventually(done).Should(BeClosed())
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+		})
+
+		It("drops datagrams that don't fit into a full-size packet", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropTooLarge)
+			f := &wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.AddAndWait(f, time.Time{})).To(Succeed())
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Expect(queue.Get(f.Length(protocol.VersionTLS)-1, f.Length(protocol.VersionTLS)-1, protocol.VersionTLS)).To(BeNil())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("keeps datagrams that don't fit into the current packet, but into a full-size packet", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().SentDatagram(protocol.ByteCount(6))
+			f := &wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.AddAndWait(f, time.Time{})).To(Succeed())
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Expect(queue.Get(f.Length(protocol.VersionTLS)-1, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(queue.Get(f.Length(protocol.VersionTLS), protocol.MaxByteCount, protocol.VersionTLS)).To(Equal(f))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("drops datagrams that expire while they're kept for the next packet", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropExpired)
+			f := &wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")}
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(f, time.Now().Add(scaleDuration(20*time.Millisecond)))
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Expect(queue.Get(f.Length(protocol.VersionTLS)-1, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+			Eventually(errChan).Should(Receive(MatchError(ErrExpired)))
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+		})
+
+		It("drops datagrams that expired before they were dequeued", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropExpired)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, time.Now().Add(scaleDuration(20*time.Millisecond)))
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Eventually(errChan).Should(Receive(MatchError(ErrExpired)))
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+		})
+
+		It("skips expired datagrams when dequeueing", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropExpired)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, time.Now().Add(-time.Second))
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+			Eventually(errChan).Should(Receive(MatchError(ErrExpired)))
+		})
+
+		It("sends datagrams that are dequeued before they expire", func() {
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().SentDatagram(protocol.ByteCount(6))
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, time.Now().Add(time.Hour))
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			f := queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Eventually(errChan).Should(Receive(BeNil()))
+		})
+
+		It("uses the clock of the connection", func() {
+			clock := utils.NewVirtualClock(time.Now())
+			queue.clock = clock
+			tracer.EXPECT().QueuedDatagram(protocol.ByteCount(6))
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropExpired)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, clock.Now().Add(time.Hour))
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			Consistently(errChan, scaleDuration(20*time.Millisecond)).ShouldNot(Receive())
+			clock.Advance(time.Hour)
+			Expect(queue.Get(protocol.MaxByteCount, protocol.MaxByteCount, protocol.VersionTLS)).To(BeNil())
+			Eventually(errChan).Should(Receive(MatchError(ErrExpired)))
+		})
+
+		It("traces lost datagrams", func() {
+			tracer.EXPECT().LostDatagram(protocol.ByteCount(6))
+			queue.OnLost(&wire.DatagramFrame{Data: []byte("foobar")})
 		})
 
 		It("closes", func() {
+			tracer.EXPECT().QueuedDatagram(gomock.Any())
 			errChan := make(chan error, 1)
 			go func() {
 				defer GinkgoRecover()
-				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})
+				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")}, time.Time{})
 			}()
 
 			Consistently(errChan).ShouldNot(Receive())
@@ -58,6 +185,7 @@ var _ = Describe("Datagram Queue", func() {
 
 	Context("receiving", func() {
 		It("receives DATAGRAM frames", func() {
+			tracer.EXPECT().ReceivedDatagram(protocol.ByteCount(3)).Times(2)
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
 			data, err := queue.Receive()
@@ -78,10 +206,23 @@ var _ = Describe("Datagram Queue", func() {
 			}()
 
 			Consistently(c).ShouldNot(Receive())
+			tracer.EXPECT().ReceivedDatagram(protocol.ByteCount(6))
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
 			Eventually(c).Should(Receive(Equal([]byte("foobar"))))
 		})
 
+		It("drops DATAGRAM frames when the receive queue is full", func() {
+			tracer.EXPECT().ReceivedDatagram(protocol.ByteCount(3)).Times(protocol.DatagramRcvQueueLen)
+			for i := 0; i < protocol.DatagramRcvQueueLen; i++ {
+				queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			}
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6), logging.DatagramDropReceiveQueueFull)
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			data, err := queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+		})
+
 		It("closes", func() {
 			errChan := make(chan error, 1)
 			go func() {
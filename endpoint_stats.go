@@ -0,0 +1,48 @@
+package quic
+
+import (
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// The endpointCounters are used to generate the EndpointStats.
+// They are updated atomically, and are therefore cheap enough to always be maintained.
+// The struct must be allocated separately, such that the counters are 64-bit aligned.
+type endpointCounters struct {
+	packetsReceived        uint64
+	packetsDropped         [256]uint64 // indexed by the logging.PacketDropReason
+	handshakesStarted      uint64
+	handshakesCompleted    uint64
+	handshakesFailed       uint64
+	retriesSent            uint64
+	versionNegotiationSent uint64
+	statelessResetsSent    uint64
+	connections            int64
+}
+
+func (c *endpointCounters) droppedPacket(reason logging.PacketDropReason) {
+	atomic.AddUint64(&c.packetsDropped[reason], 1)
+}
+
+// addTo adds the current value of the counters to the stats.
+func (c *endpointCounters) addTo(s *EndpointStats) {
+	s.PacketsReceived += atomic.LoadUint64(&c.packetsReceived)
+	for reason := range c.packetsDropped {
+		n := atomic.LoadUint64(&c.packetsDropped[reason])
+		if n == 0 {
+			continue
+		}
+		if s.PacketsDropped == nil {
+			s.PacketsDropped = make(map[logging.PacketDropReason]uint64)
+		}
+		s.PacketsDropped[logging.PacketDropReason(reason)] += n
+	}
+	s.HandshakesStarted += atomic.LoadUint64(&c.handshakesStarted)
+	s.HandshakesCompleted += atomic.LoadUint64(&c.handshakesCompleted)
+	s.HandshakesFailed += atomic.LoadUint64(&c.handshakesFailed)
+	s.RetriesSent += atomic.LoadUint64(&c.retriesSent)
+	s.VersionNegotiationPacketsSent += atomic.LoadUint64(&c.versionNegotiationSent)
+	s.StatelessResetsSent += atomic.LoadUint64(&c.statelessResetsSent)
+	s.Connections += int(atomic.LoadInt64(&c.connections))
+}
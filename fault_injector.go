@@ -0,0 +1,531 @@
+package quic
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/logutils"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// A FaultAction is the kind of fault that is injected into a packet.
+type FaultAction = logging.FaultAction
+
+const (
+	// FaultActionDrop drops the packet.
+	FaultActionDrop = logging.FaultActionDrop
+	// FaultActionDelay delays the packet by FaultRule.Delay.
+	FaultActionDelay = logging.FaultActionDelay
+	// FaultActionDuplicate sends or receives the packet twice.
+	FaultActionDuplicate = logging.FaultActionDuplicate
+	// FaultActionReorder holds back the packet until the next packet has passed.
+	FaultActionReorder = logging.FaultActionReorder
+	// FaultActionBitFlip flips a random bit in the packet.
+	FaultActionBitFlip = logging.FaultActionBitFlip
+)
+
+// A FaultDirection is the direction of the packets that a FaultRule applies to.
+type FaultDirection = logging.FaultDirection
+
+const (
+	// FaultDirectionOutgoing applies a FaultRule to packets sent by this endpoint.
+	FaultDirectionOutgoing = logging.FaultDirectionOutgoing
+	// FaultDirectionIncoming applies a FaultRule to packets received from the peer.
+	FaultDirectionIncoming = logging.FaultDirectionIncoming
+)
+
+// The default time a packet is held back by FaultActionReorder,
+// if no later packet overtakes it.
+const defaultFaultReorderDelay = 10 * time.Millisecond
+
+// A FaultRule describes a fault and the packets it is injected into.
+// A packet matches the rule if it matches all the conditions that are set.
+// The zero value doesn't inject any faults: a rule only applies if both Action and Probability are set.
+type FaultRule struct {
+	// Action is the fault that is injected.
+	// If zero, the rule doesn't apply to any packet.
+	Action FaultAction
+	// Probability is the probability that a matching packet is affected.
+	// It must be between 0 and 1. If zero, the rule doesn't apply to any packet.
+	Probability float64
+	// Delay is the delay applied by FaultActionDelay.
+	// For FaultActionReorder, it is the maximum time a packet is held back
+	// if no later packet overtakes it. If zero, 10ms are used.
+	Delay time.Duration
+	// Direction restricts the rule to outgoing or incoming packets.
+	// If zero, the rule applies to both directions.
+	Direction FaultDirection
+	// EncryptionLevels restricts the rule to packets sent at these encryption levels.
+	// For coalesced packets, the encryption level of the first packet is used.
+	EncryptionLevels []logging.EncryptionLevel
+	// Frames restricts the rule to packets containing at least one frame for which it returns true.
+	// Since received packets are still encrypted when faults are injected,
+	// a rule that sets Frames never matches incoming packets.
+	Frames func(logging.Frame) bool
+	// RemotePrefix restricts the rule to connections with a remote address in this prefix.
+	RemotePrefix *net.IPNet
+	// ConnectionTracingID restricts the rule to a single connection.
+	// The ID is the value of ConnectionTracingKey on the connection's context.
+	ConnectionTracingID uint64
+}
+
+// A FaultInjector injects faults into the packets of a connection.
+// Faults are injected at the socket boundary:
+// after a packet was packed and before it is handed to the send queue,
+// and after a packet was read from the socket and before it is unpacked.
+// Delayed, duplicated and reordered packets are sent through the send queue of the connection.
+// Every injected fault is logged through the ConnectionTracer.
+// It is intended for chaos testing, and should not be used in production.
+type FaultInjector struct {
+	mutex sync.Mutex
+	rules []FaultRule
+	rand  *rand.Rand
+}
+
+// NewFaultInjector creates a new FaultInjector.
+// Initially, it doesn't have any rules, and doesn't inject any faults.
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// SetRules replaces the rules of the FaultInjector.
+// It can be called at any time, and applies to all connections using this FaultInjector.
+// Rules are evaluated in order, and at most one fault is injected into each packet.
+func (f *FaultInjector) SetRules(rules ...FaultRule) error {
+	for _, r := range rules {
+		if r.Probability < 0 || r.Probability > 1 {
+			return errors.New("invalid fault probability")
+		}
+		if r.Delay < 0 {
+			return errors.New("invalid fault delay")
+		}
+		if r.Action > FaultActionBitFlip {
+			return errors.New("invalid fault action")
+		}
+	}
+	f.mutex.Lock()
+	f.rules = append([]FaultRule(nil), rules...)
+	f.mutex.Unlock()
+	return nil
+}
+
+type faultPacket struct {
+	direction     FaultDirection
+	encLevel      protocol.EncryptionLevel
+	knownEncLevel bool
+	frames        []logging.Frame
+	remoteAddr    net.Addr
+	tracingID     uint64
+}
+
+func (r *FaultRule) matches(p *faultPacket) bool {
+	if r.Direction != 0 && r.Direction != p.direction {
+		return false
+	}
+	if r.ConnectionTracingID != 0 && r.ConnectionTracingID != p.tracingID {
+		return false
+	}
+	if r.RemotePrefix != nil {
+		udpAddr, ok := p.remoteAddr.(*net.UDPAddr)
+		if !ok || !r.RemotePrefix.Contains(udpAddr.IP) {
+			return false
+		}
+	}
+	if len(r.EncryptionLevels) > 0 {
+		if !p.knownEncLevel {
+			return false
+		}
+		var found bool
+		for _, l := range r.EncryptionLevels {
+			if l == p.encLevel {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Frames != nil {
+		var found bool
+		for _, f := range p.frames {
+			if r.Frames(f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the first rule that matches the packet and passes the probability check.
+func (f *FaultInjector) match(p *faultPacket) (FaultRule, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, r := range f.rules {
+		if !r.matches(p) {
+			continue
+		}
+		if r.Action == 0 || r.Probability == 0 {
+			continue
+		}
+		if r.Probability == 1 || f.rand.Float64() < r.Probability {
+			return r, true
+		}
+	}
+	return FaultRule{}, false
+}
+
+func (f *FaultInjector) flipBit(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	f.mutex.Lock()
+	bit := f.rand.Intn(8 * len(data))
+	f.mutex.Unlock()
+	data[bit/8] ^= 1 << (bit % 8)
+}
+
+// The faultInjector injects faults into the packets of a connection.
+// Besides the connFaultInjector, it is implemented by the wrappers used to record and replay its decisions.
+type faultInjector interface {
+	HandleIncoming(*receivedPacket)
+	HandleOutgoing(*packetBuffer, []*packetContents) FaultAction
+	NextOutgoing() *packetBuffer
+	Close()
+}
+
+var _ faultInjector = &connFaultInjector{}
+
+// The connFaultInjector applies the rules of a FaultInjector to the packets of a single connection.
+type connFaultInjector struct {
+	injector   *FaultInjector
+	remoteAddr net.Addr
+	connIDLen  int
+	tracingID  uint64
+
+	scheduleSending func()                // wakes up the connection, such that it sends the outgoing packets
+	receive         func(*receivedPacket) // queues a packet for processing by the connection
+
+	clock  utils.Clock // the clock of the connection, used to delay packets and to timestamp delayed packets
+	tracer logging.ConnectionTracer
+	logger utils.Logger
+
+	mutex        sync.Mutex
+	closed       bool
+	timers       map[utils.FuncTimer]func() // the function releases the packet that the timer holds, if any
+	outgoing     []*packetBuffer            // packets that are ready to be handed to the send queue
+	heldOutgoing *packetBuffer
+	heldIncoming *receivedPacket
+}
+
+func newConnFaultInjector(
+	injector *FaultInjector,
+	conn sendConn,
+	connIDLen int,
+	tracingID uint64,
+	scheduleSending func(),
+	receive func(*receivedPacket),
+	clock utils.Clock,
+	tracer logging.ConnectionTracer,
+	logger utils.Logger,
+) *connFaultInjector {
+	return &connFaultInjector{
+		injector:        injector,
+		remoteAddr:      conn.RemoteAddr(),
+		connIDLen:       connIDLen,
+		tracingID:       tracingID,
+		scheduleSending: scheduleSending,
+		receive:         receive,
+		clock:           clock,
+		tracer:          tracer,
+		logger:          logger,
+		timers:          make(map[utils.FuncTimer]func()),
+	}
+}
+
+// HandleOutgoing injects faults into a packet that is about to be sent.
+// It takes care of the packet buffer. Packets that are ready to be sent are returned by NextOutgoing.
+// It returns the action that was applied to the packet, or 0 if the packet wasn't matched by any rule.
+func (i *connFaultInjector) HandleOutgoing(buf *packetBuffer, packets []*packetContents) FaultAction {
+	p := &faultPacket{
+		direction:     FaultDirectionOutgoing,
+		encLevel:      packets[0].EncryptionLevel(),
+		knownEncLevel: true,
+		remoteAddr:    i.remoteAddr,
+		tracingID:     i.tracingID,
+	}
+	for _, c := range packets {
+		if c.ack != nil {
+			p.frames = append(p.frames, logutils.ConvertFrame(c.ack))
+		}
+		for _, f := range c.frames {
+			p.frames = append(p.frames, logutils.ConvertFrame(f.Frame))
+		}
+	}
+	rule, ok := i.injector.match(p)
+	held := i.takeHeldOutgoing()
+
+	var action FaultAction
+	send := true
+	if ok {
+		action = rule.Action
+		i.trace(FaultDirectionOutgoing, rule.Action, logging.PacketTypeFromHeader(&packets[0].header.Header), buf.Len())
+		switch rule.Action {
+		case FaultActionDrop:
+			buf.Release()
+			send = false
+		case FaultActionDelay:
+			send = false
+			i.after(rule.Delay, func() { i.queueOutgoing(buf) }, buf.Release)
+		case FaultActionDuplicate:
+			dup := getPacketBuffer()
+			dup.Data = append(dup.Data, buf.Data...)
+			i.queueOutgoing(dup)
+		case FaultActionReorder:
+			send = false
+			i.holdOutgoing(buf, rule.Delay)
+		case FaultActionBitFlip:
+			i.injector.flipBit(buf.Data)
+		}
+	}
+	if send {
+		i.queueOutgoing(buf)
+	}
+	// Make sure that the packet that was held back is sent after this packet.
+	if held != nil {
+		i.queueOutgoing(held)
+	}
+	return action
+}
+
+// NextOutgoing returns the next packet that is ready to be handed to the send queue.
+// It returns nil if there is no such packet.
+func (i *connFaultInjector) NextOutgoing() *packetBuffer {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if len(i.outgoing) == 0 {
+		return nil
+	}
+	buf := i.outgoing[0]
+	i.outgoing[0] = nil
+	i.outgoing = i.outgoing[1:]
+	return buf
+}
+
+// queueOutgoing queues a packet for sending, and wakes up the connection.
+func (i *connFaultInjector) queueOutgoing(buf *packetBuffer) {
+	i.mutex.Lock()
+	if i.closed {
+		i.mutex.Unlock()
+		buf.Release()
+		return
+	}
+	i.outgoing = append(i.outgoing, buf)
+	i.mutex.Unlock()
+	i.scheduleSending()
+}
+
+// HandleIncoming injects faults into a received packet,
+// and passes it on to the connection (unless it is dropped).
+func (i *connFaultInjector) HandleIncoming(rp *receivedPacket) {
+	p := &faultPacket{
+		direction:  FaultDirectionIncoming,
+		remoteAddr: i.remoteAddr,
+		tracingID:  i.tracingID,
+	}
+	packetType := logging.PacketTypeNotDetermined
+	if !wire.IsVersionNegotiationPacket(rp.data) {
+		if hdr, _, _, err := wire.ParsePacket(rp.data, i.connIDLen); err == nil {
+			packetType = logging.PacketTypeFromHeader(hdr)
+			p.encLevel, p.knownEncLevel = encryptionLevelFromHeader(hdr)
+		}
+	}
+	rule, ok := i.injector.match(p)
+	held := i.takeHeldIncoming()
+
+	deliver := true
+	if ok {
+		i.trace(FaultDirectionIncoming, rule.Action, packetType, rp.Size())
+		switch rule.Action {
+		case FaultActionDrop:
+			rp.buffer.Release()
+			deliver = false
+		case FaultActionDelay:
+			deliver = false
+			i.after(rule.Delay, func() {
+				rp.rcvTime = i.clock.Now()
+				i.receive(rp)
+			}, rp.buffer.Release)
+		case FaultActionDuplicate:
+			buf := getPacketBuffer()
+			buf.Data = append(buf.Data, rp.data...)
+			dup := rp.Clone()
+			dup.buffer = buf
+			dup.data = buf.Data
+			i.receive(dup)
+		case FaultActionReorder:
+			deliver = false
+			i.holdIncoming(rp, rule.Delay)
+		case FaultActionBitFlip:
+			i.injector.flipBit(rp.data)
+		}
+	}
+	if deliver {
+		i.receive(rp)
+	}
+	if held != nil {
+		held.rcvTime = i.clock.Now()
+		i.receive(held)
+	}
+}
+
+func (i *connFaultInjector) trace(dir FaultDirection, action FaultAction, pt logging.PacketType, size protocol.ByteCount) {
+	if i.logger.Debug() {
+		i.logger.Debugf("Injecting fault (action %d, direction %d) into packet of type %d (%d bytes)", action, dir, pt, size)
+	}
+	if i.tracer != nil {
+		i.tracer.InjectedFault(dir, action, pt, size)
+	}
+}
+
+func (i *connFaultInjector) takeHeldOutgoing() *packetBuffer {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	held := i.heldOutgoing
+	i.heldOutgoing = nil
+	return held
+}
+
+func (i *connFaultInjector) takeHeldIncoming() *receivedPacket {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	held := i.heldIncoming
+	i.heldIncoming = nil
+	return held
+}
+
+func (i *connFaultInjector) holdOutgoing(buf *packetBuffer, maxDelay time.Duration) {
+	i.mutex.Lock()
+	i.heldOutgoing = buf
+	i.mutex.Unlock()
+	i.after(reorderDelay(maxDelay), func() {
+		i.mutex.Lock()
+		if i.heldOutgoing != buf {
+			i.mutex.Unlock()
+			return
+		}
+		i.heldOutgoing = nil
+		i.mutex.Unlock()
+		i.queueOutgoing(buf)
+	}, nil)
+}
+
+func (i *connFaultInjector) holdIncoming(p *receivedPacket, maxDelay time.Duration) {
+	i.mutex.Lock()
+	i.heldIncoming = p
+	i.mutex.Unlock()
+	i.after(reorderDelay(maxDelay), func() {
+		i.mutex.Lock()
+		if i.heldIncoming != p {
+			i.mutex.Unlock()
+			return
+		}
+		i.heldIncoming = nil
+		i.mutex.Unlock()
+		p.rcvTime = i.clock.Now()
+		i.receive(p)
+	}, nil)
+}
+
+// after runs f after the delay, unless the connFaultInjector was closed in the mean time.
+// In that case, release is called instead (if set).
+func (i *connFaultInjector) after(d time.Duration, f, release func()) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.closed {
+		if release != nil {
+			release()
+		}
+		return
+	}
+	var t utils.FuncTimer
+	t = utils.AfterFunc(i.clock, d, func() {
+		i.mutex.Lock()
+		if _, ok := i.timers[t]; !ok {
+			// Close was called, but the timer had already fired
+			i.mutex.Unlock()
+			if release != nil {
+				release()
+			}
+			return
+		}
+		delete(i.timers, t)
+		i.mutex.Unlock()
+		f()
+	})
+	i.timers[t] = release
+}
+
+// Close stops all pending timers.
+// Packets that are delayed, held back or not yet handed to the send queue are discarded.
+func (i *connFaultInjector) Close() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.closed = true
+	for t, release := range i.timers {
+		if t.Stop() && release != nil {
+			release()
+		}
+	}
+	i.timers = nil
+	for _, buf := range i.outgoing {
+		buf.Release()
+	}
+	i.outgoing = nil
+	if i.heldOutgoing != nil {
+		i.heldOutgoing.Release()
+		i.heldOutgoing = nil
+	}
+	if i.heldIncoming != nil {
+		i.heldIncoming.buffer.Release()
+		i.heldIncoming = nil
+	}
+}
+
+func reorderDelay(d time.Duration) time.Duration {
+	if d == 0 {
+		return defaultFaultReorderDelay
+	}
+	return d
+}
+
+func encryptionLevelFromHeader(hdr *wire.Header) (protocol.EncryptionLevel, bool) {
+	if !hdr.IsLongHeader {
+		return protocol.Encryption1RTT, true
+	}
+	//nolint:exhaustive // Retry packets don't have an encryption level.
+	switch hdr.Type {
+	case protocol.PacketTypeInitial:
+		return protocol.EncryptionInitial, true
+	case protocol.PacketTypeHandshake:
+		return protocol.EncryptionHandshake, true
+	case protocol.PacketType0RTT:
+		return protocol.Encryption0RTT, true
+	default:
+		return 0, false
+	}
+}
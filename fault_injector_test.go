@@ -0,0 +1,394 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fault Injector", func() {
+	It("rejects invalid rules", func() {
+		f := NewFaultInjector()
+		Expect(f.SetRules(FaultRule{Probability: 1.5})).To(MatchError("invalid fault probability"))
+		Expect(f.SetRules(FaultRule{Delay: -time.Second})).To(MatchError("invalid fault delay"))
+		Expect(f.SetRules(FaultRule{Action: 42})).To(MatchError("invalid fault action"))
+	})
+
+	Context("matching", func() {
+		var f *FaultInjector
+		remoteAddr := &net.UDPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 1234}
+
+		BeforeEach(func() {
+			f = NewFaultInjector()
+		})
+
+		It("doesn't match if there are no rules", func() {
+			_, ok := f.match(&faultPacket{direction: FaultDirectionOutgoing})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("doesn't match with the zero value rule", func() {
+			Expect(f.SetRules(FaultRule{})).To(Succeed())
+			_, ok := f.match(&faultPacket{direction: FaultDirectionOutgoing})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("doesn't match rules without an action or probability", func() {
+			Expect(f.SetRules(
+				FaultRule{Probability: 1},
+				FaultRule{Action: FaultActionDrop},
+			)).To(Succeed())
+			_, ok := f.match(&faultPacket{direction: FaultDirectionOutgoing})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("matches by direction", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Direction: FaultDirectionIncoming})).To(Succeed())
+			_, ok := f.match(&faultPacket{direction: FaultDirectionOutgoing})
+			Expect(ok).To(BeFalse())
+			r, ok := f.match(&faultPacket{direction: FaultDirectionIncoming})
+			Expect(ok).To(BeTrue())
+			Expect(r.Action).To(Equal(FaultActionDelay))
+		})
+
+		It("matches by encryption level", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1, EncryptionLevels: []logging.EncryptionLevel{logging.EncryptionHandshake}})).To(Succeed())
+			_, ok := f.match(&faultPacket{encLevel: protocol.EncryptionInitial, knownEncLevel: true})
+			Expect(ok).To(BeFalse())
+			_, ok = f.match(&faultPacket{encLevel: protocol.EncryptionHandshake})
+			Expect(ok).To(BeFalse())
+			_, ok = f.match(&faultPacket{encLevel: protocol.EncryptionHandshake, knownEncLevel: true})
+			Expect(ok).To(BeTrue())
+		})
+
+		It("matches by frame type", func() {
+			Expect(f.SetRules(FaultRule{
+				Action:      FaultActionDrop,
+				Probability: 1,
+				Frames: func(f logging.Frame) bool {
+					_, ok := f.(*logging.StreamFrame)
+					return ok
+				},
+			})).To(Succeed())
+			_, ok := f.match(&faultPacket{frames: []logging.Frame{&logging.PingFrame{}}})
+			Expect(ok).To(BeFalse())
+			_, ok = f.match(&faultPacket{frames: []logging.Frame{&logging.PingFrame{}, &logging.StreamFrame{}}})
+			Expect(ok).To(BeTrue())
+		})
+
+		It("matches by remote prefix", func() {
+			_, prefix, err := net.ParseCIDR("10.1.0.0/16")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1, RemotePrefix: prefix})).To(Succeed())
+			_, ok := f.match(&faultPacket{remoteAddr: &net.UDPAddr{IP: net.IPv4(10, 2, 0, 1)}})
+			Expect(ok).To(BeFalse())
+			_, ok = f.match(&faultPacket{remoteAddr: remoteAddr})
+			Expect(ok).To(BeTrue())
+		})
+
+		It("matches by connection", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1, ConnectionTracingID: 42})).To(Succeed())
+			_, ok := f.match(&faultPacket{tracingID: 41})
+			Expect(ok).To(BeFalse())
+			_, ok = f.match(&faultPacket{tracingID: 42})
+			Expect(ok).To(BeTrue())
+		})
+
+		It("applies the first matching rule", func() {
+			Expect(f.SetRules(
+				FaultRule{Action: FaultActionDrop, Probability: 1, Direction: FaultDirectionIncoming},
+				FaultRule{Action: FaultActionDuplicate, Probability: 1},
+				FaultRule{Action: FaultActionBitFlip, Probability: 1},
+			)).To(Succeed())
+			r, ok := f.match(&faultPacket{direction: FaultDirectionOutgoing})
+			Expect(ok).To(BeTrue())
+			Expect(r.Action).To(Equal(FaultActionDuplicate))
+		})
+
+		It("applies rules with the configured probability", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 0.25})).To(Succeed())
+			var count int
+			const num = 10000
+			for i := 0; i < num; i++ {
+				if _, ok := f.match(&faultPacket{}); ok {
+					count++
+				}
+			}
+			Expect(count).To(BeNumerically("~", num/4, num/20))
+		})
+
+		It("flips a single bit", func() {
+			data := make([]byte, 100)
+			f.flipBit(data)
+			var numBits int
+			for _, b := range data {
+				for ; b > 0; b >>= 1 {
+					numBits += int(b & 1)
+				}
+			}
+			Expect(numBits).To(Equal(1))
+		})
+	})
+
+	Context("for a connection", func() {
+		var (
+			f        *FaultInjector
+			injector *connFaultInjector
+			tracer   *mocklogging.MockConnectionTracer
+			conn     *MockSendConn
+			clock    utils.Clock
+
+			mutex    sync.Mutex
+			received []*receivedPacket
+
+			sendingScheduled chan struct{}
+		)
+
+		getReceived := func() []*receivedPacket {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return received
+		}
+
+		getOutgoingPacket := func(data string) (*packetBuffer, []*packetContents) {
+			buf := getPacketBuffer()
+			buf.Data = append(buf.Data, data...)
+			return buf, []*packetContents{{
+				header: &wire.ExtendedHeader{Header: wire.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}}},
+				frames: []ackhandler.Frame{{Frame: &wire.PingFrame{}}},
+			}}
+		}
+
+		getSent := func() []string {
+			var sent []string
+			for buf := injector.NextOutgoing(); buf != nil; buf = injector.NextOutgoing() {
+				sent = append(sent, string(buf.Data))
+				buf.Release()
+			}
+			return sent
+		}
+
+		getIncomingPacket := func(b byte) *receivedPacket {
+			buf := getPacketBuffer()
+			// short header packet, with a 4 byte connection ID
+			buf.Data = append(buf.Data, 0x40, 1, 2, 3, 4, b)
+			return &receivedPacket{buffer: buf, data: buf.Data, rcvTime: time.Now()}
+		}
+
+		BeforeEach(func() {
+			received = nil
+			sendingScheduled = make(chan struct{}, 100)
+			f = NewFaultInjector()
+			tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+			conn = NewMockSendConn(mockCtrl)
+			conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337})
+			clock = utils.DefaultClock{}
+		})
+
+		JustBeforeEach(func() {
+			injector = newConnFaultInjector(f, conn, 4, 1, func() { sendingScheduled <- struct{}{} }, func(p *receivedPacket) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				received = append(received, p)
+			}, clock, tracer, utils.DefaultLogger)
+		})
+
+		AfterEach(func() {
+			injector.Close()
+		})
+
+		It("passes through outgoing packets that don't match any rule", func() {
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(Equal([]string{"foobar"}))
+		})
+
+		It("doesn't inject faults with the zero value rule", func() {
+			Expect(f.SetRules(FaultRule{})).To(Succeed())
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(Equal([]string{"foobar"}))
+		})
+
+		It("drops outgoing packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionDrop, logging.PacketType1RTT, protocol.ByteCount(6))
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(BeEmpty())
+		})
+
+		It("delays outgoing packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Delay: scaleDuration(20 * time.Millisecond)})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionDelay, logging.PacketType1RTT, protocol.ByteCount(6))
+			buf, packets := getOutgoingPacket("foobar")
+			start := time.Now()
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(BeEmpty())
+			Eventually(sendingScheduled).Should(Receive())
+			Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(20*time.Millisecond)))
+			Expect(getSent()).To(Equal([]string{"foobar"}))
+		})
+
+		It("duplicates outgoing packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDuplicate, Probability: 1})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionDuplicate, logging.PacketType1RTT, protocol.ByteCount(6))
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(Equal([]string{"foobar", "foobar"}))
+		})
+
+		It("flips bits in outgoing packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionBitFlip, Probability: 1})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionBitFlip, logging.PacketType1RTT, protocol.ByteCount(6))
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			sent := getSent()
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0]).To(HaveLen(6))
+			Expect(sent[0]).ToNot(Equal("foobar"))
+		})
+
+		It("reorders outgoing packets", func() {
+			Expect(f.SetRules(FaultRule{
+				Action:      FaultActionReorder,
+				Probability: 1,
+				Frames: func(f logging.Frame) bool {
+					_, ok := f.(*logging.PingFrame)
+					return ok
+				},
+			})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionReorder, logging.PacketType1RTT, protocol.ByteCount(3))
+			buf, packets := getOutgoingPacket("foo")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(BeEmpty())
+			// the second packet doesn't match the rule
+			buf, packets = getOutgoingPacket("bar")
+			packets[0].frames = nil
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(Equal([]string{"bar", "foo"}))
+		})
+
+		It("releases packets held back for reordering after a timeout", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionReorder, Probability: 1, Delay: scaleDuration(10 * time.Millisecond)})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionReorder, logging.PacketType1RTT, protocol.ByteCount(3))
+			buf, packets := getOutgoingPacket("foo")
+			injector.HandleOutgoing(buf, packets)
+			Expect(getSent()).To(BeEmpty())
+			Eventually(sendingScheduled).Should(Receive())
+			Expect(getSent()).To(Equal([]string{"foo"}))
+		})
+
+		It("passes through incoming packets that don't match any rule", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1, EncryptionLevels: []logging.EncryptionLevel{logging.EncryptionInitial}})).To(Succeed())
+			injector.HandleIncoming(getIncomingPacket(1))
+			Expect(getReceived()).To(HaveLen(1))
+		})
+
+		It("drops incoming packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDrop, Probability: 1, EncryptionLevels: []logging.EncryptionLevel{logging.Encryption1RTT}})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionDrop, logging.PacketType1RTT, protocol.ByteCount(6))
+			injector.HandleIncoming(getIncomingPacket(1))
+			Expect(getReceived()).To(BeEmpty())
+		})
+
+		It("delays incoming packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Delay: scaleDuration(20 * time.Millisecond)})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionDelay, logging.PacketType1RTT, protocol.ByteCount(6))
+			start := time.Now()
+			injector.HandleIncoming(getIncomingPacket(1))
+			Expect(getReceived()).To(BeEmpty())
+			Eventually(getReceived).Should(HaveLen(1))
+			Expect(getReceived()[0].rcvTime.Sub(start)).To(BeNumerically(">=", scaleDuration(20*time.Millisecond)))
+		})
+
+		It("duplicates incoming packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDuplicate, Probability: 1})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionDuplicate, logging.PacketType1RTT, protocol.ByteCount(6))
+			p := getIncomingPacket(1)
+			injector.HandleIncoming(p)
+			Expect(getReceived()).To(HaveLen(2))
+			Expect(getReceived()[0].data).To(Equal(p.data))
+			Expect(getReceived()[1].data).To(Equal(p.data))
+			Expect(getReceived()[0].buffer).ToNot(BeIdenticalTo(getReceived()[1].buffer))
+		})
+
+		It("reorders incoming packets", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionReorder, Probability: 1})).To(Succeed())
+			tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionReorder, logging.PacketType1RTT, protocol.ByteCount(6))
+			injector.HandleIncoming(getIncomingPacket(1))
+			Expect(getReceived()).To(BeEmpty())
+			Expect(f.SetRules()).To(Succeed())
+			injector.HandleIncoming(getIncomingPacket(2))
+			Expect(getReceived()).To(HaveLen(2))
+			Expect(getReceived()[0].data[5]).To(Equal(byte(2)))
+			Expect(getReceived()[1].data[5]).To(Equal(byte(1)))
+		})
+
+		It("discards delayed packets when closed", func() {
+			Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Delay: scaleDuration(10 * time.Millisecond)})).To(Succeed())
+			tracer.EXPECT().InjectedFault(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			injector.HandleIncoming(getIncomingPacket(1))
+			buf, packets := getOutgoingPacket("foobar")
+			injector.HandleOutgoing(buf, packets)
+			injector.Close()
+			Consistently(getReceived, scaleDuration(30*time.Millisecond)).Should(BeEmpty())
+			Expect(sendingScheduled).To(BeEmpty())
+			Expect(injector.NextOutgoing()).To(BeNil())
+		})
+
+		Context("using a virtual clock", func() {
+			var virtualClock *utils.VirtualClock
+
+			BeforeEach(func() {
+				virtualClock = utils.NewVirtualClock(time.Now().Add(-time.Hour))
+				clock = virtualClock
+			})
+
+			It("delays outgoing packets on the clock of the connection", func() {
+				Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Delay: time.Second})).To(Succeed())
+				tracer.EXPECT().InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionDelay, logging.PacketType1RTT, protocol.ByteCount(6))
+				buf, packets := getOutgoingPacket("foobar")
+				injector.HandleOutgoing(buf, packets)
+				virtualClock.Advance(time.Second - time.Nanosecond)
+				Consistently(sendingScheduled).ShouldNot(Receive())
+				virtualClock.Advance(time.Nanosecond)
+				Eventually(sendingScheduled).Should(Receive())
+				Expect(getSent()).To(Equal([]string{"foobar"}))
+			})
+
+			It("timestamps delayed incoming packets using the clock of the connection", func() {
+				Expect(f.SetRules(FaultRule{Action: FaultActionDelay, Probability: 1, Delay: time.Second})).To(Succeed())
+				tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionDelay, logging.PacketType1RTT, protocol.ByteCount(6))
+				p := getIncomingPacket(1)
+				p.rcvTime = virtualClock.Now()
+				injector.HandleIncoming(p)
+				virtualClock.Advance(time.Second)
+				Eventually(getReceived).Should(HaveLen(1))
+				Expect(getReceived()[0].rcvTime).To(Equal(p.rcvTime.Add(time.Second)))
+			})
+
+			It("timestamps incoming packets held back for reordering using the clock of the connection", func() {
+				Expect(f.SetRules(FaultRule{Action: FaultActionReorder, Probability: 1})).To(Succeed())
+				tracer.EXPECT().InjectedFault(logging.FaultDirectionIncoming, logging.FaultActionReorder, logging.PacketType1RTT, protocol.ByteCount(6))
+				injector.HandleIncoming(getIncomingPacket(1))
+				Expect(f.SetRules()).To(Succeed())
+				virtualClock.Advance(time.Millisecond)
+				injector.HandleIncoming(getIncomingPacket(2))
+				Expect(getReceived()).To(HaveLen(2))
+				Expect(getReceived()[1].rcvTime).To(Equal(virtualClock.Now()))
+			})
+		})
+	})
+})
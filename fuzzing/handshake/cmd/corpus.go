@@ -57,6 +57,7 @@ func newRunner(client, server *handshake.CryptoSetup) *runner {
 	return &runner{client: client, server: server}
 }
 
+func (r *runner) OnReceivedClientHello(string, []string)     {}
 func (r *runner) OnReceivedParams(*wire.TransportParameters) {}
 func (r *runner) OnHandshakeComplete()                       {}
 func (r *runner) OnError(err error) {
@@ -88,6 +89,7 @@ func main() {
 		},
 		false,
 		utils.NewRTTStats(),
+		0,
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
 		protocol.VersionTLS,
@@ -107,6 +109,7 @@ func main() {
 		config,
 		false,
 		utils.NewRTTStats(),
+		0,
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
 		protocol.VersionTLS,
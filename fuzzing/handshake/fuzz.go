@@ -173,6 +173,7 @@ func newRunner(client, server *handshake.CryptoSetup) *runner {
 	return &runner{client: client, server: server}
 }
 
+func (r *runner) OnReceivedClientHello(string, []string)     {}
 func (r *runner) OnReceivedParams(*wire.TransportParameters) {}
 func (r *runner) OnHandshakeComplete()                       {}
 func (r *runner) OnError(err error) {
@@ -384,6 +385,7 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		clientConf,
 		enable0RTTClient,
 		utils.NewRTTStats(),
+		0,
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
 		protocol.VersionTLS,
@@ -401,6 +403,7 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		serverConf,
 		enable0RTTServer,
 		utils.NewRTTStats(),
+		0,
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
 		protocol.VersionTLS,
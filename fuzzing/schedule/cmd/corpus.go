@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+
+	"github.com/lucas-clemente/quic-go/fuzzing/internal/helper"
+	"github.com/lucas-clemente/quic-go/fuzzing/schedule"
+)
+
+// the opcodes used by the fuzzer, see fuzzing/schedule/fuzz.go
+const (
+	opOpen = iota
+	opWrite
+	opClose
+	opCancel
+	opMigrate
+	opAdvance
+	opSetLoss
+	numOpcodes
+)
+
+func getPrefix() []byte {
+	b := make([]byte, schedule.PrefixLen)
+	rand.Read(b)
+	return b
+}
+
+func getOps(n int) []byte {
+	// always start by opening a stream, otherwise most operations are no-ops
+	ops := []byte{opOpen, 0, 0}
+	for i := 0; i < n; i++ {
+		op := rand.Intn(numOpcodes)
+		// make writes more likely
+		if rand.Intn(2) == 0 {
+			op = opWrite
+		}
+		ops = append(ops, byte(op), byte(rand.Intn(256)), byte(rand.Intn(256)))
+	}
+	return ops
+}
+
+func main() {
+	// a transfer on a perfect network
+	perfect := append(make([]byte, schedule.PrefixLen), opOpen, 0, 0, opWrite, 0, 100, opClose, 0, 0)
+	if err := helper.WriteCorpusFile("corpus", perfect); err != nil {
+		log.Fatal(err)
+	}
+
+	for i := 0; i < 30; i++ {
+		data := append(getPrefix(), getOps(rand.Intn(30))...)
+		if err := helper.WriteCorpusFile("corpus", data); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+// Package schedule fuzzes the interaction of a client and a server under particular network and API call schedules.
+// Both endpoints run over an in-memory network, and use the network's virtual clock for all their timers
+// (e.g. for loss detection, the PTO and the idle timeout), such that replaying an input reproduces the timing of the endpoints.
+// The fuzzer input controls the network conditions (loss, delay, reordering and duplication)
+// as well as the sequence of API calls on the client side.
+// The server echoes every stream.
+//
+// The following invariants are checked:
+//   - all data that was written on a stream that was neither canceled nor reset is echoed back unmodified
+//   - all streams are completed within a (virtual) time limit, i.e. there's no deadlock
+//   - no Go routines are leaked once both endpoints are closed
+//   - nothing panics
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/fuzzing/internal/helper"
+	"github.com/lucas-clemente/quic-go/internal/testnet"
+)
+
+// PrefixLen is the number of bytes used to configure the network.
+// The remaining bytes are interpreted as a sequence of operations.
+const PrefixLen = 6
+
+const opLen = 3
+
+type opcode uint8
+
+const (
+	// opens a new stream
+	opOpen opcode = iota
+	// writes to a stream
+	opWrite
+	// closes a stream for writing
+	opClose
+	// cancels writing and reading on a stream
+	opCancel
+	// rebinds the client to a new address
+	opMigrate
+	// advances the virtual clock
+	opAdvance
+	// changes the packet loss rate
+	opSetLoss
+	numOpcodes
+)
+
+const (
+	alpn = "quic-go-schedule-fuzzer"
+
+	maxStreams = 16
+	maxLoss    = 30 // in percent
+	// The virtual time that the network is given to complete all streams after all operations were executed.
+	maxVirtualDuration = 60 * time.Second
+	// The clock is advanced in steps of virtualTick.
+	// Every step takes realTick of real time, giving the endpoints the opportunity to react.
+	// Since the endpoints use the virtual clock, this only affects how fast the fuzzer runs.
+	virtualTick = time.Millisecond
+	realTick    = 100 * time.Microsecond
+	// The time the Go routines are given to shut down once the endpoints were closed.
+	leakTimeout = 5 * time.Second
+)
+
+// The start time of the virtual clock.
+var virtualStart = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var tlsServerConf, tlsClientConf *tls.Config
+
+func init() {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cert, certPool, err := helper.GenerateCertificate(priv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsServerConf = &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		NextProtos:   []string{alpn},
+	}
+	tlsClientConf = &tls.Config{
+		RootCAs:    certPool,
+		ServerName: "localhost",
+		NextProtos: []string{alpn},
+	}
+}
+
+// Every run uses new addresses.
+func nextAddrs() (server, client *net.UDPAddr) {
+	ip := testnet.NewIP()
+	return &net.UDPAddr{IP: ip, Port: 443}, &net.UDPAddr{IP: ip, Port: 10000}
+}
+
+// A clientStream is a stream opened by the client.
+// Writes are performed on a separate Go routine, such that a write that is blocked
+// (e.g. by flow control) doesn't prevent the clock from being advanced.
+type clientStream struct {
+	str quic.Stream
+
+	writes chan []byte
+	sent   bytes.Buffer
+
+	mutex    sync.Mutex
+	canceled bool
+	closed   bool
+
+	writerDone chan struct{}
+	readerDone chan struct{}
+	received   []byte
+	readErr    error
+}
+
+func newClientStream(str quic.Stream) *clientStream {
+	s := &clientStream{
+		str:        str,
+		writes:     make(chan []byte, 64),
+		writerDone: make(chan struct{}),
+		readerDone: make(chan struct{}),
+	}
+	go s.runWriter()
+	go s.runReader()
+	return s
+}
+
+func (s *clientStream) runWriter() {
+	defer close(s.writerDone)
+	for b := range s.writes {
+		// Write only fails if the stream was canceled.
+		// Keep draining the channel in that case.
+		s.str.Write(b)
+	}
+	s.mutex.Lock()
+	canceled := s.canceled
+	s.mutex.Unlock()
+	if !canceled {
+		s.str.Close()
+	}
+}
+
+func (s *clientStream) runReader() {
+	defer close(s.readerDone)
+	s.received, s.readErr = io.ReadAll(s.str)
+}
+
+func (s *clientStream) Write(b []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.canceled || s.closed {
+		return
+	}
+	select {
+	case s.writes <- b:
+		s.sent.Write(b)
+	default: // too many pending writes
+	}
+}
+
+func (s *clientStream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.canceled || s.closed {
+		return
+	}
+	s.closed = true
+	close(s.writes)
+}
+
+func (s *clientStream) Cancel() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.canceled || s.closed {
+		return
+	}
+	s.canceled = true
+	s.str.CancelWrite(0)
+	s.str.CancelRead(0)
+	close(s.writes)
+}
+
+func (s *clientStream) Done() bool {
+	select {
+	case <-s.readerDone:
+	default:
+		return false
+	}
+	select {
+	case <-s.writerDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// Check checks the data integrity of a completed stream.
+func (s *clientStream) Check() {
+	s.mutex.Lock()
+	canceled := s.canceled
+	s.mutex.Unlock()
+	if canceled {
+		return
+	}
+	if s.readErr != nil {
+		panic(fmt.Sprintf("stream %d: reading failed: %s", s.str.StreamID(), s.readErr))
+	}
+	if !bytes.Equal(s.received, s.sent.Bytes()) {
+		panic(fmt.Sprintf("stream %d: data corrupted (sent %d bytes, received %d bytes)", s.str.StreamID(), s.sent.Len(), len(s.received)))
+	}
+}
+
+type server struct {
+	ln quic.Listener
+	wg sync.WaitGroup
+
+	mutex sync.Mutex
+	conns []quic.Connection
+}
+
+func newServer(ln quic.Listener) *server {
+	s := &server{ln: ln}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *server) run() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for {
+				str, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				s.wg.Add(1)
+				go func() {
+					defer s.wg.Done()
+					if _, err := io.Copy(str, str); err != nil {
+						str.CancelWrite(1)
+						str.CancelRead(1)
+						return
+					}
+					str.Close()
+				}()
+			}
+		}()
+	}
+}
+
+// Close closes the listener and all connections,
+// and waits for all Go routines to return.
+func (s *server) Close() {
+	s.ln.Close()
+	s.mutex.Lock()
+	for _, conn := range s.conns {
+		conn.CloseWithError(0, "")
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+func getConfig(network *simNetwork) *quic.Config {
+	return &quic.Config{
+		Clock:                network.Clock(),
+		HandshakeIdleTimeout: maxVirtualDuration,
+		MaxIdleTimeout:       maxVirtualDuration,
+		MaxIncomingStreams:   maxStreams,
+	}
+}
+
+// Fuzz runs a client and a server using the schedule encoded in data.
+func Fuzz(data []byte) int {
+	if len(data) < PrefixLen {
+		return -1
+	}
+	params := testnet.LinkParams{
+		Loss:      int(data[0]) % (maxLoss + 1),
+		Duplicate: int(data[1]) % 10,
+		Delay:     time.Duration(data[2]%64) * time.Millisecond,
+		Jitter:    time.Duration(data[3]%64) * time.Millisecond,
+	}
+	seed := int64(binary.BigEndian.Uint16(data[4:6]))
+	data = data[PrefixLen:]
+
+	numGoroutines := runtime.NumGoroutine()
+	network := newSimNetwork(params, seed)
+	serverAddr, clientAddr := nextAddrs()
+	serverConn := network.NewConn(serverAddr)
+	clientConn := network.NewConn(clientAddr)
+
+	ln, err := quic.Listen(serverConn, tlsServerConf, getConfig(network))
+	if err != nil {
+		panic(err)
+	}
+	server := newServer(ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type dialResult struct {
+		conn quic.Connection
+		err  error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		conn, err := quic.DialContext(ctx, clientConn, serverAddr, "localhost", tlsClientConf, getConfig(network))
+		dialed <- dialResult{conn: conn, err: err}
+	}()
+	var conn quic.Connection
+	for conn == nil {
+		select {
+		case res := <-dialed:
+			if res.err != nil {
+				panic(fmt.Sprintf("handshake failed: %s", res.err))
+			}
+			conn = res.conn
+		default:
+			if network.Now() > maxVirtualDuration {
+				panic("deadlock: handshake didn't complete")
+			}
+			network.Advance(virtualTick)
+			time.Sleep(realTick)
+		}
+	}
+	cancel()
+
+	var streams []*clientStream
+	getStream := func(b byte) *clientStream {
+		if len(streams) == 0 {
+			return nil
+		}
+		return streams[int(b)%len(streams)]
+	}
+	var numMigrations int
+	for len(data) >= opLen {
+		op := opcode(data[0] % uint8(numOpcodes))
+		arg1, arg2 := data[1], data[2]
+		data = data[opLen:]
+
+		switch op {
+		case opOpen:
+			if len(streams) >= maxStreams {
+				continue
+			}
+			str, err := conn.OpenStream()
+			if err != nil {
+				continue
+			}
+			streams = append(streams, newClientStream(str))
+		case opWrite:
+			if s := getStream(arg1); s != nil {
+				b := make([]byte, (int(arg2)+1)*64)
+				for i := range b {
+					b[i] = byte(s.sent.Len() + i)
+				}
+				s.Write(b)
+			}
+		case opClose:
+			if s := getStream(arg1); s != nil {
+				s.Close()
+			}
+		case opCancel:
+			if s := getStream(arg1); s != nil {
+				s.Cancel()
+			}
+		case opMigrate:
+			numMigrations++
+			clientConn.Rebind(&net.UDPAddr{IP: clientAddr.IP, Port: clientAddr.Port + numMigrations})
+		case opAdvance:
+			for i := 0; i < int(arg1); i++ {
+				network.Advance(virtualTick)
+				time.Sleep(realTick)
+			}
+		case opSetLoss:
+			network.SetLoss(int(arg1) % (maxLoss + 1))
+		}
+	}
+
+	// Close all streams that are still open, and wait for them to complete.
+	for _, s := range streams {
+		s.Close()
+	}
+	deadline := network.Now() + maxVirtualDuration
+	for {
+		done := true
+		for _, s := range streams {
+			if !s.Done() {
+				done = false
+				break
+			}
+		}
+		if done {
+			break
+		}
+		if network.Now() > deadline {
+			panic(fmt.Sprintf("deadlock: streams didn't complete within %s", maxVirtualDuration))
+		}
+		network.Advance(virtualTick)
+		time.Sleep(realTick)
+	}
+	for _, s := range streams {
+		s.Check()
+	}
+
+	conn.CloseWithError(0, "")
+	server.Close()
+	clientConn.Close()
+	serverConn.Close()
+	checkGoroutineLeaks(numGoroutines)
+
+	if len(streams) == 0 {
+		return 0
+	}
+	return 1
+}
+
+func checkGoroutineLeaks(num int) {
+	deadline := time.Now().Add(leakTimeout)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= num {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var b strings.Builder
+	pprof.Lookup("goroutine").WriteTo(&b, 1)
+	panic(errors.New("leaked Go routines:\n" + b.String()))
+}
@@ -0,0 +1,46 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testnet"
+)
+
+// The simNetwork is an in-memory network with a virtual clock.
+// The same clock is used by the endpoints, see quic.Config.Clock.
+// Packets are only delivered when the clock is advanced past their delivery time.
+// Loss, delay and reordering are decided by a PRNG seeded from the fuzzer input,
+// so that the same input always results in the same network schedule.
+type simNetwork struct {
+	*testnet.Network
+
+	clock *quic.VirtualClock
+	start time.Time
+}
+
+func newSimNetwork(params testnet.LinkParams, seed int64) *simNetwork {
+	clock := quic.NewVirtualClock(virtualStart)
+	return &simNetwork{
+		Network: testnet.NewNetwork(testnet.NetworkOptions{
+			Clock: clock,
+			Link:  params,
+			Seed:  seed,
+			// Packets sent to the old address are still delivered after a rebinding.
+			// QUIC doesn't require the peer to switch to the new path.
+			KeepOldAddresses: true,
+		}),
+		clock: clock,
+		start: virtualStart,
+	}
+}
+
+// Clock returns the virtual clock of the network.
+func (n *simNetwork) Clock() *quic.VirtualClock {
+	return n.clock
+}
+
+// Now returns the virtual time that has elapsed since the network was created.
+func (n *simNetwork) Now() time.Duration {
+	return n.clock.Now().Sub(n.start)
+}
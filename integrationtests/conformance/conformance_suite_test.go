@@ -0,0 +1,45 @@
+package conformance_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const alpn = "quic-go conformance tests"
+
+func TestConformance(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Conformance Suite")
+}
+
+// runServer runs a quic-go server that accepts all connections.
+// It doesn't perform a Retry, so the peer can start the handshake right away.
+func runServer() (quic.Listener, func()) {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	ln, err := quic.ListenAddr("localhost:0", tlsConf, &quic.Config{
+		AcceptToken: func(net.Addr, *quic.Token) bool { return true },
+	})
+	Expect(err).ToNot(HaveOccurred())
+	done := make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		defer close(done)
+		for {
+			if _, err := ln.Accept(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+	return ln, func() {
+		ln.Close()
+		Eventually(done).Should(BeClosed())
+	}
+}
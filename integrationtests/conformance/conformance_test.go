@@ -0,0 +1,210 @@
+package conformance_test
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// cryptoErrorCode is the error code used for a TLS alert (see section 4.8 of RFC 9001).
+func cryptoErrorCode(alert uint8) uint64 {
+	return uint64(qerr.NewCryptoError(alert, "").ErrorCode)
+}
+
+const alertUnexpectedMessage = 10
+
+var _ = Describe("Conformance", func() {
+	var (
+		server  quic.Listener
+		closeFn func()
+	)
+
+	BeforeEach(func() {
+		server, closeFn = runServer()
+	})
+
+	AfterEach(func() {
+		closeFn()
+	})
+
+	send1RTT := func(p *peer, frames ...wire.Frame) {
+		p.handshake()
+		p.sendPacket(&packet{encLevel: protocol.Encryption1RTT, frames: frames})
+	}
+
+	table.DescribeTable("closing the connection",
+		func(violate func(p *peer), code uint64) {
+			p := newPeer(server.Addr())
+			defer p.close()
+			violate(p)
+			p.expectConnectionClose(code)
+		},
+		// RFC 9000, section 17.2
+		table.Entry("reserved bits set in an Initial packet", func(p *peer) {
+			var chunk cryptoChunk
+			Eventually(p.cryptoChunks).Should(Receive(&chunk))
+			p.sendPacket(&packet{
+				encLevel:     protocol.EncryptionInitial,
+				frames:       []wire.Frame{p.cryptoFrame(chunk)},
+				reservedBits: true,
+				padTo:        protocol.MinInitialPacketSize,
+			})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 17.3.1
+		table.Entry("reserved bits set in a 1-RTT packet", func(p *peer) {
+			p.handshake()
+			p.sendPacket(&packet{
+				encLevel:     protocol.Encryption1RTT,
+				frames:       []wire.Frame{&wire.PingFrame{}},
+				reservedBits: true,
+			})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 12.4
+		table.Entry("packet without any frames", func(p *peer) {
+			send1RTT(p)
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 12.4
+		table.Entry("unknown frame type", func(p *peer) {
+			send1RTT(p, rawFrame{0x21})
+		}, uint64(qerr.FrameEncodingError)),
+		// RFC 9000, section 12.4
+		table.Entry("STREAM frame in an Initial packet", func(p *peer) {
+			var chunk cryptoChunk
+			Eventually(p.cryptoChunks).Should(Receive(&chunk))
+			p.sendPacket(&packet{
+				encLevel: protocol.EncryptionInitial,
+				frames: []wire.Frame{
+					p.cryptoFrame(chunk),
+					&wire.StreamFrame{StreamID: 0, Data: []byte("foobar")},
+				},
+				padTo: protocol.MinInitialPacketSize,
+			})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 13.1
+		table.Entry("ACK frame acknowledging a packet that was never sent", func(p *peer) {
+			send1RTT(p, &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1000, Largest: 1000}}})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 19.7
+		table.Entry("NEW_TOKEN frame sent by the client", func(p *peer) {
+			send1RTT(p, &wire.NewTokenFrame{Token: []byte("foobar")})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 19.20
+		table.Entry("HANDSHAKE_DONE frame sent by the client", func(p *peer) {
+			send1RTT(p, &wire.HandshakeDoneFrame{})
+		}, uint64(qerr.ProtocolViolation)),
+		// RFC 9000, section 19.11
+		table.Entry("MAX_STREAMS frame allowing more than 2^60 streams", func(p *peer) {
+			send1RTT(p, &wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: 1<<60 + 1})
+		}, uint64(qerr.FrameEncodingError)),
+		// RFC 9000, section 19.15
+		table.Entry("NEW_CONNECTION_ID frame retiring connection IDs that were not issued yet", func(p *peer) {
+			send1RTT(p, &wire.NewConnectionIDFrame{
+				SequenceNumber:      1,
+				RetirePriorTo:       2,
+				ConnectionID:        protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+				StatelessResetToken: protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			})
+		}, uint64(qerr.FrameEncodingError)),
+		// RFC 9000, section 4.1
+		table.Entry("STREAM frame exceeding the stream flow control limit", func(p *peer) {
+			p.handshake()
+			p.sendPacket(&packet{
+				encLevel: protocol.Encryption1RTT,
+				frames: []wire.Frame{&wire.StreamFrame{
+					StreamID: 0,
+					Offset:   p.serverParams.InitialMaxStreamDataBidiRemote,
+					Data:     []byte("foobar"),
+				}},
+			})
+		}, uint64(qerr.FlowControlError)),
+		// RFC 9000, section 4.6
+		table.Entry("STREAM frame exceeding the stream limit", func(p *peer) {
+			p.handshake()
+			num := protocol.StreamNum(p.serverParams.MaxBidiStreamNum + 1)
+			p.sendPacket(&packet{
+				encLevel: protocol.Encryption1RTT,
+				frames:   []wire.Frame{&wire.StreamFrame{StreamID: num.StreamID(protocol.StreamTypeBidi, protocol.PerspectiveClient), Data: []byte("foobar")}},
+			})
+		}, uint64(qerr.StreamLimitError)),
+		// RFC 9000, section 19.8
+		table.Entry("STREAM frame for a server-initiated bidirectional stream that was not opened", func(p *peer) {
+			send1RTT(p, &wire.StreamFrame{StreamID: 1, Data: []byte("foobar")})
+		}, uint64(qerr.StreamStateError)),
+		// RFC 9000, section 19.8
+		table.Entry("STREAM frame for a server-initiated unidirectional stream", func(p *peer) {
+			send1RTT(p, &wire.StreamFrame{StreamID: 3, Data: []byte("foobar")})
+		}, uint64(qerr.StreamStateError)),
+		// RFC 9000, section 4.5
+		table.Entry("STREAM frame exceeding the final size", func(p *peer) {
+			send1RTT(p,
+				&wire.StreamFrame{StreamID: 0, Data: []byte("foobar"), Fin: true, DataLenPresent: true},
+				&wire.StreamFrame{StreamID: 0, Offset: 6, Data: []byte("foobar")},
+			)
+		}, uint64(qerr.FinalSizeError)),
+		// RFC 9001, section 6
+		table.Entry("TLS KeyUpdate message", func(p *peer) {
+			send1RTT(p, p.cryptoFrame(cryptoChunk{encLevel: protocol.Encryption1RTT, data: []byte{24, 0, 0, 1, 0}}))
+		}, cryptoErrorCode(alertUnexpectedMessage)),
+	)
+
+	// RFC 9000, section 14.1
+	It("discards Initial packets in datagrams smaller than 1200 bytes", func() {
+		p := newPeer(server.Addr())
+		defer p.close()
+		var chunk cryptoChunk
+		Eventually(p.cryptoChunks).Should(Receive(&chunk))
+		p.sendPacket(&packet{
+			encLevel: protocol.EncryptionInitial,
+			frames:   []wire.Frame{&wire.CryptoFrame{Data: chunk.data}},
+		})
+		p.expectNoResponse()
+		// The server didn't create any state, so the handshake can be retried with a padded packet.
+		p.sendPacket(&packet{
+			encLevel: protocol.EncryptionInitial,
+			frames:   []wire.Frame{p.cryptoFrame(chunk)},
+			padTo:    protocol.MinInitialPacketSize,
+		})
+		Eventually(p.runner.handshakeComplete).Should(BeClosed())
+		Eventually(p.handshakeDone).Should(BeClosed())
+		p.ping()
+	})
+
+	// RFC 9000, section 17.2
+	It("discards long header packets with a connection ID longer than 20 bytes", func() {
+		p := newPeer(server.Addr())
+		defer p.close()
+		// The wire package refuses to serialize such a header, so it is composed manually.
+		b := &bytes.Buffer{}
+		b.WriteByte(0xc0) // Initial packet
+		v := make([]byte, 4)
+		binary.BigEndian.PutUint32(v, uint32(protocol.Version1))
+		b.Write(v)
+		b.WriteByte(21)
+		b.Write(bytes.Repeat([]byte{0x42}, 21))
+		b.WriteByte(uint8(p.srcConnID.Len()))
+		b.Write(p.srcConnID)
+		b.Write(make([]byte, protocol.MinInitialPacketSize-b.Len()))
+		p.sendDatagram(b.Bytes())
+		p.expectNoResponse()
+	})
+
+	It("keeps the connection alive if the peer behaves", func() {
+		p := newPeer(server.Addr())
+		defer p.close()
+		p.handshake()
+		p.ping()
+		p.sendPacket(&packet{
+			encLevel: protocol.Encryption1RTT,
+			frames:   []wire.Frame{&wire.StreamFrame{StreamID: 0, Data: []byte("foobar"), Fin: true}},
+		})
+		p.ping()
+	})
+})
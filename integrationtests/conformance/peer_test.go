@@ -0,0 +1,493 @@
+package conformance_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A rawFrame is written to the packet as is.
+// It can be used to send frames that the wire package refuses to serialize.
+type rawFrame []byte
+
+var _ wire.Frame = rawFrame{}
+
+func (f rawFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	b.Write(f)
+	return nil
+}
+
+func (f rawFrame) Length(protocol.VersionNumber) protocol.ByteCount {
+	return protocol.ByteCount(len(f))
+}
+
+// A packet is a packet sent by the peer.
+// The zero values of the fields result in a well-formed packet.
+type packet struct {
+	encLevel protocol.EncryptionLevel
+	frames   []wire.Frame
+	// reservedBits sets the reserved bits of the first byte, before header protection is applied.
+	reservedBits bool
+	// destConnID overrides the destination connection ID.
+	destConnID protocol.ConnectionID
+	// padTo pads the packet to the given size.
+	padTo int
+}
+
+type cryptoChunk struct {
+	encLevel protocol.EncryptionLevel
+	data     []byte
+}
+
+// The cryptoWriter is passed to the crypto setup.
+// The crypto setup holds a lock while writing, so the data is sent by the peer's run loop.
+type cryptoWriter struct {
+	encLevel protocol.EncryptionLevel
+	chunks   chan<- cryptoChunk
+}
+
+func (w *cryptoWriter) Write(b []byte) (int, error) {
+	w.chunks <- cryptoChunk{encLevel: w.encLevel, data: append([]byte{}, b...)}
+	return len(b), nil
+}
+
+// The cryptoReceiver reassembles the CRYPTO frames of one encryption level into TLS messages.
+// Only in-order delivery is supported, which is sufficient on the loopback interface.
+type cryptoReceiver struct {
+	offset protocol.ByteCount
+	buf    []byte
+}
+
+func (r *cryptoReceiver) handle(f *wire.CryptoFrame) [][]byte {
+	end := f.Offset + protocol.ByteCount(len(f.Data))
+	if f.Offset > r.offset || end <= r.offset {
+		return nil
+	}
+	r.buf = append(r.buf, f.Data[r.offset-f.Offset:]...)
+	r.offset = end
+	var msgs [][]byte
+	for len(r.buf) >= 4 {
+		msgLen := 4 + int(r.buf[1])<<16 + int(r.buf[2])<<8 + int(r.buf[3])
+		if len(r.buf) < msgLen {
+			break
+		}
+		msgs = append(msgs, r.buf[:msgLen])
+		r.buf = r.buf[msgLen:]
+	}
+	return msgs
+}
+
+// The handshakeRunner is the handshake runner of the crypto setup.
+type handshakeRunner struct {
+	errs               chan error
+	params             chan *wire.TransportParameters
+	handshakeComplete  chan struct{}
+	handshakeCompleted sync.Once
+}
+
+func (r *handshakeRunner) OnReceivedClientHello(string, []string) {}
+func (r *handshakeRunner) OnReceivedParams(tp *wire.TransportParameters) {
+	r.params <- tp
+}
+func (r *handshakeRunner) OnHandshakeComplete() {
+	r.handshakeCompleted.Do(func() { close(r.handshakeComplete) })
+}
+func (r *handshakeRunner) OnError(err error) {
+	select {
+	case r.errs <- err:
+	default:
+	}
+}
+func (r *handshakeRunner) DropKeys(protocol.EncryptionLevel) {}
+
+// The peer is a scripted QUIC client.
+// It performs the handshake using the crypto setup, and sends arbitrary (and possibly invalid) packets.
+type peer struct {
+	conn       *net.UDPConn
+	remoteAddr net.Addr
+	version    protocol.VersionNumber
+
+	srcConnID protocol.ConnectionID
+
+	mutex      sync.Mutex
+	destConnID protocol.ConnectionID
+	pns        map[protocol.EncryptionLevel]protocol.PacketNumber
+
+	cs             handshake.CryptoSetup
+	runner         *handshakeRunner
+	cryptoChunks   chan cryptoChunk
+	cryptoOffsets  map[protocol.EncryptionLevel]protocol.ByteCount
+	cryptoReceived map[protocol.EncryptionLevel]*cryptoReceiver
+	frameParser    wire.FrameParser
+	serverParams   *wire.TransportParameters
+
+	receivedPacket   chan struct{}
+	handshakeDone    chan struct{}
+	closeFrame       chan *wire.ConnectionCloseFrame
+	receivedFirstPkt bool
+	runDone          chan struct{}
+}
+
+func newPeer(remoteAddr net.Addr) *peer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	Expect(err).ToNot(HaveOccurred())
+	srcConnID, err := protocol.GenerateConnectionID(8)
+	Expect(err).ToNot(HaveOccurred())
+	destConnID, err := protocol.GenerateConnectionIDForInitial()
+	Expect(err).ToNot(HaveOccurred())
+	p := &peer{
+		conn:       conn,
+		remoteAddr: remoteAddr,
+		version:    protocol.Version1,
+		srcConnID:  srcConnID,
+		destConnID: destConnID,
+		pns:        make(map[protocol.EncryptionLevel]protocol.PacketNumber),
+		runner: &handshakeRunner{
+			errs:              make(chan error, 1),
+			params:            make(chan *wire.TransportParameters, 1),
+			handshakeComplete: make(chan struct{}),
+		},
+		cryptoChunks:   make(chan cryptoChunk, 100),
+		cryptoOffsets:  make(map[protocol.EncryptionLevel]protocol.ByteCount),
+		cryptoReceived: make(map[protocol.EncryptionLevel]*cryptoReceiver),
+		frameParser:    wire.NewFrameParser(false, false, protocol.Version1),
+		receivedPacket: make(chan struct{}, 1),
+		handshakeDone:  make(chan struct{}),
+		closeFrame:     make(chan *wire.ConnectionCloseFrame, 1),
+		runDone:        make(chan struct{}),
+	}
+	tlsConf := &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    testdata.GetRootCA(),
+		NextProtos: []string{alpn},
+	}
+	p.cs, _ = handshake.NewCryptoSetupClient(
+		&cryptoWriter{encLevel: protocol.EncryptionInitial, chunks: p.cryptoChunks},
+		&cryptoWriter{encLevel: protocol.EncryptionHandshake, chunks: p.cryptoChunks},
+		destConnID,
+		conn.LocalAddr(),
+		remoteAddr,
+		&wire.TransportParameters{
+			InitialMaxStreamDataBidiLocal:  protocol.DefaultMaxReceiveStreamFlowControlWindow,
+			InitialMaxStreamDataBidiRemote: protocol.DefaultMaxReceiveStreamFlowControlWindow,
+			InitialMaxStreamDataUni:        protocol.DefaultMaxReceiveStreamFlowControlWindow,
+			InitialMaxData:                 protocol.DefaultMaxReceiveConnectionFlowControlWindow,
+			MaxIdleTimeout:                 protocol.DefaultIdleTimeout,
+			MaxBidiStreamNum:               protocol.DefaultMaxIncomingStreams,
+			MaxUniStreamNum:                protocol.DefaultMaxIncomingUniStreams,
+			AckDelayExponent:               protocol.DefaultAckDelayExponent,
+			MaxAckDelay:                    protocol.MaxAckDelay,
+			MaxUDPPayloadSize:              protocol.MaxPacketBufferSize,
+			DisableActiveMigration:         true,
+			ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+			InitialSourceConnectionID:      srcConnID,
+			MaxDatagramFrameSize:           protocol.InvalidByteCount,
+		},
+		p.runner,
+		tlsConf,
+		false,
+		&utils.RTTStats{},
+		0,
+		nil,
+		utils.DefaultLogger.WithPrefix("conformance peer"),
+		p.version,
+	)
+	go p.cs.RunHandshake()
+	go p.run()
+	return p
+}
+
+// startHandshake sends the ClientHello, without waiting for the server's response.
+func (p *peer) startHandshake() {
+	var chunk cryptoChunk
+	Eventually(p.cryptoChunks).Should(Receive(&chunk))
+	Expect(chunk.encLevel).To(Equal(protocol.EncryptionInitial))
+	p.sendPacket(&packet{
+		encLevel: protocol.EncryptionInitial,
+		frames:   []wire.Frame{p.cryptoFrame(chunk)},
+		padTo:    protocol.MinInitialPacketSize,
+	})
+}
+
+// handshake performs the handshake, and waits until the handshake is confirmed by the server.
+func (p *peer) handshake() {
+	p.startHandshake()
+	Eventually(p.runner.handshakeComplete).Should(BeClosed())
+	Eventually(p.runner.params).Should(Receive(&p.serverParams))
+	Eventually(p.handshakeDone).Should(BeClosed())
+	Expect(p.runner.errs).ToNot(Receive())
+}
+
+func (p *peer) cryptoFrame(chunk cryptoChunk) *wire.CryptoFrame {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	f := &wire.CryptoFrame{Offset: p.cryptoOffsets[chunk.encLevel], Data: chunk.data}
+	p.cryptoOffsets[chunk.encLevel] += protocol.ByteCount(len(chunk.data))
+	return f
+}
+
+// run receives packets from the server.
+// It passes CRYPTO frames to the crypto setup, and sends the handshake messages generated by the crypto setup.
+func (p *peer) run() {
+	defer GinkgoRecover()
+	defer close(p.runDone)
+	b := make([]byte, protocol.MaxPacketBufferSize)
+	for {
+		n, _, err := p.conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		select {
+		case p.receivedPacket <- struct{}{}:
+		default:
+		}
+		data := b[:n]
+		for len(data) > 0 {
+			hdr, packetData, rest, err := wire.ParsePacket(data, p.srcConnID.Len())
+			if err != nil {
+				break
+			}
+			data = rest
+			p.handlePacket(hdr, packetData)
+		}
+		p.sendCryptoData()
+	}
+}
+
+func (p *peer) sendCryptoData() {
+	for {
+		select {
+		case chunk := <-p.cryptoChunks:
+			p.sendPacket(&packet{encLevel: chunk.encLevel, frames: []wire.Frame{p.cryptoFrame(chunk)}})
+		default:
+			return
+		}
+	}
+}
+
+func (p *peer) handlePacket(hdr *wire.Header, data []byte) {
+	encLevel, payload, ok := p.unpackPacket(hdr, data)
+	if !ok {
+		return
+	}
+
+	r := bytes.NewReader(payload)
+	for {
+		frame, err := p.frameParser.ParseNext(r, encLevel)
+		Expect(err).ToNot(HaveOccurred())
+		if frame == nil {
+			return
+		}
+		switch f := frame.(type) {
+		case *wire.CryptoFrame:
+			if _, ok := p.cryptoReceived[encLevel]; !ok {
+				p.cryptoReceived[encLevel] = &cryptoReceiver{}
+			}
+			for _, msg := range p.cryptoReceived[encLevel].handle(f) {
+				p.cs.HandleMessage(msg, encLevel)
+			}
+		case *wire.HandshakeDoneFrame:
+			select {
+			case <-p.handshakeDone:
+			default:
+				close(p.handshakeDone)
+			}
+		case *wire.ConnectionCloseFrame:
+			select {
+			case p.closeFrame <- f:
+			default:
+			}
+		}
+	}
+}
+
+// unpackPacket removes header protection and decrypts the packet.
+// The AEADs are not safe for concurrent use, so this happens under the same lock that is held when sealing packets.
+func (p *peer) unpackPacket(hdr *wire.Header, data []byte) (encLevel protocol.EncryptionLevel, payload []byte, ok bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var opener interface {
+		DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte)
+		DecodePacketNumber(protocol.PacketNumber, protocol.PacketNumberLen) protocol.PacketNumber
+	}
+	var open func(extHdr *wire.ExtendedHeader, hdrLen protocol.ByteCount) ([]byte, error)
+	var err error
+	switch {
+	case !hdr.IsLongHeader:
+		encLevel = protocol.Encryption1RTT
+		var o handshake.ShortHeaderOpener
+		o, err = p.cs.Get1RTTOpener()
+		opener = o
+		open = func(extHdr *wire.ExtendedHeader, hdrLen protocol.ByteCount) ([]byte, error) {
+			return o.Open(nil, data[hdrLen:], time.Now(), extHdr.PacketNumber, extHdr.KeyPhase, data[:hdrLen])
+		}
+	case hdr.Type == protocol.PacketTypeInitial || hdr.Type == protocol.PacketTypeHandshake:
+		var o handshake.LongHeaderOpener
+		if hdr.Type == protocol.PacketTypeInitial {
+			encLevel = protocol.EncryptionInitial
+			o, err = p.cs.GetInitialOpener()
+		} else {
+			encLevel = protocol.EncryptionHandshake
+			o, err = p.cs.GetHandshakeOpener()
+		}
+		opener = o
+		open = func(extHdr *wire.ExtendedHeader, hdrLen protocol.ByteCount) ([]byte, error) {
+			return o.Open(nil, data[hdrLen:], extHdr.PacketNumber, data[:hdrLen])
+		}
+	default:
+		return encLevel, nil, false
+	}
+	if err != nil {
+		return encLevel, nil, false
+	}
+	if hdr.IsLongHeader && !p.receivedFirstPkt {
+		p.receivedFirstPkt = true
+		p.destConnID = hdr.SrcConnectionID
+	}
+
+	// remove header protection
+	hdrLen := hdr.ParsedLen()
+	if protocol.ByteCount(len(data)) < hdrLen+4+16 {
+		return encLevel, nil, false
+	}
+	// The packet number is assumed to be 4 bytes long, since its actual length is only known after decryption.
+	origPNBytes := make([]byte, 4)
+	copy(origPNBytes, data[hdrLen:hdrLen+4])
+	opener.DecryptHeader(data[hdrLen+4:hdrLen+4+16], &data[0], data[hdrLen:hdrLen+4])
+	extHdr, err := hdr.ParseExtended(bytes.NewReader(data), p.version)
+	if err != nil {
+		return encLevel, nil, false
+	}
+	// restore the bytes that were wrongly unprotected, if the packet number was shorter
+	copy(data[extHdr.ParsedLen():hdrLen+4], origPNBytes[extHdr.PacketNumberLen:])
+	extHdr.PacketNumber = opener.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	payload, err = open(extHdr, extHdr.ParsedLen())
+	if err != nil {
+		return encLevel, nil, false
+	}
+	return encLevel, payload, true
+}
+
+func (p *peer) composePacket(pkt *packet) []byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	destConnID := p.destConnID
+	if pkt.destConnID != nil {
+		destConnID = pkt.destConnID
+	}
+	hdr := &wire.ExtendedHeader{
+		Header: wire.Header{
+			IsLongHeader:     pkt.encLevel != protocol.Encryption1RTT,
+			DestConnectionID: destConnID,
+		},
+		PacketNumber:    p.pns[pkt.encLevel],
+		PacketNumberLen: protocol.PacketNumberLen4,
+	}
+	p.pns[pkt.encLevel]++
+
+	var sealer handshake.LongHeaderSealer
+	var err error
+	//nolint:exhaustive // 0-RTT packets are not sent.
+	switch pkt.encLevel {
+	case protocol.EncryptionInitial:
+		hdr.Type = protocol.PacketTypeInitial
+		sealer, err = p.cs.GetInitialSealer()
+	case protocol.EncryptionHandshake:
+		hdr.Type = protocol.PacketTypeHandshake
+		sealer, err = p.cs.GetHandshakeSealer()
+	case protocol.Encryption1RTT:
+		var s handshake.ShortHeaderSealer
+		s, err = p.cs.Get1RTTSealer()
+		if err == nil {
+			hdr.KeyPhase = s.KeyPhase()
+		}
+		sealer = s
+	}
+	Expect(err).ToNot(HaveOccurred())
+	if hdr.IsLongHeader {
+		hdr.Version = p.version
+		hdr.SrcConnectionID = p.srcConnID
+	}
+
+	payload := &bytes.Buffer{}
+	for _, f := range pkt.frames {
+		Expect(f.Write(payload, p.version)).To(Succeed())
+	}
+	if pkt.padTo > 0 {
+		if l := pkt.padTo - int(hdr.GetLength(p.version)) - payload.Len() - sealer.Overhead(); l > 0 {
+			payload.Write(make([]byte, l))
+		}
+	}
+	if hdr.IsLongHeader {
+		hdr.Length = protocol.ByteCount(hdr.PacketNumberLen) + protocol.ByteCount(payload.Len()+sealer.Overhead())
+	}
+
+	buf := &bytes.Buffer{}
+	Expect(hdr.Write(buf, p.version)).To(Succeed())
+	payloadOffset := buf.Len()
+	if pkt.reservedBits {
+		raw := buf.Bytes()
+		if hdr.IsLongHeader {
+			raw[0] |= 0x0c
+		} else {
+			raw[0] |= 0x18
+		}
+	}
+	raw := sealer.Seal(buf.Bytes(), payload.Bytes(), hdr.PacketNumber, buf.Bytes())
+	pnOffset := payloadOffset - int(hdr.PacketNumberLen)
+	sealer.EncryptHeader(raw[pnOffset+4:pnOffset+4+16], &raw[0], raw[pnOffset:payloadOffset])
+	return raw
+}
+
+func (p *peer) sendPacket(pkt *packet) {
+	p.sendDatagram(p.composePacket(pkt))
+}
+
+func (p *peer) sendDatagram(b []byte) {
+	_, err := p.conn.WriteTo(b, p.remoteAddr)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+// expectConnectionClose waits for a CONNECTION_CLOSE frame, and checks the error code.
+func (p *peer) expectConnectionClose(code uint64) {
+	var f *wire.ConnectionCloseFrame
+	Eventually(p.closeFrame).Should(Receive(&f))
+	Expect(f.IsApplicationError).To(BeFalse())
+	Expect(f.ErrorCode).To(Equal(code), "CONNECTION_CLOSE with unexpected error code. Reason: %s", f.ReasonPhrase)
+}
+
+// expectNoResponse checks that the server doesn't send any packets.
+func (p *peer) expectNoResponse() {
+	Consistently(p.receivedPacket, 250*time.Millisecond).ShouldNot(Receive())
+}
+
+// ping sends a PING frame in a 1-RTT packet, and waits for the server to acknowledge it.
+func (p *peer) ping() {
+	// drain the channel, so that only packets received after sending the PING are taken into account
+	select {
+	case <-p.receivedPacket:
+	default:
+	}
+	p.sendPacket(&packet{encLevel: protocol.Encryption1RTT, frames: []wire.Frame{&wire.PingFrame{}}})
+	Eventually(p.receivedPacket).Should(Receive())
+	Expect(p.closeFrame).ToNot(Receive())
+}
+
+func (p *peer) close() {
+	p.conn.Close()
+	Eventually(p.runDone).Should(BeClosed())
+	p.cs.Close()
+}
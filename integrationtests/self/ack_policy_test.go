@@ -0,0 +1,114 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ackPolicyConnTracer struct {
+	connTracer
+
+	mutex          sync.Mutex
+	params         *logging.TransportParameters
+	numSentPackets int
+	ackDelays      []time.Duration
+}
+
+func (t *ackPolicyConnTracer) ReceivedTransportParameters(p *logging.TransportParameters) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.params = p
+}
+
+func (t *ackPolicyConnTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.numSentPackets++
+}
+
+func (t *ackPolicyConnTracer) ReceivedPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, frames []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, f := range frames {
+		if ack, ok := f.(*logging.AckFrame); ok {
+			t.ackDelays = append(t.ackDelays, ack.DelayTime)
+		}
+	}
+}
+
+var _ = Describe("ACK policy", func() {
+	It("advertises and uses the configured ACK policy", func() {
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				MaxAckDelay:           5 * time.Millisecond,
+				AckDelayExponent:      10,
+				AckElicitingThreshold: 10,
+				MaxAckRanges:          4,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRDataLong))
+			conn.CloseWithError(0, "")
+		}()
+
+		tracer := &ackPolicyConnTracer{}
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{Tracer: newTracer(func() logging.ConnectionTracer { return tracer })}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRDataLong)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(done, scaleDuration(10*time.Second)).Should(BeClosed())
+		Eventually(conn.Context().Done()).Should(BeClosed())
+
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		// the advertised max_ack_delay includes the timer granularity
+		Expect(tracer.params.MaxAckDelay).To(Equal(6 * time.Millisecond))
+		Expect(tracer.params.AckDelayExponent).To(BeEquivalentTo(10))
+		// The ack delays are decoded using the advertised exponent.
+		// If the server used a different exponent, the delays would be off by orders of magnitude.
+		Expect(tracer.ackDelays).ToNot(BeEmpty())
+		for _, d := range tracer.ackDelays {
+			Expect(d).To(BeNumerically("<", scaleDuration(100*time.Millisecond)))
+		}
+		// With the default policy, every second packet is acknowledged.
+		fmt.Fprintf(GinkgoWriter, "Received %d ACKs for %d packets.\n", len(tracer.ackDelays), tracer.numSentPackets)
+		Expect(3 * len(tracer.ackDelays)).To(BeNumerically("<", tracer.numSentPackets))
+	})
+})
@@ -0,0 +1,117 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello Splitting", func() {
+	var server quic.Listener
+
+	BeforeEach(func() {
+		var err error
+		server, err = quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+
+		go func() {
+			defer GinkgoRecover()
+			for {
+				conn, err := server.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				str, err := conn.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	// dial establishes a connection, and returns the Initial packets sent by the client
+	dial := func(conf *quic.ClientHelloSplitting) []packet {
+		tracer := newPacketTracer()
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				ClientHelloSplitting: conf,
+				Tracer:               newTracer(func() logging.ConnectionTracer { return tracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+
+		var initials []packet
+		for _, p := range tracer.getSentPackets() {
+			if logging.PacketTypeFromHeader(&p.hdr.Header) == logging.PacketTypeInitial {
+				initials = append(initials, p)
+			}
+		}
+		return initials
+	}
+
+	// cryptoFrames returns the CRYPTO frames sent in the Initial packets
+	cryptoFrames := func(packets []packet) []*logging.CryptoFrame {
+		var frames []*logging.CryptoFrame
+		for _, p := range packets {
+			for _, f := range p.frames {
+				if cf, ok := f.(*logging.CryptoFrame); ok {
+					frames = append(frames, cf)
+				}
+			}
+		}
+		return frames
+	}
+
+	It("splits the ClientHello", func() {
+		frames := cryptoFrames(dial(&quic.ClientHelloSplitting{Fragments: 4}))
+		Expect(frames).To(HaveLen(4))
+		for i := 1; i < len(frames); i++ {
+			Expect(frames[i].Offset).To(Equal(frames[i-1].Offset + frames[i-1].Length))
+		}
+	})
+
+	It("reorders the fragments", func() {
+		frames := cryptoFrames(dial(&quic.ClientHelloSplitting{Fragments: 3, Reorder: true}))
+		Expect(frames).To(HaveLen(3))
+		Expect(frames[2].Offset).To(BeZero())
+		for i := 1; i < len(frames); i++ {
+			Expect(frames[i].Offset).To(BeNumerically("<", frames[i-1].Offset))
+		}
+	})
+
+	It("inserts PING frames", func() {
+		packets := dial(&quic.ClientHelloSplitting{Fragments: 2, Reorder: true, Filler: quic.ClientHelloFillerPing})
+		Expect(cryptoFrames(packets[:1])).To(HaveLen(1))
+		Expect(packets[1].frames).To(ContainElement(BeAssignableToTypeOf(&logging.PingFrame{})))
+		Expect(cryptoFrames(packets[1:2])).To(BeEmpty())
+		Expect(cryptoFrames(packets[2:3])).To(HaveLen(1))
+	})
+
+	It("inserts PADDING packets", func() {
+		packets := dial(&quic.ClientHelloSplitting{Fragments: 2, Filler: quic.ClientHelloFillerPadding})
+		Expect(cryptoFrames(packets[:1])).To(HaveLen(1))
+		Expect(packets[1].frames).ToNot(ContainElement(BeAssignableToTypeOf(&logging.PingFrame{})))
+		Expect(cryptoFrames(packets[1:2])).To(BeEmpty())
+		Expect(cryptoFrames(packets[2:3])).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,93 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type congestionConnTracer struct {
+	connTracer
+
+	mutex   sync.Mutex
+	params  []logging.CongestionParameters
+	maxCwnd logging.ByteCount
+}
+
+func (t *congestionConnTracer) UpdatedCongestionParameters(p *logging.CongestionParameters) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.params = append(t.params, *p)
+}
+
+func (t *congestionConnTracer) UpdatedMetrics(_ *logging.RTTStats, cwnd, _ logging.ByteCount, _ int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if cwnd > t.maxCwnd {
+		t.maxCwnd = cwnd
+	}
+}
+
+var _ = Describe("Congestion controller configuration", func() {
+	It("uses the configured congestion controller parameters", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			conn.CloseWithError(0, "")
+		}()
+
+		tracer := &congestionConnTracer{}
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				InitialCongestionWindow:       8,
+				MinCongestionWindow:           4,
+				MaxCongestionWindow:           40,
+				CongestionLossReductionFactor: 0.5,
+				Tracer:                        newTracer(func() logging.ConnectionTracer { return tracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(conn.Context().Done()).Should(BeClosed())
+
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		Expect(tracer.params).ToNot(BeEmpty())
+		initialParams := tracer.params[0]
+		// The window sizes are scaled by the initial maximum datagram size.
+		Expect(initialParams.InitialCongestionWindow % 8).To(BeZero())
+		Expect(initialParams.MinCongestionWindow).To(Equal(initialParams.InitialCongestionWindow / 2))
+		Expect(initialParams.MaxCongestionWindow).To(Equal(5 * initialParams.InitialCongestionWindow))
+		Expect(initialParams.LossReductionFactor).To(Equal(0.5))
+		// If Path MTU Discovery increased the packet size, the parameters are reported again.
+		// Slow start might overshoot the maximum by a single packet.
+		lastParams := tracer.params[len(tracer.params)-1]
+		Expect(tracer.maxCwnd).To(BeNumerically(">", 0))
+		Expect(tracer.maxCwnd).To(BeNumerically("<=", lastParams.MaxCongestionWindow+protocol.MaxPacketBufferSize))
+	})
+})
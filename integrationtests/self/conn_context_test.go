@@ -0,0 +1,146 @@
+package self_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type tenantCtxKey struct{}
+
+type handshakeInfoConnTracer struct {
+	connTracer
+
+	mutex  sync.Mutex
+	tenant interface{}
+	info   *logging.HandshakeInfo
+}
+
+func (t *handshakeInfoConnTracer) StartedHandshake(ctx context.Context, info *logging.HandshakeInfo) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tenant = ctx.Value(tenantCtxKey{})
+	t.info = info
+}
+
+func (t *handshakeInfoConnTracer) getHandshakeInfo() (interface{}, *logging.HandshakeInfo) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.tenant, t.info
+}
+
+var _ = Describe("Connection Context", func() {
+	connContext := func(ctx context.Context, info *quic.HandshakeInfo) context.Context {
+		return context.WithValue(ctx, tenantCtxKey{}, "tenant-"+info.ServerName)
+	}
+	// On the server side, ConnContext is called before the ClientHello is processed.
+	serverConnContext := func(ctx context.Context, info *quic.HandshakeInfo) context.Context {
+		Expect(info.ServerName).To(BeEmpty())
+		return context.WithValue(ctx, tenantCtxKey{}, fmt.Sprintf("tenant-%d", info.RemoteAddr.(*net.UDPAddr).Port))
+	}
+
+	It("sets the context when the connection is created", func() {
+		serverTracer := &handshakeInfoConnTracer{}
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				ConnContext: serverConnContext,
+				Tracer:      newTracer(func() logging.ConnectionTracer { return serverTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		clientTracer := &handshakeInfoConnTracer{}
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				ConnContext: connContext,
+				Tracer:      newTracer(func() logging.ConnectionTracer { return clientTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Context().Value(tenantCtxKey{})).To(Equal("tenant-localhost"))
+		tenant, info := clientTracer.getHandshakeInfo()
+		Expect(tenant).To(Equal("tenant-localhost"))
+		Expect(info.ServerName).To(Equal("localhost"))
+		Expect(info.SupportedProtocols).To(Equal(getTLSClientConfig().NextProtos))
+		Expect(info.RemoteAddr.(*net.UDPAddr).Port).To(Equal(server.Addr().(*net.UDPAddr).Port))
+
+		serverConn, err := server.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		serverTenant := fmt.Sprintf("tenant-%d", conn.LocalAddr().(*net.UDPAddr).Port)
+		Expect(serverConn.Context().Value(tenantCtxKey{})).To(Equal(serverTenant))
+		Expect(serverConn.Context().Value(quic.ConnectionTracingKey)).ToNot(BeNil())
+		tenant, info = serverTracer.getHandshakeInfo()
+		Expect(tenant).To(Equal(serverTenant))
+		Expect(info.ServerName).To(Equal("localhost"))
+		Expect(info.SupportedProtocols).To(Equal(getTLSClientConfig().NextProtos))
+		Expect(info.RemoteAddr.(*net.UDPAddr).Port).To(Equal(conn.LocalAddr().(*net.UDPAddr).Port))
+
+		ctx := serverConn.Context()
+		Consistently(ctx.Done()).ShouldNot(BeClosed())
+		conn.CloseWithError(0, "")
+		Eventually(ctx.Done()).Should(BeClosed())
+	})
+
+	It("closes the connection if ConnContext returns nil", func() {
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				ConnContext: func(context.Context, *quic.HandshakeInfo) context.Context { return nil },
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		_, err = quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("works with a GetConfigForClient callback, and with session resumption", func() {
+		var getConfigForClientCalled bool
+		tlsConf := getTLSConfig()
+		tlsConf.GetConfigForClient = func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+			getConfigForClientCalled = true
+			return nil, nil
+		}
+		server, err := quic.ListenAddr("localhost:0", tlsConf, getQuicConfig(&quic.Config{ConnContext: serverConnContext}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		puts := make(chan string, 10)
+		clientConf := getTLSClientConfig()
+		clientConf.ClientSessionCache = newClientSessionCache(make(chan string, 10), puts)
+		for i := 0; i < 2; i++ {
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				clientConf,
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			serverConn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverConn.Context().Value(tenantCtxKey{})).To(Equal(fmt.Sprintf("tenant-%d", conn.LocalAddr().(*net.UDPAddr).Port)))
+			Expect(serverConn.ConnectionState().TLS.DidResume).To(Equal(i == 1))
+			Eventually(puts).Should(Receive())
+			conn.CloseWithError(0, "")
+		}
+		Expect(getConfigForClientCalled).To(BeTrue())
+	})
+})
@@ -0,0 +1,115 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Corking", func() {
+	const numWrites = 100
+
+	var server quic.Listener
+
+	BeforeEach(func() {
+		var err error
+		server, err = quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	// countStreamPackets performs numWrites small writes on a stream,
+	// and returns the number of 1-RTT packets containing STREAM frames sent by the client
+	countStreamPackets := func(conf *quic.Config, useStream func(quic.SendStream)) int {
+		received := make(chan []byte)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			received <- data
+		}()
+
+		tracer := newPacketTracer()
+		conf.Tracer = newTracer(func() logging.ConnectionTracer { return tracer })
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(conf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.OpenUniStreamSync(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		useStream(str)
+		var data []byte
+		Eventually(received).Should(Receive(&data))
+		Expect(data).To(HaveLen(numWrites * 3))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+
+		var num int
+		for _, p := range tracer.getSentPackets() {
+			for _, f := range p.frames {
+				if _, ok := f.(*logging.StreamFrame); ok {
+					num++
+					break
+				}
+			}
+		}
+		return num
+	}
+
+	writeSlowly := func(str quic.SendStream) {
+		for i := 0; i < numWrites; i++ {
+			_, err := str.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+
+	It("sends small writes in a few packets", func() {
+		numPackets := countStreamPackets(&quic.Config{CorkDelay: 100 * time.Millisecond}, func(str quic.SendStream) {
+			writeSlowly(str)
+			Expect(str.Close()).To(Succeed())
+		})
+		fmt.Fprintf(GinkgoWriter, "Sent %d packets with STREAM frames.\n", numPackets)
+		Expect(numPackets).To(BeNumerically("<", 10))
+	})
+
+	It("sends small writes right away when not corked", func() {
+		numPackets := countStreamPackets(&quic.Config{}, func(str quic.SendStream) {
+			writeSlowly(str)
+			Expect(str.Close()).To(Succeed())
+		})
+		fmt.Fprintf(GinkgoWriter, "Sent %d packets with STREAM frames.\n", numPackets)
+		Expect(numPackets).To(BeNumerically(">", 10))
+	})
+
+	It("corks individual streams, and flushes them", func() {
+		numPackets := countStreamPackets(&quic.Config{}, func(str quic.SendStream) {
+			str.SetCorkDelay(time.Second)
+			writeSlowly(str)
+			start := time.Now()
+			Expect(str.Flush()).To(Succeed())
+			// wait for the data to be sent, before closing the stream
+			time.Sleep(scaleDuration(20 * time.Millisecond))
+			Expect(str.Close()).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+		fmt.Fprintf(GinkgoWriter, "Sent %d packets with STREAM frames.\n", numPackets)
+		Expect(numPackets).To(BeNumerically("<=", 3))
+	})
+})
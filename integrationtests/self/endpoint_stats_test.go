@@ -0,0 +1,91 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Endpoint Stats", func() {
+	const numConns = 3
+
+	dial := func(ln quic.Listener, conf *quic.Config) (quic.Connection, error) {
+		return quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(conf),
+		)
+	}
+
+	It("counts handshakes and connections", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			AcceptToken: func(net.Addr, *quic.Token) bool { return true },
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		var clientConns []quic.Connection
+		for i := 0; i < numConns; i++ {
+			conn, err := dial(ln, nil)
+			Expect(err).ToNot(HaveOccurred())
+			clientConns = append(clientConns, conn)
+		}
+		Eventually(func() int { return ln.Stats().AcceptQueueLen }).Should(Equal(numConns))
+		Eventually(func() uint64 { return ln.Stats().HandshakesCompleted }).Should(BeEquivalentTo(numConns))
+		stats := ln.Stats()
+		Expect(stats.HandshakesStarted).To(BeEquivalentTo(numConns))
+		Expect(stats.HandshakesFailed).To(BeZero())
+		Expect(stats.Connections).To(Equal(numConns))
+		Expect(stats.RetriesSent).To(BeZero())
+		Expect(stats.PacketsReceived).To(BeNumerically(">=", 2*numConns))
+
+		for i := 0; i < numConns; i++ {
+			_, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(ln.Stats().AcceptQueueLen).To(BeZero())
+		for _, conn := range clientConns {
+			conn.CloseWithError(0, "")
+		}
+		Eventually(func() int { return ln.Stats().Connections }).Should(BeZero())
+	})
+
+	It("counts Retries", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			AcceptToken: func(_ net.Addr, token *quic.Token) bool { return token != nil },
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := dial(ln, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		stats := ln.Stats()
+		Expect(stats.RetriesSent).To(BeEquivalentTo(1))
+		Expect(stats.HandshakesStarted).To(BeEquivalentTo(1))
+	})
+
+	It("counts Version Negotiation packets", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			Versions: []quic.VersionNumber{quic.Version1},
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		_, err = dial(ln, &quic.Config{
+			Versions:             []quic.VersionNumber{quic.VersionDraft29},
+			HandshakeIdleTimeout: time.Second,
+		})
+		var versionNegErr *quic.VersionNegotiationError
+		Expect(errors.As(err, &versionNegErr)).To(BeTrue())
+		Eventually(func() uint64 { return ln.Stats().VersionNegotiationPacketsSent }).Should(BeEquivalentTo(1))
+		Expect(ln.Stats().HandshakesStarted).To(BeZero())
+	})
+})
@@ -0,0 +1,116 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expiry", func() {
+	const errorCode = 42
+
+	Context("streams", func() {
+		const flowControlWindow = 16 << 10
+
+		// runTransfer writes more data than the flow control window allows on a stream with an expiry.
+		// The server only starts reading after the data expired.
+		// It returns the data that the server received before the stream was reset.
+		runTransfer := func(serverEnable, clientEnable bool) (sent, received []byte) {
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+				EnableReliableStreamReset:      serverEnable,
+				InitialStreamReceiveWindow:     flowControlWindow,
+				InitialConnectionReceiveWindow: 10 * flowControlWindow,
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			expired := make(chan struct{})
+			receivedChan := make(chan []byte, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				<-expired
+				// wait for the RESET_STREAM(_AT) frame to arrive
+				time.Sleep(scaleDuration(50 * time.Millisecond))
+				data, err := io.ReadAll(str)
+				var streamErr *quic.StreamError
+				Expect(errors.As(err, &streamErr)).To(BeTrue())
+				Expect(streamErr.ErrorCode).To(BeEquivalentTo(errorCode))
+				receivedChan <- data
+			}()
+
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{EnableReliableStreamReset: clientEnable}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			str, err := conn.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str.SetExpiry(time.Now().Add(scaleDuration(50*time.Millisecond)), errorCode)
+			sent = GeneratePRData(4 * flowControlWindow)
+			_, err = str.Write(sent)
+			Expect(err).To(MatchError(quic.ErrExpired))
+			close(expired)
+			Eventually(receivedChan).Should(Receive(&received))
+			return sent, received
+		}
+
+		It("delivers the data that was sent before the expiry, if both endpoints support reliable resets", func() {
+			sent, received := runTransfer(true, true)
+			Expect(received).To(HaveLen(flowControlWindow))
+			Expect(received).To(Equal(sent[:flowControlWindow]))
+		})
+
+		It("discards all data, if the peer doesn't support reliable resets", func() {
+			_, received := runTransfer(true, false)
+			Expect(received).To(BeEmpty())
+		})
+	})
+
+	Context("datagrams", func() {
+		It("drops datagrams that expired", func() {
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{EnableDatagrams: true}))
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			received := make(chan []byte, 10)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				for {
+					b, err := conn.ReceiveMessage()
+					if err != nil {
+						return
+					}
+					received <- b
+				}
+			}()
+
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{EnableDatagrams: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			Expect(conn.SendMessageWithExpiry([]byte("expired"), time.Now().Add(-time.Second))).To(MatchError(quic.ErrExpired))
+			Expect(conn.SendMessageWithExpiry([]byte("foobar"), time.Now().Add(time.Minute))).To(Succeed())
+			Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+			Consistently(received).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type faultCountingConnTracer struct {
+	connTracer
+	counter *int32
+}
+
+func (t *faultCountingConnTracer) InjectedFault(logging.FaultDirection, logging.FaultAction, logging.PacketType, logging.ByteCount) {
+	atomic.AddInt32(t.counter, 1)
+}
+
+var _ = Describe("Fault Injection", func() {
+	for _, a := range []quic.FaultAction{
+		quic.FaultActionDrop,
+		quic.FaultActionDelay,
+		quic.FaultActionDuplicate,
+		quic.FaultActionReorder,
+		quic.FaultActionBitFlip,
+	} {
+		action := a
+
+		It(fmt.Sprintf("transfers data with injected faults (action %d)", action), func() {
+			var numFaults int32
+			injector := quic.NewFaultInjector()
+			ln, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{
+					FaultInjector: injector,
+					Tracer: newTracer(func() logging.ConnectionTracer {
+						return &faultCountingConnTracer{counter: &numFaults}
+					}),
+				}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			// Only inject faults after the handshake, and only into the server's 1-RTT packets.
+			Expect(injector.SetRules(quic.FaultRule{
+				Action:           action,
+				Probability:      0.1,
+				Direction:        quic.FaultDirectionOutgoing,
+				EncryptionLevels: []logging.EncryptionLevel{protocol.Encryption1RTT},
+			})).To(Succeed())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			Eventually(done).Should(BeClosed())
+			Expect(atomic.LoadInt32(&numFaults)).To(BeNumerically(">", 0))
+		})
+	}
+})
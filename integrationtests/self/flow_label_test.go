@@ -0,0 +1,152 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type flowLabelUpdate struct {
+	label   uint32
+	trigger logging.FlowLabelTrigger
+}
+
+type flowLabelConnTracer struct {
+	connTracer
+
+	mutex   sync.Mutex
+	updates []flowLabelUpdate
+}
+
+func (t *flowLabelConnTracer) UpdatedFlowLabel(label uint32, trigger logging.FlowLabelTrigger) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.updates = append(t.updates, flowLabelUpdate{label: label, trigger: trigger})
+}
+
+func (t *flowLabelConnTracer) getUpdates() []flowLabelUpdate {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]flowLabelUpdate{}, t.updates...)
+}
+
+var _ = Describe("Flow Labels", func() {
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" {
+			Skip("setting the flow label is not supported on this platform")
+		}
+	})
+
+	runServer := func() (quic.Listener, *flowLabelConnTracer) {
+		tracer := &flowLabelConnTracer{}
+		server, err := quic.ListenAddr("[::1]:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			EnableFlowLabels: true,
+			Tracer:           newTracer(func() logging.ConnectionTracer { return tracer }),
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			conn.CloseWithError(0, "")
+		}()
+		return server, tracer
+	}
+
+	var udpConn *net.UDPConn
+
+	AfterEach(func() {
+		if udpConn != nil {
+			udpConn.Close()
+		}
+	})
+
+	dial := func(port int) (quic.Connection, *flowLabelConnTracer) {
+		var err error
+		udpConn, err = net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+		Expect(err).ToNot(HaveOccurred())
+		tracer := &flowLabelConnTracer{}
+		conn, err := quic.Dial(
+			udpConn,
+			&net.UDPAddr{IP: net.IPv6loopback, Port: port},
+			"localhost",
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				EnableFlowLabels: true,
+				Tracer:           newTracer(func() logging.ConnectionTracer { return tracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return conn, tracer
+	}
+
+	transfer := func(conn quic.Connection) {
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(conn.Context().Done(), 10*time.Second).Should(BeClosed())
+	}
+
+	It("sets a flow label", func() {
+		server, serverTracer := runServer()
+		defer server.Close()
+		conn, clientTracer := dial(server.Addr().(*net.UDPAddr).Port)
+		transfer(conn)
+
+		for _, tracer := range []*flowLabelConnTracer{clientTracer, serverTracer} {
+			updates := tracer.getUpdates()
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].label).ToNot(BeZero())
+			Expect(updates[0].trigger).To(Equal(logging.FlowLabelTriggerInitial))
+		}
+	})
+
+	It("changes the flow label after repeated PTOs", func() {
+		server, _ := runServer()
+		defer server.Close()
+
+		var dropping int32 // atomic
+		proxy, err := quicproxy.NewQuicProxy("[::1]:0", &quicproxy.Opts{
+			RemoteAddr: server.Addr().String(),
+			DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+				return dir == quicproxy.DirectionIncoming && atomic.LoadInt32(&dropping) == 1
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, tracer := dial(proxy.LocalPort())
+		// The path to the server is failing until the flow label changes.
+		atomic.StoreInt32(&dropping, 1)
+		go func() {
+			defer GinkgoRecover()
+			Eventually(func() int { return len(tracer.getUpdates()) }, 10*time.Second).Should(BeNumerically(">", 1))
+			atomic.StoreInt32(&dropping, 0)
+		}()
+		transfer(conn)
+
+		updates := tracer.getUpdates()
+		Expect(len(updates)).To(BeNumerically(">", 1))
+		Expect(updates[0].trigger).To(Equal(logging.FlowLabelTriggerInitial))
+		Expect(updates[1].trigger).To(Equal(logging.FlowLabelTriggerPTO))
+		Expect(updates[1].label).ToNot(Equal(updates[0].label))
+	})
+})
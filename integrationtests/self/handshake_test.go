@@ -183,6 +183,48 @@ var _ = Describe("Handshake tests", func() {
 	}
 
 	Context("using different cipher suites", func() {
+		// runHandshake establishes a connection, transfers some data on a stream,
+		// and returns the cipher suites reported by the client and by the server.
+		runHandshake := func(serverSuites, clientSuites []uint16) (uint16, uint16) {
+			tlsConf := getTLSConfig()
+			tlsConf.CipherSuites = serverSuites
+			ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			serverSuiteChan := make(chan uint16, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				serverSuiteChan <- conn.ConnectionState().TLS.CipherSuite
+				str, err := conn.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				defer str.Close()
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			clientConf := getTLSClientConfig()
+			clientConf.CipherSuites = clientSuites
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				clientConf,
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			clientSuite := conn.ConnectionState().TLS.CipherSuite
+			Expect(conn.CloseWithError(0, "")).To(Succeed())
+			var serverSuite uint16
+			Eventually(serverSuiteChan).Should(Receive(&serverSuite))
+			return clientSuite, serverSuite
+		}
+
 		for n, id := range map[string]uint16{
 			"TLS_AES_128_GCM_SHA256":       tls.TLS_AES_128_GCM_SHA256,
 			"TLS_AES_256_GCM_SHA384":       tls.TLS_AES_256_GCM_SHA384,
@@ -191,39 +233,67 @@ var _ = Describe("Handshake tests", func() {
 			name := n
 			suiteID := id
 
-			It(fmt.Sprintf("using %s", name), func() {
-				tlsConf := getTLSConfig()
-				tlsConf.CipherSuites = []uint16{suiteID}
-				ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
-				Expect(err).ToNot(HaveOccurred())
-				defer ln.Close()
-
-				go func() {
-					defer GinkgoRecover()
-					conn, err := ln.Accept(context.Background())
-					Expect(err).ToNot(HaveOccurred())
-					str, err := conn.OpenStream()
-					Expect(err).ToNot(HaveOccurred())
-					defer str.Close()
-					_, err = str.Write(PRData)
-					Expect(err).ToNot(HaveOccurred())
-				}()
+			It(fmt.Sprintf("using %s, restricted by the server", name), func() {
+				clientSuite, serverSuite := runHandshake([]uint16{suiteID}, nil)
+				Expect(clientSuite).To(Equal(suiteID))
+				Expect(serverSuite).To(Equal(suiteID))
+			})
 
-				conn, err := quic.DialAddr(
-					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
-					getTLSClientConfig(),
-					nil,
-				)
-				Expect(err).ToNot(HaveOccurred())
-				str, err := conn.AcceptStream(context.Background())
-				Expect(err).ToNot(HaveOccurred())
-				data, err := io.ReadAll(str)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(data).To(Equal(PRData))
-				Expect(conn.ConnectionState().TLS.CipherSuite).To(Equal(suiteID))
-				Expect(conn.CloseWithError(0, "")).To(Succeed())
+			It(fmt.Sprintf("using %s, restricted by the client", name), func() {
+				clientSuite, serverSuite := runHandshake(nil, []uint16{suiteID})
+				Expect(clientSuite).To(Equal(suiteID))
+				Expect(serverSuite).To(Equal(suiteID))
 			})
 		}
+
+		It("ignores cipher suites that can't be used with TLS 1.3", func() {
+			clientSuite, serverSuite := runHandshake(
+				[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384},
+				[]uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_AES_256_GCM_SHA384},
+			)
+			Expect(clientSuite).To(Equal(tls.TLS_AES_256_GCM_SHA384))
+			Expect(serverSuite).To(Equal(tls.TLS_AES_256_GCM_SHA384))
+		})
+
+		It("uses the server's preference", func() {
+			clientSuite, serverSuite := runHandshake(
+				[]uint16{tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256},
+				[]uint16{tls.TLS_CHACHA20_POLY1305_SHA256, tls.TLS_AES_256_GCM_SHA384},
+			)
+			Expect(clientSuite).To(Equal(tls.TLS_AES_256_GCM_SHA384))
+			Expect(serverSuite).To(Equal(tls.TLS_AES_256_GCM_SHA384))
+		})
+
+		It("uses the client's preference, if the server doesn't restrict the cipher suites", func() {
+			clientSuite, serverSuite := runHandshake(
+				nil,
+				[]uint16{tls.TLS_CHACHA20_POLY1305_SHA256, tls.TLS_AES_128_GCM_SHA256},
+			)
+			Expect(clientSuite).To(Equal(tls.TLS_CHACHA20_POLY1305_SHA256))
+			Expect(serverSuite).To(Equal(tls.TLS_CHACHA20_POLY1305_SHA256))
+		})
+
+		It("fails the handshake if there's no common cipher suite", func() {
+			tlsConf := getTLSConfig()
+			tlsConf.CipherSuites = []uint16{tls.TLS_AES_128_GCM_SHA256}
+			ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			clientConf := getTLSClientConfig()
+			clientConf.CipherSuites = []uint16{tls.TLS_CHACHA20_POLY1305_SHA256}
+			_, err = quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				clientConf,
+				getQuicConfig(&quic.Config{HandshakeIdleTimeout: scaleDuration(500 * time.Millisecond)}),
+			)
+			Expect(err).To(HaveOccurred())
+			var transportErr *quic.TransportError
+			Expect(errors.As(err, &transportErr)).To(BeTrue())
+			Expect(transportErr.Remote).To(BeTrue())
+			// the server sends a handshake_failure alert
+			Expect(transportErr.ErrorCode).To(BeEquivalentTo(0x100 + 40))
+		})
 	})
 
 	Context("Certificate validation", func() {
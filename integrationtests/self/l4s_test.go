@@ -0,0 +1,109 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ecnConnTracer struct {
+	connTracer
+
+	mutex                    sync.Mutex
+	numAcks, numAcksWithECT1 int
+}
+
+func (t *ecnConnTracer) ReceivedPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, frames []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, f := range frames {
+		if ack, ok := f.(*logging.AckFrame); ok {
+			t.numAcks++
+			if ack.ECT1 > 0 {
+				t.numAcksWithECT1++
+			}
+		}
+	}
+}
+
+var _ = Describe("L4S", func() {
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+			Skip("setting the ECN codepoint is not supported on this platform")
+		}
+	})
+
+	runTransfer := func(useProxy bool) *ecnConnTracer {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{EnableL4S: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			conn.CloseWithError(0, "")
+		}()
+
+		serverPort := server.Addr().(*net.UDPAddr).Port
+		if useProxy {
+			// The proxy forwards the payload of the UDP datagrams, but not the ECN codepoint.
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{RemoteAddr: fmt.Sprintf("localhost:%d", serverPort)})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+			serverPort = proxy.LocalPort()
+		}
+
+		tracer := &ecnConnTracer{}
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", serverPort),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				EnableL4S: true,
+				Tracer:    newTracer(func() logging.ConnectionTracer { return tracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(conn.Context().Done()).Should(BeClosed())
+		return tracer
+	}
+
+	It("marks packets with ECT(1)", func() {
+		tracer := runTransfer(false)
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		Expect(tracer.numAcks).ToNot(BeZero())
+		Expect(tracer.numAcksWithECT1).To(Equal(tracer.numAcks))
+	})
+
+	It("falls back to not marking packets if the ECN codepoint is cleared on the path", func() {
+		tracer := runTransfer(true)
+		tracer.mutex.Lock()
+		defer tracer.mutex.Unlock()
+		Expect(tracer.numAcks).ToNot(BeZero())
+		Expect(tracer.numAcksWithECT1).To(BeZero())
+	})
+})
@@ -0,0 +1,118 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Non-blocking stream I/O", func() {
+	const numStreams = 100
+
+	// nonBlockingStream is a stream that is driven by the event loop
+	type nonBlockingStream struct {
+		quic.Stream
+		pending  int32 // set while the stream is queued for the event loop
+		toSend   []byte
+		received []byte
+		finished bool
+	}
+
+	It("multiplexes streams on a single Go routine", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		// The server echoes the data on every stream, using a Go routine per stream.
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for {
+				str, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		// The callbacks don't call any methods on the stream, they only hand it to the event loop.
+		// Every stream is queued at most once, so the channel never blocks.
+		ready := make(chan *nonBlockingStream, numStreams)
+		notify := func(s *nonBlockingStream) func() {
+			return func() {
+				if atomic.CompareAndSwapInt32(&s.pending, 0, 1) {
+					ready <- s
+				}
+			}
+		}
+		streams := make([]*nonBlockingStream, numStreams)
+		for i := 0; i < numStreams; i++ {
+			str, err := conn.OpenStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			s := &nonBlockingStream{Stream: str, toSend: GeneratePRData(500 * (i + 1))}
+			streams[i] = s
+			str.SetReadNotify(notify(s))
+			str.SetWriteNotify(notify(s))
+		}
+
+		b := make([]byte, 1000)
+		var numFinished int
+		for numFinished < numStreams {
+			var s *nonBlockingStream
+			Eventually(ready).Should(Receive(&s))
+			atomic.StoreInt32(&s.pending, 0)
+			for len(s.toSend) > 0 {
+				n, err := s.TryWrite(s.toSend)
+				if errors.Is(err, quic.ErrWouldBlock) {
+					break
+				}
+				Expect(err).ToNot(HaveOccurred())
+				s.toSend = s.toSend[n:]
+				if len(s.toSend) == 0 {
+					Expect(s.Close()).To(Succeed())
+				}
+			}
+			for !s.finished {
+				n, err := s.TryRead(b)
+				s.received = append(s.received, b[:n]...)
+				if errors.Is(err, quic.ErrWouldBlock) {
+					break
+				}
+				if err == io.EOF {
+					s.finished = true
+					numFinished++
+					break
+				}
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+		for i, s := range streams {
+			Expect(s.received).To(Equal(GeneratePRData(500 * (i + 1))))
+		}
+	})
+})
@@ -0,0 +1,186 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/qlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingBuffer struct {
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+	closed chan struct{}
+}
+
+func newRecordingBuffer() *recordingBuffer {
+	return &recordingBuffer{closed: make(chan struct{})}
+}
+
+func (b *recordingBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *recordingBuffer) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func (b *recordingBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Bytes()
+}
+
+var _ = Describe("Recording and Replay", func() {
+	replay := func(recording []byte, tlsConf *tls.Config) ([]byte, error) {
+		qlogBuf := newRecordingBuffer()
+		err := quic.Replay(bytes.NewReader(recording), tlsConf, &quic.Config{
+			Tracer: qlog.NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return qlogBuf }),
+		})
+		Eventually(qlogBuf.closed).Should(BeClosed())
+		return qlogBuf.Bytes(), err
+	}
+
+	It("replays a recorded connection", func() {
+		serverRecording := newRecordingBuffer()
+		clientRecording := newRecordingBuffer()
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				Recorder: func(p logging.Perspective, _ []byte) io.WriteCloser {
+					Expect(p).To(Equal(logging.PerspectiveServer))
+					return serverRecording
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			<-conn.Context().Done()
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Recorder: func(p logging.Perspective, _ []byte) io.WriteCloser {
+					Expect(p).To(Equal(logging.PerspectiveClient))
+					return clientRecording
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		// give the server some time to send the HANDSHAKE_DONE and NEW_CONNECTION_ID frames
+		time.Sleep(scaleDuration(50 * time.Millisecond))
+		Expect(conn.CloseWithError(0x1337, "done")).To(Succeed())
+		Eventually(clientRecording.closed).Should(BeClosed())
+		Eventually(serverRecording.closed).Should(BeClosed())
+
+		for _, r := range []struct {
+			recording []byte
+			tlsConf   *tls.Config
+			local     bool
+		}{
+			{recording: serverRecording.Bytes(), tlsConf: getTLSConfig()},
+			{recording: clientRecording.Bytes(), tlsConf: getTLSClientConfig(), local: true},
+		} {
+			qlog1, err := replay(r.recording, r.tlsConf)
+			Expect(errors.Is(err, quic.ErrReplayDiverged)).To(BeFalse())
+			var appErr *quic.ApplicationError
+			Expect(errors.As(err, &appErr)).To(BeTrue())
+			Expect(appErr.ErrorCode).To(BeEquivalentTo(0x1337))
+			Expect(appErr.Remote).To(Equal(!r.local))
+			Expect(qlog1).To(ContainSubstring("handshake_done"))
+
+			qlog2, err := replay(r.recording, r.tlsConf)
+			Expect(errors.As(err, &appErr)).To(BeTrue())
+			Expect(qlog2).To(Equal(qlog1))
+		}
+	})
+
+	It("replays a connection recorded with a fault injector", func() {
+		injector := quic.NewFaultInjector()
+		Expect(injector.SetRules(
+			quic.FaultRule{Action: quic.FaultActionDuplicate, Probability: 0.5, Direction: quic.FaultDirectionOutgoing},
+			quic.FaultRule{Action: quic.FaultActionDelay, Probability: 0.5, Delay: scaleDuration(5 * time.Millisecond), Direction: quic.FaultDirectionOutgoing},
+		)).To(Succeed())
+		rec := newRecordingBuffer()
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				FaultInjector: injector,
+				Recorder:      func(logging.Perspective, []byte) io.WriteCloser { return rec },
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(scaleDuration(50 * time.Millisecond))
+		Expect(conn.CloseWithError(0x1337, "done")).To(Succeed())
+		Eventually(rec.closed).Should(BeClosed())
+
+		qlog1, err := replay(rec.Bytes(), getTLSConfig())
+		Expect(errors.Is(err, quic.ErrReplayDiverged)).To(BeFalse())
+		var appErr *quic.ApplicationError
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(appErr.ErrorCode).To(BeEquivalentTo(0x1337))
+		Expect(qlog1).To(ContainSubstring("fault_injected"))
+		qlog2, err := replay(rec.Bytes(), getTLSConfig())
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(qlog2).To(Equal(qlog1))
+	})
+
+	It("detects when a replay diverges", func() {
+		rec := newRecordingBuffer()
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				Recorder: func(logging.Perspective, []byte) io.WriteCloser { return rec },
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+		Eventually(rec.closed).Should(BeClosed())
+
+		// Replaying with a different certificate changes the size of the server's flight,
+		// and therefore the packet numbers and the amount of randomness consumed.
+		_, err = replay(rec.Bytes(), getTLSConfigWithLongCertChain())
+		Expect(err).To(MatchError(quic.ErrReplayDiverged))
+	})
+})
@@ -341,6 +341,7 @@ var _ logging.ConnectionTracer = &connTracer{}
 
 func (t *connTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
 }
+func (t *connTracer) StartedHandshake(context.Context, *logging.HandshakeInfo) {}
 
 func (t *connTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
 }
@@ -363,7 +364,10 @@ func (t *connTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketN
 func (t *connTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
 func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *connTracer) UpdatedCongestionParameters(*logging.CongestionParameters)          {}
+func (t *connTracer) UpdatedCongestionMetrics(*logging.CongestionMetrics)                {}
 func (t *connTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *connTracer) UpdatedFlowLabel(uint32, logging.FlowLabelTrigger)                  {}
 func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
 func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
@@ -373,6 +377,13 @@ func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel
 func (t *connTracer) LossTimerCanceled()                                                 {}
 func (t *connTracer) Debug(string, string)                                               {}
 func (t *connTracer) Close()                                                             {}
+func (t *connTracer) InjectedFault(logging.FaultDirection, logging.FaultAction, logging.PacketType, logging.ByteCount) {
+}
+func (t *connTracer) QueuedDatagram(logging.ByteCount)                              {}
+func (t *connTracer) SentDatagram(logging.ByteCount)                                {}
+func (t *connTracer) DroppedDatagram(logging.ByteCount, logging.DatagramDropReason) {}
+func (t *connTracer) ReceivedDatagram(logging.ByteCount)                            {}
+func (t *connTracer) LostDatagram(logging.ByteCount)                                {}
 
 type packet struct {
 	time   time.Time
@@ -0,0 +1,96 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Leak Detector", func() {
+	It("resets streams that the application abandoned", func() {
+		leaks := make(chan *quic.LeakedStream, 10)
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				MaxIncomingStreams: 1,
+				StreamLeakDetector: &quic.StreamLeakDetector{
+					ResetLeakedStreams: true,
+					ResetErrorCode:     42,
+					OnLeak:             func(l *quic.LeakedStream) { leaks <- l },
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		// acceptAndAbandon reads the request, but neither reads until the end of the stream nor closes it
+		acceptAndAbandon := func(conn quic.Connection) {
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadFull(str, make([]byte, 6))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+			acceptAndAbandon(conn)
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var l *quic.LeakedStream
+		Eventually(func() bool {
+			runtime.GC()
+			select {
+			case l = <-leaks:
+				return true
+			default:
+				return false
+			}
+		}).Should(BeTrue())
+		Expect(l.StreamID).To(Equal(str.StreamID()))
+		Expect(l.Reason).To(Equal(quic.StreamLeakUnreachable))
+		Expect(l.Accepted).To(BeTrue())
+		Expect(l.SendOpen).To(BeTrue())
+		Expect(l.ReceiveOpen).To(BeTrue())
+		Expect(l.Reset).To(BeTrue())
+		Expect(l.Stack).To(ContainSubstring("stream_leak_test.go"))
+
+		_, err = io.ReadAll(str)
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(BeEquivalentTo(42))
+
+		// The server only allows a single stream.
+		// Since the leaked stream was reset, the client can open a new one.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = conn.OpenStreamSync(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		serverConn := <-serverConnChan
+		serverConn.CloseWithError(0, "")
+	})
+})
@@ -38,6 +38,7 @@ var _ logging.ConnectionTracer = &customConnTracer{}
 
 func (t *customConnTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
 }
+func (t *customConnTracer) StartedHandshake(context.Context, *logging.HandshakeInfo) {}
 
 func (t *customConnTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
 }
@@ -64,7 +65,10 @@ func (t *customConnTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.P
 func (t *customConnTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
 func (t *customConnTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *customConnTracer) UpdatedCongestionParameters(*logging.CongestionParameters)          {}
+func (t *customConnTracer) UpdatedCongestionMetrics(*logging.CongestionMetrics)                {}
 func (t *customConnTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *customConnTracer) UpdatedFlowLabel(uint32, logging.FlowLabelTrigger)                  {}
 func (t *customConnTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
 func (t *customConnTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
 func (t *customConnTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
@@ -74,6 +78,13 @@ func (t *customConnTracer) LossTimerExpired(logging.TimerType, logging.Encryptio
 func (t *customConnTracer) LossTimerCanceled()                                                 {}
 func (t *customConnTracer) Debug(string, string)                                               {}
 func (t *customConnTracer) Close()                                                             {}
+func (t *customConnTracer) InjectedFault(logging.FaultDirection, logging.FaultAction, logging.PacketType, logging.ByteCount) {
+}
+func (t *customConnTracer) QueuedDatagram(logging.ByteCount)                              {}
+func (t *customConnTracer) SentDatagram(logging.ByteCount)                                {}
+func (t *customConnTracer) DroppedDatagram(logging.ByteCount, logging.DatagramDropReason) {}
+func (t *customConnTracer) ReceivedDatagram(logging.ByteCount)                            {}
+func (t *customConnTracer) LostDatagram(logging.ByteCount)                                {}
 
 var _ = Describe("Handshake tests", func() {
 	addTracers := func(pers protocol.Perspective, conf *quic.Config) *quic.Config {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 )
 
@@ -18,6 +19,19 @@ type StreamID = protocol.StreamID
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
+// HandshakeInfo contains information about a connection that is known when the handshake starts.
+// For the server, this is the information from the ClientHello.
+type HandshakeInfo = logging.HandshakeInfo
+
+// A VirtualClock is a clock whose time only changes when it is advanced.
+// It allows running connections in simulations, see Config.Clock.
+type VirtualClock = utils.VirtualClock
+
+// NewVirtualClock creates a new VirtualClock, starting at start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return utils.NewVirtualClock(start)
+}
+
 const (
 	// VersionDraft29 is IETF QUIC draft-29
 	VersionDraft29 = protocol.VersionDraft29
@@ -60,6 +74,13 @@ type TokenStore interface {
 // when the server rejects a 0-RTT connection attempt.
 var Err0RTTRejected = errors.New("0-RTT rejected")
 
+// ErrWouldBlock is returned by TryRead and TryWrite when the operation can't be performed without blocking.
+var ErrWouldBlock = errors.New("operation would block")
+
+// ErrExpired is returned when data expired before it could be sent.
+// See SendStream.SetExpiry and Connection.SendMessageWithExpiry.
+var ErrExpired = errors.New("data expired")
+
 // ConnectionTracingKey can be used to associate a ConnectionTracer with a Connection.
 // It is set on the Connection.Context() context,
 // as well as on the context passed to logging.Tracer.NewConnectionTracer.
@@ -101,6 +122,18 @@ type ReceiveStream interface {
 	// A zero value for t means Read will not time out.
 
 	SetReadDeadline(t time.Time) error
+	// TryRead is the non-blocking version of Read.
+	// It reads the data that is available, and returns ErrWouldBlock if no data is available.
+	// It is subject to the same restrictions as Read, and must not be called concurrently with Read.
+	// The read deadline doesn't apply to TryRead.
+	TryRead(p []byte) (int, error)
+	// SetReadNotify sets a callback that is called when the stream might have become readable:
+	// when data arrives, when the stream is reset by the peer, and when the connection is closed.
+	// Notifications may be spurious, in which case TryRead returns ErrWouldBlock.
+	// The callback is also called once when it is set.
+	// It is called from quic-go's internal Go routines, and must neither block nor call any methods on the stream.
+	// Setting the callback to nil disables notifications.
+	SetReadNotify(f func())
 }
 
 // A SendStream is a unidirectional Send Stream.
@@ -135,6 +168,39 @@ type SendStream interface {
 	// some data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// TryWrite is the non-blocking version of Write.
+	// It copies as much of p as fits into the stream's send buffer, and returns the number of bytes copied.
+	// If the send buffer is full, it returns ErrWouldBlock.
+	// It is subject to the same restrictions as Write, and must not be called concurrently with Write.
+	// The write deadline doesn't apply to TryWrite.
+	TryWrite(p []byte) (int, error)
+	// SetWriteNotify sets a callback that is called when the stream might have become writable:
+	// when space in the send buffer becomes available, when the stream is canceled, and when the connection is closed.
+	// Notifications may be spurious, in which case TryWrite returns ErrWouldBlock.
+	// The callback is also called once when it is set.
+	// It is called from quic-go's internal Go routines, and must neither block nor call any methods on the stream.
+	// Setting the callback to nil disables notifications.
+	SetWriteNotify(f func())
+	// SetCorkDelay corks the stream: small writes are then not sent right away.
+	// Instead, sending is delayed by up to d (at most 1 second), such that more data can be written,
+	// until enough data for a full packet is buffered, or until Flush or Close is called.
+	// This reduces the number of small packets sent when the application performs many small writes.
+	// While data is held back, retransmissions of lost STREAM frames are delayed as well.
+	// ACKs and control frames are never delayed.
+	// A value of 0 uncorks the stream, and sends the data that is currently held back.
+	// The default value is Config.CorkDelay.
+	SetCorkDelay(d time.Duration)
+	// Flush sends the data that is held back because the stream is corked.
+	// It doesn't block until the data has been sent.
+	Flush() error
+	// SetExpiry sets the time at which the data written to the stream expires.
+	// If data written to the stream hasn't been sent by then, the stream is reset using errorCode,
+	// and the unsent data is abandoned. Write then unblocks and returns ErrExpired.
+	// If both peers enable reliable stream resets (see Config.EnableReliableStreamReset),
+	// the data that was sent before the expiry is still delivered to the peer.
+	// Once the expiry has passed, Write returns ErrExpired, until the expiry is set again.
+	// A zero value for t means the data doesn't expire.
+	SetExpiry(t time.Time, errorCode StreamErrorCode)
 }
 
 // A Connection is a QUIC connection between two peers.
@@ -184,6 +250,8 @@ type Connection interface {
 	// The error string will be sent to the peer.
 	CloseWithError(ApplicationErrorCode, string) error
 	// The context is cancelled when the connection is closed.
+	// If Config.ConnContext is set, it is the context returned by that function.
+	// The same context is returned for the whole lifetime of the connection.
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
@@ -192,6 +260,9 @@ type Connection interface {
 
 	// SendMessage sends a message as a datagram, as specified in RFC 9221.
 	SendMessage([]byte) error
+	// SendMessageWithExpiry sends a message as a datagram.
+	// If the datagram can't be sent before the expiry, it is dropped, and ErrExpired is returned.
+	SendMessageWithExpiry(p []byte, expiry time.Time) error
 	// ReceiveMessage gets a message received in a datagram, as specified in RFC 9221.
 	ReceiveMessage() ([]byte, error)
 }
@@ -286,6 +357,79 @@ type Config struct {
 	// If set to 0, then no keep alive is sent. Otherwise, the keep alive is sent on that period (or at most
 	// every half of MaxIdleTimeout, whichever is smaller).
 	KeepAlivePeriod time.Duration
+	// MaxAckDelay is the maximum time by which sending of ACKs for application data packets is delayed.
+	// It is advertised to the peer in the max_ack_delay transport parameter (increased by the timer granularity of 1ms).
+	// Values must not exceed 16 seconds.
+	// If not set, it will default to 25ms.
+	MaxAckDelay time.Duration
+	// AckDelayExponent is the exponent used to encode the ACK Delay field of ACK frames.
+	// It is advertised to the peer in the ack_delay_exponent transport parameter.
+	// Values above 20 are invalid.
+	// If not set, it will default to 3. An exponent of 0 can't be configured.
+	AckDelayExponent uint8
+	// AckElicitingThreshold is the number of ack-eliciting packets that are received
+	// before an ACK is sent without waiting for MaxAckDelay.
+	// If not set, it will default to 2.
+	AckElicitingThreshold int
+	// DisableImmediateAckOnReordering disables sending an ACK immediately
+	// when a packet arrives out of order, or when a new gap in the received packet numbers opens up.
+	// ACKs are then only sent based on the AckElicitingThreshold and the MaxAckDelay.
+	DisableImmediateAckOnReordering bool
+	// MaxAckRanges is the maximum number of ACK ranges sent in an ACK frame.
+	// If not set, it will default to 32.
+	// Values larger than 500 are reduced to 500, since ACK frames with more ranges don't fit into a packet.
+	MaxAckRanges int
+	// InitialCongestionWindow is the initial congestion window, in packets.
+	// It must not be smaller than MinCongestionWindow, and not be larger than MaxCongestionWindow.
+	// If not set, it will default to 32.
+	InitialCongestionWindow int
+	// MinCongestionWindow is the minimum congestion window, in packets.
+	// Values below 2 are invalid.
+	// If not set, it will default to 2.
+	MinCongestionWindow int
+	// MaxCongestionWindow is the maximum congestion window, in packets.
+	// Values above 10000 are invalid.
+	// If not set, it will default to 10000.
+	MaxCongestionWindow int
+	// CongestionLossReductionFactor is the factor that the congestion window is multiplied with on a loss event.
+	// Values must be larger than 0 and smaller than 1.
+	// If not set, it will default to 0.7.
+	CongestionLossReductionFactor float64
+	// EnableL4S enables Low Latency, Low Loss, and Scalable Throughput (L4S, RFC 9330).
+	// Outgoing packets are then marked with ECT(1), and a Prague congestion controller is used,
+	// which reduces the congestion window proportionally to the fraction of CE-marked packets.
+	// Marking packets is only possible on Linux, macOS and FreeBSD, if the connection uses a *net.UDPConn.
+	// If the peer doesn't report ECN counts, or the ECN validation fails, marking is disabled again.
+	EnableL4S bool
+	// EnableFlowLabels sets a random IPv6 flow label on outgoing packets.
+	// Networks using ECMP routing use the flow label to choose between multiple paths.
+	// If the path appears to be persistently congested, or if it is failing (as indicated by repeated PTOs),
+	// a new random flow label is chosen, so that the network moves the connection to a different path.
+	// This is known as Protective Load Balancing (PLB).
+	// Setting the flow label is only possible on Linux, for IPv6 connections that use a *net.UDPConn.
+	EnableFlowLabels bool
+	// DecryptionWorkers is the number of Go routines used to unpack 1-RTT packets (i.e. to remove header protection and decrypt the payload).
+	// When receiving at high rates, packets are then decrypted in parallel, while still being processed in order.
+	// This is useful for a small number of connections with a high throughput.
+	// Every connection starts its own workers.
+	// If zero, packets are decrypted on the connection's Go routine.
+	// It has no effect if the connection is recorded.
+	DecryptionWorkers int
+	// KeyUpdateInterval is the number of packets sent or received with the same 1-RTT keys,
+	// after which a key update is initiated.
+	// Key updates are only initiated after the handshake has been confirmed.
+	// If not set, it will default to 100000.
+	KeyUpdateInterval uint64
+	// CorkDelay is the default cork delay of all streams of a connection.
+	// If set, small amounts of stream data are sent with a delay of up to CorkDelay, in the hope of filling a full packet.
+	// See SendStream.SetCorkDelay for details.
+	// Values above 1 second are invalid.
+	// If zero, stream data is sent immediately.
+	CorkDelay time.Duration
+	// ClientHelloSplitting splits the ClientHello into multiple CRYPTO frames, sent in separate Initial packets.
+	// This makes it harder for middleboxes to read the SNI.
+	// It is only valid for the client.
+	ClientHelloSplitting *ClientHelloSplitting
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
 	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
 	// Note that if Path MTU discovery is causing issues on your system, please open a new issue
@@ -297,11 +441,50 @@ type Config struct {
 	// See https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/.
 	// Datagrams will only be available when both peers enable datagram support.
 	EnableDatagrams bool
-	Tracer          logging.Tracer
+	// EnableReliableStreamReset enables support for the RESET_STREAM_AT frame,
+	// see https://datatracker.ietf.org/doc/draft-ietf-quic-reliable-stream-reset/.
+	// When a stream expires (see SendStream.SetExpiry), the data that was already sent
+	// is still delivered to the peer, if both peers enable reliable stream resets.
+	EnableReliableStreamReset bool
+	Tracer                    logging.Tracer
+	// ConnContext optionally specifies a function that modifies the context used for a new connection.
+	// The context passed in is derived from the connection's base context, and the returned context
+	// must be derived from it, such that it is cancelled when the connection is closed.
+	// If it returns nil, the connection is closed.
+	// It is called when the connection is created. For the server, this happens before the ClientHello is processed,
+	// so the HandshakeInfo only contains the addresses.
+	// The returned context is returned by Connection.Context, and it is passed to the StartedHandshake event of the tracer,
+	// together with the complete HandshakeInfo.
+	// This can be used to label logs and metrics, e.g. with the tenant.
+	ConnContext func(ctx context.Context, info *HandshakeInfo) context.Context
+	// FaultInjector injects faults (packet loss, delay, duplication, reordering and corruption)
+	// into the packets sent and received on a connection.
+	// It is intended for chaos testing, and should not be used in production.
+	FaultInjector *FaultInjector
+	// StreamLeakDetector reports streams that the application abandoned without closing them,
+	// together with the stack trace of where they were opened or accepted.
+	// It is intended for debugging, and should not be used in production.
+	StreamLeakDetector *StreamLeakDetector
+	// Recorder is called for every connection.
+	// If it returns a non-nil io.WriteCloser, everything needed to reproduce the connection
+	// (received packets, timestamps, random numbers and timer firings) is written to it.
+	// The recording can then be replayed offline using Replay.
+	// Recording is intended for debugging, and comes with a performance cost.
+	// Note that the recording contains all the random bytes used in the handshake,
+	// and therefore allows deriving the connection's keys.
+	Recorder func(p logging.Perspective, connectionID []byte) io.WriteCloser
+	// Clock is the clock used by connections, both to obtain the current time and to run their timers
+	// (e.g. for loss detection, the PTO and the idle timeout).
+	// If nil, the system clock is used.
+	// It is intended for simulations and fuzzing, and should not be used in production.
+	// It has no effect if the connection is recorded.
+	Clock *VirtualClock
 }
 
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
+	// TLS contains the state of the TLS handshake.
+	// TLS.CipherSuite is the TLS 1.3 cipher suite negotiated for the 1-RTT keys.
 	TLS               handshake.ConnectionState
 	SupportsDatagrams bool
 }
@@ -314,6 +497,8 @@ type Listener interface {
 	Addr() net.Addr
 	// Accept returns new connections. It should be called in a loop.
 	Accept(context.Context) (Connection, error)
+	// Stats returns statistics about the listener.
+	Stats() EndpointStats
 }
 
 // An EarlyListener listens for incoming QUIC connections,
@@ -325,4 +510,36 @@ type EarlyListener interface {
 	Addr() net.Addr
 	// Accept returns new early connections. It should be called in a loop.
 	Accept(context.Context) (EarlyConnection, error)
+	// Stats returns statistics about the listener.
+	Stats() EndpointStats
+}
+
+// EndpointStats are statistics about the operation of a Listener.
+// Packets are counted for the net.PacketConn the listener is using.
+// If the same net.PacketConn is used to dial connections, packets for those connections are counted as well.
+type EndpointStats struct {
+	// PacketsReceived is the number of packets read from the net.PacketConn.
+	PacketsReceived uint64
+	// PacketsDropped is the number of packets that were dropped before being passed to a connection, by drop reason.
+	// Packets dropped by a connection are not counted.
+	PacketsDropped map[logging.PacketDropReason]uint64
+
+	// HandshakesStarted is the number of connections the server created for new connection attempts.
+	HandshakesStarted uint64
+	// HandshakesCompleted is the number of connections that completed the handshake.
+	HandshakesCompleted uint64
+	// HandshakesFailed is the number of connections that were closed before completing the handshake.
+	HandshakesFailed uint64
+
+	// RetriesSent is the number of Retry packets sent.
+	RetriesSent uint64
+	// VersionNegotiationPacketsSent is the number of Version Negotiation packets sent.
+	VersionNegotiationPacketsSent uint64
+	// StatelessResetsSent is the number of stateless resets sent.
+	StatelessResetsSent uint64
+
+	// AcceptQueueLen is the number of connections waiting to be accepted.
+	AcceptQueueLen int
+	// Connections is the number of server connections that haven't been closed yet.
+	Connections int
 }
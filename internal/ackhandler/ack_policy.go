@@ -0,0 +1,32 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// An AckPolicy determines when ACKs are sent, and how ACK frames are encoded.
+type AckPolicy struct {
+	// MaxAckDelay is the maximum time by which sending of an ACK is delayed.
+	MaxAckDelay time.Duration
+	// AckDelayExponent is the exponent used to encode the ack delay in ACK frames.
+	AckDelayExponent uint8
+	// PacketsBeforeAck is the number of ack-eliciting packets received before an ACK is sent immediately.
+	PacketsBeforeAck int
+	// ImmediateAckOnReordering makes the tracker send an ACK immediately
+	// when a packet is received out of order or when a new gap opens up.
+	ImmediateAckOnReordering bool
+	// MaxAckRanges is the maximum number of ACK ranges sent in an ACK frame.
+	MaxAckRanges int
+}
+
+// DefaultAckPolicy is the ACK policy used when nothing else is configured.
+// It is always used for Initial and Handshake packets.
+var DefaultAckPolicy = AckPolicy{
+	MaxAckDelay:              protocol.MaxAckDelay,
+	AckDelayExponent:         protocol.AckDelayExponent,
+	PacketsBeforeAck:         2,
+	ImmediateAckOnReordering: true,
+	MaxAckRanges:             protocol.MaxNumAckRanges,
+}
@@ -1,21 +1,33 @@
 package ackhandler
 
 import (
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 )
 
-// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler
+// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
+// The clock and the source of randomness are used by both handlers.
+// If rand is nil, crypto/rand is used.
+// The ACK policy is applied to application data packets.
+// If outgoing packets are marked with ECT(1), the Prague congestion controller is used.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	clock utils.Clock,
+	rand io.Reader,
+	ackPolicy AckPolicy,
+	congestionConf congestion.Config,
+	ecn protocol.ECN,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, pers, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, pers, clock, rand, congestionConf, ecn, tracer, logger)
+	return sph, newReceivedPacketHandler(sph, rttStats, ackPolicy, clock, logger, version)
 }
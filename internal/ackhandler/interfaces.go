@@ -5,6 +5,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 // A Packet is a packet
@@ -41,6 +42,13 @@ type SentPacketHandler interface {
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// ECNMode is the ECN codepoint that outgoing packets should be marked with.
+	// It changes to protocol.ECNNon if ECN validation fails.
+	ECNMode() protocol.ECN
+	// PopRepath says if the flow should be moved to a different path,
+	// because the current path is persistently congested or failing (see Protective Load Balancing).
+	// It returns true at most once for every such event.
+	PopRepath() (bool, logging.FlowLabelTrigger)
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
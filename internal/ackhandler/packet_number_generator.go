@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"io"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
@@ -45,11 +47,13 @@ type skippingPacketNumberGenerator struct {
 
 var _ packetNumberGenerator = &skippingPacketNumberGenerator{}
 
-func newSkippingPacketNumberGenerator(initial, initialPeriod, maxPeriod protocol.PacketNumber) packetNumberGenerator {
+// If rand is nil, crypto/rand is used to decide which packet numbers to skip.
+func newSkippingPacketNumberGenerator(initial, initialPeriod, maxPeriod protocol.PacketNumber, rand io.Reader) packetNumberGenerator {
 	g := &skippingPacketNumberGenerator{
 		next:      initial,
 		period:    initialPeriod,
 		maxPeriod: maxPeriod,
+		rng:       utils.Rand{Source: rand},
 	}
 	g.generateNewSkip()
 	return g
@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 
@@ -33,12 +34,21 @@ var _ = Describe("Skipping Packet Number Generator", func() {
 	})
 
 	It("can be initialized to return any first packet number", func() {
-		png := newSkippingPacketNumberGenerator(12345, initialPeriod, maxPeriod)
+		png := newSkippingPacketNumberGenerator(12345, initialPeriod, maxPeriod, nil)
 		Expect(png.Pop()).To(Equal(protocol.PacketNumber(12345)))
 	})
 
+	It("skips the same packet numbers when using the same source of randomness", func() {
+		random := bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 100)
+		png1 := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod, bytes.NewReader(random))
+		png2 := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod, bytes.NewReader(random))
+		for i := 0; i < 1000; i++ {
+			Expect(png1.Pop()).To(Equal(png2.Pop()))
+		}
+	})
+
 	It("allows peeking", func() {
-		png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod).(*skippingPacketNumberGenerator)
+		png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod, nil).(*skippingPacketNumberGenerator)
 		png.nextToSkip = 1000
 		Expect(png.Peek()).To(Equal(initialPN))
 		Expect(png.Peek()).To(Equal(initialPN))
@@ -48,7 +58,7 @@ var _ = Describe("Skipping Packet Number Generator", func() {
 	})
 
 	It("skips a packet number", func() {
-		png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod)
+		png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod, nil)
 		var last protocol.PacketNumber
 		var skipped bool
 		for i := 0; i < 1000; i++ {
@@ -68,7 +78,7 @@ var _ = Describe("Skipping Packet Number Generator", func() {
 		expectedPeriods := []protocol.PacketNumber{25, 50, 100, 200, 300, 300, 300}
 
 		for i := 0; i < rep; i++ {
-			png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod)
+			png := newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod, nil)
 			last := initialPN
 			lastSkip := initialPN
 			for len(periods[i]) < len(expectedPeriods) {
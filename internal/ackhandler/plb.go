@@ -0,0 +1,103 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+const (
+	// A round is considered congested if at least this fraction of the packets acknowledged or lost in that round
+	// were either CE-marked or lost.
+	plbCongestedFractionThreshold = 0.5
+	// The number of consecutive congested rounds after which the flow is moved to a different path.
+	plbCongestedRounds = 12
+	// The number of consecutive PTOs after which the flow is moved to a different path.
+	plbPTOThreshold = 2
+	// After repathing because of PTOs, the flow isn't moved because of congestion for this time.
+	// This prevents moving back onto a path that was failing.
+	plbPauseAfterPTO = 60 * time.Second
+)
+
+// plb detects when a flow should be moved to a different path, as done by Protective Load Balancing (PLB).
+// See https://dl.acm.org/doi/10.1145/3544216.3544226 for details.
+// A flow is repathed when it experienced congestion for a number of consecutive rounds (round trips),
+// or when repeated PTOs indicate that the path is failing.
+type plb struct {
+	rttStats *utils.RTTStats
+
+	// The number of packets acknowledged or lost in the current round,
+	// and how many of them were CE-marked or lost.
+	roundEnd     time.Time
+	numPackets   int
+	numCongested int
+
+	congestedRounds int
+	pausedUntil     time.Time
+
+	repath        bool
+	repathTrigger logging.FlowLabelTrigger
+}
+
+func newPLB(rttStats *utils.RTTStats) *plb {
+	return &plb{rttStats: rttStats}
+}
+
+// OnAck is called for ACK frames that newly acknowledge packets.
+// numCEMarked is the number of newly acknowledged packets that were CE-marked.
+func (p *plb) OnAck(numAcked, numCEMarked int, now time.Time) {
+	p.maybeEndRound(now)
+	p.numPackets += numAcked
+	p.numCongested += numCEMarked
+}
+
+// OnPacketLost is called when a packet is declared lost.
+func (p *plb) OnPacketLost(now time.Time) {
+	p.maybeEndRound(now)
+	p.numPackets++
+	p.numCongested++
+}
+
+func (p *plb) maybeEndRound(now time.Time) {
+	if p.roundEnd.IsZero() {
+		p.roundEnd = now.Add(p.rttStats.SmoothedRTT())
+		return
+	}
+	if now.Before(p.roundEnd) {
+		return
+	}
+	if p.numPackets > 0 && float64(p.numCongested) >= plbCongestedFractionThreshold*float64(p.numPackets) {
+		p.congestedRounds++
+	} else {
+		p.congestedRounds = 0
+	}
+	p.numPackets = 0
+	p.numCongested = 0
+	p.roundEnd = now.Add(p.rttStats.SmoothedRTT())
+	if p.congestedRounds >= plbCongestedRounds && !now.Before(p.pausedUntil) {
+		p.congestedRounds = 0
+		p.repath = true
+		p.repathTrigger = logging.FlowLabelTriggerCongestion
+	}
+}
+
+// OnPTO is called when the PTO timer fires.
+func (p *plb) OnPTO(ptoCount uint32, now time.Time) {
+	if ptoCount < plbPTOThreshold {
+		return
+	}
+	p.congestedRounds = 0
+	p.pausedUntil = now.Add(plbPauseAfterPTO)
+	p.repath = true
+	p.repathTrigger = logging.FlowLabelTriggerPTO
+}
+
+// PopRepath returns if the flow should be moved to a different path, and why.
+func (p *plb) PopRepath() (bool, logging.FlowLabelTrigger) {
+	if !p.repath {
+		return false, 0
+	}
+	p.repath = false
+	return true, p.repathTrigger
+}
@@ -0,0 +1,104 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Protective Load Balancing", func() {
+	const rtt = 10 * time.Millisecond
+
+	var (
+		p   *plb
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		rttStats := &utils.RTTStats{}
+		rttStats.UpdateRTT(rtt, 0, time.Time{})
+		p = newPLB(rttStats)
+		now = time.Now()
+	})
+
+	// runRounds runs the given number of rounds, with numCE out of 10 packets being CE-marked in every round.
+	runRounds := func(rounds, numCE int) {
+		for i := 0; i < rounds; i++ {
+			p.OnAck(10-numCE, 0, now)
+			p.OnAck(numCE, numCE, now)
+			now = now.Add(rtt)
+		}
+	}
+
+	It("doesn't repath if there's no congestion", func() {
+		runRounds(100, 2)
+		repath, _ := p.PopRepath()
+		Expect(repath).To(BeFalse())
+	})
+
+	It("repaths after a number of congested rounds", func() {
+		runRounds(plbCongestedRounds, 5)
+		repath, _ := p.PopRepath()
+		Expect(repath).To(BeFalse())
+		runRounds(1, 5)
+		repath, trigger := p.PopRepath()
+		Expect(repath).To(BeTrue())
+		Expect(trigger).To(Equal(logging.FlowLabelTriggerCongestion))
+		// only returns true once
+		repath, _ = p.PopRepath()
+		Expect(repath).To(BeFalse())
+	})
+
+	It("only repaths if the rounds are consecutive", func() {
+		runRounds(plbCongestedRounds-1, 8)
+		runRounds(1, 1)
+		runRounds(plbCongestedRounds-1, 8)
+		repath, _ := p.PopRepath()
+		Expect(repath).To(BeFalse())
+	})
+
+	It("counts lost packets as congested", func() {
+		for i := 0; i <= plbCongestedRounds; i++ {
+			p.OnAck(5, 0, now)
+			for j := 0; j < 5; j++ {
+				p.OnPacketLost(now)
+			}
+			now = now.Add(rtt)
+		}
+		repath, trigger := p.PopRepath()
+		Expect(repath).To(BeTrue())
+		Expect(trigger).To(Equal(logging.FlowLabelTriggerCongestion))
+	})
+
+	It("repaths after repeated PTOs", func() {
+		p.OnPTO(1, now)
+		repath, _ := p.PopRepath()
+		Expect(repath).To(BeFalse())
+		p.OnPTO(2, now)
+		repath, trigger := p.PopRepath()
+		Expect(repath).To(BeTrue())
+		Expect(trigger).To(Equal(logging.FlowLabelTriggerPTO))
+		p.OnPTO(3, now)
+		repath, trigger = p.PopRepath()
+		Expect(repath).To(BeTrue())
+		Expect(trigger).To(Equal(logging.FlowLabelTriggerPTO))
+	})
+
+	It("doesn't repath because of congestion shortly after repathing because of PTOs", func() {
+		p.OnPTO(2, now)
+		repath, _ := p.PopRepath()
+		Expect(repath).To(BeTrue())
+		runRounds(2*plbCongestedRounds, 10)
+		repath, _ = p.PopRepath()
+		Expect(repath).To(BeFalse())
+		now = now.Add(plbPauseAfterPTO)
+		runRounds(plbCongestedRounds+1, 10)
+		repath, trigger := p.PopRepath()
+		Expect(repath).To(BeTrue())
+		Expect(trigger).To(Equal(logging.FlowLabelTriggerCongestion))
+	})
+})
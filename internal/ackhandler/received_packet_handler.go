@@ -24,14 +24,16 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
 	rttStats *utils.RTTStats,
+	ackPolicy AckPolicy,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(rttStats, DefaultAckPolicy, clock, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, DefaultAckPolicy, clock, logger, version),
+		appDataPackets:   newReceivedPacketTracker(rttStats, ackPolicy, clock, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
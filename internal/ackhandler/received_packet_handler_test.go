@@ -22,6 +22,8 @@ var _ = Describe("Received Packet Handler", func() {
 		handler = newReceivedPacketHandler(
 			sentPackets,
 			&utils.RTTStats{},
+			DefaultAckPolicy,
+			utils.DefaultClock{},
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
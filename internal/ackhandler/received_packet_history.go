@@ -10,14 +10,16 @@ import (
 // It generates ACK ranges which can be used to assemble an ACK frame.
 // It does not store packet contents.
 type receivedPacketHistory struct {
-	ranges *utils.PacketIntervalList
+	ranges       *utils.PacketIntervalList
+	maxNumRanges int
 
 	deletedBelow protocol.PacketNumber
 }
 
-func newReceivedPacketHistory() *receivedPacketHistory {
+func newReceivedPacketHistory(maxNumRanges int) *receivedPacketHistory {
 	return &receivedPacketHistory{
-		ranges: utils.NewPacketIntervalList(),
+		ranges:       utils.NewPacketIntervalList(),
+		maxNumRanges: maxNumRanges,
 	}
 }
 
@@ -71,10 +73,10 @@ func (h *receivedPacketHistory) addToRanges(p protocol.PacketNumber) bool /* is
 	return true
 }
 
-// Delete old ranges, if we're tracking more than 500 of them.
+// Delete old ranges, if we're tracking more than maxNumRanges of them.
 // This is a DoS defense against a peer that sends us too many gaps.
 func (h *receivedPacketHistory) maybeDeleteOldRanges() {
-	for h.ranges.Len() > protocol.MaxNumAckRanges {
+	for h.ranges.Len() > h.maxNumRanges {
 		h.ranges.Remove(h.ranges.Front())
 	}
 }
@@ -16,7 +16,7 @@ var _ = Describe("receivedPacketHistory", func() {
 	var hist *receivedPacketHistory
 
 	BeforeEach(func() {
-		hist = newReceivedPacketHistory()
+		hist = newReceivedPacketHistory(protocol.MaxNumAckRanges)
 	})
 
 	Context("ranges", func() {
@@ -8,9 +8,6 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
-// number of ack-eliciting packets received before sending an ack.
-const packetsBeforeAck = 2
-
 type receivedPacketTracker struct {
 	largestObserved             protocol.PacketNumber
 	ignoreBelow                 protocol.PacketNumber
@@ -19,8 +16,8 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
-	maxAckDelay time.Duration
-	rttStats    *utils.RTTStats
+	ackPolicy AckPolicy
+	rttStats  *utils.RTTStats
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
@@ -29,6 +26,7 @@ type receivedPacketTracker struct {
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
 
+	clock  utils.Clock
 	logger utils.Logger
 
 	version protocol.VersionNumber
@@ -36,13 +34,16 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *utils.RTTStats,
+	ackPolicy AckPolicy,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
+		packetHistory: newReceivedPacketHistory(ackPolicy.MaxAckRanges),
+		ackPolicy:     ackPolicy,
 		rttStats:      rttStats,
+		clock:         clock,
 		logger:        logger,
 		version:       version,
 	}
@@ -125,28 +126,28 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && h.ackPolicy.ImmediateAckOnReordering {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %d was missing before.", pn)
 		}
 		h.ackQueued = true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	// send an ACK every PacketsBeforeAck ack-eliciting packets
+	if h.ackElicitingPacketsReceivedSinceLastAck >= h.ackPolicy.PacketsBeforeAck {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.ackPolicy.PacketsBeforeAck)
 		}
 		h.ackQueued = true
 	} else if h.ackAlarm.IsZero() {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tSetting ACK timer to max ack delay: %s", h.maxAckDelay)
+			h.logger.Debugf("\tSetting ACK timer to max ack delay: %s", h.ackPolicy.MaxAckDelay)
 		}
-		h.ackAlarm = rcvTime.Add(h.maxAckDelay)
+		h.ackAlarm = rcvTime.Add(h.ackPolicy.MaxAckDelay)
 	}
 
 	// Queue an ACK if there are new missing packets to report.
-	if h.hasNewMissingPackets() {
+	if h.ackPolicy.ImmediateAckOnReordering && h.hasNewMissingPackets() {
 		h.logger.Debugf("\tQueuing ACK because there's a new missing packet to report.")
 		h.ackQueued = true
 	}
@@ -161,7 +162,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	if !h.hasNewAck {
 		return nil
 	}
-	now := time.Now()
+	now := h.clock.Now()
 	if onlyIfQueued {
 		if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 			return nil
@@ -175,10 +176,11 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 		AckRanges: h.packetHistory.GetAckRanges(),
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
-		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
-		ECT0:      h.ect0,
-		ECT1:      h.ect1,
-		ECNCE:     h.ecnce,
+		DelayTime:     utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		DelayExponent: h.ackPolicy.AckDelayExponent,
+		ECT0:          h.ect0,
+		ECT1:          h.ect1,
+		ECNCE:         h.ecnce,
 	}
 
 	h.lastAck = ack
@@ -19,7 +19,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(rttStats, DefaultAckPolicy, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -189,6 +189,76 @@ var _ = Describe("Received Packet Tracker", func() {
 				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 			})
+
+			Context("using a custom ACK policy", func() {
+				setPolicy := func(f func(*AckPolicy)) {
+					policy := DefaultAckPolicy
+					f(&policy)
+					tracker = newReceivedPacketTracker(rttStats, policy, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
+				}
+
+				It("uses the configured max ack delay", func() {
+					setPolicy(func(p *AckPolicy) { p.MaxAckDelay = 3 * time.Millisecond })
+					receiveAndAck10Packets()
+					rcvTime := time.Now()
+					tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
+					Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(3 * time.Millisecond)))
+				})
+
+				It("uses the configured ack-eliciting threshold", func() {
+					setPolicy(func(p *AckPolicy) { p.PacketsBeforeAck = 5 })
+					receiveAndAck10Packets()
+					p := protocol.PacketNumber(11)
+					for i := 0; i < 3; i++ {
+						for j := 0; j < 4; j++ {
+							tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
+							Expect(tracker.ackQueued).To(BeFalse())
+							p++
+						}
+						tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
+						Expect(tracker.ackQueued).To(BeTrue())
+						p++
+						Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+					}
+				})
+
+				It("doesn't queue an ACK on reordering, if disabled", func() {
+					setPolicy(func(p *AckPolicy) {
+						p.ImmediateAckOnReordering = false
+						p.PacketsBeforeAck = 10
+					})
+					receiveAndAck10Packets()
+					tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+					// a new gap
+					tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					ack := tracker.GetAckFrame(false) // ACK: 1-11 and 13, missing: 12
+					Expect(ack.HasMissingRanges()).To(BeTrue())
+					// a packet that was reported missing
+					tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				})
+
+				It("encodes ACK frames using the configured ack delay exponent", func() {
+					setPolicy(func(p *AckPolicy) { p.AckDelayExponent = 10 })
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.GetAckFrame(true).DelayExponent).To(BeEquivalentTo(10))
+				})
+
+				It("limits the number of ACK ranges", func() {
+					setPolicy(func(p *AckPolicy) { p.MaxAckRanges = 3 })
+					for i := 0; i < 10; i++ {
+						tracker.ReceivedPacket(protocol.PacketNumber(2*i), protocol.ECNNon, time.Now(), true)
+					}
+					ack := tracker.GetAckFrame(false)
+					Expect(ack.AckRanges).To(Equal([]wire.AckRange{
+						{Smallest: 18, Largest: 18},
+						{Smallest: 16, Largest: 16},
+						{Smallest: 14, Largest: 14},
+					}))
+				})
+			})
 		})
 
 		Context("ACK generation", func() {
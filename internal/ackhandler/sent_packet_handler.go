@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -36,10 +37,10 @@ type packetNumberSpace struct {
 	largestSent  protocol.PacketNumber
 }
 
-func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStats *utils.RTTStats) *packetNumberSpace {
+func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStats *utils.RTTStats, rand io.Reader) *packetNumberSpace {
 	var pns packetNumberGenerator
 	if skipPNs {
-		pns = newSkippingPacketNumberGenerator(initialPN, protocol.SkipPacketInitialPeriod, protocol.SkipPacketMaxPeriod)
+		pns = newSkippingPacketNumberGenerator(initialPN, protocol.SkipPacketInitialPeriod, protocol.SkipPacketMaxPeriod, rand)
 	} else {
 		pns = newSequentialPacketNumberGenerator(initialPN)
 	}
@@ -78,8 +79,21 @@ type sentPacketHandler struct {
 	bytesInFlight protocol.ByteCount
 
 	congestion congestion.SendAlgorithmWithDebugInfos
+	// ecnHandler is set if the congestion controller reacts to ECN feedback.
+	ecnHandler congestion.ECNHandler
 	rttStats   *utils.RTTStats
 
+	// The ECN codepoint that outgoing packets are marked with.
+	// It is set to protocol.ECNNon if ECN validation fails.
+	ecn protocol.ECN
+	// The ECN counts reported in the last ACK frame for the application data packet number space.
+	ect0, ect1, ecnce uint64
+	// The number of packets acknowledged since the ECN counts were last evaluated.
+	numAckedSinceECNCounts int
+
+	// plb detects when the flow should be moved to a different path.
+	plb *plb
+
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
@@ -92,6 +106,9 @@ type sentPacketHandler struct {
 
 	perspective protocol.Perspective
 
+	clock utils.Clock
+	rand  io.Reader // used for skipping packet numbers
+
 	tracer logging.ConnectionTracer
 	logger utils.Logger
 }
@@ -106,26 +123,44 @@ func newSentPacketHandler(
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	clock utils.Clock,
+	rand io.Reader,
+	congestionConf congestion.Config,
+	ecn protocol.ECN,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		initialMaxDatagramSize,
-		true, // use Reno
-		tracer,
-	)
+	var cong congestion.SendAlgorithmWithDebugInfos
+	var ecnHandler congestion.ECNHandler
+	if ecn == protocol.ECT1 {
+		prague := congestion.NewPragueSender(clock, rttStats, initialMaxDatagramSize, congestionConf, tracer)
+		cong = prague
+		ecnHandler = prague
+	} else {
+		cong = congestion.NewCubicSender(
+			clock,
+			rttStats,
+			initialMaxDatagramSize,
+			true, // use Reno
+			congestionConf,
+			tracer,
+		)
+	}
 
 	return &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient,
-		initialPackets:                 newPacketNumberSpace(initialPN, false, rttStats),
-		handshakePackets:               newPacketNumberSpace(0, false, rttStats),
-		appDataPackets:                 newPacketNumberSpace(0, true, rttStats),
+		initialPackets:                 newPacketNumberSpace(initialPN, false, rttStats, rand),
+		handshakePackets:               newPacketNumberSpace(0, false, rttStats, rand),
+		appDataPackets:                 newPacketNumberSpace(0, true, rttStats, rand),
 		rttStats:                       rttStats,
-		congestion:                     congestion,
+		congestion:                     cong,
+		ecnHandler:                     ecnHandler,
+		ecn:                            ecn,
+		plb:                            newPLB(rttStats),
 		perspective:                    pers,
+		clock:                          clock,
+		rand:                           rand,
 		tracer:                         tracer,
 		logger:                         logger,
 	}
@@ -212,6 +247,14 @@ func (h *sentPacketHandler) ReceivedPacket(l protocol.EncryptionLevel) {
 	}
 }
 
+// traceCongestionMetrics reports the internals of the congestion controller to the tracer.
+// It is called once per ACK frame (and not for every acknowledged packet), since computing the metrics isn't free.
+func (h *sentPacketHandler) traceCongestionMetrics() {
+	if t, ok := h.congestion.(congestion.MetricsTracer); ok {
+		t.TraceMetrics()
+	}
+}
+
 func (h *sentPacketHandler) packetsInFlight() int {
 	packetsInFlight := h.appDataPackets.history.Len()
 	if h.handshakePackets != nil {
@@ -288,6 +331,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		}
 	}
 
+	// ECN counts are only evaluated if the ACK frame increases the largest acknowledged packet number,
+	// since the counts in reordered ACK frames might be lower.
+	largestAckedIncreased := largestAcked > pnSpace.largestAcked
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
 
 	// Servers complete address validation when a protected packet is received.
@@ -332,6 +378,19 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		}
 		h.removeFromBytesInFlight(p)
 	}
+	if encLevel == protocol.Encryption1RTT {
+		var numCEMarked int
+		if h.ecn != protocol.ECNNon {
+			// The packets acknowledged by reordered ACK frames are accounted for
+			// when the next ACK frame increasing the largest acknowledged is processed.
+			h.numAckedSinceECNCounts += len(ackedPackets)
+			if largestAckedIncreased {
+				numCEMarked = h.processECNCounts(ack, h.numAckedSinceECNCounts)
+				h.numAckedSinceECNCounts = 0
+			}
+		}
+		h.plb.OnAck(len(ackedPackets), numCEMarked, rcvTime)
+	}
 
 	// Reset the pto_count unless the client is unsure if the server has validated the client's address.
 	if h.peerCompletedAddressValidation {
@@ -344,6 +403,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 
 	if h.tracer != nil {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
+		h.traceCongestionMetrics()
 	}
 
 	pnSpace.history.DeleteOldPackets(rcvTime)
@@ -351,6 +411,51 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return acked1RTTPacket, nil
 }
 
+// processECNCounts validates the ECN counts of an ACK frame (see section 13.4.2 of RFC 9000),
+// and passes the number of newly CE-marked packets to the congestion controller.
+// It returns the number of newly CE-marked packets.
+func (h *sentPacketHandler) processECNCounts(ack *wire.AckFrame, numNewlyAcked int) int {
+	if ack.ECT0 < h.ect0 || ack.ECT1 < h.ect1 || ack.ECNCE < h.ecnce {
+		h.disableECN("ECN counts decreased")
+		return 0
+	}
+	newECT0 := ack.ECT0 - h.ect0
+	newECT1 := ack.ECT1 - h.ect1
+	newECNCE := ack.ECNCE - h.ecnce
+	// All packets are sent with ECT(1).
+	// If the peer received packets with ECT(0), the codepoint was changed on the path.
+	if newECT0 > 0 {
+		h.disableECN("peer received ECT(0) packets")
+		return 0
+	}
+	if newECT1+newECNCE < uint64(numNewlyAcked) {
+		h.disableECN("missing ECN counts")
+		return 0
+	}
+	h.ect0, h.ect1, h.ecnce = ack.ECT0, ack.ECT1, ack.ECNCE
+	if h.ecnHandler != nil {
+		h.ecnHandler.OnECNFeedback(numNewlyAcked, int(newECNCE))
+	}
+	return int(newECNCE)
+}
+
+func (h *sentPacketHandler) disableECN(reason string) {
+	h.logger.Debugf("ECN validation failed (%s). Disabling ECN.", reason)
+	h.ecn = protocol.ECNNon
+	if h.ecnHandler != nil {
+		h.ecnHandler.OnECNValidationFailed()
+		h.ecnHandler = nil
+	}
+}
+
+func (h *sentPacketHandler) ECNMode() protocol.ECN {
+	return h.ecn
+}
+
+func (h *sentPacketHandler) PopRepath() (bool, logging.FlowLabelTrigger) {
+	return h.plb.PopRepath()
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -452,7 +557,7 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 		if h.peerCompletedAddressValidation {
 			return
 		}
-		t := time.Now().Add(h.rttStats.PTO(false) << h.ptoCount)
+		t := h.clock.Now().Add(h.rttStats.PTO(false) << h.ptoCount)
 		if h.initialPackets != nil {
 			return t, protocol.EncryptionInitial, true
 		}
@@ -604,6 +709,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			h.queueFramesForRetransmission(p)
 			if !p.IsPathMTUProbePacket {
 				h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+				if encLevel == protocol.Encryption1RTT {
+					h.plb.OnPacketLost(now)
+				}
 			}
 		}
 		return true, nil
@@ -621,7 +729,13 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 			h.tracer.LossTimerExpired(logging.TimerTypeACK, encLevel)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel)
+		if err := h.detectLostPackets(h.clock.Now(), encLevel); err != nil {
+			return err
+		}
+		if h.tracer != nil {
+			h.traceCongestionMetrics()
+		}
+		return nil
 	}
 
 	// PTO
@@ -657,6 +771,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 		h.tracer.LossTimerExpired(logging.TimerTypePTO, encLevel)
 		h.tracer.UpdatedPTOCount(h.ptoCount)
 	}
+	h.plb.OnPTO(h.ptoCount, h.clock.Now())
 	h.numProbesToSend += 2
 	//nolint:exhaustive // We never arm a PTO timer for 0-RTT packets.
 	switch encLevel {
@@ -807,7 +922,7 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	// Otherwise, we don't know which Initial the Retry was sent in response to.
 	if h.ptoCount == 0 {
 		// Don't set the RTT to a value lower than 5ms here.
-		now := time.Now()
+		now := h.clock.Now()
 		h.rttStats.UpdateRTT(utils.MaxDuration(minRTTAfterRetry, now.Sub(firstPacketSendTime)), 0, now)
 		if h.logger.Debug() {
 			h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
@@ -816,8 +931,8 @@ func (h *sentPacketHandler) ResetForRetry() error {
 			h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 		}
 	}
-	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop(), false, h.rttStats)
-	h.appDataPackets = newPacketNumberSpace(h.appDataPackets.pns.Pop(), true, h.rttStats)
+	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop(), false, h.rttStats, h.rand)
+	h.appDataPackets = newPacketNumberSpace(h.appDataPackets.pns.Pop(), true, h.rttStats, h.rand)
 	oldAlarm := h.alarm
 	h.alarm = time.Time{}
 	if h.tracer != nil {
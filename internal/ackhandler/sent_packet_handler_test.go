@@ -6,16 +6,28 @@ import (
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type mockECNHandler struct {
+	feedback         [][2]int
+	validationFailed bool
+}
+
+func (h *mockECNHandler) OnECNFeedback(numAcked, numCEMarked int) {
+	h.feedback = append(h.feedback, [2]int{numAcked, numCEMarked})
+}
+func (h *mockECNHandler) OnECNValidationFailed() { h.validationFailed = true }
+
 var _ = Describe("SentPacketHandler", func() {
 	var (
 		handler     *sentPacketHandler
@@ -29,7 +41,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, perspective, utils.DefaultClock{}, nil, congestion.DefaultConfig, protocol.ECNNon, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -660,6 +672,117 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.SendMode()).To(Equal(SendAny))
 	})
 
+	Context("ECN", func() {
+		var ecnHandler *mockECNHandler
+
+		JustBeforeEach(func() {
+			ecnHandler = &mockECNHandler{}
+			handler.ecn = protocol.ECT1
+			handler.ecnHandler = ecnHandler
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+		})
+
+		receiveAck := func(smallest, largest protocol.PacketNumber, ect0, ect1, ecnce uint64) {
+			ack := &wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: smallest, Largest: largest}},
+				ECT0:      ect0,
+				ECT1:      ect1,
+				ECNCE:     ecnce,
+			}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		}
+
+		It("uses the Prague congestion controller when sending ECT(1) packets", func() {
+			h := newSentPacketHandler(0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), protocol.PerspectiveClient, utils.DefaultClock{}, nil, congestion.DefaultConfig, protocol.ECT1, nil, utils.DefaultLogger)
+			Expect(h.ECNMode()).To(Equal(protocol.ECT1))
+			Expect(h.ecnHandler).ToNot(BeNil())
+			Expect(h.ecnHandler).To(BeIdenticalTo(h.congestion))
+		})
+
+		It("passes the number of CE-marked packets to the congestion controller", func() {
+			receiveAck(1, 2, 0, 1, 1)
+			receiveAck(1, 4, 0, 2, 2)
+			receiveAck(1, 6, 0, 4, 2)
+			Expect(ecnHandler.feedback).To(Equal([][2]int{{2, 1}, {2, 1}, {2, 0}}))
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT1))
+		})
+
+		It("disables ECN if the ACK frame doesn't contain ECN counts", func() {
+			receiveAck(1, 2, 0, 0, 0)
+			Expect(ecnHandler.feedback).To(BeEmpty())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+			Expect(ecnHandler.validationFailed).To(BeTrue())
+			// ECN counts are ignored from now on
+			receiveAck(1, 4, 0, 2, 2)
+			Expect(ecnHandler.feedback).To(BeEmpty())
+		})
+
+		It("disables ECN if the ECN counts don't account for all acknowledged packets", func() {
+			receiveAck(1, 3, 0, 1, 1)
+			Expect(ecnHandler.feedback).To(BeEmpty())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
+		It("disables ECN if the ECN counts decrease", func() {
+			receiveAck(1, 2, 0, 2, 0)
+			receiveAck(1, 4, 0, 1, 3)
+			Expect(ecnHandler.feedback).To(Equal([][2]int{{2, 0}}))
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
+		It("disables ECN if the peer receives ECT(0) packets", func() {
+			receiveAck(1, 2, 2, 0, 0)
+			Expect(ecnHandler.feedback).To(BeEmpty())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
+		It("ignores the ECN counts of reordered ACK frames", func() {
+			receiveAck(3, 4, 0, 2, 0)
+			// This ACK frame was sent before the first one.
+			receiveAck(1, 2, 0, 1, 0)
+			Expect(ecnHandler.feedback).To(Equal([][2]int{{2, 0}}))
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT1))
+			// The packets acknowledged by the reordered ACK frame are accounted for with the next ACK frame.
+			receiveAck(1, 6, 0, 5, 1)
+			Expect(ecnHandler.feedback).To(Equal([][2]int{{2, 0}, {4, 1}}))
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT1))
+		})
+
+		It("makes the Prague sender fall back to Reno when ECN validation fails", func() {
+			h := newSentPacketHandler(0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), protocol.PerspectiveClient, utils.DefaultClock{}, nil, congestion.DefaultConfig, protocol.ECT1, nil, utils.DefaultLogger)
+			for i := protocol.PacketNumber(0); i < 10; i++ {
+				h.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 1}}, ECT1: 2}
+			_, err := h.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			cwnd := h.congestion.GetCongestionWindow()
+			// The ECN counts decrease. This fails ECN validation.
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 3}}, ECT1: 1, ECNCE: 3}
+			_, err = h.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(h.ECNMode()).To(Equal(protocol.ECNNon))
+			Expect(h.ecnHandler).To(BeNil())
+			// CE marks don't reduce the congestion window any more
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 5}}, ECNCE: 10}
+			_, err = h.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(h.congestion.InRecovery()).To(BeFalse())
+			Expect(h.congestion.GetCongestionWindow()).To(Equal(cwnd))
+		})
+
+		It("ignores ECN counts for Handshake packets", func() {
+			handler.SentPacket(handshakePacket(&Packet{PacketNumber: 1}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.EncryptionHandshake, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT1))
+		})
+	})
+
 	Context("probe packets", func() {
 		It("queues a probe packet", func() {
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 10}))
@@ -759,6 +882,22 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).ToNot(Equal(SendPTOAppData))
 		})
 
+		It("requests repathing after repeated PTOs", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.SetHandshakeConfirmed()
+			handler.SentPacket(ackElicitingPacket(&Packet{
+				PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT),
+				SendTime:     time.Now().Add(-time.Hour),
+			}))
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			repath, _ := handler.PopRepath()
+			Expect(repath).To(BeFalse())
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			repath, trigger := handler.PopRepath()
+			Expect(repath).To(BeTrue())
+			Expect(trigger).To(Equal(logging.FlowLabelTriggerPTO))
+		})
+
 		It("skips a packet number for 1-RTT PTOs", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			handler.SetHandshakeConfirmed()
@@ -1,18 +1,9 @@
 package congestion
 
-import "time"
+import "github.com/lucas-clemente/quic-go/internal/utils"
 
 // A Clock returns the current time
-type Clock interface {
-	Now() time.Time
-}
+type Clock = utils.Clock
 
 // DefaultClock implements the Clock interface using the Go stdlib clock.
-type DefaultClock struct{}
-
-var _ Clock = DefaultClock{}
-
-// Now gets the current time
-func (DefaultClock) Now() time.Time {
-	return time.Now()
-}
+type DefaultClock = utils.DefaultClock
@@ -0,0 +1,21 @@
+package congestion
+
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+// Config contains the tunable parameters of the congestion controller.
+// Congestion window sizes are given in packets, and scaled by the maximum datagram size.
+type Config struct {
+	InitialWindowPackets int
+	MinWindowPackets     int
+	MaxWindowPackets     int
+	// LossReductionFactor is the factor that the congestion window is multiplied with on a loss event.
+	LossReductionFactor float64
+}
+
+// DefaultConfig is the configuration used if no other values are configured.
+var DefaultConfig = Config{
+	InitialWindowPackets: initialCongestionWindow,
+	MinWindowPackets:     minCongestionWindowPackets,
+	MaxWindowPackets:     protocol.MaxCongestionWindowPackets,
+	LossReductionFactor:  renoBeta,
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 // This cubic implementation is based on the one found in Chromiums's QUIC
@@ -42,6 +43,11 @@ type Cubic struct {
 	// Number of connections to simulate.
 	numConnections int
 
+	// The backoff factors for a single connection.
+	// See beta and betaLastMax.
+	singleBeta        float32
+	singleBetaLastMax float32
+
 	// Time when this cycle started, after last loss event.
 	epoch time.Time
 
@@ -69,8 +75,10 @@ type Cubic struct {
 // NewCubic returns a new Cubic instance
 func NewCubic(clock Clock) *Cubic {
 	c := &Cubic{
-		clock:          clock,
-		numConnections: defaultNumConnections,
+		clock:             clock,
+		numConnections:    defaultNumConnections,
+		singleBeta:        beta,
+		singleBetaLastMax: betaLastMax,
 	}
 	c.Reset()
 	return c
@@ -100,7 +108,7 @@ func (c *Cubic) beta() float32 {
 	// emulation, which emulates the effective backoff of an ensemble of N
 	// TCP-Reno connections on a single loss event. The effective multiplier is
 	// computed as:
-	return (float32(c.numConnections) - 1 + beta) / float32(c.numConnections)
+	return (float32(c.numConnections) - 1 + c.singleBeta) / float32(c.numConnections)
 }
 
 func (c *Cubic) betaLastMax() float32 {
@@ -108,7 +116,7 @@ func (c *Cubic) betaLastMax() float32 {
 	// N-connection emulation, which emulates the additional backoff of
 	// an ensemble of N TCP-Reno connections on a single loss event. The
 	// effective multiplier is computed as:
-	return (float32(c.numConnections) - 1 + betaLastMax) / float32(c.numConnections)
+	return (float32(c.numConnections) - 1 + c.singleBetaLastMax) / float32(c.numConnections)
 }
 
 // OnApplicationLimited is called on ack arrival when sender is unable to use
@@ -208,7 +216,26 @@ func (c *Cubic) CongestionWindowAfterAck(
 	return targetCongestionWindow
 }
 
+// State returns the state of the current epoch.
+func (c *Cubic) State() *logging.CubicState {
+	return &logging.CubicState{
+		EpochStart:                    c.epoch,
+		LastMaxCongestionWindow:       c.lastMaxCongestionWindow,
+		OriginPointCongestionWindow:   c.originPointCongestionWindow,
+		TimeToOriginPoint:             time.Duration(c.timeToOriginPoint) * time.Second / 1024,
+		EstimatedRenoCongestionWindow: c.estimatedTCPcongestionWindow,
+	}
+}
+
 // SetNumConnections sets the number of emulated connections
 func (c *Cubic) SetNumConnections(n int) {
 	c.numConnections = n
 }
+
+// SetBeta sets the backoff factor applied to the congestion window after a loss event.
+// The additional backoff factor for losses in the concave part of the Cubic curve
+// is set halfway between beta and 1.
+func (c *Cubic) SetBeta(b float32) {
+	c.singleBeta = b
+	c.singleBetaLastMax = (1 + b) / 2
+}
@@ -55,6 +55,8 @@ type cubicSender struct {
 
 	maxDatagramSize protocol.ByteCount
 
+	conf Config
+
 	lastState logging.CongestionState
 	tracer    logging.ConnectionTracer
 }
@@ -62,6 +64,7 @@ type cubicSender struct {
 var (
 	_ SendAlgorithm               = &cubicSender{}
 	_ SendAlgorithmWithDebugInfos = &cubicSender{}
+	_ MetricsTracer               = &cubicSender{}
 )
 
 // NewCubicSender makes a new cubic sender
@@ -70,15 +73,17 @@ func NewCubicSender(
 	rttStats *utils.RTTStats,
 	initialMaxDatagramSize protocol.ByteCount,
 	reno bool,
+	conf Config,
 	tracer logging.ConnectionTracer,
 ) *cubicSender {
 	return newCubicSender(
 		clock,
 		rttStats,
 		reno,
+		conf,
 		initialMaxDatagramSize,
-		initialCongestionWindow*initialMaxDatagramSize,
-		protocol.MaxCongestionWindowPackets*initialMaxDatagramSize,
+		protocol.ByteCount(conf.InitialWindowPackets)*initialMaxDatagramSize,
+		protocol.ByteCount(conf.MaxWindowPackets)*initialMaxDatagramSize,
 		tracer,
 	)
 }
@@ -87,6 +92,7 @@ func newCubicSender(
 	clock Clock,
 	rttStats *utils.RTTStats,
 	reno bool,
+	conf Config,
 	initialMaxDatagramSize,
 	initialCongestionWindow,
 	initialMaxCongestionWindow protocol.ByteCount,
@@ -106,11 +112,17 @@ func newCubicSender(
 		reno:                       reno,
 		tracer:                     tracer,
 		maxDatagramSize:            initialMaxDatagramSize,
+		conf:                       conf,
+	}
+	// Only change Cubic's backoff factor if necessary, to avoid rounding errors.
+	if conf.LossReductionFactor != renoBeta {
+		c.cubic.SetBeta(float32(conf.LossReductionFactor))
 	}
 	c.pacer = newPacer(c.BandwidthEstimate)
 	if c.tracer != nil {
+		c.traceParameters()
 		c.lastState = logging.CongestionStateSlowStart
-		c.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
+		c.traceStateChange(logging.CongestionStateSlowStart, logging.CongestionStateTriggerNone)
 	}
 	return c
 }
@@ -125,11 +137,11 @@ func (c *cubicSender) HasPacingBudget() bool {
 }
 
 func (c *cubicSender) maxCongestionWindow() protocol.ByteCount {
-	return c.maxDatagramSize * protocol.MaxCongestionWindowPackets
+	return c.maxDatagramSize * protocol.ByteCount(c.conf.MaxWindowPackets)
 }
 
 func (c *cubicSender) minCongestionWindow() protocol.ByteCount {
-	return c.maxDatagramSize * minCongestionWindowPackets
+	return c.maxDatagramSize * protocol.ByteCount(c.conf.MinWindowPackets)
 }
 
 func (c *cubicSender) OnPacketSent(
@@ -168,7 +180,7 @@ func (c *cubicSender) MaybeExitSlowStart() {
 		c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/c.maxDatagramSize) {
 		// exit slow start
 		c.slowStartThreshold = c.congestionWindow
-		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance, logging.CongestionStateTriggerHybridSlowStart)
 	}
 }
 
@@ -195,16 +207,20 @@ func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes
 		return
 	}
 	c.lastCutbackExitedSlowstart = c.InSlowStart()
-	c.maybeTraceStateChange(logging.CongestionStateRecovery)
+	c.maybeTraceStateChange(logging.CongestionStateRecovery, logging.CongestionStateTriggerPacketLoss)
 
 	if c.reno {
-		c.congestionWindow = protocol.ByteCount(float64(c.congestionWindow) * renoBeta)
+		c.cutback(protocol.ByteCount(float64(c.congestionWindow) * c.conf.LossReductionFactor))
 	} else {
-		c.congestionWindow = c.cubic.CongestionWindowAfterPacketLoss(c.congestionWindow)
-	}
-	if minCwnd := c.minCongestionWindow(); c.congestionWindow < minCwnd {
-		c.congestionWindow = minCwnd
+		c.cutback(c.cubic.CongestionWindowAfterPacketLoss(c.congestionWindow))
 	}
+}
+
+// cutback reduces the congestion window and enters recovery.
+// Until a packet sent after the cutback is acknowledged, the congestion window is not increased,
+// and no further cutbacks happen due to packet loss.
+func (c *cubicSender) cutback(congestionWindow protocol.ByteCount) {
+	c.congestionWindow = utils.MaxByteCount(congestionWindow, c.minCongestionWindow())
 	c.slowStartThreshold = c.congestionWindow
 	c.largestSentAtLastCutback = c.largestSentPacketNumber
 	// reset packet count from congestion avoidance mode. We start
@@ -224,7 +240,7 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	// the current window.
 	if !c.isCwndLimited(priorInFlight) {
 		c.cubic.OnApplicationLimited()
-		c.maybeTraceStateChange(logging.CongestionStateApplicationLimited)
+		c.maybeTraceStateChange(logging.CongestionStateApplicationLimited, logging.CongestionStateTriggerNone)
 		return
 	}
 	if c.congestionWindow >= c.maxCongestionWindow() {
@@ -233,11 +249,15 @@ func (c *cubicSender) maybeIncreaseCwnd(
 	if c.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
 		c.congestionWindow += c.maxDatagramSize
-		c.maybeTraceStateChange(logging.CongestionStateSlowStart)
+		c.maybeTraceStateChange(logging.CongestionStateSlowStart, logging.CongestionStateTriggerNone)
 		return
 	}
 	// Congestion avoidance
-	c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+	if c.lastState == logging.CongestionStateSlowStart {
+		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance, logging.CongestionStateTriggerSlowStartThreshold)
+	} else {
+		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance, logging.CongestionStateTriggerNone)
+	}
 	if c.reno {
 		// Classic Reno congestion avoidance.
 		c.numAckedPackets++
@@ -295,14 +315,52 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.slowStartThreshold = c.initialMaxCongestionWindow
 }
 
-func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
+// traceParameters reports the parameters of the congestion controller to the tracer.
+// The window sizes depend on the current maximum datagram size.
+func (c *cubicSender) traceParameters() {
+	c.tracer.UpdatedCongestionParameters(&logging.CongestionParameters{
+		InitialCongestionWindow: c.initialCongestionWindow,
+		MinCongestionWindow:     c.minCongestionWindow(),
+		MaxCongestionWindow:     c.maxCongestionWindow(),
+		LossReductionFactor:     c.conf.LossReductionFactor,
+	})
+}
+
+// TraceMetrics reports the pacing rate, the bandwidth estimate and the state of Cubic to the tracer.
+func (c *cubicSender) TraceMetrics() {
+	if c.tracer == nil {
+		return
+	}
+	m := &logging.CongestionMetrics{}
+	// Without an RTT sample, the bandwidth estimate is infinite.
+	if c.rttStats.SmoothedRTT() != 0 {
+		m.PacingRate = uint64(c.pacer.PacingRate())
+		m.BandwidthEstimate = uint64(c.BandwidthEstimate())
+	}
+	if !c.reno {
+		m.Cubic = c.cubic.State()
+	}
+	c.tracer.UpdatedCongestionMetrics(m)
+}
+
+func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState, trigger logging.CongestionStateTrigger) {
 	if c.tracer == nil || new == c.lastState {
 		return
 	}
-	c.tracer.UpdatedCongestionState(new)
+	c.traceStateChange(new, trigger)
 	c.lastState = new
 }
 
+// traceStateChange reports a state change to the tracer.
+// The trigger is only reported to tracers that implement logging.CongestionStateTriggerTracer.
+func (c *cubicSender) traceStateChange(new logging.CongestionState, trigger logging.CongestionStateTrigger) {
+	if t, ok := c.tracer.(logging.CongestionStateTriggerTracer); ok {
+		t.UpdatedCongestionStateWithTrigger(new, trigger)
+		return
+	}
+	c.tracer.UpdatedCongestionState(new)
+}
+
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
 	if s < c.maxDatagramSize {
 		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", c.maxDatagramSize, s))
@@ -313,4 +371,7 @@ func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
 		c.congestionWindow = c.minCongestionWindow()
 	}
 	c.pacer.SetMaxDatagramSize(s)
+	if c.tracer != nil {
+		c.traceParameters()
+	}
 }
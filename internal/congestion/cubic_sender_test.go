@@ -5,6 +5,8 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -46,6 +48,7 @@ var _ = Describe("Cubic Sender", func() {
 			&clock,
 			rttStats,
 			true, /*reno*/
+			DefaultConfig,
 			protocol.InitialPacketSizeIPv4,
 			initialCongestionWindowPackets*maxDatagramSize,
 			MaxCongestionWindow,
@@ -318,7 +321,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
 		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, nil)
+		sender = newCubicSender(&clock, rttStats, false, DefaultConfig, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, nil)
 
 		numSent := SendAvailableSendWindow()
 
@@ -459,7 +462,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("slow starts up to the maximum congestion window", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true, DefaultConfig, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
 
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
 			sender.MaybeExitSlowStart()
@@ -474,7 +477,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("slow starts up to maximum congestion window, if larger packets are sent", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true, DefaultConfig, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
 		const packetSize = initialMaxDatagramSize + 100
 		sender.SetMaxDatagramSize(packetSize)
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
@@ -489,7 +492,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, false, DefaultConfig, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
@@ -523,4 +526,183 @@ var _ = Describe("Cubic Sender", func() {
 		AckNPackets(2)
 		Expect(sender.GetCongestionWindow()).To(Equal(savedCwnd + maxDatagramSize))
 	})
+
+	Context("using a custom configuration", func() {
+		conf := Config{
+			InitialWindowPackets: 5,
+			MinWindowPackets:     3,
+			MaxWindowPackets:     20,
+			LossReductionFactor:  0.5,
+		}
+
+		BeforeEach(func() {
+			sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, true, conf, nil)
+		})
+
+		It("uses the configured windows", func() {
+			Expect(sender.GetCongestionWindow()).To(Equal(5 * maxDatagramSize))
+			// slow start up to the maximum congestion window
+			for i := 0; i < 100; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(1)
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(20 * maxDatagramSize))
+			// on a retransmission timeout, the window is reduced to the minimum
+			sender.OnRetransmissionTimeout(true)
+			Expect(sender.GetCongestionWindow()).To(Equal(3 * maxDatagramSize))
+		})
+
+		It("uses the configured loss reduction factor", func() {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+			SendAvailableSendWindow()
+			Expect(sender.GetCongestionWindow()).To(Equal(7 * maxDatagramSize))
+			LoseNPackets(1)
+			Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float64(7*maxDatagramSize) * 0.5)))
+		})
+
+		It("reports the parameters to the tracer", func() {
+			tracer := &congestionTracer{}
+			sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, true, conf, tracer)
+			Expect(tracer.params).To(Equal([]logging.CongestionParameters{{
+				InitialCongestionWindow: 5 * maxDatagramSize,
+				MinCongestionWindow:     3 * maxDatagramSize,
+				MaxCongestionWindow:     20 * maxDatagramSize,
+				LossReductionFactor:     0.5,
+			}}))
+			// the parameters are reported again when the maximum datagram size changes
+			sender.SetMaxDatagramSize(2000)
+			Expect(tracer.params).To(HaveLen(2))
+			Expect(tracer.params[1].MinCongestionWindow).To(Equal(3 * protocol.ByteCount(2000)))
+			Expect(tracer.params[1].MaxCongestionWindow).To(Equal(20 * protocol.ByteCount(2000)))
+		})
+	})
+
+	Context("tracing", func() {
+		var tracer *congestionTracer
+
+		BeforeEach(func() {
+			tracer = &congestionTracer{}
+			sender = newCubicSender(
+				&clock,
+				rttStats,
+				false, /*reno*/
+				DefaultConfig,
+				protocol.InitialPacketSizeIPv4,
+				initialCongestionWindowPackets*maxDatagramSize,
+				MaxCongestionWindow,
+				tracer,
+			)
+			Expect(tracer.states).To(Equal([]logging.CongestionState{logging.CongestionStateSlowStart}))
+		})
+
+		It("reports when HyStart ends slow start", func() {
+			rttStats.UpdateRTT(60*time.Millisecond, 0, clock.Now())
+			// the RTT increases, while the congestion window is growing
+			for i := 0; i < 100 && sender.InSlowStart(); i++ {
+				SendAvailableSendWindow()
+				rttStats.UpdateRTT(100*time.Millisecond, 0, clock.Now())
+				sender.MaybeExitSlowStart()
+				ackedPacketNumber++
+				sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+				bytesInFlight -= maxDatagramSize
+			}
+			Expect(sender.InSlowStart()).To(BeFalse())
+			Expect(tracer.states).To(Equal([]logging.CongestionState{logging.CongestionStateSlowStart, logging.CongestionStateCongestionAvoidance}))
+			Expect(tracer.triggers[1]).To(Equal(logging.CongestionStateTriggerHybridSlowStart))
+		})
+
+		It("reports when the slow start threshold is reached", func() {
+			SendAvailableSendWindow()
+			sender.OnRetransmissionTimeout(true)
+			bytesInFlight = 0
+			for sender.InSlowStart() {
+				SendAvailableSendWindow()
+				AckNPackets(1)
+			}
+			SendAvailableSendWindow()
+			AckNPackets(1)
+			Expect(tracer.states).To(Equal([]logging.CongestionState{logging.CongestionStateSlowStart, logging.CongestionStateCongestionAvoidance}))
+			Expect(tracer.triggers[1]).To(Equal(logging.CongestionStateTriggerSlowStartThreshold))
+		})
+
+		It("reports packet loss", func() {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+			LoseNPackets(1)
+			Expect(tracer.states).To(Equal([]logging.CongestionState{logging.CongestionStateSlowStart, logging.CongestionStateRecovery}))
+			Expect(tracer.triggers[1]).To(Equal(logging.CongestionStateTriggerPacketLoss))
+		})
+
+		It("reports the pacing rate, the bandwidth estimate and the Cubic state", func() {
+			SendAvailableSendWindow()
+			AckNPackets(1)
+			sender.TraceMetrics()
+			Expect(tracer.metrics).To(HaveLen(1))
+			m := tracer.metrics[0]
+			Expect(m.BandwidthEstimate).To(Equal(uint64(sender.BandwidthEstimate())))
+			Expect(m.PacingRate).To(BeNumerically("~", m.BandwidthEstimate*5/4, 8))
+			Expect(m.Cubic).ToNot(BeNil())
+			Expect(m.Cubic.EpochStart).To(BeZero())
+
+			cwnd := sender.GetCongestionWindow()
+			LoseNPackets(1)
+			sender.TraceMetrics()
+			Expect(tracer.metrics).To(HaveLen(2))
+			Expect(tracer.metrics[1].Cubic.LastMaxCongestionWindow).To(Equal(cwnd))
+			Expect(tracer.metrics[1].BandwidthEstimate).To(BeNumerically("<", m.BandwidthEstimate))
+
+			// leave recovery and start a new Cubic epoch
+			for sender.InRecovery() {
+				SendAvailableSendWindow()
+				AckNPackets(1)
+			}
+			SendAvailableSendWindow()
+			AckNPackets(1)
+			sender.TraceMetrics()
+			cubic := tracer.metrics[len(tracer.metrics)-1].Cubic
+			Expect(cubic.EpochStart).ToNot(BeZero())
+			Expect(cubic.OriginPointCongestionWindow).To(Equal(cwnd))
+			Expect(cubic.TimeToOriginPoint).To(BeNumerically(">", 0))
+			Expect(cubic.EstimatedRenoCongestionWindow).To(BeNumerically(">", 0))
+		})
+
+		It("doesn't report the Cubic state when using Reno", func() {
+			sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, true, DefaultConfig, tracer)
+			SendAvailableSendWindow()
+			AckNPackets(1)
+			sender.TraceMetrics()
+			Expect(tracer.metrics).To(HaveLen(1))
+			Expect(tracer.metrics[0].Cubic).To(BeNil())
+		})
+
+		It("only reports metrics when asked to", func() {
+			SendAvailableSendWindow()
+			AckNPackets(5)
+			LoseNPackets(1)
+			Expect(tracer.metrics).To(BeEmpty())
+		})
+	})
 })
+
+type congestionTracer struct {
+	logging.ConnectionTracer // nil, only the methods overridden below may be called
+
+	params   []logging.CongestionParameters
+	states   []logging.CongestionState
+	triggers []logging.CongestionStateTrigger
+	metrics  []logging.CongestionMetrics
+}
+
+func (t *congestionTracer) UpdatedCongestionParameters(p *logging.CongestionParameters) {
+	t.params = append(t.params, *p)
+}
+
+func (t *congestionTracer) UpdatedCongestionStateWithTrigger(s logging.CongestionState, trigger logging.CongestionStateTrigger) {
+	t.states = append(t.states, s)
+	t.triggers = append(t.triggers, trigger)
+}
+
+func (t *congestionTracer) UpdatedCongestionMetrics(m *logging.CongestionMetrics) {
+	t.metrics = append(t.metrics, *m)
+}
@@ -214,6 +214,18 @@ var _ = Describe("Cubic", func() {
 		Expect(cubic.lastMaxCongestionWindow).To(Equal(expectedLastMax))
 	})
 
+	It("uses a custom backoff factor", func() {
+		cubic.SetNumConnections(1)
+		cubic.SetBeta(0.5)
+		currentCwnd := 100 * maxDatagramSize
+		Expect(cubic.CongestionWindowAfterPacketLoss(currentCwnd)).To(Equal(50 * maxDatagramSize))
+		Expect(cubic.lastMaxCongestionWindow).To(Equal(currentCwnd))
+		// The second loss happens before reaching the last max congestion window.
+		currentCwnd = 50 * maxDatagramSize
+		Expect(cubic.CongestionWindowAfterPacketLoss(currentCwnd)).To(Equal(25 * maxDatagramSize))
+		Expect(cubic.lastMaxCongestionWindow).To(Equal(protocol.ByteCount(float32(currentCwnd) * 0.75)))
+	})
+
 	It("works below origin", func() {
 		// Concave growth.
 		rttMin := 100 * time.Millisecond
@@ -26,3 +26,22 @@ type SendAlgorithmWithDebugInfos interface {
 	InRecovery() bool
 	GetCongestionWindow() protocol.ByteCount
 }
+
+// An ECNHandler is a SendAlgorithm that reacts to ECN feedback.
+type ECNHandler interface {
+	// OnECNFeedback is called for every ACK frame that increases the largest acknowledged packet number,
+	// after OnPacketAcked was called for the packets it acknowledges.
+	// numAcked includes the packets acknowledged by reordered ACK frames since the last call.
+	// numCEMarked is the number of packets that were newly reported as CE-marked.
+	OnECNFeedback(numAcked, numCEMarked int)
+	// OnECNValidationFailed is called when ECN validation fails.
+	// Packets aren't ECN-marked any more, and the sender falls back to classic congestion control.
+	OnECNValidationFailed()
+}
+
+// A MetricsTracer is a SendAlgorithm that reports its internal metrics to the tracer.
+type MetricsTracer interface {
+	// TraceMetrics is called once an ACK frame was processed,
+	// and when packets were declared lost because the loss detection timer fired.
+	TraceMetrics()
+}
@@ -72,6 +72,11 @@ func (p *pacer) TimeUntilSend() time.Time {
 	))
 }
 
+// PacingRate returns the rate at which packets are sent.
+func (p *pacer) PacingRate() Bandwidth {
+	return Bandwidth(p.getAdjustedBandwidth()) * BytesPerSecond
+}
+
 func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
 	p.maxDatagramSize = s
 }
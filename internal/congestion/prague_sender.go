@@ -0,0 +1,112 @@
+package congestion
+
+import (
+	"math"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// pragueG is the gain used for the moving average of the fraction of CE-marked packets.
+const pragueG = 1. / 16
+
+// The pragueSender implements a scalable congestion controller for L4S,
+// loosely following the TCP Prague (draft-briscoe-iccrg-prague-congestion-control).
+// Like DCTCP, it reduces the congestion window proportionally to the fraction of CE-marked packets,
+// instead of halving it.
+// Everything else, including the handling of packet loss, is done by the Reno sender.
+type pragueSender struct {
+	*cubicSender
+
+	// l4s is unset when ECN validation fails.
+	// The sender then behaves like a Reno sender, and ignores ECN feedback.
+	l4s bool
+
+	// alpha is the moving average of the fraction of CE-marked packets.
+	alpha float64
+	// The CE fraction is measured once per round trip.
+	// The current round trip ends when a packet sent after endOfRound is acknowledged.
+	endOfRound         protocol.PacketNumber
+	numAckedInRound    int
+	numCEMarkedInRound int
+}
+
+var (
+	_ SendAlgorithm               = &pragueSender{}
+	_ SendAlgorithmWithDebugInfos = &pragueSender{}
+	_ ECNHandler                  = &pragueSender{}
+	_ MetricsTracer               = &pragueSender{}
+)
+
+// NewPragueSender makes a new Prague sender.
+// It should only be used if outgoing packets are marked ECT(1).
+func NewPragueSender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	conf Config,
+	tracer logging.ConnectionTracer,
+) *pragueSender {
+	return newPragueSender(
+		clock,
+		rttStats,
+		conf,
+		initialMaxDatagramSize,
+		protocol.ByteCount(conf.InitialWindowPackets)*initialMaxDatagramSize,
+		protocol.ByteCount(conf.MaxWindowPackets)*initialMaxDatagramSize,
+		tracer,
+	)
+}
+
+func newPragueSender(
+	clock Clock,
+	rttStats *utils.RTTStats,
+	conf Config,
+	initialMaxDatagramSize,
+	initialCongestionWindow,
+	initialMaxCongestionWindow protocol.ByteCount,
+	tracer logging.ConnectionTracer,
+) *pragueSender {
+	return &pragueSender{
+		cubicSender: newCubicSender(clock, rttStats, true, conf, initialMaxDatagramSize, initialCongestionWindow, initialMaxCongestionWindow, tracer),
+		l4s:         true,
+		endOfRound:  protocol.InvalidPacketNumber,
+		// Start with the assumption that all packets are CE-marked.
+		// The first reduction will therefore halve the congestion window.
+		alpha: 1,
+	}
+}
+
+// OnECNFeedback updates the estimate of the fraction of CE-marked packets,
+// and reduces the congestion window if packets were CE-marked.
+// The congestion window is reduced at most once per round trip.
+func (p *pragueSender) OnECNFeedback(numAcked, numCEMarked int) {
+	if !p.l4s {
+		return
+	}
+	p.numAckedInRound += numAcked
+	p.numCEMarkedInRound += numCEMarked
+	if p.largestAckedPacketNumber > p.endOfRound {
+		if p.numAckedInRound > 0 {
+			frac := float64(p.numCEMarkedInRound) / float64(p.numAckedInRound)
+			p.alpha = (1-pragueG)*p.alpha + pragueG*math.Min(frac, 1)
+		}
+		p.numAckedInRound = 0
+		p.numCEMarkedInRound = 0
+		p.endOfRound = p.largestSentPacketNumber
+	}
+	if numCEMarked == 0 || p.InRecovery() {
+		return
+	}
+	p.maybeTraceStateChange(logging.CongestionStateRecovery, logging.CongestionStateTriggerECN)
+	p.cutback(protocol.ByteCount(float64(p.congestionWindow) * (1 - p.alpha/2)))
+}
+
+// OnECNValidationFailed makes the sender fall back to Reno.
+func (p *pragueSender) OnECNValidationFailed() {
+	p.l4s = false
+	p.alpha = 0
+	p.numAckedInRound = 0
+	p.numCEMarkedInRound = 0
+}
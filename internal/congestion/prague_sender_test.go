@@ -0,0 +1,271 @@
+package congestion
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prague Sender", func() {
+	var (
+		sender            *pragueSender
+		clock             mockClock
+		bytesInFlight     protocol.ByteCount
+		packetNumber      protocol.PacketNumber
+		ackedPacketNumber protocol.PacketNumber
+		rttStats          *utils.RTTStats
+	)
+
+	BeforeEach(func() {
+		bytesInFlight = 0
+		packetNumber = 1
+		ackedPacketNumber = 0
+		clock = mockClock{}
+		rttStats = utils.NewRTTStats()
+		sender = newPragueSender(
+			&clock,
+			rttStats,
+			DefaultConfig,
+			maxDatagramSize,
+			initialCongestionWindowPackets*maxDatagramSize,
+			MaxCongestionWindow,
+			nil,
+		)
+	})
+
+	sendAvailableSendWindow := func() int {
+		var packetsSent int
+		for sender.CanSend(bytesInFlight) {
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			packetNumber++
+			packetsSent++
+			bytesInFlight += maxDatagramSize
+		}
+		return packetsSent
+	}
+
+	// ackNPackets acknowledges n packets in a single ACK frame.
+	// numCEMarked of them are reported as CE-marked.
+	ackNPackets := func(n, numCEMarked int) {
+		rttStats.UpdateRTT(60*time.Millisecond, 0, clock.Now())
+		sender.MaybeExitSlowStart()
+		for i := 0; i < n; i++ {
+			ackedPacketNumber++
+			sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+		}
+		bytesInFlight -= protocol.ByteCount(n) * maxDatagramSize
+		sender.OnECNFeedback(n, numCEMarked)
+		clock.Advance(time.Millisecond)
+	}
+
+	It("grows the congestion window in slow start", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		sendAvailableSendWindow()
+		ackNPackets(2, 0)
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP + 2*maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("halves the congestion window on the first CE mark", func() {
+		sendAvailableSendWindow()
+		ackNPackets(2, 2)
+		// alpha is initialized to 1
+		Expect(sender.GetCongestionWindow()).To(Equal((defaultWindowTCP + 2*maxDatagramSize) / 2))
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.InRecovery()).To(BeTrue())
+	})
+
+	It("reduces the congestion window at most once per round trip", func() {
+		sendAvailableSendWindow()
+		ackNPackets(1, 1)
+		cwnd := sender.GetCongestionWindow()
+		// packets sent before the reduction don't cause another reduction
+		ackNPackets(1, 1)
+		ackNPackets(1, 1)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		// acknowledge all packets sent before the reduction
+		ackNPackets(int(bytesInFlight/maxDatagramSize), 0)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		// the first packet sent after the reduction ends the recovery period
+		sendAvailableSendWindow()
+		ackNPackets(1, 1)
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", cwnd))
+	})
+
+	It("reduces the congestion window proportionally to the fraction of CE-marked packets", func() {
+		// Run a couple of rounds without any CE marks, until the alpha has decayed.
+		for i := 0; i < 20; i++ {
+			sendAvailableSendWindow()
+			ackNPackets(int(bytesInFlight/maxDatagramSize), 0)
+		}
+		Expect(sender.alpha).To(BeNumerically("<", 0.3))
+		Expect(sender.alpha).To(BeNumerically(">", 0))
+		sendAvailableSendWindow()
+		cwnd := sender.GetCongestionWindow()
+		// Acknowledge a single packet.
+		// Since this packet ends the round, it is used to update alpha.
+		ackNPackets(1, 1)
+		alpha := sender.alpha
+		Expect(alpha).To(BeNumerically("~", 1./16, 0.3))
+		// The acknowledgement might have increased the congestion window by one packet before the reduction.
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("~", float64(cwnd)*(1-alpha/2), maxDatagramSize))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", cwnd*3/4))
+	})
+
+	It("doesn't reduce the congestion window below the minimum", func() {
+		for i := 0; i < 10; i++ {
+			sendAvailableSendWindow()
+			ackNPackets(int(bytesInFlight/maxDatagramSize), int(bytesInFlight/maxDatagramSize))
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(minCongestionWindowPackets * maxDatagramSize))
+	})
+
+	It("ignores CE marks after ECN validation failed", func() {
+		sendAvailableSendWindow()
+		sender.OnECNValidationFailed()
+		ackNPackets(2, 2)
+		cwnd := sender.GetCongestionWindow()
+		Expect(cwnd).To(Equal(defaultWindowTCP + 2*maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.InRecovery()).To(BeFalse())
+		// packet loss is still handled like a Reno sender would
+		sender.OnPacketLost(packetNumber-1, maxDatagramSize, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float64(cwnd) * renoBeta)))
+	})
+
+	It("reduces the congestion window on packet loss", func() {
+		sendAvailableSendWindow()
+		ackNPackets(2, 0)
+		cwnd := sender.GetCongestionWindow()
+		ackedPacketNumber++
+		sender.OnPacketLost(ackedPacketNumber, maxDatagramSize, bytesInFlight)
+		bytesInFlight -= maxDatagramSize
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float64(cwnd) * renoBeta)))
+		Expect(sender.InRecovery()).To(BeTrue())
+	})
+
+	It("resets the congestion window on a retransmission timeout", func() {
+		sendAvailableSendWindow()
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(minCongestionWindowPackets * maxDatagramSize))
+	})
+
+	It("reports CE marks and packet loss to the tracer", func() {
+		tracer := &congestionTracer{}
+		sender = NewPragueSender(&clock, rttStats, maxDatagramSize, DefaultConfig, tracer)
+		sendAvailableSendWindow()
+		ackNPackets(2, 2)
+		Expect(tracer.states).To(Equal([]logging.CongestionState{logging.CongestionStateSlowStart, logging.CongestionStateRecovery}))
+		Expect(tracer.triggers[1]).To(Equal(logging.CongestionStateTriggerECN))
+		sender.TraceMetrics()
+		Expect(tracer.metrics).To(HaveLen(1))
+		m := tracer.metrics[0]
+		Expect(m.BandwidthEstimate).To(Equal(uint64(sender.BandwidthEstimate())))
+		Expect(m.PacingRate).To(BeNumerically(">", m.BandwidthEstimate))
+		Expect(m.Cubic).To(BeNil())
+
+		// leave recovery
+		for sender.InRecovery() {
+			sendAvailableSendWindow()
+			ackNPackets(1, 0)
+		}
+		sendAvailableSendWindow()
+		ackedPacketNumber++
+		sender.OnPacketLost(ackedPacketNumber, maxDatagramSize, bytesInFlight)
+		Expect(tracer.states[len(tracer.states)-1]).To(Equal(logging.CongestionStateRecovery))
+		Expect(tracer.triggers[len(tracer.triggers)-1]).To(Equal(logging.CongestionStateTriggerPacketLoss))
+	})
+
+	Context("using a simulated marking queue", func() {
+		const (
+			linkRate        = 1200 // bytes per millisecond, i.e. 9.6 Mbit/s
+			baseRTT         = 20 * time.Millisecond
+			markingDelay    = 3 * time.Millisecond
+			simulationTime  = 10 * time.Second
+			measurementFrom = 2 * time.Second
+		)
+
+		type sentPacket struct {
+			pn       protocol.PacketNumber
+			sendTime time.Time
+			ackTime  time.Time
+			ceMarked bool
+		}
+
+		It("keeps the queue short while utilizing the link", func() {
+			start := clock.Now()
+			// The time when the bottleneck link is done sending all queued packets.
+			linkFreeAt := start
+			var inFlight []sentPacket
+			var deliveredBytes protocol.ByteCount
+			var queueDelaySum time.Duration
+			var maxQueueDelay time.Duration
+			var numMeasured, numCEMarked int
+
+			for clock.Now().Sub(start) < simulationTime {
+				now := clock.Now()
+				for sender.CanSend(bytesInFlight) && !sender.TimeUntilSend(bytesInFlight).After(now) {
+					queueDelay := linkFreeAt.Sub(now)
+					if queueDelay < 0 {
+						queueDelay = 0
+					}
+					linkFreeAt = now.Add(queueDelay + time.Duration(maxDatagramSize)*time.Millisecond/linkRate)
+					p := sentPacket{
+						pn:       packetNumber,
+						sendTime: now,
+						ackTime:  linkFreeAt.Add(baseRTT),
+						ceMarked: queueDelay > markingDelay,
+					}
+					inFlight = append(inFlight, p)
+					sender.OnPacketSent(now, bytesInFlight, packetNumber, maxDatagramSize, true)
+					packetNumber++
+					bytesInFlight += maxDatagramSize
+					if now.Sub(start) > measurementFrom {
+						queueDelaySum += queueDelay
+						maxQueueDelay = utils.MaxDuration(maxQueueDelay, queueDelay)
+						numMeasured++
+					}
+				}
+
+				// The bottleneck queue is FIFO, so the packets are acknowledged in order.
+				// Every packet is acknowledged immediately.
+				for len(inFlight) > 0 && !inFlight[0].ackTime.After(now) {
+					p := inFlight[0]
+					inFlight = inFlight[1:]
+					rttStats.UpdateRTT(now.Sub(p.sendTime), 0, now)
+					sender.MaybeExitSlowStart()
+					sender.OnPacketAcked(p.pn, maxDatagramSize, bytesInFlight, now)
+					bytesInFlight -= maxDatagramSize
+					var ce int
+					if p.ceMarked {
+						ce = 1
+						numCEMarked++
+					}
+					sender.OnECNFeedback(1, ce)
+					if now.Sub(start) > measurementFrom {
+						deliveredBytes += maxDatagramSize
+					}
+				}
+				clock.Advance(100 * time.Microsecond)
+			}
+
+			capacity := float64(linkRate) * float64((simulationTime-measurementFrom)/time.Millisecond)
+			utilization := float64(deliveredBytes) / capacity
+			avgQueueDelay := queueDelaySum / time.Duration(numMeasured)
+			fmt.Fprintf(GinkgoWriter, "Utilization: %.2f, average queuing delay: %s, max. queuing delay: %s, alpha: %.3f, CE marks: %d\n", utilization, avgQueueDelay, maxQueueDelay, sender.alpha, numCEMarked)
+			Expect(numCEMarked).ToNot(BeZero())
+			Expect(utilization).To(BeNumerically(">", 0.9))
+			Expect(avgQueueDelay).To(BeNumerically("<", 2*markingDelay))
+			Expect(maxQueueDelay).To(BeNumerically("<", 5*markingDelay))
+			// The fraction of CE-marked packets stays small, and the congestion window is only reduced slightly.
+			Expect(sender.alpha).To(BeNumerically("<", 0.5))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package handshake
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	extensionServerName uint16 = 0
+	extensionALPN       uint16 = 16
+)
+
+var errInvalidClientHello = errors.New("invalid ClientHello")
+
+// parseClientHello parses the server name (SNI) and the application protocols (ALPN) offered in a ClientHello.
+// The ClientHello is only validated as far as needed to extract these values,
+// the full validation is performed by crypto/tls.
+func parseClientHello(data []byte) (serverName string, protos []string, _ error) {
+	s := cryptobyte.String(data)
+	var msgType uint8
+	var body cryptobyte.String
+	if !s.ReadUint8(&msgType) || messageType(msgType) != typeClientHello ||
+		!s.ReadUint24LengthPrefixed(&body) || !s.Empty() {
+		return "", nil, errInvalidClientHello
+	}
+	var sessionID, cipherSuites, compressionMethods cryptobyte.String
+	if !body.Skip(2+32) || // legacy_version, random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) {
+		return "", nil, errInvalidClientHello
+	}
+	if body.Empty() { // no extensions
+		return "", nil, nil
+	}
+	var extensions cryptobyte.String
+	if !body.ReadUint16LengthPrefixed(&extensions) || !body.Empty() {
+		return "", nil, errInvalidClientHello
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return "", nil, errInvalidClientHello
+		}
+		switch extType {
+		case extensionServerName:
+			var nameList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&nameList) {
+				return "", nil, errInvalidClientHello
+			}
+			for !nameList.Empty() {
+				var nameType uint8
+				var name cryptobyte.String
+				if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) {
+					return "", nil, errInvalidClientHello
+				}
+				if nameType == 0 { // host_name
+					serverName = string(name)
+				}
+			}
+		case extensionALPN:
+			var protoList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&protoList) {
+				return "", nil, errInvalidClientHello
+			}
+			for !protoList.Empty() {
+				var proto cryptobyte.String
+				if !protoList.ReadUint8LengthPrefixed(&proto) {
+					return "", nil, errInvalidClientHello
+				}
+				protos = append(protos, string(proto))
+			}
+		}
+	}
+	return serverName, protos, nil
+}
@@ -0,0 +1,109 @@
+package handshake
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello parsing", func() {
+	type extension struct {
+		typ  uint16
+		data []byte
+	}
+
+	buildClientHello := func(exts ...extension) []byte {
+		var b cryptobyte.Builder
+		b.AddUint8(uint8(typeClientHello))
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)          // legacy_version
+			b.AddBytes(make([]byte, 32)) // random
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(0x1301) })
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			if exts == nil {
+				return
+			}
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, ext := range exts {
+					b.AddUint16(ext.typ)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(ext.data) })
+				}
+			})
+		})
+		return b.BytesOrPanic()
+	}
+
+	serverNameExtension := func(name string) extension {
+		var b cryptobyte.Builder
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(0) // host_name
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(name)) })
+		})
+		return extension{typ: extensionServerName, data: b.BytesOrPanic()}
+	}
+
+	alpnExtension := func(protos ...string) extension {
+		var b cryptobyte.Builder
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, proto := range protos {
+				b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(proto)) })
+			}
+		})
+		return extension{typ: extensionALPN, data: b.BytesOrPanic()}
+	}
+
+	It("parses the server name and the application protocols", func() {
+		data := buildClientHello(
+			extension{typ: 0x1337, data: []byte("foobar")},
+			serverNameExtension("quic-go.net"),
+			alpnExtension("h3", "hq-interop"),
+		)
+		serverName, protos, err := parseClientHello(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverName).To(Equal("quic-go.net"))
+		Expect(protos).To(Equal([]string{"h3", "hq-interop"}))
+	})
+
+	It("parses a ClientHello without server name and application protocols", func() {
+		serverName, protos, err := parseClientHello(buildClientHello(extension{typ: 0x1337}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverName).To(BeEmpty())
+		Expect(protos).To(BeEmpty())
+	})
+
+	It("parses a ClientHello without extensions", func() {
+		serverName, protos, err := parseClientHello(buildClientHello())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverName).To(BeEmpty())
+		Expect(protos).To(BeEmpty())
+	})
+
+	It("errors on other message types", func() {
+		data := buildClientHello()
+		data[0] = byte(typeServerHello)
+		_, _, err := parseClientHello(data)
+		Expect(err).To(MatchError(errInvalidClientHello))
+	})
+
+	It("errors on trailing data", func() {
+		_, _, err := parseClientHello(append(buildClientHello(), 0))
+		Expect(err).To(MatchError(errInvalidClientHello))
+	})
+
+	It("errors on truncated ClientHellos", func() {
+		data := buildClientHello(serverNameExtension("quic-go.net"), alpnExtension("h3"))
+		for i := 0; i < len(data); i++ {
+			_, _, err := parseClientHello(data[:i])
+			Expect(err).To(MatchError(errInvalidClientHello))
+		}
+	})
+
+	It("errors on invalid extensions", func() {
+		ext := alpnExtension("h3")
+		ext.data = ext.data[:len(ext.data)-1]
+		_, _, err := parseClientHello(buildClientHello(ext))
+		Expect(err).To(MatchError(errInvalidClientHello))
+	})
+})
@@ -111,6 +111,8 @@ type cryptoSetup struct {
 	// is closed when Close() is called
 	closeChan chan struct{}
 
+	receivedClientHello bool // only used for the server
+
 	zeroRTTParameters      *wire.TransportParameters
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan struct{} // is closed as soon as the ClientHello is written
@@ -163,6 +165,7 @@ func NewCryptoSetupClient(
 	tlsConf *tls.Config,
 	enable0RTT bool,
 	rttStats *utils.RTTStats,
+	keyUpdateInterval uint64,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
@@ -176,6 +179,7 @@ func NewCryptoSetupClient(
 		tlsConf,
 		enable0RTT,
 		rttStats,
+		keyUpdateInterval,
 		tracer,
 		logger,
 		protocol.PerspectiveClient,
@@ -197,6 +201,7 @@ func NewCryptoSetupServer(
 	tlsConf *tls.Config,
 	enable0RTT bool,
 	rttStats *utils.RTTStats,
+	keyUpdateInterval uint64,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
@@ -210,6 +215,7 @@ func NewCryptoSetupServer(
 		tlsConf,
 		enable0RTT,
 		rttStats,
+		keyUpdateInterval,
 		tracer,
 		logger,
 		protocol.PerspectiveServer,
@@ -228,6 +234,7 @@ func newCryptoSetup(
 	tlsConf *tls.Config,
 	enable0RTT bool,
 	rttStats *utils.RTTStats,
+	keyUpdateInterval uint64,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	perspective protocol.Perspective,
@@ -238,7 +245,13 @@ func newCryptoSetup(
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
-	extHandler := newExtensionHandler(tp.Marshal(perspective), perspective, version)
+	var tpData []byte
+	if tlsConf != nil && tlsConf.Rand != nil {
+		tpData = tp.MarshalWithRand(perspective, tlsConf.Rand)
+	} else {
+		tpData = tp.Marshal(perspective)
+	}
+	extHandler := newExtensionHandler(tpData, perspective, version)
 	zeroRTTParametersChan := make(chan *wire.TransportParameters, 1)
 	cs := &cryptoSetup{
 		tlsConf:                   tlsConf,
@@ -246,7 +259,7 @@ func newCryptoSetup(
 		initialSealer:             initialSealer,
 		initialOpener:             initialOpener,
 		handshakeStream:           handshakeStream,
-		aead:                      newUpdatableAEAD(rttStats, tracer, logger, version),
+		aead:                      newUpdatableAEAD(rttStats, keyUpdateInterval, tracer, logger, version),
 		readEncLevel:              protocol.EncryptionInitial,
 		writeEncLevel:             protocol.EncryptionInitial,
 		runner:                    runner,
@@ -322,9 +335,6 @@ func (h *cryptoSetup) RunHandshake() {
 
 	select {
 	case <-handshakeComplete: // return when the handshake is done
-		h.mutex.Lock()
-		h.handshakeCompleteTime = time.Now()
-		h.mutex.Unlock()
 		h.runner.OnHandshakeComplete()
 	case <-h.closeChan:
 		// wait until the Handshake() go routine has returned
@@ -365,6 +375,10 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 		h.onError(alertUnexpectedMessage, err.Error())
 		return false
 	}
+	if msgType == typeClientHello && h.perspective == protocol.PerspectiveServer && !h.receivedClientHello {
+		h.receivedClientHello = true
+		h.handleClientHello(data)
+	}
 	h.messageChan <- data
 	if encLevel == protocol.Encryption1RTT {
 		h.handlePostHandshakeMessage()
@@ -382,6 +396,13 @@ readLoop:
 		case <-h.isReadingHandshakeMessage:
 			break readLoop
 		case <-h.handshakeDone:
+			// Take the timestamp here, and not on the Go routine running the handshake.
+			// This way, it is ordered with respect to all other reads of the clock.
+			h.mutex.Lock()
+			if h.handshakeCompleteTime.IsZero() {
+				h.handshakeCompleteTime = h.now()
+			}
+			h.mutex.Unlock()
 			break readLoop
 		case <-h.closeChan:
 			break readLoop
@@ -395,6 +416,17 @@ readLoop:
 		msgType == typeFinished
 }
 
+// handleClientHello passes the server name and the application protocols of the first ClientHello to the runner.
+// If the ClientHello can't be parsed, qtls will reject it.
+func (h *cryptoSetup) handleClientHello(data []byte) {
+	serverName, protos, err := parseClientHello(data)
+	if err != nil {
+		h.logger.Debugf("Parsing the ClientHello failed: %s", err)
+		return
+	}
+	h.runner.OnReceivedClientHello(serverName, protos)
+}
+
 func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protocol.EncryptionLevel) error {
 	var expected protocol.EncryptionLevel
 	switch msgType {
@@ -800,7 +832,7 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if h.zeroRTTOpener != nil && time.Since(h.handshakeCompleteTime) > 3*h.rttStats.PTO(true) {
+	if h.zeroRTTOpener != nil && h.now().Sub(h.handshakeCompleteTime) > 3*h.rttStats.PTO(true) {
 		h.zeroRTTOpener = nil
 		h.logger.Debugf("Dropping 0-RTT keys.")
 		if h.tracer != nil {
@@ -814,6 +846,14 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 	return h.aead, nil
 }
 
+// now returns the current time, using the same clock as the TLS stack.
+func (h *cryptoSetup) now() time.Time {
+	if h.tlsConf != nil && h.tlsConf.Time != nil {
+		return h.tlsConf.Time()
+	}
+	return time.Now()
+}
+
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return qtls.GetConnectionState(h.conn)
 }
@@ -97,6 +97,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			testdata.GetTLSConfig(),
 			false,
 			&utils.RTTStats{},
+			0,
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 			protocol.VersionTLS,
@@ -124,6 +125,73 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("passes the server name and the application protocols from the ClientHello to the runner", func() {
+		cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+		cRunner := NewMockHandshakeRunner(mockCtrl)
+		cRunner.EXPECT().OnError(gomock.Any()).AnyTimes()
+		client, _ := NewCryptoSetupClient(
+			cInitialStream,
+			cHandshakeStream,
+			protocol.ConnectionID{},
+			nil,
+			nil,
+			&wire.TransportParameters{},
+			cRunner,
+			clientConf,
+			false,
+			&utils.RTTStats{},
+			0,
+			nil,
+			utils.DefaultLogger.WithPrefix("client"),
+			protocol.VersionTLS,
+		)
+		clientDone := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(clientDone)
+			client.RunHandshake()
+		}()
+		var ch chunk
+		Eventually(cChunkChan).Should(Receive(&ch))
+		Expect(messageType(ch.data[0])).To(Equal(typeClientHello))
+
+		received := make(chan struct{})
+		runner := NewMockHandshakeRunner(mockCtrl)
+		runner.EXPECT().OnReceivedClientHello("localhost", []string{"crypto-setup"}).Do(func(string, []string) { close(received) })
+		runner.EXPECT().OnReceivedParams(gomock.Any()).AnyTimes()
+		runner.EXPECT().OnError(gomock.Any()).AnyTimes()
+		_, sInitialStream, sHandshakeStream := initStreams()
+		var token protocol.StatelessResetToken
+		server := NewCryptoSetupServer(
+			sInitialStream,
+			sHandshakeStream,
+			protocol.ConnectionID{},
+			nil,
+			nil,
+			&wire.TransportParameters{StatelessResetToken: &token},
+			runner,
+			serverConf,
+			false,
+			&utils.RTTStats{},
+			0,
+			nil,
+			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
+		)
+		serverDone := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(serverDone)
+			server.RunHandshake()
+		}()
+		server.HandleMessage(ch.data, ch.encLevel)
+		Eventually(received).Should(BeClosed())
+		Expect(server.Close()).To(Succeed())
+		Eventually(serverDone).Should(BeClosed())
+		Expect(client.Close()).To(Succeed())
+		Eventually(clientDone).Should(BeClosed())
+	})
+
 	It("handles qtls errors occurring before during ClientHello generation", func() {
 		sErrChan := make(chan error, 1)
 		runner := NewMockHandshakeRunner(mockCtrl)
@@ -143,6 +211,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			tlsConf,
 			false,
 			&utils.RTTStats{},
+			0,
 			nil,
 			utils.DefaultLogger.WithPrefix("client"),
 			protocol.VersionTLS,
@@ -179,6 +248,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			testdata.GetTLSConfig(),
 			false,
 			&utils.RTTStats{},
+			0,
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 			protocol.VersionTLS,
@@ -220,6 +290,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			serverConf,
 			false,
 			&utils.RTTStats{},
+			0,
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 			protocol.VersionTLS,
@@ -255,6 +326,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			serverConf,
 			false,
 			&utils.RTTStats{},
+			0,
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 			protocol.VersionTLS,
@@ -373,6 +445,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				clientConf,
 				enable0RTT,
 				clientRTTStats,
+				0,
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 				protocol.VersionTLS,
@@ -382,6 +455,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 			sErrChan := make(chan error, 1)
 			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnReceivedClientHello(gomock.Any(), gomock.Any())
 			sRunner.EXPECT().OnReceivedParams(gomock.Any())
 			sRunner.EXPECT().OnError(gomock.Any()).Do(func(e error) { sErrChan <- e }).MaxTimes(1)
 			sRunner.EXPECT().OnHandshakeComplete().Do(func() { sHandshakeComplete = true }).MaxTimes(1)
@@ -400,6 +474,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				serverConf,
 				enable0RTT,
 				serverRTTStats,
+				0,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 				protocol.VersionTLS,
@@ -470,6 +545,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&tls.Config{InsecureSkipVerify: true},
 				false,
 				&utils.RTTStats{},
+				0,
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 				protocol.VersionTLS,
@@ -513,6 +589,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				clientConf,
 				false,
 				&utils.RTTStats{},
+				0,
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 				protocol.VersionTLS,
@@ -521,6 +598,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 			var token protocol.StatelessResetToken
 			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnReceivedClientHello(gomock.Any(), gomock.Any())
 			sRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *wire.TransportParameters) { cTransportParametersRcvd = tp })
 			sRunner.EXPECT().OnHandshakeComplete()
 			sTransportParameters := &wire.TransportParameters{
@@ -538,6 +616,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				serverConf,
 				false,
 				&utils.RTTStats{},
+				0,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 				protocol.VersionTLS,
@@ -572,6 +651,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					clientConf,
 					false,
 					&utils.RTTStats{},
+					0,
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
 					protocol.VersionTLS,
@@ -579,6 +659,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 				sRunner := NewMockHandshakeRunner(mockCtrl)
+				sRunner.EXPECT().OnReceivedClientHello(gomock.Any(), gomock.Any())
 				sRunner.EXPECT().OnReceivedParams(gomock.Any())
 				sRunner.EXPECT().OnHandshakeComplete()
 				var token protocol.StatelessResetToken
@@ -593,6 +674,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					serverConf,
 					false,
 					&utils.RTTStats{},
+					0,
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
 					protocol.VersionTLS,
@@ -631,6 +713,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					clientConf,
 					false,
 					&utils.RTTStats{},
+					0,
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
 					protocol.VersionTLS,
@@ -638,6 +721,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 				sRunner := NewMockHandshakeRunner(mockCtrl)
+				sRunner.EXPECT().OnReceivedClientHello(gomock.Any(), gomock.Any())
 				sRunner.EXPECT().OnReceivedParams(gomock.Any())
 				sRunner.EXPECT().OnHandshakeComplete()
 				var token protocol.StatelessResetToken
@@ -652,6 +736,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					serverConf,
 					false,
 					&utils.RTTStats{},
+					0,
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
 					protocol.VersionTLS,
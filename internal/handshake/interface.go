@@ -44,6 +44,14 @@ type ShortHeaderOpener interface {
 	Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte) ([]byte, error)
 }
 
+// ParallelShortHeaderOpener is a ShortHeaderOpener that allows unpacking packets on other Go routines.
+// The key phase is selected when the packets are passed to OpenDecrypted, in order.
+type ParallelShortHeaderOpener interface {
+	ShortHeaderOpener
+	DecryptionState() DecryptionState
+	OpenDecrypted(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte, key *DecryptionKey, decrypted []byte, decryptErr error) ([]byte, error)
+}
+
 // LongHeaderSealer seals a long header packet
 type LongHeaderSealer interface {
 	Seal(dst, src []byte, packetNumber protocol.PacketNumber, associatedData []byte) []byte
@@ -65,6 +73,7 @@ type tlsExtensionHandler interface {
 }
 
 type handshakeRunner interface {
+	OnReceivedClientHello(serverName string, supportedProtocols []string)
 	OnReceivedParams(*wire.TransportParameters)
 	OnHandshakeComplete()
 	OnError(error)
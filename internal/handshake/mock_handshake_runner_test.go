@@ -71,6 +71,18 @@ func (mr *MockHandshakeRunnerMockRecorder) OnHandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnHandshakeComplete", reflect.TypeOf((*MockHandshakeRunner)(nil).OnHandshakeComplete))
 }
 
+// OnReceivedClientHello mocks base method.
+func (m *MockHandshakeRunner) OnReceivedClientHello(serverName string, supportedProtocols []string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnReceivedClientHello", serverName, supportedProtocols)
+}
+
+// OnReceivedClientHello indicates an expected call of OnReceivedClientHello.
+func (mr *MockHandshakeRunnerMockRecorder) OnReceivedClientHello(serverName, supportedProtocols interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReceivedClientHello", reflect.TypeOf((*MockHandshakeRunner)(nil).OnReceivedClientHello), serverName, supportedProtocols)
+}
+
 // OnReceivedParams mocks base method.
 func (m *MockHandshakeRunner) OnReceivedParams(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
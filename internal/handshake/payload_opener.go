@@ -0,0 +1,127 @@
+package handshake
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+)
+
+// A DecryptionKey is the key of a single key phase, used to decrypt the payload of 1-RTT packets.
+// It is immutable, and can therefore be passed to other Go routines.
+type DecryptionKey struct {
+	keyPhase      protocol.KeyPhase
+	suite         *qtls.CipherSuiteTLS13
+	trafficSecret []byte
+	version       protocol.VersionNumber
+}
+
+// KeyPhase returns the key phase of this key.
+func (k *DecryptionKey) KeyPhase() protocol.KeyPhase {
+	return k.keyPhase
+}
+
+// A headerProtectionKey is the key used to remove header protection from 1-RTT packets.
+// It doesn't change when the keys are updated.
+type headerProtectionKey struct {
+	suite         *qtls.CipherSuiteTLS13
+	trafficSecret []byte
+	version       protocol.VersionNumber
+}
+
+// A DecryptionState is a snapshot of the state needed to remove header protection from 1-RTT packets,
+// to decode their packet numbers and to select the key to decrypt their payload with.
+// It is immutable, and can therefore be passed to other Go routines.
+// Since processing packets changes the state, the results are only provisional:
+// OpenDecrypted checks that the packet number and the key would still be the same.
+type DecryptionState struct {
+	highestRcvdPN           protocol.PacketNumber
+	keyPhase                protocol.KeyPhase
+	firstRcvdWithCurrentKey protocol.PacketNumber
+	headerKey               *headerProtectionKey
+	prevKey, key, nextKey   *DecryptionKey // prevKey is nil if the previous keys were dropped
+}
+
+// DecodePacketNumber decodes the packet number, based on the highest packet number received at the time of the snapshot.
+func (s DecryptionState) DecodePacketNumber(wirePN protocol.PacketNumber, wirePNLen protocol.PacketNumberLen) protocol.PacketNumber {
+	return protocol.DecodePacketNumber(wirePNLen, s.highestRcvdPN, wirePN)
+}
+
+// Key returns the key that the packet would be opened with, at the time of the snapshot.
+func (s DecryptionState) Key(pn protocol.PacketNumber, kp protocol.KeyPhaseBit) (*DecryptionKey, error) {
+	keyPhase, err := selectKeyPhase(s.keyPhase, s.firstRcvdWithCurrentKey, s.prevKey != nil, pn, kp)
+	if err != nil {
+		return nil, err
+	}
+	switch keyPhase {
+	case s.keyPhase:
+		return s.key, nil
+	case s.keyPhase + 1:
+		return s.nextKey, nil
+	default:
+		return s.prevKey, nil
+	}
+}
+
+// The number of AEADs kept by the PayloadOpener.
+// During a key update, packets might be protected with the previous, the current and the next key phase.
+const numPayloadOpenerAEADs = 3
+
+// A PayloadOpener removes header protection and decrypts packet payloads using DecryptionStates and DecryptionKeys.
+// It is not safe for concurrent use: when decrypting on multiple Go routines,
+// every Go routine needs to use its own PayloadOpener.
+type PayloadOpener struct {
+	keys  [numPayloadOpenerAEADs]*DecryptionKey
+	aeads [numPayloadOpenerAEADs]cipher.AEAD
+	next  int // the index that is replaced when a new key is used
+
+	headerKey       *headerProtectionKey
+	headerDecrypter headerProtector
+
+	// use a single slice to avoid allocations
+	nonceBuf []byte
+}
+
+// NewPayloadOpener creates a new PayloadOpener.
+func NewPayloadOpener() *PayloadOpener {
+	return &PayloadOpener{}
+}
+
+// Open decrypts the payload of a packet.
+// It returns ErrDecryptionFailed if the packet can't be decrypted.
+// Unlike ShortHeaderOpener.Open, it doesn't modify the state of the connection's keys.
+func (o *PayloadOpener) Open(dst, src []byte, key *DecryptionKey, pn protocol.PacketNumber, ad []byte) ([]byte, error) {
+	aead := o.getAEAD(key)
+	if len(o.nonceBuf) != aead.NonceSize() {
+		o.nonceBuf = make([]byte, aead.NonceSize())
+	}
+	binary.BigEndian.PutUint64(o.nonceBuf[len(o.nonceBuf)-8:], uint64(pn))
+	dec, err := aead.Open(dst, o.nonceBuf, src, ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return dec, nil
+}
+
+// DecryptHeader removes header protection, using the header protection key of the DecryptionState.
+func (o *PayloadOpener) DecryptHeader(s *DecryptionState, sample []byte, firstByte *byte, hdrBytes []byte) {
+	if o.headerKey != s.headerKey {
+		o.headerKey = s.headerKey
+		o.headerDecrypter = newHeaderProtector(s.headerKey.suite, s.headerKey.trafficSecret, false, s.headerKey.version)
+	}
+	o.headerDecrypter.DecryptHeader(sample, firstByte, hdrBytes)
+}
+
+func (o *PayloadOpener) getAEAD(key *DecryptionKey) cipher.AEAD {
+	for i, k := range o.keys {
+		if k == key {
+			return o.aeads[i]
+		}
+	}
+	aead := createAEAD(key.suite, key.trafficSecret, key.version)
+	o.keys[o.next] = key
+	o.aeads[o.next] = aead
+	o.next = (o.next + 1) % numPayloadOpenerAEADs
+	return aead
+}
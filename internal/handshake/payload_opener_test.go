@@ -0,0 +1,87 @@
+package handshake
+
+import (
+	"crypto/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Payload Opener", func() {
+	var (
+		sealer *updatableAEAD
+		key    *DecryptionKey
+	)
+	ad := []byte("associated data")
+	msg := []byte("Lorem ipsum dolor sit amet.")
+
+	newKey := func() *DecryptionKey {
+		trafficSecret := make([]byte, 16)
+		rand.Read(trafficSecret)
+		sealer = newUpdatableAEAD(&utils.RTTStats{}, 0, nil, utils.DefaultLogger, protocol.Version1)
+		sealer.SetWriteKey(cipherSuites[0], trafficSecret)
+		return &DecryptionKey{
+			suite:         cipherSuites[0],
+			trafficSecret: trafficSecret,
+			version:       protocol.Version1,
+		}
+	}
+
+	BeforeEach(func() {
+		key = newKey()
+	})
+
+	It("decrypts packets", func() {
+		o := NewPayloadOpener()
+		dec, err := o.Open(nil, sealer.Seal(nil, msg, 0x1337, ad), key, 0x1337, ad)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dec).To(Equal(msg))
+	})
+
+	It("fails to decrypt packets with the wrong packet number", func() {
+		o := NewPayloadOpener()
+		_, err := o.Open(nil, sealer.Seal(nil, msg, 0x1337, ad), key, 0x42, ad)
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
+	It("fails to decrypt packets with the wrong associated data", func() {
+		o := NewPayloadOpener()
+		_, err := o.Open(nil, sealer.Seal(nil, msg, 0x1337, ad), key, 0x1337, []byte("foobar"))
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
+	It("caches AEADs", func() {
+		o := NewPayloadOpener()
+		_, err := o.Open(nil, sealer.Seal(nil, msg, 1, ad), key, 1, ad)
+		Expect(err).ToNot(HaveOccurred())
+		aead := o.aeads[0]
+		_, err = o.Open(nil, sealer.Seal(nil, msg, 2, ad), key, 2, ad)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.aeads[0]).To(BeIdenticalTo(aead))
+		Expect(o.aeads[1]).To(BeNil())
+	})
+
+	It("uses multiple keys", func() {
+		o := NewPayloadOpener()
+		type packet struct {
+			key       *DecryptionKey
+			encrypted []byte
+		}
+		var packets []packet
+		for i := 0; i < numPayloadOpenerAEADs+1; i++ {
+			k := newKey()
+			packets = append(packets, packet{key: k, encrypted: sealer.Seal(nil, msg, 1, ad)})
+		}
+		// decrypt each packet twice, to make sure that evicted keys are recreated
+		for i := 0; i < 2; i++ {
+			for _, p := range packets {
+				dec, err := o.Open(nil, p.encrypted, p.key, 1, ad)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dec).To(Equal(msg))
+			}
+		}
+	})
+})
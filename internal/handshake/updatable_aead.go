@@ -3,6 +3,7 @@ package handshake
 import (
 	"crypto"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/tls"
 	"encoding/binary"
 	"fmt"
@@ -15,7 +16,8 @@ import (
 	"github.com/lucas-clemente/quic-go/logging"
 )
 
-// KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
+// KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update,
+// if no other value is configured for the connection.
 // It's a package-level variable to allow modifying it for testing purposes.
 var KeyUpdateInterval uint64 = protocol.KeyUpdateInterval
 
@@ -50,6 +52,12 @@ type updatableAEAD struct {
 	nextRcvTrafficSecret  []byte
 	nextSendTrafficSecret []byte
 
+	// The receive traffic secrets are needed to create DecryptionKeys.
+	rcvTrafficSecret     []byte
+	prevRcvTrafficSecret []byte
+	rcvKeys              []*DecryptionKey
+	headerKey            *headerProtectionKey
+
 	headerDecrypter headerProtector
 	headerEncrypter headerProtector
 
@@ -64,17 +72,22 @@ type updatableAEAD struct {
 }
 
 var (
-	_ ShortHeaderOpener = &updatableAEAD{}
-	_ ShortHeaderSealer = &updatableAEAD{}
+	_ ParallelShortHeaderOpener = &updatableAEAD{}
+	_ ShortHeaderSealer         = &updatableAEAD{}
 )
 
-func newUpdatableAEAD(rttStats *utils.RTTStats, tracer logging.ConnectionTracer, logger utils.Logger, version protocol.VersionNumber) *updatableAEAD {
+// newUpdatableAEAD creates a new updatableAEAD.
+// If keyUpdateInterval is 0, KeyUpdateInterval is used.
+func newUpdatableAEAD(rttStats *utils.RTTStats, keyUpdateInterval uint64, tracer logging.ConnectionTracer, logger utils.Logger, version protocol.VersionNumber) *updatableAEAD {
+	if keyUpdateInterval == 0 {
+		keyUpdateInterval = KeyUpdateInterval
+	}
 	return &updatableAEAD{
 		firstPacketNumber:       protocol.InvalidPacketNumber,
 		largestAcked:            protocol.InvalidPacketNumber,
 		firstRcvdWithCurrentKey: protocol.InvalidPacketNumber,
 		firstSentWithCurrentKey: protocol.InvalidPacketNumber,
-		keyUpdateInterval:       KeyUpdateInterval,
+		keyUpdateInterval:       keyUpdateInterval,
 		rttStats:                rttStats,
 		tracer:                  tracer,
 		logger:                  logger,
@@ -99,6 +112,8 @@ func (a *updatableAEAD) rollKeys() {
 	a.prevRcvAEAD = a.rcvAEAD
 	a.rcvAEAD = a.nextRcvAEAD
 	a.sendAEAD = a.nextSendAEAD
+	a.prevRcvTrafficSecret = a.rcvTrafficSecret
+	a.rcvTrafficSecret = a.nextRcvTrafficSecret
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextRcvTrafficSecret)
 	a.nextSendTrafficSecret = a.getNextTrafficSecret(a.suite.Hash, a.nextSendTrafficSecret)
@@ -120,7 +135,9 @@ func (a *updatableAEAD) getNextTrafficSecret(hash crypto.Hash, ts []byte) []byte
 // For the server, this function is called after SetWriteKey.
 func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
 	a.rcvAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.rcvTrafficSecret = trafficSecret
 	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
+	a.headerKey = &headerProtectionKey{suite: suite, trafficSecret: trafficSecret, version: a.version}
 	if a.suite == nil {
 		a.setAEADParameters(a.rcvAEAD, suite)
 	}
@@ -162,6 +179,10 @@ func (a *updatableAEAD) DecodePacketNumber(wirePN protocol.PacketNumber, wirePNL
 
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
 	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
+	return a.finishOpen(dec, pn, err)
+}
+
+func (a *updatableAEAD) finishOpen(dec []byte, pn protocol.PacketNumber, err error) ([]byte, error) {
 	if err == ErrDecryptionFailed {
 		a.invalidPacketCount++
 		if a.invalidPacketCount >= a.invalidPacketLimit {
@@ -175,35 +196,81 @@ func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 }
 
 func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	a.maybeDropPrevKey(rcvTime)
+	keyPhase, err := a.selectKeyPhase(pn, kp)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
+	// The AEAD we're using here will be the qtls.aeadAESGCM13.
+	// It uses the nonce provided here and XOR it with the IV.
+	dec, err := a.rcvAEADForKeyPhase(keyPhase).Open(dst, a.nonceBuf, src, ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	if err := a.onOpened(keyPhase, pn, rcvTime); err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+func (a *updatableAEAD) maybeDropPrevKey(rcvTime time.Time) {
 	if a.prevRcvAEAD != nil && !a.prevRcvAEADExpiry.IsZero() && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
+		a.prevRcvTrafficSecret = nil
 		a.logger.Debugf("Dropping key phase %d", a.keyPhase-1)
 		a.prevRcvAEADExpiry = time.Time{}
 		if a.tracer != nil {
 			a.tracer.DroppedKey(a.keyPhase - 1)
 		}
 	}
-	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
-	if kp != a.keyPhase.Bit() {
-		if a.keyPhase > 0 && a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < a.firstRcvdWithCurrentKey {
-			if a.prevRcvAEAD == nil {
-				return nil, ErrKeysDropped
-			}
-			// we updated the key, but the peer hasn't updated yet
-			dec, err := a.prevRcvAEAD.Open(dst, a.nonceBuf, src, ad)
-			if err != nil {
-				err = ErrDecryptionFailed
-			}
-			return dec, err
-		}
-		// try opening the packet with the next key phase
-		dec, err := a.nextRcvAEAD.Open(dst, a.nonceBuf, src, ad)
-		if err != nil {
-			return nil, ErrDecryptionFailed
+}
+
+// selectKeyPhase determines the key phase that a packet has to be opened with.
+// It doesn't modify any state.
+func (a *updatableAEAD) selectKeyPhase(pn protocol.PacketNumber, kp protocol.KeyPhaseBit) (protocol.KeyPhase, error) {
+	return selectKeyPhase(a.keyPhase, a.firstRcvdWithCurrentKey, a.prevRcvAEAD != nil, pn, kp)
+}
+
+func selectKeyPhase(
+	keyPhase protocol.KeyPhase,
+	firstRcvdWithCurrentKey protocol.PacketNumber,
+	hasPrevKey bool,
+	pn protocol.PacketNumber,
+	kp protocol.KeyPhaseBit,
+) (protocol.KeyPhase, error) {
+	if kp == keyPhase.Bit() {
+		return keyPhase, nil
+	}
+	if keyPhase > 0 && firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < firstRcvdWithCurrentKey {
+		if !hasPrevKey {
+			return 0, ErrKeysDropped
 		}
+		// we updated the key, but the peer hasn't updated yet
+		return keyPhase - 1, nil
+	}
+	// try opening the packet with the next key phase
+	return keyPhase + 1, nil
+}
+
+func (a *updatableAEAD) rcvAEADForKeyPhase(keyPhase protocol.KeyPhase) cipher.AEAD {
+	switch keyPhase {
+	case a.keyPhase:
+		return a.rcvAEAD
+	case a.keyPhase + 1:
+		return a.nextRcvAEAD
+	default:
+		return a.prevRcvAEAD
+	}
+}
+
+// onOpened is called after a packet was successfully opened with the key phase returned by selectKeyPhase.
+func (a *updatableAEAD) onOpened(keyPhase protocol.KeyPhase, pn protocol.PacketNumber, rcvTime time.Time) error {
+	switch keyPhase {
+	case a.keyPhase + 1:
 		// Opening succeeded. Check if the peer was allowed to update.
 		if a.keyPhase > 0 && a.firstSentWithCurrentKey == protocol.InvalidPacketNumber {
-			return nil, &qerr.TransportError{
+			return &qerr.TransportError{
 				ErrorCode:    qerr.KeyUpdateError,
 				ErrorMessage: "keys updated too quickly",
 			}
@@ -217,25 +284,91 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 			a.tracer.UpdatedKey(a.keyPhase, true)
 		}
 		a.firstRcvdWithCurrentKey = pn
-		return dec, err
+	case a.keyPhase:
+		a.numRcvdWithCurrentKey++
+		if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
+			// We initiated the key updated, and now we received the first packet protected with the new key phase.
+			// Therefore, we are certain that the peer rolled its keys as well. Start a timer to drop the old keys.
+			if a.keyPhase > 0 {
+				a.logger.Debugf("Peer confirmed key update to phase %d", a.keyPhase)
+				a.startKeyDropTimer(rcvTime)
+			}
+			a.firstRcvdWithCurrentKey = pn
+		}
 	}
-	// The AEAD we're using here will be the qtls.aeadAESGCM13.
-	// It uses the nonce provided here and XOR it with the IV.
-	dec, err := a.rcvAEAD.Open(dst, a.nonceBuf, src, ad)
-	if err != nil {
-		return dec, ErrDecryptionFailed
-	}
-	a.numRcvdWithCurrentKey++
-	if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
-		// We initiated the key updated, and now we received the first packet protected with the new key phase.
-		// Therefore, we are certain that the peer rolled its keys as well. Start a timer to drop the old keys.
-		if a.keyPhase > 0 {
-			a.logger.Debugf("Peer confirmed key update to phase %d", a.keyPhase)
-			a.startKeyDropTimer(rcvTime)
+	return nil
+}
+
+// DecryptionState returns a snapshot of the state needed to decrypt 1-RTT packets on a different Go routine,
+// using a PayloadOpener.
+// The result then needs to be passed to OpenDecrypted.
+func (a *updatableAEAD) DecryptionState() DecryptionState {
+	s := DecryptionState{
+		highestRcvdPN:           a.highestRcvdPN,
+		keyPhase:                a.keyPhase,
+		firstRcvdWithCurrentKey: a.firstRcvdWithCurrentKey,
+		headerKey:               a.headerKey,
+		key:                     a.decryptionKey(a.keyPhase),
+		nextKey:                 a.decryptionKey(a.keyPhase + 1),
+	}
+	if a.prevRcvAEAD != nil {
+		s.prevKey = a.decryptionKey(a.keyPhase - 1)
+	}
+	return s
+}
+
+func (a *updatableAEAD) decryptionKey(keyPhase protocol.KeyPhase) *DecryptionKey {
+	for _, key := range a.rcvKeys {
+		if key.keyPhase == keyPhase {
+			return key
 		}
-		a.firstRcvdWithCurrentKey = pn
 	}
-	return dec, err
+	var trafficSecret []byte
+	switch keyPhase {
+	case a.keyPhase:
+		trafficSecret = a.rcvTrafficSecret
+	case a.keyPhase + 1:
+		trafficSecret = a.nextRcvTrafficSecret
+	default:
+		trafficSecret = a.prevRcvTrafficSecret
+	}
+	key := &DecryptionKey{
+		keyPhase:      keyPhase,
+		suite:         a.suite,
+		trafficSecret: trafficSecret,
+		version:       a.version,
+	}
+	// only keep the keys that might still be used
+	keys := a.rcvKeys[:0]
+	for _, k := range a.rcvKeys {
+		if k.keyPhase+1 >= a.keyPhase {
+			keys = append(keys, k)
+		}
+	}
+	a.rcvKeys = append(keys, key)
+	return key
+}
+
+// OpenDecrypted opens a packet that was decrypted using a key obtained from a DecryptionState.
+// It must be called in the order that the packets would have been passed to Open,
+// and it applies the same state updates as Open.
+// If the key doesn't match the key the packet would be opened with by now
+// (e.g. because a preceding packet updated the keys), the packet is opened using Open.
+// In that case, src must still contain the encrypted payload, and the packet is decrypted into dst.
+// Otherwise, decrypted is returned.
+func (a *updatableAEAD) OpenDecrypted(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte, key *DecryptionKey, decrypted []byte, decryptErr error) ([]byte, error) {
+	a.maybeDropPrevKey(rcvTime)
+	keyPhase, err := a.selectKeyPhase(pn, kp)
+	if key == nil || err != nil || keyPhase != key.keyPhase {
+		return a.Open(dst, src, rcvTime, pn, kp, ad)
+	}
+	if decryptErr != nil {
+		return a.finishOpen(nil, pn, ErrDecryptionFailed)
+	}
+	if err := a.onOpened(keyPhase, pn, rcvTime); err != nil {
+		return a.finishOpen(nil, pn, err)
+	}
+	return a.finishOpen(decrypted, pn, nil)
 }
 
 func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
@@ -321,3 +454,27 @@ func (a *updatableAEAD) DecryptHeader(sample []byte, firstByte *byte, hdrBytes [
 func (a *updatableAEAD) FirstPacketNumber() protocol.PacketNumber {
 	return a.firstPacketNumber
 }
+
+// NewUpdatableAEADPair creates the 1-RTT sealer of a client and the 1-RTT opener of a server, using random traffic secrets.
+// It is used to test the unpacking of 1-RTT packets without running a handshake.
+// The handshake is confirmed on the sealer, so it initiates a key update after sending keyUpdateInterval packets.
+// If invalidPacketLimit is not 0, it replaces the integrity limit of the cipher suite.
+func NewUpdatableAEADPair(keyUpdateInterval, invalidPacketLimit uint64, version protocol.VersionNumber) (ShortHeaderSealer, ParallelShortHeaderOpener) {
+	suite := qtls.CipherSuiteTLS13ByID(tls.TLS_AES_128_GCM_SHA256)
+	clientSecret := make([]byte, suite.Hash.Size())
+	serverSecret := make([]byte, suite.Hash.Size())
+	rand.Read(clientSecret)
+	rand.Read(serverSecret)
+	rttStats := &utils.RTTStats{}
+	client := newUpdatableAEAD(rttStats, keyUpdateInterval, nil, utils.DefaultLogger, version)
+	client.SetReadKey(suite, serverSecret)
+	client.SetWriteKey(suite, clientSecret)
+	client.SetHandshakeConfirmed()
+	server := newUpdatableAEAD(rttStats, keyUpdateInterval, nil, utils.DefaultLogger, version)
+	server.SetWriteKey(suite, serverSecret)
+	server.SetReadKey(suite, clientSecret)
+	if invalidPacketLimit != 0 {
+		server.invalidPacketLimit = invalidPacketLimit
+	}
+	return client, server
+}
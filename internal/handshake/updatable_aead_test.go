@@ -19,10 +19,15 @@ import (
 )
 
 var _ = Describe("Updatable AEAD", func() {
+	It("uses the configured key update interval", func() {
+		Expect(newUpdatableAEAD(&utils.RTTStats{}, 0, nil, nil, protocol.Version1).keyUpdateInterval).To(Equal(KeyUpdateInterval))
+		Expect(newUpdatableAEAD(&utils.RTTStats{}, 42, nil, nil, protocol.Version1).keyUpdateInterval).To(BeEquivalentTo(42))
+	})
+
 	DescribeTable("ChaCha test vector",
 		func(v protocol.VersionNumber, expectedPayload, expectedPacket []byte) {
 			secret := splitHexString("9ac312a7f877468ebe69422748ad00a1 5443f18203a07d6060f688f30f21632b")
-			aead := newUpdatableAEAD(&utils.RTTStats{}, nil, nil, v)
+			aead := newUpdatableAEAD(&utils.RTTStats{}, 0, nil, nil, v)
 			chacha := cipherSuites[2]
 			Expect(chacha.ID).To(Equal(tls.TLS_CHACHA20_POLY1305_SHA256))
 			aead.SetWriteKey(chacha, secret)
@@ -70,8 +75,8 @@ var _ = Describe("Updatable AEAD", func() {
 						rand.Read(trafficSecret2)
 
 						rttStats = utils.NewRTTStats()
-						client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, v)
-						server = newUpdatableAEAD(rttStats, serverTracer, utils.DefaultLogger, v)
+						client = newUpdatableAEAD(rttStats, 0, nil, utils.DefaultLogger, v)
+						server = newUpdatableAEAD(rttStats, 0, serverTracer, utils.DefaultLogger, v)
 						client.SetReadKey(cs, trafficSecret2)
 						client.SetWriteKey(cs, trafficSecret1)
 						server.SetReadKey(cs, trafficSecret1)
@@ -159,6 +164,145 @@ var _ = Describe("Updatable AEAD", func() {
 							Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.AEADLimitReached))
 						})
 
+						Context("decrypting in parallel", func() {
+							// decrypt decrypts a packet like a worker Go routine would
+							decrypt := func(o *PayloadOpener, key *DecryptionKey, encrypted []byte, pn protocol.PacketNumber) ([]byte, error) {
+								return o.Open(nil, encrypted, key, pn, ad)
+							}
+
+							It("opens packets", func() {
+								encrypted := server.Seal(nil, msg, 0x1337, ad)
+								key, err := client.DecryptionState().Key(0x1337, protocol.KeyPhaseZero)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(key.KeyPhase()).To(BeZero())
+								dec, decErr := decrypt(NewPayloadOpener(), key, encrypted, 0x1337)
+								Expect(decErr).ToNot(HaveOccurred())
+								opened, err := client.OpenDecrypted(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, ad, key, dec, decErr)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								Expect(client.DecodePacketNumber(0x38, protocol.PacketNumberLen1)).To(BeEquivalentTo(0x1338))
+							})
+
+							It("returns the decrypted payload without copying it", func() {
+								encrypted := server.Seal(nil, msg, 0x1337, ad)
+								key, err := client.DecryptionState().Key(0x1337, protocol.KeyPhaseZero)
+								Expect(err).ToNot(HaveOccurred())
+								dec, decErr := decrypt(NewPayloadOpener(), key, encrypted, 0x1337)
+								Expect(decErr).ToNot(HaveOccurred())
+								opened, err := client.OpenDecrypted(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, ad, key, dec, decErr)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(&opened[0]).To(Equal(&dec[0]))
+							})
+
+							It("removes header protection and decodes packet numbers", func() {
+								_, err := client.Open(nil, server.Seal(nil, msg, 0x1337, ad), time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								state := client.DecryptionState()
+								Expect(state.DecodePacketNumber(0x38, protocol.PacketNumberLen1)).To(BeEquivalentTo(0x1338))
+								sample := make([]byte, 16)
+								rand.Read(sample)
+								header := []byte{0xb5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xde, 0xad, 0xbe, 0xef}
+								server.EncryptHeader(sample, &header[0], header[9:13])
+								Expect(header[9:13]).ToNot(Equal([]byte{9, 0xde, 0xad, 0xbe}))
+								NewPayloadOpener().DecryptHeader(&state, sample, &header[0], header[9:13])
+								Expect(header).To(Equal([]byte{0xb5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xde, 0xad, 0xbe, 0xef}))
+							})
+
+							It("reuses keys", func() {
+								key1, err := client.DecryptionState().Key(1, protocol.KeyPhaseZero)
+								Expect(err).ToNot(HaveOccurred())
+								key2, err := client.DecryptionState().Key(2, protocol.KeyPhaseZero)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(key1).To(BeIdenticalTo(key2))
+							})
+
+							It("opens packets when the peer updates keys", func() {
+								now := time.Now()
+								encrypted0 := client.Seal(nil, msg, 0x42, ad)
+								_, err := server.Open(nil, encrypted0, now, 0x42, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								_ = server.Seal(nil, msg, 0x1, ad)
+								client.rollKeys()
+								encrypted1 := client.Seal(nil, msg, 0x43, ad)
+								encrypted2 := client.Seal(nil, msg, 0x44, ad)
+								// get the keys for both packets before processing the first one
+								key1, err := server.DecryptionState().Key(0x43, protocol.KeyPhaseOne)
+								Expect(err).ToNot(HaveOccurred())
+								key2, err := server.DecryptionState().Key(0x44, protocol.KeyPhaseOne)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(key1.KeyPhase()).To(Equal(protocol.KeyPhase(1)))
+								opener := NewPayloadOpener()
+								dec1, decErr1 := decrypt(opener, key1, encrypted1, 0x43)
+								dec2, decErr2 := decrypt(opener, key2, encrypted2, 0x44)
+								serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), true)
+								opened, err := server.OpenDecrypted(nil, encrypted1, now, 0x43, protocol.KeyPhaseOne, ad, key1, dec1, decErr1)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								opened, err = server.OpenDecrypted(nil, encrypted2, now, 0x44, protocol.KeyPhaseOne, ad, key2, dec2, decErr2)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							})
+
+							It("opens the packet serially if a preceding packet changed the key phase", func() {
+								now := time.Now()
+								encrypted0 := client.Seal(nil, msg, 0x42, ad)
+								_, err := server.Open(nil, encrypted0, now, 0x42, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								_ = server.Seal(nil, msg, 0x1, ad)
+								client.rollKeys()
+								encrypted1 := client.Seal(nil, msg, 0x43, ad)
+								client.rollKeys()
+								encrypted2 := client.Seal(nil, msg, 0x44, ad)
+								key1, err := server.DecryptionState().Key(0x43, protocol.KeyPhaseOne)
+								Expect(err).ToNot(HaveOccurred())
+								// At this point, the server assumes that the second packet uses the current key phase.
+								key2, err := server.DecryptionState().Key(0x44, protocol.KeyPhaseZero)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(key2.KeyPhase()).To(BeZero())
+								opener := NewPayloadOpener()
+								dec1, decErr1 := decrypt(opener, key1, encrypted1, 0x43)
+								dec2, decErr2 := decrypt(opener, key2, encrypted2, 0x44)
+								Expect(decErr2).To(MatchError(ErrDecryptionFailed))
+								serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), true)
+								_, err = server.OpenDecrypted(nil, encrypted1, now, 0x43, protocol.KeyPhaseOne, ad, key1, dec1, decErr1)
+								Expect(err).ToNot(HaveOccurred())
+								_ = server.Seal(nil, msg, 0x2, ad)
+								// The first packet updated the keys, so the second packet is now opened with the next key phase.
+								serverTracer.EXPECT().DroppedKey(protocol.KeyPhase(0))
+								serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(2), true)
+								opened, err := server.OpenDecrypted(nil, encrypted2, now, 0x44, protocol.KeyPhaseZero, ad, key2, dec2, decErr2)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							})
+
+							It("opens the packet serially if no key is given", func() {
+								encrypted := server.Seal(nil, msg, 0x1337, ad)
+								opened, err := client.OpenDecrypted(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, ad, nil, nil, nil)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+							})
+
+							It("returns an AEAD_LIMIT_REACHED error when reaching the AEAD limit", func() {
+								client.invalidPacketLimit = 3
+								for i := 0; i < 3; i++ {
+									pn := protocol.PacketNumber(i)
+									key, err := client.DecryptionState().Key(pn, protocol.KeyPhaseZero)
+									Expect(err).ToNot(HaveOccurred())
+									dec, decErr := decrypt(NewPayloadOpener(), key, []byte("foobar"), pn)
+									Expect(decErr).To(MatchError(ErrDecryptionFailed))
+									_, err = client.OpenDecrypted(nil, []byte("foobar"), time.Now(), pn, protocol.KeyPhaseZero, ad, key, dec, decErr)
+									if i < 2 {
+										Expect(err).To(MatchError(ErrDecryptionFailed))
+									} else {
+										Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
+										Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.AEADLimitReached))
+									}
+								}
+							})
+						})
+
 						Context("key updates", func() {
 							Context("receiving key updates", func() {
 								It("updates keys", func() {
@@ -89,6 +89,20 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// Flush mocks base method.
+func (m *MockStream) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockStreamMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStream)(nil).Flush))
+}
+
 // Read mocks base method.
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -104,6 +118,18 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// SetCorkDelay mocks base method.
+func (m *MockStream) SetCorkDelay(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCorkDelay", arg0)
+}
+
+// SetCorkDelay indicates an expected call of SetCorkDelay.
+func (mr *MockStreamMockRecorder) SetCorkDelay(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCorkDelay", reflect.TypeOf((*MockStream)(nil).SetCorkDelay), arg0)
+}
+
 // SetDeadline mocks base method.
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -118,6 +144,18 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetExpiry mocks base method.
+func (m *MockStream) SetExpiry(arg0 time.Time, arg1 qerr.StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetExpiry", arg0, arg1)
+}
+
+// SetExpiry indicates an expected call of SetExpiry.
+func (mr *MockStreamMockRecorder) SetExpiry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExpiry", reflect.TypeOf((*MockStream)(nil).SetExpiry), arg0, arg1)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -132,6 +170,18 @@ func (mr *MockStreamMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStream)(nil).SetReadDeadline), arg0)
 }
 
+// SetReadNotify mocks base method.
+func (m *MockStream) SetReadNotify(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadNotify", arg0)
+}
+
+// SetReadNotify indicates an expected call of SetReadNotify.
+func (mr *MockStreamMockRecorder) SetReadNotify(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadNotify", reflect.TypeOf((*MockStream)(nil).SetReadNotify), arg0)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStream) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -146,6 +196,18 @@ func (mr *MockStreamMockRecorder) SetWriteDeadline(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockStream)(nil).SetWriteDeadline), arg0)
 }
 
+// SetWriteNotify mocks base method.
+func (m *MockStream) SetWriteNotify(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteNotify", arg0)
+}
+
+// SetWriteNotify indicates an expected call of SetWriteNotify.
+func (mr *MockStreamMockRecorder) SetWriteNotify(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteNotify", reflect.TypeOf((*MockStream)(nil).SetWriteNotify), arg0)
+}
+
 // StreamID mocks base method.
 func (m *MockStream) StreamID() protocol.StreamID {
 	m.ctrl.T.Helper()
@@ -160,6 +222,36 @@ func (mr *MockStreamMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStream)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockStream) TryRead(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamMockRecorder) TryRead(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStream)(nil).TryRead), arg0)
+}
+
+// TryWrite mocks base method.
+func (m *MockStream) TryWrite(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamMockRecorder) TryWrite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStream)(nil).TryWrite), arg0)
+}
+
 // Write mocks base method.
 func (m *MockStream) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -139,11 +139,16 @@ const MaxDatagramFrameSize ByteCount = 1220
 // DatagramRcvQueueLen is the length of the receive queue for DATAGRAM frames (RFC 9221)
 const DatagramRcvQueueLen = 128
 
-// MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame.
+// MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame, if no other value is configured.
 // It also serves as a limit for the packet history.
 // If at any point we keep track of more ranges, old ranges are discarded.
 const MaxNumAckRanges = 32
 
+// MaxMaxAckRanges is the maximum value that can be configured for the number of ACK ranges.
+// Every additional ACK range takes at least 2 bytes (a Gap and an ACK Range Length),
+// so ACK frames with more ranges wouldn't fit into MaxAckFrameSize.
+const MaxMaxAckRanges = int(MaxAckFrameSize / 2)
+
 // MinPacingDelay is the minimum duration that is used for packet pacing
 // If the packet packing frequency is higher, multiple packets might be sent at once.
 // Example: For a packet pacing delay of 200μs, we would send 5 packets at once, wait for 1ms, and so forth.
@@ -163,20 +168,18 @@ const MaxIssuedConnectionIDs = 6
 // If the peer provices us with enough new connection IDs, we switch to a new connection ID.
 const PacketsPerConnectionID = 10000
 
-// AckDelayExponent is the ack delay exponent used when sending ACKs.
+// AckDelayExponent is the ack delay exponent used when sending ACKs, if no other value is configured.
+// It is always used for ACKs sent in Initial and Handshake packets.
 const AckDelayExponent = 3
 
 // Estimated timer granularity.
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
 
-// MaxAckDelay is the maximum time by which we delay sending ACKs.
+// MaxAckDelay is the maximum time by which we delay sending ACKs, if no other value is configured.
+// The value advertised to the peer includes the timer granularity.
 const MaxAckDelay = 25 * time.Millisecond
 
-// MaxAckDelayInclGranularity is the max_ack_delay including the timer granularity.
-// This is the value that should be advertised to the peer.
-const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
-
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
 
@@ -191,3 +194,10 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxConnUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the connection, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// CorkedDataThreshold is the amount of buffered stream data that is sent right away, even if the stream is corked.
+// This roughly corresponds to the payload of a full packet.
+const CorkedDataThreshold ByteCount = 1100
+
+// MaxCorkDelay is the maximum time by which sending of stream data can be delayed when a stream is corked.
+const MaxCorkDelay = time.Second
@@ -0,0 +1,29 @@
+package qtls
+
+import "crypto/tls"
+
+// FilterCipherSuitesTLS13 returns the TLS 1.3 cipher suites contained in ids, preserving their order.
+// Cipher suites that can only be used with older TLS versions are removed.
+func FilterCipherSuitesTLS13(ids []uint16) []uint16 {
+	var suites []uint16
+	for _, id := range ids {
+		if isCipherSuiteTLS13(id) {
+			suites = append(suites, id)
+		}
+	}
+	return suites
+}
+
+func isCipherSuiteTLS13(id uint16) bool {
+	for _, cs := range tls.CipherSuites() {
+		if cs.ID != id {
+			continue
+		}
+		for _, v := range cs.SupportedVersions {
+			if v == tls.VersionTLS13 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -14,4 +14,15 @@ var _ = Describe("qtls wrapper", func() {
 			Expect(cs.ID).To(Equal(id))
 		}
 	})
+
+	It("filters TLS 1.3 cipher suites", func() {
+		Expect(FilterCipherSuitesTLS13(nil)).To(BeEmpty())
+		Expect(FilterCipherSuitesTLS13([]uint16{
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_AES_128_GCM_SHA256,
+			0x1337,
+		})).To(Equal([]uint16{tls.TLS_CHACHA20_POLY1305_SHA256, tls.TLS_AES_128_GCM_SHA256}))
+		Expect(FilterCipherSuitesTLS13([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})).To(BeEmpty())
+	})
 })
@@ -0,0 +1,270 @@
+// Package testnet provides networks for testing and simulations:
+// an in-memory network, and a UDP conn that simulates NAT rebindings.
+//
+// The quic-go multiplexer keeps track of the packet conns by their local address.
+// Conns therefore keep their local address when they are rebound,
+// and the addresses returned by NewIP are unique within the process, across all Networks.
+package testnet
+
+import (
+	"container/heap"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The number of packets that can be queued for reading on a conn.
+// Packets arriving when the queue is full are dropped, just like a full socket buffer would.
+const receiveQueueLen = 1024
+
+type receivedPacket struct {
+	data []byte
+	addr net.Addr
+}
+
+// LinkParams configures how a Network impairs packets.
+type LinkParams struct {
+	// Loss and Duplicate are percentages.
+	Loss, Duplicate int
+	// Every packet is delayed by Delay plus a random value in [0, Jitter).
+	// A non-zero Jitter reorders packets.
+	Delay, Jitter time.Duration
+}
+
+// NetworkOptions configure a Network.
+type NetworkOptions struct {
+	// Clock is the virtual clock of the network.
+	// If set, packets are only delivered when the clock is advanced (using Network.Advance) past their delivery time.
+	// If nil, packets are delivered immediately, and the Link is not used.
+	Clock *utils.VirtualClock
+	// Link configures how packets are impaired.
+	Link LinkParams
+	// Seed seeds the PRNG that decides about loss, duplication and delays,
+	// so that the same seed always results in the same network schedule.
+	Seed int64
+	// KeepOldAddresses says if packets sent to the address that a conn was rebound from are still delivered.
+	// If not set, they are dropped, just like after a NAT rebinding.
+	KeepOldAddresses bool
+}
+
+var ipCounter uint32
+
+// NewIP returns a new IP address in 10.0.0.0/8, to be used on an in-memory network.
+// Every call returns a different address.
+func NewIP() net.IP {
+	n := atomic.AddUint32(&ipCounter, 1)
+	return net.IPv4(10, byte(n>>16), byte(n>>8), byte(n))
+}
+
+type inFlightPacket struct {
+	receivedPacket
+	to        *Conn
+	deliverAt time.Time
+	seq       uint64 // keeps the ordering stable for packets delivered at the same time
+}
+
+type packetQueue []*inFlightPacket
+
+func (q packetQueue) Len() int { return len(q) }
+func (q packetQueue) Less(i, j int) bool {
+	if !q[i].deliverAt.Equal(q[j].deliverAt) {
+		return q[i].deliverAt.Before(q[j].deliverAt)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q packetQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *packetQueue) Push(x interface{}) { *q = append(*q, x.(*inFlightPacket)) }
+func (q *packetQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
+
+// A Network is an in-memory network.
+type Network struct {
+	opts NetworkOptions
+
+	mutex sync.Mutex
+	link  LinkParams
+	rand  *rand.Rand
+	seq   uint64
+	queue packetQueue
+	// maps every address that packets are delivered to to its conn
+	conns map[string]*Conn
+}
+
+// NewNetwork creates a new in-memory network.
+func NewNetwork(opts NetworkOptions) *Network {
+	return &Network{
+		opts:  opts,
+		link:  opts.Link,
+		rand:  rand.New(rand.NewSource(opts.Seed)),
+		conns: make(map[string]*Conn),
+	}
+}
+
+// NewConn creates a new conn on the network, using addr as its local address.
+func (n *Network) NewConn(addr *net.UDPAddr) *Conn {
+	c := &Conn{
+		network:   n,
+		localAddr: addr,
+		pathAddr:  addr,
+		packets:   make(chan receivedPacket, receiveQueueLen),
+		closed:    make(chan struct{}),
+	}
+	n.mutex.Lock()
+	n.conns[addr.String()] = c
+	n.mutex.Unlock()
+	return c
+}
+
+// SetLoss changes the packet loss percentage of the network.
+func (n *Network) SetLoss(loss int) {
+	n.mutex.Lock()
+	n.link.Loss = loss
+	n.mutex.Unlock()
+}
+
+// Advance advances the virtual clock by d,
+// and delivers all packets that are due by then.
+// It must only be called if the network uses a virtual clock.
+func (n *Network) Advance(d time.Duration) {
+	n.opts.Clock.Advance(d)
+	now := n.opts.Clock.Now()
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for len(n.queue) > 0 && !n.queue[0].deliverAt.After(now) {
+		p := heap.Pop(&n.queue).(*inFlightPacket)
+		p.to.deliver(p.receivedPacket)
+	}
+}
+
+func (n *Network) send(b []byte, from *Conn, to net.Addr) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	c, ok := n.conns[to.String()]
+	if !ok {
+		return
+	}
+	p := receivedPacket{data: append([]byte{}, b...), addr: from.pathAddr}
+	if n.opts.Clock == nil {
+		c.deliver(p)
+		return
+	}
+	if n.rand.Intn(100) < n.link.Loss {
+		return
+	}
+	copies := 1
+	if n.rand.Intn(100) < n.link.Duplicate {
+		copies++
+	}
+	for i := 0; i < copies; i++ {
+		deliverAt := n.opts.Clock.Now().Add(n.link.Delay)
+		if n.link.Jitter > 0 {
+			deliverAt = deliverAt.Add(time.Duration(n.rand.Int63n(int64(n.link.Jitter))))
+		}
+		n.seq++
+		heap.Push(&n.queue, &inFlightPacket{
+			receivedPacket: p,
+			to:             c,
+			deliverAt:      deliverAt,
+			seq:            n.seq,
+		})
+	}
+}
+
+func (n *Network) rebind(c *Conn, addr *net.UDPAddr) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	default:
+	}
+	if !n.opts.KeepOldAddresses {
+		delete(n.conns, c.pathAddr.String())
+	}
+	c.pathAddr = addr
+	n.conns[addr.String()] = c
+	return nil
+}
+
+func (n *Network) remove(c *Conn) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for addr, conn := range n.conns {
+		if conn == c {
+			delete(n.conns, addr)
+		}
+	}
+}
+
+// A Conn is a net.PacketConn on a Network.
+type Conn struct {
+	network *Network
+
+	localAddr *net.UDPAddr
+	// The address that the peer sees for packets sent on this conn.
+	// This changes when the conn is rebound, simulating a NAT rebinding.
+	// The local address stays the same.
+	// Protected by the network's mutex.
+	pathAddr *net.UDPAddr
+
+	packets   chan receivedPacket
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &Conn{}
+
+// Rebind changes the address that the peer sees for packets sent on this conn.
+func (c *Conn) Rebind(addr *net.UDPAddr) error {
+	return c.network.rebind(c, addr)
+}
+
+func (c *Conn) deliver(p receivedPacket) {
+	select {
+	case c.packets <- p:
+	default: // receive queue full
+	}
+}
+
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.addr, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *Conn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.network.send(b, c, addr)
+	return len(b), nil
+}
+
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		c.network.remove(c)
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr              { return c.localAddr }
+func (c *Conn) SetDeadline(time.Time) error      { return errors.New("not supported") }
+func (c *Conn) SetReadDeadline(time.Time) error  { return errors.New("not supported") }
+func (c *Conn) SetWriteDeadline(time.Time) error { return errors.New("not supported") }
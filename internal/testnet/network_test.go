@@ -0,0 +1,175 @@
+package testnet
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network", func() {
+	addr := func(n byte) *net.UDPAddr { return &net.UDPAddr{IP: net.IPv4(10, 0, 0, n), Port: 443} }
+
+	It("returns unique IP addresses", func() {
+		ips := make(map[string]struct{})
+		for i := 0; i < 1000; i++ {
+			ip := NewIP()
+			Expect(ip.To4()[0]).To(BeEquivalentTo(10))
+			ips[ip.String()] = struct{}{}
+		}
+		Expect(ips).To(HaveLen(1000))
+	})
+
+	It("delivers packets immediately", func() {
+		n := NewNetwork(NetworkOptions{})
+		c1 := n.NewConn(addr(1))
+		c2 := n.NewConn(addr(2))
+		_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 100)
+		l, from, err := c2.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:l]).To(Equal([]byte("foobar")))
+		Expect(from).To(Equal(c1.LocalAddr()))
+	})
+
+	It("drops packets sent to the old address after rebinding", func() {
+		n := NewNetwork(NetworkOptions{})
+		c1 := n.NewConn(addr(1))
+		c2 := n.NewConn(addr(2))
+		Expect(c1.Rebind(addr(3))).To(Succeed())
+		Expect(c1.LocalAddr()).To(Equal(addr(1)))
+		_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 100)
+		_, from, err := c2.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(from).To(Equal(addr(3)))
+		_, err = c2.WriteTo([]byte("foo"), addr(1))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = c2.WriteTo([]byte("bar"), addr(3))
+		Expect(err).ToNot(HaveOccurred())
+		l, _, err := c1.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:l]).To(Equal([]byte("bar")))
+		Expect(c1.packets).To(BeEmpty())
+	})
+
+	It("keeps the old address routable, if configured", func() {
+		n := NewNetwork(NetworkOptions{KeepOldAddresses: true})
+		c1 := n.NewConn(addr(1))
+		c2 := n.NewConn(addr(2))
+		Expect(c1.Rebind(addr(3))).To(Succeed())
+		_, err := c2.WriteTo([]byte("foo"), addr(1))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = c2.WriteTo([]byte("bar"), addr(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1.packets).To(HaveLen(2))
+	})
+
+	It("unblocks ReadFrom when closed", func() {
+		c := NewNetwork(NetworkOptions{}).NewConn(addr(1))
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, _, err := c.ReadFrom(make([]byte, 100))
+			Expect(err).To(MatchError(net.ErrClosed))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(c.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		_, err := c.WriteTo([]byte("foobar"), addr(2))
+		Expect(err).To(MatchError(net.ErrClosed))
+		Expect(c.Rebind(addr(3))).To(MatchError(net.ErrClosed))
+	})
+
+	Context("with a virtual clock", func() {
+		var clock *utils.VirtualClock
+
+		BeforeEach(func() {
+			clock = utils.NewVirtualClock(time.Now())
+		})
+
+		It("delivers packets when the clock is advanced", func() {
+			n := NewNetwork(NetworkOptions{
+				Clock: clock,
+				Link:  LinkParams{Delay: 10 * time.Millisecond},
+			})
+			c1 := n.NewConn(addr(1))
+			c2 := n.NewConn(addr(2))
+			_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			n.Advance(9 * time.Millisecond)
+			Expect(c2.packets).To(BeEmpty())
+			n.Advance(time.Millisecond)
+			Expect(c2.packets).To(HaveLen(1))
+		})
+
+		It("reorders packets", func() {
+			n := NewNetwork(NetworkOptions{
+				Clock: clock,
+				Link:  LinkParams{Jitter: 10 * time.Millisecond},
+				Seed:  GinkgoRandomSeed(),
+			})
+			c1 := n.NewConn(addr(1))
+			c2 := n.NewConn(addr(2))
+			for i := 0; i < 100; i++ {
+				_, err := c1.WriteTo([]byte{byte(i)}, c2.LocalAddr())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			n.Advance(10 * time.Millisecond)
+			Expect(c2.packets).To(HaveLen(100))
+			var reordered bool
+			b := make([]byte, 1)
+			for i := 0; i < 100; i++ {
+				_, _, err := c2.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				if b[0] != byte(i) {
+					reordered = true
+				}
+			}
+			Expect(reordered).To(BeTrue())
+		})
+
+		It("drops packets", func() {
+			n := NewNetwork(NetworkOptions{
+				Clock: clock,
+				Link:  LinkParams{Loss: 50},
+				Seed:  GinkgoRandomSeed(),
+			})
+			c1 := n.NewConn(addr(1))
+			c2 := n.NewConn(addr(2))
+			for i := 0; i < 400; i++ {
+				_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			n.Advance(time.Millisecond)
+			Expect(len(c2.packets)).To(BeNumerically("~", 200, 100))
+			n.SetLoss(0)
+			_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			l := len(c2.packets)
+			n.Advance(time.Millisecond)
+			Expect(c2.packets).To(HaveLen(l + 1))
+		})
+
+		It("duplicates packets", func() {
+			n := NewNetwork(NetworkOptions{
+				Clock: clock,
+				Link:  LinkParams{Duplicate: 100},
+			})
+			c1 := n.NewConn(addr(1))
+			c2 := n.NewConn(addr(2))
+			for i := 0; i < 100; i++ {
+				_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			n.Advance(time.Millisecond)
+			Expect(c2.packets).To(HaveLen(200))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+package testnet
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The RebindingConn is a net.PacketConn that simulates a NAT rebinding.
+// After Rebind is called, packets are sent from a new UDP socket,
+// and packets received on the previous sockets are dropped.
+// The previous socket is only closed on the next rebinding, such that the peer doesn't receive ICMP errors
+// for packets that were in flight.
+// The local address doesn't change.
+type RebindingConn struct {
+	ip        net.IP
+	localAddr net.Addr
+	packets   chan receivedPacket
+	closed    chan struct{}
+
+	mutex               sync.Mutex
+	conns               []*net.UDPConn // the last element is the current socket
+	receivedAfterRebind int
+}
+
+var _ net.PacketConn = &RebindingConn{}
+
+// NewRebindingConn creates a new RebindingConn.
+// The UDP sockets are bound to ip. If ip is nil, they're bound to all local addresses.
+func NewRebindingConn(ip net.IP) (*RebindingConn, error) {
+	c := &RebindingConn{
+		ip:      ip,
+		packets: make(chan receivedPacket, receiveQueueLen),
+		closed:  make(chan struct{}),
+	}
+	if err := c.Rebind(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Rebind opens a new UDP socket, and uses it for sending and receiving packets.
+func (c *RebindingConn) Rebind() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: c.ip})
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.closed:
+		conn.Close()
+		return net.ErrClosed
+	default:
+	}
+	if len(c.conns) > 1 {
+		c.conns[0].Close()
+		c.conns = c.conns[1:]
+	}
+	if c.localAddr == nil {
+		c.localAddr = conn.LocalAddr()
+	}
+	c.conns = append(c.conns, conn)
+	go c.read(conn)
+	return nil
+}
+
+// ReceivedAfterRebind returns the number of packets received on the current socket, if Rebind was called at least once.
+func (c *RebindingConn) ReceivedAfterRebind() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.receivedAfterRebind
+}
+
+func (c *RebindingConn) read(conn *net.UDPConn) {
+	for {
+		b := make([]byte, protocol.MaxPacketBufferSize)
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		c.mutex.Lock()
+		current := c.conns[len(c.conns)-1] == conn
+		if current && len(c.conns) > 1 {
+			c.receivedAfterRebind++
+		}
+		c.mutex.Unlock()
+		if !current {
+			continue
+		}
+		select {
+		case c.packets <- receivedPacket{data: b[:n], addr: addr}:
+		default: // drop the packet if the queue is full
+		}
+	}
+}
+
+func (c *RebindingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.addr, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *RebindingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.currentConn().WriteTo(b, addr)
+}
+
+func (c *RebindingConn) currentConn() *net.UDPConn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conns[len(c.conns)-1]
+}
+
+func (c *RebindingConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	close(c.closed)
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func (c *RebindingConn) LocalAddr() net.Addr {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.localAddr
+}
+
+func (c *RebindingConn) SetDeadline(time.Time) error      { return nil }
+func (c *RebindingConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *RebindingConn) SetWriteDeadline(time.Time) error { return nil }
@@ -0,0 +1,85 @@
+package testnet
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rebinding Conn", func() {
+	var (
+		conn *RebindingConn
+		peer *net.UDPConn
+	)
+
+	BeforeEach(func() {
+		var err error
+		conn, err = NewRebindingConn(net.IPv4(127, 0, 0, 1))
+		Expect(err).ToNot(HaveOccurred())
+		peer, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).To(Succeed())
+		Expect(peer.Close()).To(Succeed())
+	})
+
+	// sends a packet to the peer, and returns the address the peer received it from
+	send := func() net.Addr {
+		_, err := conn.WriteTo([]byte("foobar"), peer.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 100)
+		_, addr, err := peer.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		return addr
+	}
+
+	It("sends packets from a new socket after rebinding", func() {
+		localAddr := conn.LocalAddr()
+		addr1 := send()
+		Expect(conn.Rebind()).To(Succeed())
+		addr2 := send()
+		Expect(addr2).ToNot(Equal(addr1))
+		Expect(conn.LocalAddr()).To(Equal(localAddr))
+	})
+
+	It("drops packets received on the old socket", func() {
+		addr1 := send()
+		Expect(conn.Rebind()).To(Succeed())
+		addr2 := send()
+		_, err := peer.WriteTo([]byte("foo"), addr1)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = peer.WriteTo([]byte("bar"), addr2)
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 100)
+		l, _, err := conn.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:l]).To(Equal([]byte("bar")))
+		Expect(conn.ReceivedAfterRebind()).To(Equal(1))
+		Consistently(conn.packets).Should(BeEmpty())
+	})
+
+	It("doesn't count packets received before rebinding", func() {
+		addr := send()
+		_, err := peer.WriteTo([]byte("foo"), addr)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = conn.ReadFrom(make([]byte, 100))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.ReceivedAfterRebind()).To(BeZero())
+	})
+
+	It("unblocks ReadFrom when closed", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, _, err := conn.ReadFrom(make([]byte, 100))
+			Expect(err).To(MatchError(net.ErrClosed))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(conn.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+})
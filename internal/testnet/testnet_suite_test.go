@@ -0,0 +1,13 @@
+package testnet
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTestnet(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Testnet Suite")
+}
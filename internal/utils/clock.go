@@ -0,0 +1,35 @@
+package utils
+
+import "time"
+
+// A Clock returns the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// DefaultClock implements the Clock interface using the Go stdlib clock.
+type DefaultClock struct{}
+
+var _ Clock = DefaultClock{}
+
+// Now gets the current time
+func (DefaultClock) Now() time.Time {
+	return time.Now()
+}
+
+// A FuncTimer calls a function once it fires, like a timer created by time.AfterFunc.
+type FuncTimer interface {
+	// Stop prevents the timer from firing.
+	// It returns false if the timer already fired or was stopped.
+	Stop() bool
+}
+
+// AfterFunc waits for d to elapse on clock, and then calls f in its own Go routine.
+// For a VirtualClock, this happens once the clock is advanced past the deadline.
+// For all other clocks, the timer runs on the system clock.
+func AfterFunc(clock Clock, d time.Duration, f func()) FuncTimer {
+	if c, ok := clock.(*VirtualClock); ok {
+		return c.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f)
+}
@@ -3,15 +3,24 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 )
 
 // Rand is a wrapper around crypto/rand that adds some convenience functions known from math/rand.
 type Rand struct {
+	// Source is the source of the random bytes.
+	// If nil, crypto/rand.Reader is used.
+	Source io.Reader
+
 	buf [4]byte
 }
 
 func (r *Rand) Int31() int32 {
-	rand.Read(r.buf[:])
+	if r.Source != nil {
+		io.ReadFull(r.Source, r.buf[:])
+	} else {
+		rand.Read(r.buf[:])
+	}
 	return int32(binary.BigEndian.Uint32(r.buf[:]) & ^uint32(1<<31))
 }
 
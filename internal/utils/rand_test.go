@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"bytes"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -29,4 +31,10 @@ var _ = Describe("Rand", func() {
 		}
 		Expect(float64(sum) / num).To(BeNumerically("~", max/2, max/25))
 	})
+
+	It("uses the source, if set", func() {
+		r := Rand{Source: bytes.NewReader([]byte{0, 0, 0, 42, 0x80, 0, 0, 7})}
+		Expect(r.Int31()).To(BeEquivalentTo(42))
+		Expect(r.Int31()).To(BeEquivalentTo(7))
+	})
 })
@@ -7,19 +7,34 @@ import (
 
 // A Timer wrapper that behaves correctly when resetting
 type Timer struct {
-	t        *time.Timer
+	t        timer
 	read     bool
 	deadline time.Time
 }
 
+// The timer is either a timer of the Go stdlib, or a timer run by a VirtualClock.
+type timer interface {
+	Chan() <-chan time.Time
+	Stop() bool
+	Reset(deadline time.Time)
+}
+
+type stdlibTimer struct {
+	t *time.Timer
+}
+
+func (t *stdlibTimer) Chan() <-chan time.Time   { return t.t.C }
+func (t *stdlibTimer) Stop() bool               { return t.t.Stop() }
+func (t *stdlibTimer) Reset(deadline time.Time) { t.t.Reset(time.Until(deadline)) }
+
 // NewTimer creates a new timer that is not set
 func NewTimer() *Timer {
-	return &Timer{t: time.NewTimer(time.Duration(math.MaxInt64))}
+	return &Timer{t: &stdlibTimer{t: time.NewTimer(time.Duration(math.MaxInt64))}}
 }
 
 // Chan returns the channel of the wrapped timer
 func (t *Timer) Chan() <-chan time.Time {
-	return t.t.C
+	return t.t.Chan()
 }
 
 // Reset the timer, no matter whether the value was read or not
@@ -32,10 +47,10 @@ func (t *Timer) Reset(deadline time.Time) {
 	// We need to drain the timer if the value from its channel was not read yet.
 	// See https://groups.google.com/forum/#!topic/golang-dev/c9UUfASVPoU
 	if !t.t.Stop() && !t.read {
-		<-t.t.C
+		<-t.t.Chan()
 	}
 	if !deadline.IsZero() {
-		t.t.Reset(time.Until(deadline))
+		t.t.Reset(deadline)
 	}
 
 	t.read = false
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// A VirtualClock is a Clock whose time only changes when it is advanced.
+// The timers created by the clock fire as soon as the clock reaches their deadline.
+// It is safe for concurrent use.
+type VirtualClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers map[*virtualTimer]struct{} // the timers that didn't fire yet
+}
+
+var _ Clock = &VirtualClock{}
+
+// NewVirtualClock creates a new virtual clock, starting at start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{
+		now:    start,
+		timers: make(map[*virtualTimer]struct{}),
+	}
+}
+
+// Now returns the current virtual time.
+func (c *VirtualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance advances the clock by d, and fires all timers that expired.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.deadline.IsZero() && !t.deadline.After(c.now) {
+			c.fire(t)
+		}
+	}
+}
+
+// NewTimer creates a new timer that is not set.
+// The timer fires when the virtual time reaches its deadline.
+func (c *VirtualClock) NewTimer() *Timer {
+	t := &virtualTimer{clock: c, c: make(chan time.Time, 1)}
+	c.mutex.Lock()
+	c.timers[t] = struct{}{}
+	c.mutex.Unlock()
+	return &Timer{t: t}
+}
+
+// AfterFunc calls f in its own Go routine once the virtual time advanced by d.
+func (c *VirtualClock) AfterFunc(d time.Duration, f func()) FuncTimer {
+	t := &virtualTimer{clock: c, f: f}
+	t.Reset(c.Now().Add(d))
+	return t
+}
+
+func (c *VirtualClock) fire(t *virtualTimer) {
+	delete(c.timers, t)
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- c.now:
+	default:
+	}
+}
+
+type virtualTimer struct {
+	clock    *VirtualClock
+	c        chan time.Time
+	f        func()    // only set for timers created by AfterFunc
+	deadline time.Time // the zero value if the timer is not set
+}
+
+func (t *virtualTimer) Chan() <-chan time.Time { return t.c }
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	_, ok := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return ok
+}
+
+func (t *virtualTimer) Reset(deadline time.Time) {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.deadline = deadline
+	if !deadline.After(t.clock.now) {
+		t.clock.fire(t)
+		return
+	}
+	t.clock.timers[t] = struct{}{}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Virtual Clock", func() {
+	var (
+		clock *VirtualClock
+		start time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Now()
+		clock = NewVirtualClock(start)
+	})
+
+	It("only advances when told to", func() {
+		Expect(clock.Now()).To(Equal(start))
+		time.Sleep(time.Millisecond)
+		Expect(clock.Now()).To(Equal(start))
+		clock.Advance(time.Second)
+		Expect(clock.Now()).To(Equal(start.Add(time.Second)))
+	})
+
+	It("doesn't fire a newly created timer", func() {
+		t := clock.NewTimer()
+		clock.Advance(time.Hour)
+		Expect(t.Chan()).ToNot(Receive())
+	})
+
+	It("fires timers when the deadline is reached", func() {
+		t := clock.NewTimer()
+		t.Reset(start.Add(10 * time.Millisecond))
+		clock.Advance(9 * time.Millisecond)
+		Expect(t.Chan()).ToNot(Receive())
+		clock.Advance(time.Millisecond)
+		Expect(t.Chan()).To(Receive(Equal(start.Add(10 * time.Millisecond))))
+	})
+
+	It("works multiple times with reading", func() {
+		t := clock.NewTimer()
+		for i := 0; i < 10; i++ {
+			t.Reset(clock.Now().Add(time.Millisecond))
+			clock.Advance(time.Millisecond)
+			Expect(t.Chan()).To(Receive())
+			t.SetRead()
+		}
+	})
+
+	It("works multiple times without reading", func() {
+		t := clock.NewTimer()
+		for i := 0; i < 10; i++ {
+			t.Reset(clock.Now().Add(time.Millisecond))
+			clock.Advance(2 * time.Millisecond)
+		}
+		Expect(t.Chan()).To(Receive())
+		Expect(t.Chan()).ToNot(Receive())
+	})
+
+	It("immediately fires the timer, if the deadline has already passed", func() {
+		t := clock.NewTimer()
+		t.Reset(start.Add(-time.Second))
+		Expect(t.Chan()).To(Receive())
+	})
+
+	It("doesn't fire stopped timers", func() {
+		t := clock.NewTimer()
+		t.Reset(start.Add(time.Millisecond))
+		t.Stop()
+		clock.Advance(time.Second)
+		Expect(t.Chan()).ToNot(Receive())
+	})
+
+	It("calls functions after the virtual time advanced", func() {
+		called := make(chan time.Time, 1)
+		clock.AfterFunc(10*time.Millisecond, func() { called <- clock.Now() })
+		clock.Advance(9 * time.Millisecond)
+		Consistently(called).ShouldNot(Receive())
+		clock.Advance(time.Millisecond)
+		Eventually(called).Should(Receive(Equal(start.Add(10 * time.Millisecond))))
+	})
+
+	It("doesn't call functions of stopped timers", func() {
+		called := make(chan struct{}, 1)
+		t := clock.AfterFunc(10*time.Millisecond, func() { close(called) })
+		Expect(t.Stop()).To(BeTrue())
+		Expect(t.Stop()).To(BeFalse())
+		clock.Advance(time.Second)
+		Consistently(called).ShouldNot(BeClosed())
+	})
+})
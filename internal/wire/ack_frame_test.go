@@ -130,6 +130,23 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			}
 		})
 
+		It("writes the delay time using the configured ack delay exponent", func() {
+			const delayTime = 1 << 10 * time.Millisecond
+			for _, exp := range []uint8{1, 10, protocol.MaxAckDelayExponent} {
+				buf := &bytes.Buffer{}
+				f := &AckFrame{
+					AckRanges:     []AckRange{{Smallest: 1, Largest: 1}},
+					DelayTime:     delayTime,
+					DelayExponent: exp,
+				}
+				Expect(f.Write(buf, protocol.Version1)).To(Succeed())
+				Expect(f.Length(protocol.Version1)).To(BeEquivalentTo(buf.Len()))
+				frame, err := parseAckFrame(bytes.NewReader(buf.Bytes()), exp, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.DelayTime).To(BeNumerically("~", delayTime, time.Duration(1<<exp)*time.Microsecond))
+			}
+		})
+
 		It("gracefully handles overflows of the delay time", func() {
 			data := []byte{0x2}
 			data = append(data, encodeVarInt(100)...)              // largest acked
@@ -176,6 +193,9 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 				Expect(frame.LargestAcked()).To(Equal(protocol.PacketNumber(100)))
 				Expect(frame.LowestAcked()).To(Equal(protocol.PacketNumber(90)))
 				Expect(frame.HasMissingRanges()).To(BeFalse())
+				Expect(frame.ECT0).To(BeEquivalentTo(0x42))
+				Expect(frame.ECT1).To(BeEquivalentTo(0x12345))
+				Expect(frame.ECNCE).To(BeEquivalentTo(0x12345678))
 				Expect(b.Len()).To(BeZero())
 			})
 
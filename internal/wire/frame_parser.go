@@ -13,16 +13,18 @@ import (
 type frameParser struct {
 	ackDelayExponent uint8
 
-	supportsDatagrams bool
+	supportsDatagrams     bool
+	supportsResetStreamAt bool
 
 	version protocol.VersionNumber
 }
 
 // NewFrameParser creates a new frame parser.
-func NewFrameParser(supportsDatagrams bool, v protocol.VersionNumber) FrameParser {
+func NewFrameParser(supportsDatagrams, supportsResetStreamAt bool, v protocol.VersionNumber) FrameParser {
 	return &frameParser{
-		supportsDatagrams: supportsDatagrams,
-		version:           v,
+		supportsDatagrams:     supportsDatagrams,
+		supportsResetStreamAt: supportsResetStreamAt,
+		version:               v,
 	}
 }
 
@@ -38,6 +40,9 @@ func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 
 		f, err := p.parseFrame(r, typeByte, encLevel)
 		if err != nil {
+			if _, ok := err.(*qerr.TransportError); ok {
+				return nil, err
+			}
 			return nil, &qerr.TransportError{
 				FrameType:    uint64(typeByte),
 				ErrorCode:    qerr.FrameEncodingError,
@@ -96,6 +101,12 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case resetStreamAtFrameType:
+			if p.supportsResetStreamAt {
+				frame, err = parseResetStreamFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, p.version)
@@ -109,8 +120,13 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 	if err != nil {
 		return nil, err
 	}
+	// RFC 9000, section 12.4: a frame in a packet type that doesn't permit it is a PROTOCOL_VIOLATION
 	if !p.isAllowedAtEncLevel(frame, encLevel) {
-		return nil, fmt.Errorf("%s not allowed at encryption level %s", reflect.TypeOf(frame).Elem().Name(), encLevel)
+		return nil, &qerr.TransportError{
+			FrameType:    uint64(typeByte),
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("%s not allowed at encryption level %s", reflect.TypeOf(frame).Elem().Name(), encLevel),
+		}
 	}
 	return frame, nil
 }
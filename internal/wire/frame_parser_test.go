@@ -18,7 +18,7 @@ var _ = Describe("Frame parsing", func() {
 
 	BeforeEach(func() {
 		buf = &bytes.Buffer{}
-		parser = NewFrameParser(true, protocol.Version1)
+		parser = NewFrameParser(true, true, protocol.Version1)
 	})
 
 	It("returns nil if there's nothing more to read", func() {
@@ -290,7 +290,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("errors when DATAGRAM frames are not supported", func() {
-		parser = NewFrameParser(false, protocol.Version1)
+		parser = NewFrameParser(false, false, protocol.Version1)
 		f := &DatagramFrame{Data: []byte("foobar")}
 		buf := &bytes.Buffer{}
 		Expect(f.Write(buf, protocol.Version1)).To(Succeed())
@@ -302,6 +302,38 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	It("unpacks RESET_STREAM_AT frames", func() {
+		f := &ResetStreamFrame{
+			StreamID:     0x1337,
+			ErrorCode:    0x42,
+			FinalSize:    0xdeadbeef,
+			ReliableSize: 0xdecafbad,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, protocol.Version1)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("errors when RESET_STREAM_AT frames are not supported", func() {
+		parser = NewFrameParser(false, false, protocol.Version1)
+		f := &ResetStreamFrame{
+			StreamID:     0x1337,
+			ErrorCode:    0x42,
+			FinalSize:    0xdeadbeef,
+			ReliableSize: 0xdecafbad,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, protocol.Version1)).To(Succeed())
+		_, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x24,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
 	It("errors on invalid type", func() {
 		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
 		Expect(err).To(MatchError(&qerr.TransportError{
@@ -366,7 +398,7 @@ var _ = Describe("Frame parsing", func() {
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level Initial"))
 				}
 			}
@@ -380,7 +412,7 @@ var _ = Describe("Frame parsing", func() {
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level Handshake"))
 				}
 			}
@@ -392,7 +424,7 @@ var _ = Describe("Frame parsing", func() {
 				switch frames[i].(type) {
 				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level 0-RTT"))
 				default:
 					Expect(err).ToNot(HaveOccurred())
@@ -38,6 +38,46 @@ var _ = Describe("RESET_STREAM frame", func() {
 				Expect(err).To(HaveOccurred())
 			}
 		})
+
+		It("accepts a RESET_STREAM_AT frame", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // byte offset
+			data = append(data, encodeVarInt(0x12345)...)     // reliable size
+			b := bytes.NewReader(data)
+			frame, err := parseResetStreamFrame(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.FinalSize).To(Equal(protocol.ByteCount(0x987654321)))
+			Expect(frame.ErrorCode).To(Equal(qerr.StreamErrorCode(0x1337)))
+			Expect(frame.ReliableSize).To(Equal(protocol.ByteCount(0x12345)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors when the reliable size is larger than the final size", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...) // stream ID
+			data = append(data, encodeVarInt(0x1337)...)     // error code
+			data = append(data, encodeVarInt(0x1000)...)     // byte offset
+			data = append(data, encodeVarInt(0x1001)...)     // reliable size
+			_, err := parseResetStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).To(MatchError("RESET_STREAM_AT frame: reliable size can't be larger than the final size"))
+		})
+
+		It("errors on EOFs, for RESET_STREAM_AT frames", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // byte offset
+			data = append(data, encodeVarInt(0x12345)...)     // reliable size
+			_, err := parseResetStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseResetStreamFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
 	})
 
 	Context("when writing", func() {
@@ -57,6 +97,25 @@ var _ = Describe("RESET_STREAM frame", func() {
 			Expect(b.Bytes()).To(Equal(expected))
 		})
 
+		It("writes a RESET_STREAM_AT frame", func() {
+			frame := ResetStreamFrame{
+				StreamID:     0x1337,
+				FinalSize:    0x11223344decafbad,
+				ErrorCode:    0xcafe,
+				ReliableSize: 0xdeadbeef,
+			}
+			b := &bytes.Buffer{}
+			err := frame.Write(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := []byte{0x24}
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(0xcafe)...)
+			expected = append(expected, encodeVarInt(0x11223344decafbad)...)
+			expected = append(expected, encodeVarInt(0xdeadbeef)...)
+			Expect(b.Bytes()).To(Equal(expected))
+			Expect(frame.Length(protocol.Version1)).To(BeEquivalentTo(b.Len()))
+		})
+
 		It("has the correct min length", func() {
 			rst := ResetStreamFrame{
 				StreamID:  0x1337,
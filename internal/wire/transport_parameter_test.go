@@ -57,8 +57,9 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:             &protocol.StatelessResetToken{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 			ActiveConnectionIDLimit:         123,
 			MaxDatagramFrameSize:            876,
+			EnableResetStreamAt:             true,
 		}
-		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876}"))
+		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876, EnableResetStreamAt: true}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token, no Retry source connection id and no datagram support", func() {
@@ -100,6 +101,7 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			EnableResetStreamAt:             true,
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -121,6 +123,25 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.EnableResetStreamAt).To(BeTrue())
+	})
+
+	It("uses the source of randomness for the greased transport parameter", func() {
+		params := &TransportParameters{
+			InitialSourceConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+			ActiveConnectionIDLimit:   2,
+			MaxDatagramFrameSize:      protocol.InvalidByteCount,
+		}
+		random := bytes.Repeat([]byte{42}, 100)
+		data := params.MarshalWithRand(protocol.PerspectiveClient, bytes.NewReader(random))
+		Expect(params.MarshalWithRand(protocol.PerspectiveClient, bytes.NewReader(random))).To(Equal(data))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.InitialSourceConnectionID).To(Equal(params.InitialSourceConnectionID))
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(27+31*42))
+		quicvarint.Write(b, 42%16)
+		Expect(data).To(HavePrefix(b.String()))
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -176,6 +197,17 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("errors when reset_stream_at has content", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(resetStreamAtParameterID))
+		quicvarint.Write(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for reset_stream_at: 6 (expected empty)",
+		}))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(statelessResetTokenParameterID))
@@ -44,6 +44,8 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// draft-ietf-quic-reliable-stream-reset
+	resetStreamAtParameterID transportParameterID = 0x17f7586d2cb571
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -85,6 +87,8 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	EnableResetStreamAt bool
 }
 
 // Unmarshal the transport parameters
@@ -153,6 +157,11 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 			}
 			p.DisableActiveMigration = true
+		case resetStreamAtParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for reset_stream_at: %d (expected empty)", paramLen)
+			}
+			p.EnableResetStreamAt = true
 		case statelessResetTokenParameterID:
 			if sentBy == protocol.PerspectiveClient {
 				return errors.New("client sent a stateless_reset_token")
@@ -323,6 +332,30 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	quicvarint.Write(b, uint64(length))
 	b.Write(randomData)
 
+	p.marshal(b, pers)
+	return b.Bytes()
+}
+
+// MarshalWithRand marshals the transport parameters.
+// It uses r to generate the greased transport parameter.
+func (p *TransportParameters) MarshalWithRand(pers protocol.Perspective, r io.Reader) []byte {
+	b := &bytes.Buffer{}
+
+	// add a greased value
+	var buf [2]byte
+	io.ReadFull(r, buf[:])
+	quicvarint.Write(b, uint64(27+31*(int(buf[0])%100)))
+	length := int(buf[1]) % 16
+	randomData := make([]byte, length)
+	io.ReadFull(r, randomData)
+	quicvarint.Write(b, uint64(length))
+	b.Write(randomData)
+
+	p.marshal(b, pers)
+	return b.Bytes()
+}
+
+func (p *TransportParameters) marshal(b *bytes.Buffer, pers protocol.Perspective) {
 	// initial_max_stream_data_bidi_local
 	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
 	// initial_max_stream_data_bidi_remote
@@ -394,7 +427,11 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
-	return b.Bytes()
+	// reset_stream_at
+	if p.EnableResetStreamAt {
+		quicvarint.Write(b, uint64(resetStreamAtParameterID))
+		quicvarint.Write(b, 0)
+	}
 }
 
 func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportParameterID, val uint64) {
@@ -471,6 +508,9 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.EnableResetStreamAt {
+		logString += ", EnableResetStreamAt: true"
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -105,6 +105,10 @@ type Tracer interface {
 // A ConnectionTracer records events.
 type ConnectionTracer interface {
 	StartedConnection(local, remote net.Addr, srcConnID, destConnID ConnectionID)
+	// StartedHandshake is called once the handshake information is known:
+	// For the client, when the connection is created, and for the server, when the ClientHello is received.
+	// The context is the connection's context, as returned by Config.ConnContext when the connection was created.
+	StartedHandshake(ctx context.Context, info *HandshakeInfo)
 	NegotiatedVersion(chosen VersionNumber, clientVersions, serverVersions []VersionNumber)
 	ClosedConnection(error)
 	SentTransportParameters(*TransportParameters)
@@ -120,7 +124,10 @@ type ConnectionTracer interface {
 	AcknowledgedPacket(EncryptionLevel, PacketNumber)
 	LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)
 	UpdatedCongestionState(CongestionState)
+	UpdatedCongestionParameters(*CongestionParameters)
+	UpdatedCongestionMetrics(*CongestionMetrics)
 	UpdatedPTOCount(value uint32)
+	UpdatedFlowLabel(label uint32, trigger FlowLabelTrigger)
 	UpdatedKeyFromTLS(EncryptionLevel, Perspective)
 	UpdatedKey(generation KeyPhase, remote bool)
 	DroppedEncryptionLevel(EncryptionLevel)
@@ -128,7 +135,21 @@ type ConnectionTracer interface {
 	SetLossTimer(TimerType, EncryptionLevel, time.Time)
 	LossTimerExpired(TimerType, EncryptionLevel)
 	LossTimerCanceled()
+	InjectedFault(FaultDirection, FaultAction, PacketType, ByteCount)
+	// The datagram events report the length of the DATAGRAM payload.
+	// QueuedDatagram is called from the goroutine calling SendMessage.
+	QueuedDatagram(length ByteCount)
+	SentDatagram(length ByteCount)
+	DroppedDatagram(length ByteCount, reason DatagramDropReason)
+	ReceivedDatagram(length ByteCount)
+	LostDatagram(length ByteCount)
 	// Close is called when the connection is closed.
 	Close()
 	Debug(name, msg string)
 }
+
+// A CongestionStateTriggerTracer is a ConnectionTracer that is told what triggered a congestion state change.
+// For tracers implementing this interface, UpdatedCongestionStateWithTrigger is called instead of UpdatedCongestionState.
+type CongestionStateTriggerTracer interface {
+	UpdatedCongestionStateWithTrigger(CongestionState, CongestionStateTrigger)
+}
@@ -5,6 +5,7 @@
 package logging
 
 import (
+	context "context"
 	net "net"
 	reflect "reflect"
 	time "time"
@@ -98,6 +99,18 @@ func (mr *MockConnectionTracerMockRecorder) Debug(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debug", reflect.TypeOf((*MockConnectionTracer)(nil).Debug), arg0, arg1)
 }
 
+// DroppedDatagram mocks base method.
+func (m *MockConnectionTracer) DroppedDatagram(arg0 protocol.ByteCount, arg1 DatagramDropReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DroppedDatagram", arg0, arg1)
+}
+
+// DroppedDatagram indicates an expected call of DroppedDatagram.
+func (mr *MockConnectionTracerMockRecorder) DroppedDatagram(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).DroppedDatagram), arg0, arg1)
+}
+
 // DroppedEncryptionLevel mocks base method.
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -134,6 +147,18 @@ func (mr *MockConnectionTracerMockRecorder) DroppedPacket(arg0, arg1, arg2 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).DroppedPacket), arg0, arg1, arg2)
 }
 
+// InjectedFault mocks base method.
+func (m *MockConnectionTracer) InjectedFault(arg0 FaultDirection, arg1 FaultAction, arg2 PacketType, arg3 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InjectedFault", arg0, arg1, arg2, arg3)
+}
+
+// InjectedFault indicates an expected call of InjectedFault.
+func (mr *MockConnectionTracerMockRecorder) InjectedFault(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InjectedFault", reflect.TypeOf((*MockConnectionTracer)(nil).InjectedFault), arg0, arg1, arg2, arg3)
+}
+
 // LossTimerCanceled mocks base method.
 func (m *MockConnectionTracer) LossTimerCanceled() {
 	m.ctrl.T.Helper()
@@ -158,6 +183,18 @@ func (mr *MockConnectionTracerMockRecorder) LossTimerExpired(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LossTimerExpired", reflect.TypeOf((*MockConnectionTracer)(nil).LossTimerExpired), arg0, arg1)
 }
 
+// LostDatagram mocks base method.
+func (m *MockConnectionTracer) LostDatagram(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LostDatagram", arg0)
+}
+
+// LostDatagram indicates an expected call of LostDatagram.
+func (mr *MockConnectionTracerMockRecorder) LostDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LostDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).LostDatagram), arg0)
+}
+
 // LostPacket mocks base method.
 func (m *MockConnectionTracer) LostPacket(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber, arg2 PacketLossReason) {
 	m.ctrl.T.Helper()
@@ -182,6 +219,30 @@ func (mr *MockConnectionTracerMockRecorder) NegotiatedVersion(arg0, arg1, arg2 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedVersion", reflect.TypeOf((*MockConnectionTracer)(nil).NegotiatedVersion), arg0, arg1, arg2)
 }
 
+// QueuedDatagram mocks base method.
+func (m *MockConnectionTracer) QueuedDatagram(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "QueuedDatagram", arg0)
+}
+
+// QueuedDatagram indicates an expected call of QueuedDatagram.
+func (mr *MockConnectionTracerMockRecorder) QueuedDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueuedDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).QueuedDatagram), arg0)
+}
+
+// ReceivedDatagram mocks base method.
+func (m *MockConnectionTracer) ReceivedDatagram(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedDatagram", arg0)
+}
+
+// ReceivedDatagram indicates an expected call of ReceivedDatagram.
+func (mr *MockConnectionTracerMockRecorder) ReceivedDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedDatagram), arg0)
+}
+
 // ReceivedPacket mocks base method.
 func (m *MockConnectionTracer) ReceivedPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 []Frame) {
 	m.ctrl.T.Helper()
@@ -242,6 +303,18 @@ func (mr *MockConnectionTracerMockRecorder) RestoredTransportParameters(arg0 int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoredTransportParameters", reflect.TypeOf((*MockConnectionTracer)(nil).RestoredTransportParameters), arg0)
 }
 
+// SentDatagram mocks base method.
+func (m *MockConnectionTracer) SentDatagram(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SentDatagram", arg0)
+}
+
+// SentDatagram indicates an expected call of SentDatagram.
+func (mr *MockConnectionTracerMockRecorder) SentDatagram(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).SentDatagram), arg0)
+}
+
 // SentPacket mocks base method.
 func (m *MockConnectionTracer) SentPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 *wire.AckFrame, arg3 []Frame) {
 	m.ctrl.T.Helper()
@@ -290,6 +363,42 @@ func (mr *MockConnectionTracerMockRecorder) StartedConnection(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).StartedConnection), arg0, arg1, arg2, arg3)
 }
 
+// StartedHandshake mocks base method.
+func (m *MockConnectionTracer) StartedHandshake(arg0 context.Context, arg1 *HandshakeInfo) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartedHandshake", arg0, arg1)
+}
+
+// StartedHandshake indicates an expected call of StartedHandshake.
+func (mr *MockConnectionTracerMockRecorder) StartedHandshake(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedHandshake", reflect.TypeOf((*MockConnectionTracer)(nil).StartedHandshake), arg0, arg1)
+}
+
+// UpdatedCongestionMetrics mocks base method.
+func (m *MockConnectionTracer) UpdatedCongestionMetrics(arg0 *CongestionMetrics) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedCongestionMetrics", arg0)
+}
+
+// UpdatedCongestionMetrics indicates an expected call of UpdatedCongestionMetrics.
+func (mr *MockConnectionTracerMockRecorder) UpdatedCongestionMetrics(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedCongestionMetrics", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedCongestionMetrics), arg0)
+}
+
+// UpdatedCongestionParameters mocks base method.
+func (m *MockConnectionTracer) UpdatedCongestionParameters(arg0 *CongestionParameters) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedCongestionParameters", arg0)
+}
+
+// UpdatedCongestionParameters indicates an expected call of UpdatedCongestionParameters.
+func (mr *MockConnectionTracerMockRecorder) UpdatedCongestionParameters(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedCongestionParameters", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedCongestionParameters), arg0)
+}
+
 // UpdatedCongestionState mocks base method.
 func (m *MockConnectionTracer) UpdatedCongestionState(arg0 CongestionState) {
 	m.ctrl.T.Helper()
@@ -302,6 +411,18 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedCongestionState(arg0 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedCongestionState", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedCongestionState), arg0)
 }
 
+// UpdatedFlowLabel mocks base method.
+func (m *MockConnectionTracer) UpdatedFlowLabel(arg0 uint32, arg1 FlowLabelTrigger) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedFlowLabel", arg0, arg1)
+}
+
+// UpdatedFlowLabel indicates an expected call of UpdatedFlowLabel.
+func (mr *MockConnectionTracerMockRecorder) UpdatedFlowLabel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedFlowLabel", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedFlowLabel), arg0, arg1)
+}
+
 // UpdatedKey mocks base method.
 func (m *MockConnectionTracer) UpdatedKey(arg0 protocol.KeyPhase, arg1 bool) {
 	m.ctrl.T.Helper()
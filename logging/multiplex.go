@@ -49,7 +49,10 @@ type connTracerMultiplexer struct {
 	tracers []ConnectionTracer
 }
 
-var _ ConnectionTracer = &connTracerMultiplexer{}
+var (
+	_ ConnectionTracer             = &connTracerMultiplexer{}
+	_ CongestionStateTriggerTracer = &connTracerMultiplexer{}
+)
 
 // NewMultiplexedConnectionTracer creates a new connection tracer that multiplexes events to multiple tracers.
 func NewMultiplexedConnectionTracer(tracers ...ConnectionTracer) ConnectionTracer {
@@ -68,6 +71,12 @@ func (m *connTracerMultiplexer) StartedConnection(local, remote net.Addr, srcCon
 	}
 }
 
+func (m *connTracerMultiplexer) StartedHandshake(ctx context.Context, info *HandshakeInfo) {
+	for _, t := range m.tracers {
+		t.StartedHandshake(ctx, info)
+	}
+}
+
 func (m *connTracerMultiplexer) NegotiatedVersion(chosen VersionNumber, clientVersions, serverVersions []VersionNumber) {
 	for _, t := range m.tracers {
 		t.NegotiatedVersion(chosen, clientVersions, serverVersions)
@@ -140,6 +149,28 @@ func (m *connTracerMultiplexer) UpdatedCongestionState(state CongestionState) {
 	}
 }
 
+func (m *connTracerMultiplexer) UpdatedCongestionStateWithTrigger(state CongestionState, trigger CongestionStateTrigger) {
+	for _, t := range m.tracers {
+		if tt, ok := t.(CongestionStateTriggerTracer); ok {
+			tt.UpdatedCongestionStateWithTrigger(state, trigger)
+		} else {
+			t.UpdatedCongestionState(state)
+		}
+	}
+}
+
+func (m *connTracerMultiplexer) UpdatedCongestionParameters(params *CongestionParameters) {
+	for _, t := range m.tracers {
+		t.UpdatedCongestionParameters(params)
+	}
+}
+
+func (m *connTracerMultiplexer) UpdatedCongestionMetrics(metrics *CongestionMetrics) {
+	for _, t := range m.tracers {
+		t.UpdatedCongestionMetrics(metrics)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFLight ByteCount, packetsInFlight int) {
 	for _, t := range m.tracers {
 		t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight)
@@ -164,6 +195,12 @@ func (m *connTracerMultiplexer) UpdatedPTOCount(value uint32) {
 	}
 }
 
+func (m *connTracerMultiplexer) UpdatedFlowLabel(label uint32, trigger FlowLabelTrigger) {
+	for _, t := range m.tracers {
+		t.UpdatedFlowLabel(label, trigger)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedKeyFromTLS(encLevel EncryptionLevel, perspective Perspective) {
 	for _, t := range m.tracers {
 		t.UpdatedKeyFromTLS(encLevel, perspective)
@@ -206,6 +243,42 @@ func (m *connTracerMultiplexer) LossTimerCanceled() {
 	}
 }
 
+func (m *connTracerMultiplexer) InjectedFault(dir FaultDirection, action FaultAction, typ PacketType, size ByteCount) {
+	for _, t := range m.tracers {
+		t.InjectedFault(dir, action, typ, size)
+	}
+}
+
+func (m *connTracerMultiplexer) QueuedDatagram(length ByteCount) {
+	for _, t := range m.tracers {
+		t.QueuedDatagram(length)
+	}
+}
+
+func (m *connTracerMultiplexer) SentDatagram(length ByteCount) {
+	for _, t := range m.tracers {
+		t.SentDatagram(length)
+	}
+}
+
+func (m *connTracerMultiplexer) DroppedDatagram(length ByteCount, reason DatagramDropReason) {
+	for _, t := range m.tracers {
+		t.DroppedDatagram(length, reason)
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedDatagram(length ByteCount) {
+	for _, t := range m.tracers {
+		t.ReceivedDatagram(length)
+	}
+}
+
+func (m *connTracerMultiplexer) LostDatagram(length ByteCount) {
+	for _, t := range m.tracers {
+		t.LostDatagram(length)
+	}
+}
+
 func (m *connTracerMultiplexer) Debug(name, msg string) {
 	for _, t := range m.tracers {
 		t.Debug(name, msg)
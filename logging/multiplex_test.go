@@ -111,6 +111,15 @@ var _ = Describe("Tracing", func() {
 			tracer.StartedConnection(local, remote, ConnectionID{1, 2, 3, 4}, ConnectionID{4, 3, 2, 1})
 		})
 
+		It("traces the StartedHandshake event", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			info := &HandshakeInfo{ServerName: "quic-go.net", SupportedProtocols: []string{"h3"}}
+			tr1.EXPECT().StartedHandshake(ctx, info)
+			tr2.EXPECT().StartedHandshake(ctx, info)
+			tracer.StartedHandshake(ctx, info)
+		})
+
 		It("traces the ClosedConnection event", func() {
 			e := errors.New("test err")
 			tr1.EXPECT().ClosedConnection(e)
@@ -188,6 +197,32 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedCongestionState(CongestionStateRecovery)
 		})
 
+		It("traces the UpdatedCongestionStateWithTrigger event, for tracers that implement it", func() {
+			tr3 := &congestionStateTriggerTracer{MockConnectionTracer: NewMockConnectionTracer(mockCtrl)}
+			tracer = NewMultiplexedConnectionTracer(tr1, tr3)
+			tr1.EXPECT().UpdatedCongestionState(CongestionStateRecovery)
+			tracer.(CongestionStateTriggerTracer).UpdatedCongestionStateWithTrigger(CongestionStateRecovery, CongestionStateTriggerPacketLoss)
+			Expect(tr3.triggers).To(Equal([]CongestionStateTrigger{CongestionStateTriggerPacketLoss}))
+		})
+
+		It("traces the UpdatedCongestionParameters event", func() {
+			params := &CongestionParameters{InitialCongestionWindow: 1337, MinCongestionWindow: 42, MaxCongestionWindow: 1e6, LossReductionFactor: 0.5}
+			tr1.EXPECT().UpdatedCongestionParameters(params)
+			tr2.EXPECT().UpdatedCongestionParameters(params)
+			tracer.UpdatedCongestionParameters(params)
+		})
+
+		It("traces the UpdatedCongestionMetrics event", func() {
+			metrics := &CongestionMetrics{
+				PacingRate:        1e6,
+				BandwidthEstimate: 8e5,
+				Cubic:             &CubicState{EpochStart: time.Now(), LastMaxCongestionWindow: 1234},
+			}
+			tr1.EXPECT().UpdatedCongestionMetrics(metrics)
+			tr2.EXPECT().UpdatedCongestionMetrics(metrics)
+			tracer.UpdatedCongestionMetrics(metrics)
+		})
+
 		It("traces the UpdatedMetrics event", func() {
 			rttStats := &RTTStats{}
 			rttStats.UpdateRTT(time.Second, 0, time.Now())
@@ -214,6 +249,12 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedPTOCount(88)
 		})
 
+		It("traces the UpdatedFlowLabel event", func() {
+			tr1.EXPECT().UpdatedFlowLabel(uint32(0x12345), FlowLabelTriggerPTO)
+			tr2.EXPECT().UpdatedFlowLabel(uint32(0x12345), FlowLabelTriggerPTO)
+			tracer.UpdatedFlowLabel(0x12345, FlowLabelTriggerPTO)
+		})
+
 		It("traces the UpdatedKeyFromTLS event", func() {
 			tr1.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
 			tr2.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
@@ -257,6 +298,42 @@ var _ = Describe("Tracing", func() {
 			tracer.LossTimerCanceled()
 		})
 
+		It("traces the InjectedFault event", func() {
+			tr1.EXPECT().InjectedFault(FaultDirectionIncoming, FaultActionDrop, PacketTypeHandshake, ByteCount(1337))
+			tr2.EXPECT().InjectedFault(FaultDirectionIncoming, FaultActionDrop, PacketTypeHandshake, ByteCount(1337))
+			tracer.InjectedFault(FaultDirectionIncoming, FaultActionDrop, PacketTypeHandshake, 1337)
+		})
+
+		It("traces the QueuedDatagram event", func() {
+			tr1.EXPECT().QueuedDatagram(ByteCount(1337))
+			tr2.EXPECT().QueuedDatagram(ByteCount(1337))
+			tracer.QueuedDatagram(1337)
+		})
+
+		It("traces the SentDatagram event", func() {
+			tr1.EXPECT().SentDatagram(ByteCount(1337))
+			tr2.EXPECT().SentDatagram(ByteCount(1337))
+			tracer.SentDatagram(1337)
+		})
+
+		It("traces the DroppedDatagram event", func() {
+			tr1.EXPECT().DroppedDatagram(ByteCount(1337), DatagramDropReceiveQueueFull)
+			tr2.EXPECT().DroppedDatagram(ByteCount(1337), DatagramDropReceiveQueueFull)
+			tracer.DroppedDatagram(1337, DatagramDropReceiveQueueFull)
+		})
+
+		It("traces the ReceivedDatagram event", func() {
+			tr1.EXPECT().ReceivedDatagram(ByteCount(1337))
+			tr2.EXPECT().ReceivedDatagram(ByteCount(1337))
+			tracer.ReceivedDatagram(1337)
+		})
+
+		It("traces the LostDatagram event", func() {
+			tr1.EXPECT().LostDatagram(ByteCount(1337))
+			tr2.EXPECT().LostDatagram(ByteCount(1337))
+			tracer.LostDatagram(1337)
+		})
+
 		It("traces the Close event", func() {
 			tr1.EXPECT().Close()
 			tr2.EXPECT().Close()
@@ -264,3 +341,13 @@ var _ = Describe("Tracing", func() {
 		})
 	})
 })
+
+type congestionStateTriggerTracer struct {
+	*MockConnectionTracer
+
+	triggers []CongestionStateTrigger
+}
+
+func (t *congestionStateTriggerTracer) UpdatedCongestionStateWithTrigger(_ CongestionState, trigger CongestionStateTrigger) {
+	t.triggers = append(t.triggers, trigger)
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"context"
+	"time"
+)
+
+type timeSourceContextKey struct{}
+
+// WithTimeSource returns a copy of ctx that carries now as the time source of a connection.
+// It is used when replaying a connection, so that tracers can use the virtual clock of the replayed connection.
+func WithTimeSource(ctx context.Context, now func() time.Time) context.Context {
+	return context.WithValue(ctx, timeSourceContextKey{}, now)
+}
+
+// TimeSource returns the time source carried by ctx.
+// If ctx doesn't carry a time source, time.Now is returned.
+func TimeSource(ctx context.Context) func() time.Time {
+	if now, ok := ctx.Value(timeSourceContextKey{}).(func() time.Time); ok {
+		return now
+	}
+	return time.Now
+}
@@ -1,5 +1,10 @@
 package logging
 
+import (
+	"net"
+	"time"
+)
+
 // PacketType is the packet type of a QUIC packet
 type PacketType uint8
 
@@ -92,3 +97,113 @@ const (
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
 )
+
+// CongestionStateTrigger is the reason for a congestion state change
+type CongestionStateTrigger uint8
+
+const (
+	// CongestionStateTriggerNone is used if no specific reason is known
+	CongestionStateTriggerNone CongestionStateTrigger = iota
+	// CongestionStateTriggerHybridSlowStart is used when HyStart detected an increase in delay and exited slow start
+	CongestionStateTriggerHybridSlowStart
+	// CongestionStateTriggerSlowStartThreshold is used when the congestion window reached the slow start threshold
+	CongestionStateTriggerSlowStartThreshold
+	// CongestionStateTriggerPacketLoss is used when a packet was declared lost
+	CongestionStateTriggerPacketLoss
+	// CongestionStateTriggerECN is used when the peer reported CE-marked packets
+	CongestionStateTriggerECN
+)
+
+// CongestionMetrics are internal values of the congestion controller.
+// Bandwidths are in bits per second. They are 0 as long as no RTT sample is available.
+type CongestionMetrics struct {
+	PacingRate        uint64
+	BandwidthEstimate uint64
+	// Cubic is only set if the Cubic congestion controller is used.
+	Cubic *CubicState
+}
+
+// CubicState is the state of the current Cubic epoch.
+type CubicState struct {
+	// EpochStart is the beginning of the current congestion avoidance epoch.
+	// It is zero if no epoch has started yet.
+	EpochStart time.Time
+	// LastMaxCongestionWindow is the congestion window before the last reduction (W_max).
+	LastMaxCongestionWindow ByteCount
+	// OriginPointCongestionWindow is the congestion window at the origin point of the cubic function.
+	OriginPointCongestionWindow ByteCount
+	// TimeToOriginPoint is the time it takes to grow the congestion window to the origin point (K).
+	TimeToOriginPoint time.Duration
+	// EstimatedRenoCongestionWindow is the congestion window that Reno would have used.
+	EstimatedRenoCongestionWindow ByteCount
+}
+
+// CongestionParameters are the parameters used by the congestion controller.
+// The window sizes are in bytes, and change when the maximum datagram size changes.
+type CongestionParameters struct {
+	InitialCongestionWindow ByteCount
+	MinCongestionWindow     ByteCount
+	MaxCongestionWindow     ByteCount
+	// LossReductionFactor is the factor that the congestion window is multiplied with on a loss event.
+	LossReductionFactor float64
+}
+
+// DatagramDropReason is the reason why a DATAGRAM frame was dropped
+type DatagramDropReason uint8
+
+const (
+	// DatagramDropTooLarge is used when a DATAGRAM frame doesn't fit into a packet
+	DatagramDropTooLarge DatagramDropReason = iota
+	// DatagramDropReceiveQueueFull is used when a received DATAGRAM frame is dropped because the receive queue is full
+	DatagramDropReceiveQueueFull
+	// DatagramDropExpired is used when a DATAGRAM frame expired before it could be sent
+	DatagramDropExpired
+)
+
+// HandshakeInfo contains information about the connection that is known when the handshake starts.
+type HandshakeInfo struct {
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// ServerName is the SNI sent by the client.
+	ServerName string
+	// SupportedProtocols are the application protocols offered by the client (ALPN).
+	SupportedProtocols []string
+}
+
+// FlowLabelTrigger is the reason why the IPv6 flow label was changed
+type FlowLabelTrigger uint8
+
+const (
+	// FlowLabelTriggerInitial is used when the flow label is set for the first time
+	FlowLabelTriggerInitial FlowLabelTrigger = iota
+	// FlowLabelTriggerPTO is used when the flow label was changed because of repeated PTOs
+	FlowLabelTriggerPTO
+	// FlowLabelTriggerCongestion is used when the flow label was changed because the path was persistently congested
+	FlowLabelTriggerCongestion
+)
+
+// FaultAction is the kind of fault injected into a packet
+type FaultAction uint8
+
+const (
+	// FaultActionDrop drops the packet
+	FaultActionDrop FaultAction = 1 + iota
+	// FaultActionDelay delays the packet
+	FaultActionDelay
+	// FaultActionDuplicate duplicates the packet
+	FaultActionDuplicate
+	// FaultActionReorder holds back the packet until the next packet has passed
+	FaultActionReorder
+	// FaultActionBitFlip flips a random bit in the packet
+	FaultActionBitFlip
+)
+
+// FaultDirection is the direction of a packet that a fault was injected into
+type FaultDirection uint8
+
+const (
+	// FaultDirectionOutgoing is used for packets sent by this endpoint
+	FaultDirectionOutgoing FaultDirection = 1 + iota
+	// FaultDirectionIncoming is used for packets received from the peer
+	FaultDirectionIncoming
+)
@@ -173,3 +173,17 @@ func (mr *MockPacketHandlerManagerMockRecorder) SetServer(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetServer", reflect.TypeOf((*MockPacketHandlerManager)(nil).SetServer), arg0)
 }
+
+// Stats mocks base method.
+func (m *MockPacketHandlerManager) Stats() EndpointStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(EndpointStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockPacketHandlerManagerMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockPacketHandlerManager)(nil).Stats))
+}
@@ -8,6 +8,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
@@ -267,6 +268,20 @@ func (mr *MockQuicConnMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicConn)(nil).SendMessage), arg0)
 }
 
+// SendMessageWithExpiry mocks base method.
+func (m *MockQuicConn) SendMessageWithExpiry(arg0 []byte, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageWithExpiry", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessageWithExpiry indicates an expected call of SendMessageWithExpiry.
+func (mr *MockQuicConnMockRecorder) SendMessageWithExpiry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageWithExpiry", reflect.TypeOf((*MockQuicConn)(nil).SendMessageWithExpiry), arg0, arg1)
+}
+
 // destroy mocks base method.
 func (m *MockQuicConn) destroy(arg0 error) {
 	m.ctrl.T.Helper()
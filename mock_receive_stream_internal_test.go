@@ -77,6 +77,18 @@ func (mr *MockReceiveStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReadNotify mocks base method.
+func (m *MockReceiveStreamI) SetReadNotify(f func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadNotify", f)
+}
+
+// SetReadNotify indicates an expected call of SetReadNotify.
+func (mr *MockReceiveStreamIMockRecorder) SetReadNotify(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadNotify", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadNotify), f)
+}
+
 // StreamID mocks base method.
 func (m *MockReceiveStreamI) StreamID() StreamID {
 	m.ctrl.T.Helper()
@@ -91,6 +103,21 @@ func (mr *MockReceiveStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockReceiveStreamI)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockReceiveStreamI) TryRead(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockReceiveStreamIMockRecorder) TryRead(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockReceiveStreamI)(nil).TryRead), p)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockReceiveStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
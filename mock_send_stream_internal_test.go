@@ -78,6 +78,44 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// Flush mocks base method.
+func (m *MockSendStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockSendStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockSendStreamI)(nil).Flush))
+}
+
+// SetCorkDelay mocks base method.
+func (m *MockSendStreamI) SetCorkDelay(d time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCorkDelay", d)
+}
+
+// SetCorkDelay indicates an expected call of SetCorkDelay.
+func (mr *MockSendStreamIMockRecorder) SetCorkDelay(d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCorkDelay", reflect.TypeOf((*MockSendStreamI)(nil).SetCorkDelay), d)
+}
+
+// SetExpiry mocks base method.
+func (m *MockSendStreamI) SetExpiry(arg0 time.Time, arg1 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetExpiry", arg0, arg1)
+}
+
+// SetExpiry indicates an expected call of SetExpiry.
+func (mr *MockSendStreamIMockRecorder) SetExpiry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExpiry", reflect.TypeOf((*MockSendStreamI)(nil).SetExpiry), arg0, arg1)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -92,6 +130,18 @@ func (mr *MockSendStreamIMockRecorder) SetWriteDeadline(t interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockSendStreamI)(nil).SetWriteDeadline), t)
 }
 
+// SetWriteNotify mocks base method.
+func (m *MockSendStreamI) SetWriteNotify(f func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteNotify", f)
+}
+
+// SetWriteNotify indicates an expected call of SetWriteNotify.
+func (mr *MockSendStreamIMockRecorder) SetWriteNotify(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteNotify", reflect.TypeOf((*MockSendStreamI)(nil).SetWriteNotify), f)
+}
+
 // StreamID mocks base method.
 func (m *MockSendStreamI) StreamID() StreamID {
 	m.ctrl.T.Helper()
@@ -106,6 +156,21 @@ func (mr *MockSendStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockSendStreamI)(nil).StreamID))
 }
 
+// TryWrite mocks base method.
+func (m *MockSendStreamI) TryWrite(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockSendStreamIMockRecorder) TryWrite(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockSendStreamI)(nil).TryWrite), p)
+}
+
 // Write mocks base method.
 func (m *MockSendStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -90,6 +90,20 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// Flush mocks base method.
+func (m *MockStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStreamI)(nil).Flush))
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +119,18 @@ func (mr *MockStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), p)
 }
 
+// SetCorkDelay mocks base method.
+func (m *MockStreamI) SetCorkDelay(d time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCorkDelay", d)
+}
+
+// SetCorkDelay indicates an expected call of SetCorkDelay.
+func (mr *MockStreamIMockRecorder) SetCorkDelay(d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCorkDelay", reflect.TypeOf((*MockStreamI)(nil).SetCorkDelay), d)
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -119,6 +145,18 @@ func (mr *MockStreamIMockRecorder) SetDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), t)
 }
 
+// SetExpiry mocks base method.
+func (m *MockStreamI) SetExpiry(arg0 time.Time, arg1 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetExpiry", arg0, arg1)
+}
+
+// SetExpiry indicates an expected call of SetExpiry.
+func (mr *MockStreamIMockRecorder) SetExpiry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetExpiry", reflect.TypeOf((*MockStreamI)(nil).SetExpiry), arg0, arg1)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -133,6 +171,18 @@ func (mr *MockStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReadNotify mocks base method.
+func (m *MockStreamI) SetReadNotify(f func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadNotify", f)
+}
+
+// SetReadNotify indicates an expected call of SetReadNotify.
+func (mr *MockStreamIMockRecorder) SetReadNotify(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadNotify", reflect.TypeOf((*MockStreamI)(nil).SetReadNotify), f)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -147,6 +197,18 @@ func (mr *MockStreamIMockRecorder) SetWriteDeadline(t interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteDeadline", reflect.TypeOf((*MockStreamI)(nil).SetWriteDeadline), t)
 }
 
+// SetWriteNotify mocks base method.
+func (m *MockStreamI) SetWriteNotify(f func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteNotify", f)
+}
+
+// SetWriteNotify indicates an expected call of SetWriteNotify.
+func (mr *MockStreamIMockRecorder) SetWriteNotify(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteNotify", reflect.TypeOf((*MockStreamI)(nil).SetWriteNotify), f)
+}
+
 // StreamID mocks base method.
 func (m *MockStreamI) StreamID() StreamID {
 	m.ctrl.T.Helper()
@@ -161,6 +223,36 @@ func (mr *MockStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStreamI)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockStreamI) TryRead(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamIMockRecorder) TryRead(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStreamI)(nil).TryRead), p)
+}
+
+// TryWrite mocks base method.
+func (m *MockStreamI) TryWrite(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamIMockRecorder) TryWrite(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStreamI)(nil).TryWrite), p)
+}
+
 // Write mocks base method.
 func (m *MockStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -70,3 +70,17 @@ func (mr *MockStreamSenderMockRecorder) queueControlFrame(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "queueControlFrame", reflect.TypeOf((*MockStreamSender)(nil).queueControlFrame), arg0)
 }
+
+// supportsReliableReset mocks base method.
+func (m *MockStreamSender) supportsReliableReset() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "supportsReliableReset")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// supportsReliableReset indicates an expected call of supportsReliableReset.
+func (mr *MockStreamSenderMockRecorder) supportsReliableReset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "supportsReliableReset", reflect.TypeOf((*MockStreamSender)(nil).supportsReliableReset))
+}
@@ -28,17 +28,19 @@ type mtuFinder struct {
 	mtuIncreased  func(protocol.ByteCount)
 
 	rttStats *utils.RTTStats
+	clock    utils.Clock
 	current  protocol.ByteCount
 	max      protocol.ByteCount // the maximum value, as advertised by the peer (or our maximum size buffer)
 }
 
 var _ mtuDiscoverer = &mtuFinder{}
 
-func newMTUDiscoverer(rttStats *utils.RTTStats, start, max protocol.ByteCount, mtuIncreased func(protocol.ByteCount)) mtuDiscoverer {
+func newMTUDiscoverer(rttStats *utils.RTTStats, clock utils.Clock, start, max protocol.ByteCount, mtuIncreased func(protocol.ByteCount)) mtuDiscoverer {
 	return &mtuFinder{
 		current:       start,
 		rttStats:      rttStats,
-		lastProbeTime: time.Now(), // to make sure the first probe packet is not sent immediately
+		clock:         clock,
+		lastProbeTime: clock.Now(), // to make sure the first probe packet is not sent immediately
 		mtuIncreased:  mtuIncreased,
 		max:           max,
 	}
@@ -57,7 +59,7 @@ func (f *mtuFinder) ShouldSendProbe(now time.Time) bool {
 
 func (f *mtuFinder) GetPing() (ackhandler.Frame, protocol.ByteCount) {
 	size := (f.max + f.current) / 2
-	f.lastProbeTime = time.Now()
+	f.lastProbeTime = f.clock.Now()
 	f.probeInFlight = true
 	return ackhandler.Frame{
 		Frame: &wire.PingFrame{},
@@ -0,0 +1,309 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// holePunchInterval is the interval at which hole punching probes are sent.
+const holePunchInterval = 100 * time.Millisecond
+
+// A hole punching probe consists of the magic, the probe type and the tie-breaker of the sender.
+// The first byte of the magic is 0, so the probe can't be mistaken for a QUIC packet (which has the fixed bit set).
+var holePunchMagic = []byte{0, 'q', 'u', 'i', 'c', 'p', '2', 'p'}
+
+const holePunchProbeLen = 8 + 1 + 8
+
+const (
+	holePunchRequest  uint8 = 1
+	holePunchResponse uint8 = 2
+)
+
+// ErrPeerClosed is returned when using a Peer that was closed.
+var ErrPeerClosed = errors.New("quic: Peer closed")
+
+type holePunchResult struct {
+	addr       net.Addr
+	tieBreaker uint64
+}
+
+// A Peer accepts and dials QUIC connections on a single net.PacketConn.
+// It can establish connections to peers behind NATs by punching holes:
+// Both peers call Connect with each other's candidate addresses.
+// Connect sends hole punching probes from the listening socket, until a probe from the other peer is received.
+// Every probe carries a random tie-breaker. When both peers call Connect at the same time,
+// only the peer with the larger tie-breaker dials, and the other peer accepts that connection.
+// This way, exactly one connection is established.
+type Peer struct {
+	conn    net.PacketConn
+	ln      Listener
+	tlsConf *tls.Config
+	config  *Config
+
+	tieBreaker uint64
+
+	mutex sync.Mutex
+	// keyed by the candidate address
+	probeWaiters map[string]chan<- holePunchResult
+	connecting   map[string]chan<- Connection
+
+	acceptQueue chan Connection
+	closeOnce   sync.Once
+	closed      chan struct{}
+
+	logger utils.Logger
+}
+
+// NewPeer creates a new Peer, listening on conn.
+// The tls.Config is used both for accepting and for dialing connections,
+// so it must contain a certificate as well as the information needed to verify the certificate of other peers.
+// The Peer reads packets using ReadFrom, so optimizations that depend on reading OOB data are disabled.
+// The net.PacketConn must not be used by anything else.
+func NewPeer(conn net.PacketConn, tlsConf *tls.Config, config *Config) (*Peer, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	p := &Peer{
+		tlsConf:      tlsConf,
+		config:       config,
+		tieBreaker:   binary.BigEndian.Uint64(b[:]),
+		probeWaiters: make(map[string]chan<- holePunchResult),
+		connecting:   make(map[string]chan<- Connection),
+		acceptQueue:  make(chan Connection),
+		closed:       make(chan struct{}),
+		logger:       utils.DefaultLogger.WithPrefix("peer"),
+	}
+	p.conn = newPunchConn(conn, p.handleProbe)
+	ln, err := Listen(p.conn, tlsConf, config)
+	if err != nil {
+		return nil, err
+	}
+	p.ln = ln
+	go p.acceptLoop()
+	return p, nil
+}
+
+func (p *Peer) acceptLoop() {
+	for {
+		conn, err := p.ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		p.mutex.Lock()
+		c, ok := p.connecting[conn.RemoteAddr().String()]
+		p.mutex.Unlock()
+		if ok {
+			select {
+			case c <- conn:
+			default:
+				conn.CloseWithError(0, "duplicate connection")
+			}
+			continue
+		}
+		select {
+		case p.acceptQueue <- conn:
+		case <-p.closed:
+			conn.CloseWithError(0, "peer closed")
+			return
+		}
+	}
+}
+
+// Accept returns connections that were dialed by other peers, and that were not established by Connect.
+func (p *Peer) Accept(ctx context.Context) (Connection, error) {
+	select {
+	case conn := <-p.acceptQueue:
+		return conn, nil
+	case <-p.closed:
+		return nil, ErrPeerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Connect establishes a connection to a peer that is simultaneously calling Connect.
+// The candidates are the addresses that the other peer might be reachable at.
+// For a peer behind a NAT, this is the address assigned by the NAT.
+func (p *Peer) Connect(ctx context.Context, candidates []net.Addr) (Connection, error) {
+	accepted := make(chan Connection, 1)
+	p.mutex.Lock()
+	for _, addr := range candidates {
+		p.connecting[addr.String()] = accepted
+	}
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		for _, addr := range candidates {
+			if p.connecting[addr.String()] == accepted {
+				delete(p.connecting, addr.String())
+			}
+		}
+		p.mutex.Unlock()
+	}()
+
+	res, err := p.holePunch(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.tieBreaker > res.tieBreaker:
+		p.logger.Debugf("Punched a hole to %s. Dialing.", res.addr)
+		return DialContext(ctx, p.conn, res.addr, res.addr.String(), p.tlsConf, p.config)
+	case p.tieBreaker < res.tieBreaker:
+		p.logger.Debugf("Punched a hole to %s. Waiting for the peer to dial.", res.addr)
+		select {
+		case conn := <-accepted:
+			return conn, nil
+		case <-p.closed:
+			return nil, ErrPeerClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	default:
+		return nil, errors.New("quic: peer uses the same tie-breaker")
+	}
+}
+
+// HolePunch sends hole punching probes to the candidate addresses,
+// until a probe from one of these addresses is received.
+// It returns the address that the probe was received from.
+func (p *Peer) HolePunch(ctx context.Context, candidates []net.Addr) (net.Addr, error) {
+	res, err := p.holePunch(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return res.addr, nil
+}
+
+func (p *Peer) holePunch(ctx context.Context, candidates []net.Addr) (holePunchResult, error) {
+	if len(candidates) == 0 {
+		return holePunchResult{}, errors.New("quic: no candidate addresses")
+	}
+	c := make(chan holePunchResult, 1)
+	p.mutex.Lock()
+	for _, addr := range candidates {
+		p.probeWaiters[addr.String()] = c
+	}
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		for _, addr := range candidates {
+			if p.probeWaiters[addr.String()] == c {
+				delete(p.probeWaiters, addr.String())
+			}
+		}
+		p.mutex.Unlock()
+	}()
+
+	ticker := time.NewTicker(holePunchInterval)
+	defer ticker.Stop()
+	for {
+		for _, addr := range candidates {
+			p.sendProbe(addr, holePunchRequest)
+		}
+		select {
+		case res := <-c:
+			return res, nil
+		case <-ticker.C:
+		case <-p.closed:
+			return holePunchResult{}, ErrPeerClosed
+		case <-ctx.Done():
+			return holePunchResult{}, ctx.Err()
+		}
+	}
+}
+
+func (p *Peer) sendProbe(addr net.Addr, typ uint8) {
+	b := make([]byte, 0, holePunchProbeLen)
+	b = append(b, holePunchMagic...)
+	b = append(b, typ)
+	b = append(b, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(b[len(b)-8:], p.tieBreaker)
+	if _, err := p.conn.WriteTo(b, addr); err != nil {
+		p.logger.Debugf("Sending hole punching probe to %s failed: %s", addr, err)
+	}
+}
+
+// handleProbe is called for every hole punching probe received.
+func (p *Peer) handleProbe(addr net.Addr, typ uint8, tieBreaker uint64) {
+	if typ == holePunchRequest {
+		p.sendProbe(addr, holePunchResponse)
+	}
+	p.mutex.Lock()
+	c, ok := p.probeWaiters[addr.String()]
+	p.mutex.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case c <- holePunchResult{addr: addr, tieBreaker: tieBreaker}:
+	default:
+	}
+}
+
+// Addr returns the local address.
+func (p *Peer) Addr() net.Addr {
+	return p.ln.Addr()
+}
+
+// Close closes the listener.
+// Connections that were already established are not closed.
+// The underlying net.PacketConn is not closed.
+func (p *Peer) Close() error {
+	p.closeOnce.Do(func() { close(p.closed) })
+	return p.ln.Close()
+}
+
+// The punchConn intercepts hole punching probes.
+type punchConn struct {
+	net.PacketConn
+
+	onProbe func(addr net.Addr, typ uint8, tieBreaker uint64)
+}
+
+// If the underlying conn supports it, the punchSysConn allows to set the receive buffer size.
+// It doesn't implement the OOBCapablePacketConn interface,
+// since packets would then be read without using ReadFrom.
+type punchSysConn struct {
+	*punchConn
+
+	sys interface {
+		SyscallConn() (syscall.RawConn, error)
+		SetReadBuffer(int) error
+	}
+}
+
+func (c *punchSysConn) SyscallConn() (syscall.RawConn, error) { return c.sys.SyscallConn() }
+func (c *punchSysConn) SetReadBuffer(bytes int) error         { return c.sys.SetReadBuffer(bytes) }
+
+func newPunchConn(c net.PacketConn, onProbe func(net.Addr, uint8, uint64)) net.PacketConn {
+	pc := &punchConn{PacketConn: c, onProbe: onProbe}
+	if sys, ok := c.(interface {
+		SyscallConn() (syscall.RawConn, error)
+		SetReadBuffer(int) error
+	}); ok {
+		return &punchSysConn{punchConn: pc, sys: sys}
+	}
+	return pc
+}
+
+func (c *punchConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(b)
+		if err != nil || n != holePunchProbeLen || !bytes.Equal(b[:len(holePunchMagic)], holePunchMagic) {
+			return n, addr, err
+		}
+		c.onProbe(addr, b[len(holePunchMagic)], binary.BigEndian.Uint64(b[len(holePunchMagic)+1:n]))
+	}
+}
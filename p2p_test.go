@@ -0,0 +1,338 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type simPacket struct {
+	data []byte
+	from net.Addr
+}
+
+// simNetwork is an in-memory network.
+// Hosts are either directly reachable at their address, or they are located behind a NAT.
+// The NAT uses an endpoint-independent mapping and an address and port-dependent filtering:
+// Packets from a remote address are only let through if the host sent a packet to this address before.
+type simNetwork struct {
+	mutex    sync.Mutex
+	conns    map[string]*simConn // by public address
+	nextHost byte
+
+	numFiltered int32 // atomic
+}
+
+func newSimNetwork() *simNetwork {
+	return &simNetwork{conns: make(map[string]*simConn)}
+}
+
+func (n *simNetwork) newConn(behindNAT bool) *simConn {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.nextHost++
+	c := &simConn{
+		network:   n,
+		local:     &net.UDPAddr{IP: net.IPv4(10, 0, 0, n.nextHost), Port: 1000},
+		public:    &net.UDPAddr{IP: net.IPv4(192, 0, 2, n.nextHost), Port: 1000},
+		behindNAT: behindNAT,
+		permitted: make(map[string]struct{}),
+		queue:     make(chan simPacket, 1000),
+		closed:    make(chan struct{}),
+	}
+	if behindNAT {
+		// the NAT assigns a different port
+		c.public.Port = 40000 + int(n.nextHost)
+	} else {
+		c.local = c.public
+	}
+	n.conns[c.public.String()] = c
+	return c
+}
+
+func (n *simNetwork) deliver(data []byte, from, to net.Addr) {
+	n.mutex.Lock()
+	c, ok := n.conns[to.String()]
+	n.mutex.Unlock()
+	if !ok {
+		return
+	}
+	if !c.isPermitted(from) {
+		atomic.AddInt32(&n.numFiltered, 1)
+		return
+	}
+	b := make([]byte, len(data))
+	copy(b, data)
+	select {
+	case c.queue <- simPacket{data: b, from: from}:
+	default:
+	}
+}
+
+type simConn struct {
+	network   *simNetwork
+	local     *net.UDPAddr
+	public    *net.UDPAddr
+	behindNAT bool
+
+	mutex     sync.Mutex
+	permitted map[string]struct{}
+
+	queue     chan simPacket
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &simConn{}
+
+func (c *simConn) isPermitted(addr net.Addr) bool {
+	if !c.behindNAT {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.permitted[addr.String()]
+	return ok
+}
+
+func (c *simConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.queue:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *simConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	if c.behindNAT {
+		c.mutex.Lock()
+		c.permitted[addr.String()] = struct{}{}
+		c.mutex.Unlock()
+	}
+	c.network.deliver(b, c.public, addr)
+	return len(b), nil
+}
+
+func (c *simConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *simConn) LocalAddr() net.Addr              { return c.local }
+func (c *simConn) SetDeadline(time.Time) error      { return nil }
+func (c *simConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *simConn) SetWriteDeadline(time.Time) error { return nil }
+
+var _ = Describe("Peer", func() {
+	var (
+		network *simNetwork
+		conns   []*simConn
+	)
+
+	newConn := func(behindNAT bool) *simConn {
+		c := network.newConn(behindNAT)
+		conns = append(conns, c)
+		return c
+	}
+
+	getTLSConf := func() *tls.Config {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.RootCAs = testdata.GetRootCA()
+		tlsConf.ServerName = "localhost"
+		tlsConf.NextProtos = []string{"p2p"}
+		return tlsConf
+	}
+
+	newPeer := func(c *simConn) *Peer {
+		p, err := NewPeer(c, getTLSConf(), &Config{HandshakeIdleTimeout: scaleDuration(2 * time.Second)})
+		Expect(err).ToNot(HaveOccurred())
+		return p
+	}
+
+	BeforeEach(func() {
+		network = newSimNetwork()
+		conns = nil
+	})
+
+	AfterEach(func() {
+		// Closing the conn makes the packet handler map remove it from the multiplexer.
+		// Wait for this to happen, since the multiplexer is reset before the next test.
+		m := getMultiplexer().(*connMultiplexer)
+		for _, c := range conns {
+			c.Close()
+			Eventually(func() bool {
+				m.mutex.Lock()
+				defer m.mutex.Unlock()
+				_, ok := m.conns[c.LocalAddr().Network()+" "+c.LocalAddr().String()]
+				return ok
+			}).Should(BeFalse())
+		}
+	})
+
+	It("doesn't allow dialing a peer behind a NAT without punching a hole", func() {
+		connA := newConn(true)
+		connB := newConn(true)
+		peerB := newPeer(connB)
+		defer peerB.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(200*time.Millisecond))
+		defer cancel()
+		_, err := DialContext(ctx, connA, connB.public, "localhost", getTLSConf(), nil)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(atomic.LoadInt32(&network.numFiltered)).ToNot(BeZero())
+	})
+
+	It("punches holes", func() {
+		connA := newConn(true)
+		connB := newConn(true)
+		peerA := newPeer(connA)
+		defer peerA.Close()
+		peerB := newPeer(connB)
+		defer peerB.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			addr, err := peerB.HolePunch(context.Background(), []net.Addr{connA.public})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(connA.public))
+		}()
+		// Wait until peer B's NAT drops the first probes.
+		Eventually(func() int32 { return atomic.LoadInt32(&network.numFiltered) }).ShouldNot(BeZero())
+		addr, err := peerA.HolePunch(context.Background(), []net.Addr{connB.public})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr).To(Equal(connB.public))
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("stops hole punching when the context is canceled", func() {
+		connA := newConn(true)
+		connB := newConn(true)
+		peerA := newPeer(connA)
+		defer peerA.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(200*time.Millisecond))
+		defer cancel()
+		_, err := peerA.HolePunch(ctx, []net.Addr{connB.public})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("establishes a single connection when both peers connect simultaneously", func() {
+		connA := newConn(true)
+		connB := newConn(true)
+		peerA := newPeer(connA)
+		defer peerA.Close()
+		peerB := newPeer(connB)
+		defer peerB.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		connChan := make(chan Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := peerB.Connect(ctx, []net.Addr{&net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 1234}, connA.public})
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- conn
+		}()
+		a, err := peerA.Connect(ctx, []net.Addr{connB.public})
+		Expect(err).ToNot(HaveOccurred())
+		var b Connection
+		Eventually(connChan).Should(Receive(&b))
+		Expect(a.RemoteAddr()).To(Equal(connB.public))
+		Expect(b.RemoteAddr()).To(Equal(connA.public))
+
+		// the peer with the larger tie-breaker dialed
+		aIsClient := a.(*connection).perspective == protocol.PerspectiveClient
+		Expect(aIsClient).To(Equal(peerA.tieBreaker > peerB.tieBreaker))
+		Expect(b.(*connection).perspective == protocol.PerspectiveClient).To(Equal(!aIsClient))
+
+		str, err := a.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		rstr, err := b.AcceptStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+
+		// no other connection was established
+		for _, p := range []*Peer{peerA, peerB} {
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+			_, err := p.Accept(ctx)
+			cancel()
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		}
+		a.CloseWithError(0, "")
+	})
+
+	It("accepts connections from peers that are not connecting", func() {
+		connA := newConn(true)
+		connB := newConn(false)
+		peerB := newPeer(connB)
+		defer peerB.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := DialContext(ctx, connA, connB.public, "localhost", getTLSConf(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			<-conn.Context().Done()
+		}()
+		conn, err := peerB.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.RemoteAddr()).To(Equal(connA.public))
+		conn.CloseWithError(0, "")
+	})
+
+	It("returns an error when the peer is closed", func() {
+		conn := newConn(true)
+		peer := newPeer(conn)
+		errChan := make(chan error, 2)
+		go func() {
+			_, err := peer.Accept(context.Background())
+			errChan <- err
+		}()
+		go func() {
+			_, err := peer.Connect(context.Background(), []net.Addr{&net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 1234}})
+			errChan <- err
+		}()
+		time.Sleep(scaleDuration(20 * time.Millisecond))
+		Expect(peer.Close()).To(Succeed())
+		Eventually(errChan).Should(Receive(MatchError(ErrPeerClosed)))
+		Eventually(errChan).Should(Receive(MatchError(ErrPeerClosed)))
+	})
+
+	It("ignores packets that look like probes, but have the wrong length", func() {
+		var probes int
+		c := newPunchConn(&simConn{queue: make(chan simPacket, 2), closed: make(chan struct{})}, func(net.Addr, uint8, uint64) { probes++ })
+		sc := c.(*punchConn).PacketConn.(*simConn)
+		probe := append(append([]byte{}, holePunchMagic...), make([]byte, 9)...)
+		sc.queue <- simPacket{data: probe, from: &net.UDPAddr{}}
+		sc.queue <- simPacket{data: append(probe, 0), from: &net.UDPAddr{}}
+		b := make([]byte, 100)
+		n, _, err := c.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(len(probe) + 1))
+		Expect(probes).To(Equal(1))
+	})
+})
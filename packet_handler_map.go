@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -77,6 +78,11 @@ type packetHandlerMap struct {
 	server            unknownPacketHandler
 	numZeroRTTEntries int
 
+	// The connections accepted by the server, used to count handshakes and connections.
+	// Until the handshake completes, a connection is identified by the connection ID chosen by the client.
+	handshakingConns map[string] /* string(ConnectionID)*/ packetHandler
+	serverConns      map[packetHandler]struct{}
+
 	listening chan struct{} // is closed when listen returns
 	closed    bool
 
@@ -87,6 +93,8 @@ type packetHandlerMap struct {
 	statelessResetMutex   sync.Mutex
 	statelessResetHasher  hash.Hash
 
+	counters *endpointCounters
+
 	tracer logging.Tracer
 	logger utils.Logger
 }
@@ -153,10 +161,13 @@ func newPacketHandlerMap(
 		listening:               make(chan struct{}),
 		handlers:                make(map[string]packetHandlerMapEntry),
 		resetTokens:             make(map[protocol.StatelessResetToken]packetHandler),
+		handshakingConns:        make(map[string]packetHandler),
+		serverConns:             make(map[packetHandler]struct{}),
 		deleteRetiredConnsAfter: protocol.RetiredConnectionIDDeleteTimeout,
 		zeroRTTQueueDuration:    protocol.Max0RTTQueueingDuration,
 		statelessResetEnabled:   len(statelessResetKey) > 0,
 		statelessResetHasher:    hmac.New(sha256.New, statelessResetKey),
+		counters:                &endpointCounters{},
 		tracer:                  tracer,
 		logger:                  logger,
 	}
@@ -230,18 +241,30 @@ func (h *packetHandlerMap) AddWithConnID(clientDestConnID, newConnID protocol.Co
 	}
 	h.handlers[string(clientDestConnID)] = packetHandlerMapEntry{packetHandler: sess}
 	h.handlers[string(newConnID)] = packetHandlerMapEntry{packetHandler: sess}
+	h.handshakingConns[string(clientDestConnID)] = sess
+	h.serverConns[sess] = struct{}{}
+	atomic.AddUint64(&h.counters.handshakesStarted, 1)
+	atomic.AddInt64(&h.counters.connections, 1)
 	h.logger.Debugf("Adding connection IDs %s and %s for a new connection.", clientDestConnID, newConnID)
 	return true
 }
 
 func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
 	h.mutex.Lock()
+	h.countClosedConn(id)
 	delete(h.handlers, string(id))
 	h.mutex.Unlock()
 	h.logger.Debugf("Removing connection ID %s.", id)
 }
 
 func (h *packetHandlerMap) Retire(id protocol.ConnectionID) {
+	h.mutex.Lock()
+	// When the handshake completes, the server retires the connection ID chosen by the client.
+	if _, ok := h.handshakingConns[string(id)]; ok {
+		delete(h.handshakingConns, string(id))
+		atomic.AddUint64(&h.counters.handshakesCompleted, 1)
+	}
+	h.mutex.Unlock()
 	h.logger.Debugf("Retiring connection ID %s in %s.", id, h.deleteRetiredConnsAfter)
 	time.AfterFunc(h.deleteRetiredConnsAfter, func() {
 		h.mutex.Lock()
@@ -253,6 +276,7 @@ func (h *packetHandlerMap) Retire(id protocol.ConnectionID) {
 
 func (h *packetHandlerMap) ReplaceWithClosed(id protocol.ConnectionID, handler packetHandler) {
 	h.mutex.Lock()
+	h.countClosedConn(id)
 	h.handlers[string(id)] = packetHandlerMapEntry{packetHandler: handler}
 	h.mutex.Unlock()
 	h.logger.Debugf("Replacing connection for connection ID %s with a closed connection.", id)
@@ -266,6 +290,24 @@ func (h *packetHandlerMap) ReplaceWithClosed(id protocol.ConnectionID, handler p
 	})
 }
 
+// countClosedConn updates the counters when the connection IDs of a server connection are removed,
+// which happens when the connection is closed.
+// It must be called with the mutex held, before the connection ID is removed.
+func (h *packetHandlerMap) countClosedConn(id protocol.ConnectionID) {
+	if _, ok := h.handshakingConns[string(id)]; ok {
+		delete(h.handshakingConns, string(id))
+		atomic.AddUint64(&h.counters.handshakesFailed, 1)
+	}
+	entry, ok := h.handlers[string(id)]
+	if !ok {
+		return
+	}
+	if _, ok := h.serverConns[entry.packetHandler]; ok {
+		delete(h.serverConns, entry.packetHandler)
+		atomic.AddInt64(&h.counters.connections, -1)
+	}
+}
+
 func (h *packetHandlerMap) AddResetToken(token protocol.StatelessResetToken, handler packetHandler) {
 	h.mutex.Lock()
 	h.resetTokens[token] = handler
@@ -362,9 +404,11 @@ func (h *packetHandlerMap) listen() {
 }
 
 func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
+	atomic.AddUint64(&h.counters.packetsReceived, 1)
 	connID, err := wire.ParseConnectionID(p.data, h.connIDLen)
 	if err != nil {
 		h.logger.Debugf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
+		h.counters.droppedPacket(logging.PacketDropHeaderParseError)
 		if h.tracer != nil {
 			h.tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropHeaderParseError)
 		}
@@ -391,15 +435,18 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 		}
 	}
 	if p.data[0]&0x80 == 0 {
+		h.counters.droppedPacket(logging.PacketDropUnknownConnectionID)
 		go h.maybeSendStatelessReset(p, connID)
 		return
 	}
 	if h.server == nil { // no server set
 		h.logger.Debugf("received a packet with an unexpected connection ID %s", connID)
+		h.counters.droppedPacket(logging.PacketDropUnknownConnectionID)
 		return
 	}
 	if wire.Is0RTTPacket(p.data) {
 		if h.numZeroRTTEntries >= protocol.Max0RTTQueues {
+			h.counters.droppedPacket(logging.PacketDropDOSPrevention)
 			return
 		}
 		h.numZeroRTTEntries++
@@ -485,5 +532,13 @@ func (h *packetHandlerMap) maybeSendStatelessReset(p *receivedPacket, connID pro
 	data = append(data, token[:]...)
 	if _, err := h.conn.WritePacket(data, p.remoteAddr, p.info.OOB()); err != nil {
 		h.logger.Debugf("Error sending Stateless Reset: %s", err)
+		return
 	}
+	atomic.AddUint64(&h.counters.statelessResetsSent, 1)
+}
+
+func (h *packetHandlerMap) Stats() EndpointStats {
+	var s EndpointStats
+	h.counters.addTo(&s)
+	return s
 }
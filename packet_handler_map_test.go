@@ -158,6 +158,9 @@ var _ = Describe("Packet Handler Map", func() {
 					remoteAddr: addr,
 					data:       []byte{0, 1, 2, 3},
 				})
+				stats := handler.Stats()
+				Expect(stats.PacketsReceived).To(BeEquivalentTo(1))
+				Expect(stats.PacketsDropped).To(Equal(map[logging.PacketDropReason]uint64{logging.PacketDropHeaderParseError: 1}))
 			})
 
 			It("deletes removed connections immediately", func() {
@@ -197,6 +200,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("drops packets for unknown receivers", func() {
 				connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 				handler.handlePacket(&receivedPacket{data: getPacket(connID)})
+				Expect(handler.Stats().PacketsDropped).To(Equal(map[logging.PacketDropReason]uint64{logging.PacketDropUnknownConnectionID: 1}))
 			})
 
 			It("closes the packet handlers when reading from the conn fails", func() {
@@ -263,6 +267,35 @@ var _ = Describe("Packet Handler Map", func() {
 				handler.CloseServer()
 			})
 
+			It("counts handshakes and connections", func() {
+				handler.deleteRetiredConnsAfter = time.Hour
+				clientDestConnID1 := protocol.ConnectionID{1, 1, 1, 1, 1, 1, 1, 1}
+				clientDestConnID2 := protocol.ConnectionID{2, 2, 2, 2, 2, 2, 2, 2}
+				connID1 := protocol.ConnectionID{1, 2, 3, 4}
+				connID2 := protocol.ConnectionID{4, 3, 2, 1}
+				Expect(handler.AddWithConnID(clientDestConnID1, connID1, func() packetHandler { return NewMockPacketHandler(mockCtrl) })).To(BeTrue())
+				Expect(handler.AddWithConnID(clientDestConnID2, connID2, func() packetHandler { return NewMockPacketHandler(mockCtrl) })).To(BeTrue())
+				stats := handler.Stats()
+				Expect(stats.HandshakesStarted).To(BeEquivalentTo(2))
+				Expect(stats.Connections).To(Equal(2))
+				// complete the handshake of the first connection
+				handler.Retire(clientDestConnID1)
+				handler.Retire(clientDestConnID1) // retired a second time by the connection ID generator
+				Expect(handler.Stats().HandshakesCompleted).To(BeEquivalentTo(1))
+				// close the second connection before completing the handshake
+				handler.Remove(clientDestConnID2)
+				handler.Remove(connID2)
+				stats = handler.Stats()
+				Expect(stats.HandshakesFailed).To(BeEquivalentTo(1))
+				Expect(stats.Connections).To(Equal(1))
+				// close the first connection
+				handler.ReplaceWithClosed(connID1, NewMockPacketHandler(mockCtrl))
+				stats = handler.Stats()
+				Expect(stats.HandshakesCompleted).To(BeEquivalentTo(1))
+				Expect(stats.HandshakesFailed).To(BeEquivalentTo(1))
+				Expect(stats.Connections).To(BeZero())
+			})
+
 			It("stops handling packets with unknown connection IDs after the server is closed", func() {
 				connID := protocol.ConnectionID{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
 				p := getPacket(connID)
@@ -323,6 +356,9 @@ var _ = Describe("Packet Handler Map", func() {
 				// We're already storing the maximum number of queues. This packet will be dropped.
 				connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}
 				handler.handlePacket(&receivedPacket{data: getPacketWithPacketType(connID, protocol.PacketType0RTT, 1)})
+				stats := handler.Stats()
+				Expect(stats.PacketsReceived).To(BeEquivalentTo(protocol.Max0RTTQueues + 1))
+				Expect(stats.PacketsDropped).To(Equal(map[logging.PacketDropReason]uint64{logging.PacketDropDOSPrevention: 1}))
 				// Don't EXPECT any handlePacket() calls.
 				conn := NewMockPacketHandler(mockCtrl)
 				handler.AddWithConnID(connID, protocol.ConnectionID{1, 2, 3, 4}, func() packetHandler { return conn })
@@ -462,6 +498,7 @@ var _ = Describe("Packet Handler Map", func() {
 						data:       p,
 					})
 					Eventually(done).Should(BeClosed())
+					Eventually(func() uint64 { return handler.Stats().StatelessResetsSent }).Should(BeEquivalentTo(1))
 				})
 
 				It("doesn't send stateless resets for small packets", func() {
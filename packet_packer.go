@@ -532,6 +532,11 @@ func (p *packetPacker) maybeGetCryptoPacket(maxPacketSize, currentSize protocol.
 			payload.length += frameLen
 			maxPacketSize -= frameLen
 		}
+	} else if fp, ok := s.(framePopper); ok && s.HasData() {
+		for _, f := range fp.PopFrames(maxPacketSize) {
+			payload.frames = append(payload.frames, f)
+			payload.length += f.Length(p.version)
+		}
 	} else if s.HasData() {
 		cf := s.PopCryptoFrame(maxPacketSize)
 		payload.frames = []ackhandler.Frame{{Frame: cf}}
@@ -563,12 +568,21 @@ func (p *packetPacker) maybeGetAppDataPacket(maxPacketSize, currentSize protocol
 	}
 
 	maxPayloadSize := maxPacketSize - hdr.GetLength(p.version) - protocol.ByteCount(sealer.Overhead())
-	payload := p.maybeGetAppDataPacketWithEncLevel(maxPayloadSize, encLevel == protocol.Encryption1RTT && currentSize == 0)
+	// DATAGRAM frames are only dropped if they don't even fit into a full-size 1-RTT packet.
+	shortHdr := hdr
+	if hdr.IsLongHeader {
+		shortHdr = &wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: hdr.DestConnectionID},
+			PacketNumberLen: hdr.PacketNumberLen,
+		}
+	}
+	maxDatagramFrameSize := p.maxPacketSize - shortHdr.GetLength(p.version) - protocol.ByteCount(sealer.Overhead())
+	payload := p.maybeGetAppDataPacketWithEncLevel(maxPayloadSize, maxDatagramFrameSize, encLevel == protocol.Encryption1RTT && currentSize == 0)
 	return sealer, hdr, payload
 }
 
-func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize protocol.ByteCount, ackAllowed bool) *payload {
-	payload := p.composeNextPacket(maxPayloadSize, ackAllowed)
+func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize, maxDatagramFrameSize protocol.ByteCount, ackAllowed bool) *payload {
+	payload := p.composeNextPacket(maxPayloadSize, maxDatagramFrameSize, ackAllowed)
 
 	// check if we have anything to send
 	if len(payload.frames) == 0 {
@@ -591,16 +605,18 @@ func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize protocol
 	return payload
 }
 
-func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAllowed bool) *payload {
+// composeNextPacket composes the payload of a 0-RTT or 1-RTT packet.
+// maxDatagramFrameSize is the space available for frames in a full-size 1-RTT packet.
+func (p *packetPacker) composeNextPacket(maxFrameSize, maxDatagramFrameSize protocol.ByteCount, ackAllowed bool) *payload {
 	payload := &payload{frames: make([]ackhandler.Frame, 0, 1)}
 
 	var hasDatagram bool
 	if p.datagramQueue != nil {
-		if datagram := p.datagramQueue.Get(); datagram != nil {
+		if datagram := p.datagramQueue.Get(maxFrameSize, maxDatagramFrameSize, p.version); datagram != nil {
 			payload.frames = append(payload.frames, ackhandler.Frame{
 				Frame: datagram,
-				// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
-				OnLost: func(wire.Frame) {},
+				// Setting the callback prevents the default callback from being set, which would retransmit the frame.
+				OnLost: p.datagramQueue.OnLost,
 			})
 			payload.length += datagram.Length(p.version)
 			hasDatagram = true
@@ -676,7 +692,8 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 		}
 		sealer = oneRTTSealer
 		hdr = p.getShortHeader(oneRTTSealer.KeyPhase())
-		payload = p.maybeGetAppDataPacketWithEncLevel(p.maxPacketSize-protocol.ByteCount(sealer.Overhead())-hdr.GetLength(p.version), true)
+		maxPayloadSize := p.maxPacketSize - protocol.ByteCount(sealer.Overhead()) - hdr.GetLength(p.version)
+		payload = p.maybeGetAppDataPacketWithEncLevel(maxPayloadSize, maxPayloadSize, true)
 	default:
 		panic("unknown encryption level")
 	}
@@ -91,7 +91,7 @@ var _ = Describe("Packet packer", func() {
 		ackFramer = NewMockAckFrameSource(mockCtrl)
 		sealingManager = NewMockSealingManager(mockCtrl)
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
-		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
+		datagramQueue = newDatagramQueue(func() {}, utils.DefaultClock{}, nil, utils.DefaultLogger)
 
 		packer = newPacketPacker(
 			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
@@ -565,7 +565,7 @@ var _ = Describe("Packet packer", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					datagramQueue.AddAndWait(f)
+					datagramQueue.AddAndWait(f, time.Time{})
 				}()
 				// make sure the DATAGRAM has actually been queued
 				time.Sleep(scaleDuration(20 * time.Millisecond))
@@ -580,6 +580,30 @@ var _ = Describe("Packet packer", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("drops DATAGRAM frames that don't fit into the packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           make([]byte, maxPacketSize-10),
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f, time.Time{})
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(p).To(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("accounts for the space consumed by control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
@@ -631,7 +655,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -668,7 +692,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(true, packer.version)
+				frameParser := wire.NewFrameParser(true, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -868,6 +892,58 @@ var _ = Describe("Packet packer", func() {
 				})
 			})
 
+			It("doesn't pack STREAM frames of a corked stream, including retransmissions", func() {
+				streamGetter := NewMockStreamGetter(mockCtrl)
+				realFramer := newFramer(streamGetter, version)
+				packer.framer = realFramer
+				sender := NewMockStreamSender(mockCtrl)
+				sender.EXPECT().onHasStreamData(gomock.Any()).Do(func(id protocol.StreamID) { realFramer.AddActiveStream(id) }).AnyTimes()
+				newStream := func(id protocol.StreamID) *sendStream {
+					fc := mocks.NewMockStreamFlowController(mockCtrl)
+					fc.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+					fc.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+					str := newSendStream(id, sender, fc, version)
+					str.clock = utils.NewVirtualClock(time.Now()) // the cork timer never fires
+					streamGetter.EXPECT().GetOrOpenSendStream(id).Return(str, nil).AnyTimes()
+					return str
+				}
+				corked := newStream(4)
+				other := newStream(8)
+
+				// send some data, and lose the packet
+				_, err := corked.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ := corked.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				corked.SetCorkDelay(protocol.MaxCorkDelay)
+				_, err = corked.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				frame.OnLost(frame.Frame)
+				_, err = other.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame.(*wire.StreamFrame).StreamID).To(Equal(protocol.StreamID(8)))
+
+				// once the stream is uncorked, the retransmission is sent
+				Expect(corked.Flush()).To(Succeed())
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame.(*wire.StreamFrame).StreamID).To(Equal(protocol.StreamID(4)))
+				Expect(p.frames[0].Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			})
+
 			Context("max packet size", func() {
 				It("increases the max packet size", func() {
 					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
@@ -1001,6 +1077,31 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
 			})
 
+			It("packs Initial packets with the frames determined by the ClientHello splitter", func() {
+				str := newCryptoStream()
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				packer.initialStream = newClientHelloSplitter(str, ClientHelloSplitting{Fragments: 2, Reorder: true, Filler: ClientHelloFillerPing}, version)
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24)).Times(2)
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil).Times(2)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable).Times(2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable).Times(2)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false).Times(2)
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].frames).To(HaveLen(1))
+				Expect(p.packets[0].frames[0].Frame).To(Equal(&wire.CryptoFrame{Offset: 3, Data: []byte("bar")}))
+				p, err = packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].frames).To(HaveLen(1))
+				Expect(p.packets[0].frames[0].Frame).To(Equal(&wire.PingFrame{}))
+				Expect(p.buffer.Len()).To(BeEquivalentTo(packer.maxPacketSize))
+				Expect(packer.initialStream.HasData()).To(BeTrue())
+			})
+
 			It("packs a coalesced packet with Initial / super short Handshake, and pads it", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
@@ -1167,6 +1268,49 @@ var _ = Describe("Packet packer", func() {
 				Expect(rest).To(BeEmpty())
 			})
 
+			It("sends a DATAGRAM frame in the next packet if it doesn't fit into the coalesced packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().GetInitialSealer().Return(nil, handshake.ErrKeysDropped)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake, false)
+				handshakeStream.EXPECT().HasData().Return(true).Times(2)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(&wire.CryptoFrame{Data: make([]byte, 600)})
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           make([]byte, 1000),
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f, time.Time{})
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				p, err := packer.PackCoalescedPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
+				Expect(done).ToNot(BeClosed())
+
+				// the next packet has enough space for the DATAGRAM frame
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData()
+				packet, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet).ToNot(BeNil())
+				Expect(packet.frames).To(HaveLen(1))
+				Expect(packet.frames[0].Frame).To(Equal(f))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("doesn't add a coalesced packet if the remaining size is smaller than MaxCoalescedPacketSize", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24))
@@ -1221,7 +1365,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
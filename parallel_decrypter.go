@@ -0,0 +1,199 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// A decryptionJob is a 1-RTT packet that is being unpacked by a worker.
+type decryptionJob struct {
+	opener handshake.ParallelShortHeaderOpener
+	state  handshake.DecryptionState
+	hdr    *wire.Header
+	data   []byte
+	done   chan struct{}
+
+	// set by the worker
+	extHdr    *wire.ExtendedHeader
+	parseErr  error // the error returned when unpacking the header
+	wirePN    protocol.PacketNumber
+	pn        protocol.PacketNumber // the packet number, as decoded by the worker
+	key       *handshake.DecryptionKey
+	buf       *packetBuffer
+	decrypted []byte
+	err       error
+}
+
+// Wait waits until the worker is done with the packet.
+func (j *decryptionJob) Wait() {
+	<-j.done
+}
+
+// Release returns the buffer holding the decrypted payload.
+// It must be called once the unpacked packet was handled.
+func (j *decryptionJob) Release() {
+	if j.buf != nil {
+		j.buf.Release()
+		j.buf = nil
+	}
+}
+
+// The parallelDecrypter unpacks 1-RTT packets on a pool of worker Go routines.
+// The workers remove header protection, decode the packet number and decrypt the payload,
+// based on a snapshot of the state of the 1-RTT keys.
+// The packets are then unpacked in order, and the result of the decryption is only used
+// if it was decrypted with the key (and the packet number) that would have been used
+// when decrypting the packets one by one. Otherwise, the packet is decrypted again.
+type parallelDecrypter struct {
+	cs           handshake.CryptoSetup
+	srcConnIDLen int
+	version      protocol.VersionNumber
+
+	numWorkers int
+	jobs       chan *decryptionJob // nil until the workers are started
+}
+
+func newParallelDecrypter(cs handshake.CryptoSetup, numWorkers, srcConnIDLen int, version protocol.VersionNumber) *parallelDecrypter {
+	return &parallelDecrypter{
+		cs:           cs,
+		srcConnIDLen: srcConnIDLen,
+		version:      version,
+		numWorkers:   numWorkers,
+	}
+}
+
+// Prepare starts unpacking a 1-RTT packet.
+// If the packet can't be prepared (e.g. because it's a long header packet), it is left untouched.
+// Otherwise, the packet's data must not be accessed before the job's Wait returns.
+// Prepared packets must be passed to Unpack, in the order they were prepared.
+func (d *parallelDecrypter) Prepare(p *receivedPacket) {
+	hdr, _, _, err := wire.ParsePacket(p.data, d.srcConnIDLen)
+	if err != nil || hdr.IsLongHeader {
+		return
+	}
+	o, err := d.cs.Get1RTTOpener()
+	if err != nil {
+		return
+	}
+	opener, ok := o.(handshake.ParallelShortHeaderOpener)
+	if !ok {
+		return
+	}
+	job := &decryptionJob{
+		opener: opener,
+		state:  opener.DecryptionState(),
+		hdr:    hdr,
+		data:   p.data,
+		done:   make(chan struct{}),
+	}
+	p.decryption = job
+	if d.jobs == nil {
+		d.startWorkers()
+	}
+	d.jobs <- job
+}
+
+func (d *parallelDecrypter) startWorkers() {
+	d.jobs = make(chan *decryptionJob, protocol.MaxConnUnprocessedPackets)
+	for i := 0; i < d.numWorkers; i++ {
+		go d.runWorker()
+	}
+}
+
+func (d *parallelDecrypter) runWorker() {
+	opener := handshake.NewPayloadOpener()
+	hd := &workerHeaderDecryptor{opener: opener}
+	for job := range d.jobs {
+		hd.state = job.state
+		d.unpack(opener, hd, job)
+		close(job.done)
+	}
+}
+
+// unpack is run on the worker.
+func (d *parallelDecrypter) unpack(opener *handshake.PayloadOpener, hd headerDecryptor, job *decryptionJob) {
+	extHdr, err := unpackHeader(hd, job.hdr, job.data, d.version)
+	if err != nil && err != wire.ErrInvalidReservedBits {
+		job.parseErr = &headerParseError{err: err}
+		return
+	}
+	job.extHdr = extHdr
+	job.parseErr = err
+	job.wirePN = extHdr.PacketNumber
+	job.pn = job.state.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	key, err := job.state.Key(job.pn, extHdr.KeyPhase)
+	if err != nil {
+		// The packet will be opened by Unpack, which will return the same error.
+		return
+	}
+	extHdrLen := extHdr.ParsedLen()
+	job.key = key
+	// Decrypt into a separate buffer, so that the packet can be decrypted again
+	// if it turns out that it needs to be opened with a different key.
+	job.buf = getPacketBuffer()
+	job.decrypted, job.err = opener.Open(job.buf.Data[:0], job.data[extHdrLen:], key, job.pn, job.data[:extHdrLen])
+}
+
+// The workerHeaderDecryptor removes header protection using the worker's PayloadOpener.
+type workerHeaderDecryptor struct {
+	opener *handshake.PayloadOpener
+	state  handshake.DecryptionState
+}
+
+func (h *workerHeaderDecryptor) DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte) {
+	h.opener.DecryptHeader(&h.state, sample, firstByte, pnBytes)
+}
+
+// Unpack unpacks a packet prepared by Prepare.
+// The packet number is decoded again, and the key phase is selected,
+// taking into account the packets that were unpacked before.
+// The errors are the same as the errors returned by packetUnpacker.Unpack.
+// The decrypted payload is only valid until the job is released.
+func (d *parallelDecrypter) Unpack(job *decryptionJob, rcvTime time.Time, data []byte) (*unpackedPacket, error) {
+	job.Wait()
+	if job.extHdr == nil {
+		return nil, job.parseErr
+	}
+	key := job.key
+	extHdr := job.extHdr
+	// The packet number might decode differently now that the preceding packets were processed.
+	extHdr.PacketNumber = job.opener.DecodePacketNumber(job.wirePN, extHdr.PacketNumberLen)
+	if extHdr.PacketNumber != job.pn {
+		key = nil
+	}
+	extHdrLen := extHdr.ParsedLen()
+	decrypted, err := job.opener.OpenDecrypted(
+		data[extHdrLen:extHdrLen],
+		data[extHdrLen:],
+		rcvTime,
+		extHdr.PacketNumber,
+		extHdr.KeyPhase,
+		data[:extHdrLen],
+		key,
+		job.decrypted,
+		job.err,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if job.parseErr != nil {
+		return nil, job.parseErr
+	}
+	return &unpackedPacket{
+		hdr:             extHdr,
+		packetNumber:    extHdr.PacketNumber,
+		encryptionLevel: protocol.Encryption1RTT,
+		data:            decrypted,
+	}, nil
+}
+
+// Close stops the workers.
+// It must not be called concurrently with Prepare.
+func (d *parallelDecrypter) Close() {
+	if d.jobs != nil {
+		close(d.jobs)
+	}
+}
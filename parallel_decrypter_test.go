@@ -0,0 +1,213 @@
+package quic
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parallel Decrypter", func() {
+	const version = protocol.VersionTLS
+
+	var (
+		decrypter *parallelDecrypter
+		cs        *mocks.MockCryptoSetup
+		sealer    handshake.ShortHeaderSealer
+		connID    = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+	)
+
+	setup := func(keyUpdateInterval, invalidPacketLimit uint64) {
+		var opener handshake.ParallelShortHeaderOpener
+		sealer, opener = handshake.NewUpdatableAEADPair(keyUpdateInterval, invalidPacketLimit, version)
+		cs = mocks.NewMockCryptoSetup(mockCtrl)
+		cs.EXPECT().Get1RTTOpener().Return(opener, nil).AnyTimes()
+		decrypter = newParallelDecrypter(cs, 4, connID.Len(), version)
+	}
+
+	AfterEach(func() {
+		decrypter.Close()
+	})
+
+	getPayload := func(pn protocol.PacketNumber) []byte {
+		return []byte(fmt.Sprintf("packet payload, packet number %d", pn))
+	}
+
+	// packPacket seals a 1-RTT packet, and applies header protection.
+	packPacket := func(pn protocol.PacketNumber, pnLen protocol.PacketNumberLen) *receivedPacket {
+		extHdr := &wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: connID},
+			PacketNumber:    pn,
+			PacketNumberLen: pnLen,
+			KeyPhase:        sealer.KeyPhase(),
+		}
+		buf := &bytes.Buffer{}
+		ExpectWithOffset(1, extHdr.Write(buf, version)).To(Succeed())
+		payloadOffset := buf.Len()
+		data := buf.Bytes()
+		data = sealer.Seal(data, getPayload(pn), pn, data[:payloadOffset])
+		pnOffset := payloadOffset - int(pnLen)
+		sealer.EncryptHeader(data[pnOffset+4:pnOffset+4+16], &data[0], data[pnOffset:payloadOffset])
+		return &receivedPacket{
+			rcvTime: time.Now(),
+			data:    data,
+			buffer:  getPacketBuffer(),
+		}
+	}
+
+	prepare := func(packets []*receivedPacket) {
+		for _, p := range packets {
+			decrypter.Prepare(p)
+			ExpectWithOffset(1, p.decryption).ToNot(BeNil())
+		}
+	}
+
+	unpack := func(p *receivedPacket) (*unpackedPacket, error) {
+		defer p.buffer.Release()
+		defer p.decryption.Release()
+		unpacked, err := decrypter.Unpack(p.decryption, p.rcvTime, p.data)
+		if err != nil {
+			return nil, err
+		}
+		// copy the payload, since it's only valid until the job is released
+		unpacked.data = append([]byte{}, unpacked.data...)
+		return unpacked, nil
+	}
+
+	It("doesn't prepare long header packets", func() {
+		setup(0, 0)
+		hdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				DestConnectionID: connID,
+				Version:          version,
+			},
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		buf := &bytes.Buffer{}
+		Expect(hdr.Write(buf, version)).To(Succeed())
+		buf.Write(make([]byte, 20))
+		p := &receivedPacket{data: buf.Bytes()}
+		decrypter.Prepare(p)
+		Expect(p.decryption).To(BeNil())
+	})
+
+	It("unpacks packets in the order they were prepared", func() {
+		setup(0, 0)
+		packets := make([]*receivedPacket, 100)
+		for i := range packets {
+			packets[i] = packPacket(protocol.PacketNumber(i), protocol.PacketNumberLen2)
+		}
+		prepare(packets)
+		for i, p := range packets {
+			unpacked, err := unpack(p)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unpacked.encryptionLevel).To(Equal(protocol.Encryption1RTT))
+			Expect(unpacked.packetNumber).To(Equal(protocol.PacketNumber(i)))
+			Expect(unpacked.hdr.KeyPhase).To(Equal(protocol.KeyPhaseZero))
+			Expect(unpacked.data).To(Equal(getPayload(protocol.PacketNumber(i))))
+		}
+	})
+
+	It("decrypts a packet again if its packet number decodes differently after the preceding packets", func() {
+		setup(0, 0)
+		// With a 1 byte packet number, the workers decode the packet numbers
+		// of the later packets relative to the packet numbers received before the batch.
+		packets := make([]*receivedPacket, 600)
+		for i := range packets {
+			packets[i] = packPacket(protocol.PacketNumber(i), protocol.PacketNumberLen1)
+		}
+		prepare(packets)
+		for i, p := range packets {
+			unpacked, err := unpack(p)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unpacked.packetNumber).To(Equal(protocol.PacketNumber(i)))
+			Expect(unpacked.data).To(Equal(getPayload(protocol.PacketNumber(i))))
+		}
+	})
+
+	It("handles a key update in the middle of a batch", func() {
+		const keyUpdateInterval = 10
+		setup(keyUpdateInterval, 0)
+		packets := make([]*receivedPacket, 3*keyUpdateInterval)
+		for i := range packets {
+			packets[i] = packPacket(protocol.PacketNumber(i), protocol.PacketNumberLen2)
+		}
+		// The last packet sent with the old keys is reordered,
+		// and received after the first packet sent with the new keys.
+		reordered := packets[keyUpdateInterval-1]
+		packets[keyUpdateInterval-1] = packets[keyUpdateInterval]
+		packets[keyUpdateInterval] = reordered
+		prepare(packets)
+		for _, p := range packets {
+			unpacked, err := unpack(p)
+			Expect(err).ToNot(HaveOccurred())
+			pn := unpacked.packetNumber
+			Expect(unpacked.data).To(Equal(getPayload(pn)))
+			if pn < keyUpdateInterval {
+				Expect(unpacked.hdr.KeyPhase).To(Equal(protocol.KeyPhaseZero))
+			} else {
+				Expect(unpacked.hdr.KeyPhase).To(Equal(protocol.KeyPhaseOne))
+			}
+		}
+	})
+
+	It("doesn't use the decryption result if the key was dropped after the packet was prepared", func() {
+		const keyUpdateInterval = 10
+		setup(keyUpdateInterval, 0)
+		packets := make([]*receivedPacket, keyUpdateInterval+1)
+		for i := range packets {
+			packets[i] = packPacket(protocol.PacketNumber(i), protocol.PacketNumberLen2)
+		}
+		// Unpack all packets up to the first packet sent with the new keys.
+		// This starts the timer to drop the old keys.
+		delayed := packets[keyUpdateInterval-1]
+		packets = append(packets[:keyUpdateInterval-1], packets[keyUpdateInterval])
+		prepare(packets)
+		for _, p := range packets {
+			_, err := unpack(p)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		// The delayed packet is decrypted by the worker using the old keys.
+		prepare([]*receivedPacket{delayed})
+		delayed.decryption.Wait()
+		Expect(delayed.decryption.err).ToNot(HaveOccurred())
+		// By the time it is unpacked, the old keys were dropped.
+		delayed.rcvTime = time.Now().Add(time.Hour)
+		_, err := unpack(delayed)
+		Expect(err).To(MatchError(handshake.ErrKeysDropped))
+	})
+
+	It("counts packets that fail to decrypt towards the integrity limit", func() {
+		const invalidPacketLimit = 5
+		setup(0, invalidPacketLimit)
+		packets := make([]*receivedPacket, 2*invalidPacketLimit)
+		for i := range packets {
+			packets[i] = packPacket(protocol.PacketNumber(i), protocol.PacketNumberLen2)
+			if i%2 == 1 {
+				packets[i].data[len(packets[i].data)-1] ^= 0xff // corrupt the authentication tag
+			}
+		}
+		prepare(packets)
+		for i, p := range packets[:len(packets)-1] {
+			_, err := unpack(p)
+			if i%2 == 0 {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(handshake.ErrDecryptionFailed))
+			}
+		}
+		_, err := unpack(packets[len(packets)-1])
+		Expect(err).To(MatchError(&qerr.TransportError{ErrorCode: qerr.AEADLimitReached}))
+	})
+})
@@ -84,6 +84,31 @@ func (e eventConnectionStarted) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("dst_cid", connectionID(e.DestConnectionID).String())
 }
 
+type eventHandshakeStarted struct {
+	ServerName         string
+	SupportedProtocols []string
+}
+
+func (e eventHandshakeStarted) Category() category { return categoryTransport }
+func (e eventHandshakeStarted) Name() string       { return "handshake_started" }
+func (e eventHandshakeStarted) IsNil() bool        { return false }
+
+func (e eventHandshakeStarted) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKeyOmitEmpty("server_name", e.ServerName)
+	if len(e.SupportedProtocols) > 0 {
+		enc.ArrayKey("client_alpns", alpns(e.SupportedProtocols))
+	}
+}
+
+type alpns []string
+
+func (a alpns) IsNil() bool { return false }
+func (a alpns) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, p := range a {
+		enc.AddString(p)
+	}
+}
+
 type eventVersionNegotiated struct {
 	clientVersions, serverVersions []versionNumber
 	chosenVersion                  versionNumber
@@ -312,6 +337,20 @@ func (e eventUpdatedPTO) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint32Key("pto_count", e.Value)
 }
 
+type eventFlowLabelUpdated struct {
+	FlowLabel uint32
+	Trigger   flowLabelTrigger
+}
+
+func (e eventFlowLabelUpdated) Category() category { return categoryConnectivity }
+func (e eventFlowLabelUpdated) Name() string       { return "flow_label_updated" }
+func (e eventFlowLabelUpdated) IsNil() bool        { return false }
+
+func (e eventFlowLabelUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint32Key("flow_label", e.FlowLabel)
+	enc.StringKey("trigger", e.Trigger.String())
+}
+
 type eventPacketLost struct {
 	PacketType   logging.PacketType
 	PacketNumber protocol.PacketNumber
@@ -504,7 +543,8 @@ func (e eventLossTimerCanceled) MarshalJSONObject(enc *gojay.Encoder) {
 }
 
 type eventCongestionStateUpdated struct {
-	state congestionState
+	state   congestionState
+	trigger congestionStateTrigger
 }
 
 func (e eventCongestionStateUpdated) Category() category { return categoryRecovery }
@@ -513,6 +553,111 @@ func (e eventCongestionStateUpdated) IsNil() bool        { return false }
 
 func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("new", e.state.String())
+	if trigger := e.trigger.String(); trigger != "" {
+		enc.StringKey("trigger", trigger)
+	}
+}
+
+type eventCongestionParametersSet struct {
+	InitialCongestionWindow protocol.ByteCount
+	MinCongestionWindow     protocol.ByteCount
+	MaxCongestionWindow     protocol.ByteCount
+	LossReductionFactor     float64
+}
+
+func (e eventCongestionParametersSet) Category() category { return categoryRecovery }
+func (e eventCongestionParametersSet) Name() string       { return "parameters_set" }
+func (e eventCongestionParametersSet) IsNil() bool        { return false }
+
+func (e eventCongestionParametersSet) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("initial_congestion_window", uint64(e.InitialCongestionWindow))
+	enc.Uint64Key("minimum_congestion_window", uint64(e.MinCongestionWindow))
+	// not defined in the qlog draft
+	enc.Uint64Key("maximum_congestion_window", uint64(e.MaxCongestionWindow))
+	enc.Float64Key("loss_reduction_factor", e.LossReductionFactor)
+}
+
+type eventPacingRateUpdated struct {
+	Rate uint64
+}
+
+func (e eventPacingRateUpdated) Category() category { return categoryRecovery }
+func (e eventPacingRateUpdated) Name() string       { return "metrics_updated" }
+func (e eventPacingRateUpdated) IsNil() bool        { return false }
+
+func (e eventPacingRateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("pacing_rate", e.Rate)
+}
+
+type cubicState struct {
+	EpochStart                    time.Duration // relative to the reference time, 0 if no epoch has started
+	LastMaxCongestionWindow       protocol.ByteCount
+	OriginPointCongestionWindow   protocol.ByteCount
+	TimeToOriginPoint             time.Duration
+	EstimatedRenoCongestionWindow protocol.ByteCount
+}
+
+func (s cubicState) IsNil() bool { return false }
+func (s cubicState) MarshalJSONObject(enc *gojay.Encoder) {
+	if s.EpochStart != 0 {
+		enc.Float64Key("epoch_start", milliseconds(s.EpochStart))
+	}
+	enc.Uint64Key("last_max_congestion_window", uint64(s.LastMaxCongestionWindow))
+	enc.Uint64Key("origin_point_congestion_window", uint64(s.OriginPointCongestionWindow))
+	enc.Float64Key("time_to_origin_point", milliseconds(s.TimeToOriginPoint))
+	enc.Uint64Key("estimated_reno_congestion_window", uint64(s.EstimatedRenoCongestionWindow))
+}
+
+type eventCongestionMetricsUpdated struct {
+	BandwidthEstimate uint64
+	Cubic             *cubicState
+}
+
+func (e eventCongestionMetricsUpdated) Category() category { return categoryRecovery }
+func (e eventCongestionMetricsUpdated) Name() string       { return "congestion_metrics_updated" }
+func (e eventCongestionMetricsUpdated) IsNil() bool        { return false }
+
+func (e eventCongestionMetricsUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	// not defined in the qlog draft
+	enc.Uint64Key("bandwidth_estimate", e.BandwidthEstimate)
+	if e.Cubic != nil {
+		enc.ObjectKey("cubic", e.Cubic)
+	}
+}
+
+type eventFaultInjected struct {
+	Direction  faultDirection
+	Action     faultAction
+	PacketType logging.PacketType
+	PacketSize protocol.ByteCount
+}
+
+func (e eventFaultInjected) Category() category { return categoryTransport }
+func (e eventFaultInjected) Name() string       { return "fault_injected" }
+func (e eventFaultInjected) IsNil() bool        { return false }
+
+func (e eventFaultInjected) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("direction", e.Direction.String())
+	enc.StringKey("action", e.Action.String())
+	enc.ObjectKey("header", packetHeaderWithType{PacketType: e.PacketType})
+	enc.ObjectKey("raw", rawInfo{Length: e.PacketSize})
+}
+
+// The DATAGRAM events are not defined in the qlog draft.
+type eventDatagram struct {
+	category category
+	name     string
+	Length   protocol.ByteCount
+	Trigger  string // only set for dropped DATAGRAM frames
+}
+
+func (e eventDatagram) Category() category { return e.category }
+func (e eventDatagram) Name() string       { return e.name }
+func (e eventDatagram) IsNil() bool        { return false }
+
+func (e eventDatagram) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("length", uint64(e.Length))
+	enc.StringKeyOmitEmpty("trigger", e.Trigger)
 }
 
 type eventGeneric struct {
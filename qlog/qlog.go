@@ -60,9 +60,9 @@ func NewTracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.
 	return &tracer{getLogWriter: getLogWriter}
 }
 
-func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+func (t *tracer) TracerForConnection(ctx context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
 	if w := t.getLogWriter(p, odcid.Bytes()); w != nil {
-		return NewConnectionTracer(w, p, odcid)
+		return newConnectionTracer(w, p, odcid, logging.TimeSource(ctx))
 	}
 	return nil
 }
@@ -77,6 +77,7 @@ type connectionTracer struct {
 	w             io.WriteCloser
 	odcid         protocol.ConnectionID
 	perspective   protocol.Perspective
+	now           func() time.Time
 	referenceTime time.Time
 
 	events     chan event
@@ -84,19 +85,30 @@ type connectionTracer struct {
 	runStopped chan struct{}
 
 	lastMetrics *metrics
+
+	lastPacingRate        uint64
+	lastCongestionMetrics *eventCongestionMetricsUpdated
 }
 
-var _ logging.ConnectionTracer = &connectionTracer{}
+var (
+	_ logging.ConnectionTracer             = &connectionTracer{}
+	_ logging.CongestionStateTriggerTracer = &connectionTracer{}
+)
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
 func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	return newConnectionTracer(w, p, odcid, time.Now)
+}
+
+func newConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID, now func() time.Time) *connectionTracer {
 	t := &connectionTracer{
 		w:             w,
 		perspective:   p,
 		odcid:         odcid,
+		now:           now,
 		runStopped:    make(chan struct{}),
 		events:        make(chan event, eventChanSize),
-		referenceTime: time.Now(),
+		referenceTime: now(),
 	}
 	go t.run()
 	return t
@@ -174,7 +186,7 @@ func (t *connectionTracer) StartedConnection(local, remote net.Addr, srcConnID,
 		return
 	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventConnectionStarted{
+	t.recordEvent(t.now(), &eventConnectionStarted{
 		SrcAddr:          localAddr,
 		DestAddr:         remoteAddr,
 		SrcConnectionID:  srcConnID,
@@ -183,6 +195,15 @@ func (t *connectionTracer) StartedConnection(local, remote net.Addr, srcConnID,
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) StartedHandshake(_ context.Context, info *logging.HandshakeInfo) {
+	t.mutex.Lock()
+	t.recordEvent(t.now(), &eventHandshakeStarted{
+		ServerName:         info.ServerName,
+		SupportedProtocols: info.SupportedProtocols,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, client, server []logging.VersionNumber) {
 	var clientVersions, serverVersions []versionNumber
 	if len(client) > 0 {
@@ -198,7 +219,7 @@ func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, clien
 		}
 	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventVersionNegotiated{
+	t.recordEvent(t.now(), &eventVersionNegotiated{
 		clientVersions: clientVersions,
 		serverVersions: serverVersions,
 		chosenVersion:  versionNumber(chosen),
@@ -208,7 +229,7 @@ func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, clien
 
 func (t *connectionTracer) ClosedConnection(e error) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventConnectionClosed{e: e})
+	t.recordEvent(t.now(), &eventConnectionClosed{e: e})
 	t.mutex.Unlock()
 }
 
@@ -225,7 +246,7 @@ func (t *connectionTracer) RestoredTransportParameters(tp *wire.TransportParamet
 	ev.Restore = true
 
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), ev)
+	t.recordEvent(t.now(), ev)
 	t.mutex.Unlock()
 }
 
@@ -238,7 +259,7 @@ func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective
 	ev.SentBy = sentBy
 
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), ev)
+	t.recordEvent(t.now(), ev)
 	t.mutex.Unlock()
 }
 
@@ -290,7 +311,7 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize loggi
 	}
 	header := *transformExtendedHeader(hdr)
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketSent{
+	t.recordEvent(t.now(), &eventPacketSent{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: hdr.Length,
@@ -306,7 +327,7 @@ func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize l
 	}
 	header := *transformExtendedHeader(hdr)
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketReceived{
+	t.recordEvent(t.now(), &eventPacketReceived{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: hdr.Length,
@@ -317,7 +338,7 @@ func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize l
 
 func (t *connectionTracer) ReceivedRetry(hdr *wire.Header) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventRetryReceived{
+	t.recordEvent(t.now(), &eventRetryReceived{
 		Header: *transformHeader(hdr),
 	})
 	t.mutex.Unlock()
@@ -329,7 +350,7 @@ func (t *connectionTracer) ReceivedVersionNegotiationPacket(hdr *wire.Header, ve
 		ver[i] = versionNumber(v)
 	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventVersionNegotiationReceived{
+	t.recordEvent(t.now(), &eventVersionNegotiationReceived{
 		Header:            *transformHeader(hdr),
 		SupportedVersions: ver,
 	})
@@ -338,13 +359,13 @@ func (t *connectionTracer) ReceivedVersionNegotiationPacket(hdr *wire.Header, ve
 
 func (t *connectionTracer) BufferedPacket(pt logging.PacketType) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketBuffered{PacketType: pt})
+	t.recordEvent(t.now(), &eventPacketBuffered{PacketType: pt})
 	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) DroppedPacket(pt logging.PacketType, size protocol.ByteCount, reason logging.PacketDropReason) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketDropped{
+	t.recordEvent(t.now(), &eventPacketDropped{
 		PacketType: pt,
 		PacketSize: size,
 		Trigger:    packetDropReason(reason),
@@ -363,7 +384,7 @@ func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesI
 		PacketsInFlight:  packetsInFlight,
 	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventMetricsUpdated{
+	t.recordEvent(t.now(), &eventMetricsUpdated{
 		Last:    t.lastMetrics,
 		Current: m,
 	})
@@ -375,7 +396,7 @@ func (t *connectionTracer) AcknowledgedPacket(protocol.EncryptionLevel, protocol
 
 func (t *connectionTracer) LostPacket(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason logging.PacketLossReason) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketLost{
+	t.recordEvent(t.now(), &eventPacketLost{
 		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
 		PacketNumber: pn,
 		Trigger:      packetLossReason(lossReason),
@@ -384,20 +405,78 @@ func (t *connectionTracer) LostPacket(encLevel protocol.EncryptionLevel, pn prot
 }
 
 func (t *connectionTracer) UpdatedCongestionState(state logging.CongestionState) {
+	t.UpdatedCongestionStateWithTrigger(state, logging.CongestionStateTriggerNone)
+}
+
+func (t *connectionTracer) UpdatedCongestionStateWithTrigger(state logging.CongestionState, trigger logging.CongestionStateTrigger) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventCongestionStateUpdated{state: congestionState(state)})
+	t.recordEvent(t.now(), &eventCongestionStateUpdated{
+		state:   congestionState(state),
+		trigger: congestionStateTrigger(trigger),
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedCongestionParameters(params *logging.CongestionParameters) {
+	t.mutex.Lock()
+	t.recordEvent(t.now(), &eventCongestionParametersSet{
+		InitialCongestionWindow: params.InitialCongestionWindow,
+		MinCongestionWindow:     params.MinCongestionWindow,
+		MaxCongestionWindow:     params.MaxCongestionWindow,
+		LossReductionFactor:     params.LossReductionFactor,
+	})
+	t.mutex.Unlock()
+}
+
+// UpdatedCongestionMetrics is called once per ACK frame.
+// Only values that changed are recorded.
+func (t *connectionTracer) UpdatedCongestionMetrics(m *logging.CongestionMetrics) {
+	ev := &eventCongestionMetricsUpdated{BandwidthEstimate: m.BandwidthEstimate}
+	if m.Cubic != nil {
+		ev.Cubic = &cubicState{
+			LastMaxCongestionWindow:       m.Cubic.LastMaxCongestionWindow,
+			OriginPointCongestionWindow:   m.Cubic.OriginPointCongestionWindow,
+			TimeToOriginPoint:             m.Cubic.TimeToOriginPoint,
+			EstimatedRenoCongestionWindow: m.Cubic.EstimatedRenoCongestionWindow,
+		}
+		if !m.Cubic.EpochStart.IsZero() {
+			ev.Cubic.EpochStart = m.Cubic.EpochStart.Sub(t.referenceTime)
+		}
+	}
+	t.mutex.Lock()
+	now := t.now()
+	if m.PacingRate != t.lastPacingRate {
+		t.recordEvent(now, &eventPacingRateUpdated{Rate: m.PacingRate})
+		t.lastPacingRate = m.PacingRate
+	}
+	if last := t.lastCongestionMetrics; last == nil ||
+		last.BandwidthEstimate != ev.BandwidthEstimate ||
+		(last.Cubic == nil) != (ev.Cubic == nil) ||
+		(last.Cubic != nil && *last.Cubic != *ev.Cubic) {
+		t.recordEvent(now, ev)
+		t.lastCongestionMetrics = ev
+	}
 	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventUpdatedPTO{Value: value})
+	t.recordEvent(t.now(), &eventUpdatedPTO{Value: value})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedFlowLabel(label uint32, trigger logging.FlowLabelTrigger) {
+	t.mutex.Lock()
+	t.recordEvent(t.now(), &eventFlowLabelUpdated{
+		FlowLabel: label,
+		Trigger:   flowLabelTrigger(trigger),
+	})
 	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) UpdatedKeyFromTLS(encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventKeyUpdated{
+	t.recordEvent(t.now(), &eventKeyUpdated{
 		Trigger: keyUpdateTLS,
 		KeyType: encLevelToKeyType(encLevel, pers),
 	})
@@ -410,7 +489,7 @@ func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool)
 		trigger = keyUpdateRemote
 	}
 	t.mutex.Lock()
-	now := time.Now()
+	now := t.now()
 	t.recordEvent(now, &eventKeyUpdated{
 		Trigger:    trigger,
 		KeyType:    keyTypeClient1RTT,
@@ -426,7 +505,7 @@ func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool)
 
 func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	t.mutex.Lock()
-	now := time.Now()
+	now := t.now()
 	if encLevel == protocol.Encryption0RTT {
 		t.recordEvent(now, &eventKeyRetired{KeyType: encLevelToKeyType(encLevel, t.perspective)})
 	} else {
@@ -438,7 +517,7 @@ func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLe
 
 func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 	t.mutex.Lock()
-	now := time.Now()
+	now := t.now()
 	t.recordEvent(now, &eventKeyRetired{
 		KeyType:    encLevelToKeyType(protocol.Encryption1RTT, protocol.PerspectiveServer),
 		Generation: generation,
@@ -452,7 +531,7 @@ func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 
 func (t *connectionTracer) SetLossTimer(tt logging.TimerType, encLevel protocol.EncryptionLevel, timeout time.Time) {
 	t.mutex.Lock()
-	now := time.Now()
+	now := t.now()
 	t.recordEvent(now, &eventLossTimerSet{
 		TimerType: timerType(tt),
 		EncLevel:  encLevel,
@@ -463,7 +542,7 @@ func (t *connectionTracer) SetLossTimer(tt logging.TimerType, encLevel protocol.
 
 func (t *connectionTracer) LossTimerExpired(tt logging.TimerType, encLevel protocol.EncryptionLevel) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventLossTimerExpired{
+	t.recordEvent(t.now(), &eventLossTimerExpired{
 		TimerType: timerType(tt),
 		EncLevel:  encLevel,
 	})
@@ -472,13 +551,55 @@ func (t *connectionTracer) LossTimerExpired(tt logging.TimerType, encLevel proto
 
 func (t *connectionTracer) LossTimerCanceled() {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventLossTimerCanceled{})
+	t.recordEvent(t.now(), &eventLossTimerCanceled{})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) InjectedFault(dir logging.FaultDirection, action logging.FaultAction, pt logging.PacketType, size protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(t.now(), &eventFaultInjected{
+		Direction:  faultDirection(dir),
+		Action:     faultAction(action),
+		PacketType: pt,
+		PacketSize: size,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) QueuedDatagram(length protocol.ByteCount) {
+	t.recordDatagramEvent(categoryTransport, "datagram_queued", length, "")
+}
+
+func (t *connectionTracer) SentDatagram(length protocol.ByteCount) {
+	t.recordDatagramEvent(categoryTransport, "datagram_sent", length, "")
+}
+
+func (t *connectionTracer) DroppedDatagram(length protocol.ByteCount, reason logging.DatagramDropReason) {
+	t.recordDatagramEvent(categoryTransport, "datagram_dropped", length, datagramDropReason(reason).String())
+}
+
+func (t *connectionTracer) ReceivedDatagram(length protocol.ByteCount) {
+	t.recordDatagramEvent(categoryTransport, "datagram_received", length, "")
+}
+
+func (t *connectionTracer) LostDatagram(length protocol.ByteCount) {
+	t.recordDatagramEvent(categoryRecovery, "datagram_lost", length, "")
+}
+
+func (t *connectionTracer) recordDatagramEvent(cat category, name string, length protocol.ByteCount, trigger string) {
+	t.mutex.Lock()
+	t.recordEvent(t.now(), &eventDatagram{
+		category: cat,
+		name:     name,
+		Length:   length,
+		Trigger:  trigger,
+	})
 	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) Debug(name, msg string) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventGeneric{
+	t.recordEvent(t.now(), &eventGeneric{
 		name: name,
 		msg:  msg,
 	})
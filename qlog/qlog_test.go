@@ -56,6 +56,29 @@ var _ = Describe("Tracing", func() {
 			t := NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return nil })
 			Expect(t.TracerForConnection(context.Background(), logging.PerspectiveClient, logging.ConnectionID{1, 2, 3, 4})).To(BeNil())
 		})
+
+		It("uses the time source from the context", func() {
+			buf := &bytes.Buffer{}
+			t := NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return nopWriteCloser(buf) })
+			now := time.Unix(1234, 0)
+			ctx := logging.WithTimeSource(context.Background(), func() time.Time { return now })
+			tracer := t.TracerForConnection(ctx, logging.PerspectiveClient, logging.ConnectionID{1, 2, 3, 4})
+			now = now.Add(1337 * time.Millisecond)
+			tracer.UpdatedPTOCount(1)
+			tracer.Close()
+
+			var m map[string]interface{}
+			line, err := buf.ReadBytes('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(line, &m)).To(Succeed())
+			commonFields := m["trace"].(map[string]interface{})["common_fields"].(map[string]interface{})
+			Expect(commonFields).To(HaveKeyWithValue("reference_time", float64(1234000)))
+			line, err = buf.ReadBytes('\n')
+			Expect(err).ToNot(HaveOccurred())
+			var ev map[string]interface{}
+			Expect(json.Unmarshal(line, &ev)).To(Succeed())
+			Expect(ev).To(HaveKeyWithValue("time", float64(1337)))
+		})
 	})
 
 	It("stops writing when encountering an error", func() {
@@ -171,6 +194,27 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("dst_cid", "05060708"))
 			})
 
+			It("records the start of the handshake", func() {
+				tracer.StartedHandshake(context.Background(), &logging.HandshakeInfo{
+					ServerName:         "quic-go.net",
+					SupportedProtocols: []string{"h3", "hq-interop"},
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:handshake_started"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("server_name", "quic-go.net"))
+				Expect(ev).To(HaveKeyWithValue("client_alpns", []interface{}{"h3", "hq-interop"}))
+			})
+
+			It("records the start of the handshake, if no SNI and ALPN were sent", func() {
+				tracer.StartedHandshake(context.Background(), &logging.HandshakeInfo{})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:handshake_started"))
+				Expect(entry.Event).ToNot(HaveKey("server_name"))
+				Expect(entry.Event).ToNot(HaveKey("client_alpns"))
+			})
+
 			It("records the version, if no version negotiation happened", func() {
 				tracer.NegotiatedVersion(0x1337, nil, nil)
 				entry := exportAndParseSingle()
@@ -600,6 +644,47 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "payload_decrypt_error"))
 			})
 
+			It("records DATAGRAM events", func() {
+				tracer.QueuedDatagram(1337)
+				tracer.SentDatagram(1337)
+				tracer.LostDatagram(1337)
+				tracer.ReceivedDatagram(42)
+				tracer.DroppedDatagram(43, logging.DatagramDropReceiveQueueFull)
+				tracer.DroppedDatagram(1400, logging.DatagramDropTooLarge)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(6))
+				Expect(entries[0].Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entries[0].Name).To(Equal("transport:datagram_queued"))
+				Expect(entries[0].Event).To(Equal(map[string]interface{}{"length": float64(1337)}))
+				Expect(entries[1].Name).To(Equal("transport:datagram_sent"))
+				Expect(entries[1].Event).To(Equal(map[string]interface{}{"length": float64(1337)}))
+				Expect(entries[2].Name).To(Equal("recovery:datagram_lost"))
+				Expect(entries[2].Event).To(Equal(map[string]interface{}{"length": float64(1337)}))
+				Expect(entries[3].Name).To(Equal("transport:datagram_received"))
+				Expect(entries[3].Event).To(Equal(map[string]interface{}{"length": float64(42)}))
+				Expect(entries[4].Name).To(Equal("transport:datagram_dropped"))
+				Expect(entries[4].Event).To(HaveKeyWithValue("length", float64(43)))
+				Expect(entries[4].Event).To(HaveKeyWithValue("trigger", "receive_queue_full"))
+				Expect(entries[5].Name).To(Equal("transport:datagram_dropped"))
+				Expect(entries[5].Event).To(HaveKeyWithValue("trigger", "too_large"))
+			})
+
+			It("records injected faults", func() {
+				tracer.InjectedFault(logging.FaultDirectionOutgoing, logging.FaultActionDelay, logging.PacketType1RTT, 1337)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:fault_injected"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("direction", "outgoing"))
+				Expect(ev).To(HaveKeyWithValue("action", "delay"))
+				Expect(ev).To(HaveKey("raw"))
+				Expect(ev["raw"].(map[string]interface{})).To(HaveKeyWithValue("length", float64(1337)))
+				Expect(ev).To(HaveKey("header"))
+				hdr := ev["header"].(map[string]interface{})
+				Expect(hdr).To(HaveLen(1))
+				Expect(hdr).To(HaveKeyWithValue("packet_type", "1RTT"))
+			})
+
 			It("records metrics updates", func() {
 				now := time.Now()
 				rttStats := utils.NewRTTStats()
@@ -696,6 +781,83 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Name).To(Equal("recovery:congestion_state_updated"))
 				ev := entry.Event
 				Expect(ev).To(HaveKeyWithValue("new", "congestion_avoidance"))
+				Expect(ev).ToNot(HaveKey("trigger"))
+			})
+
+			It("records the trigger of congestion state updates", func() {
+				triggerTracer := tracer.(logging.CongestionStateTriggerTracer)
+				triggerTracer.UpdatedCongestionStateWithTrigger(logging.CongestionStateCongestionAvoidance, logging.CongestionStateTriggerHybridSlowStart)
+				triggerTracer.UpdatedCongestionStateWithTrigger(logging.CongestionStateRecovery, logging.CongestionStateTriggerECN)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Name).To(Equal("recovery:congestion_state_updated"))
+				Expect(entries[0].Event).To(HaveKeyWithValue("new", "congestion_avoidance"))
+				Expect(entries[0].Event).To(HaveKeyWithValue("trigger", "hystart_delay"))
+				Expect(entries[1].Event).To(HaveKeyWithValue("new", "recovery"))
+				Expect(entries[1].Event).To(HaveKeyWithValue("trigger", "ecn"))
+			})
+
+			It("records congestion metrics", func() {
+				tracer.UpdatedCongestionMetrics(&logging.CongestionMetrics{
+					PacingRate:        1000000,
+					BandwidthEstimate: 800000,
+					Cubic: &logging.CubicState{
+						LastMaxCongestionWindow:       24000,
+						OriginPointCongestionWindow:   24000,
+						TimeToOriginPoint:             1500 * time.Millisecond,
+						EstimatedRenoCongestionWindow: 20000,
+					},
+				})
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entries[0].Name).To(Equal("recovery:metrics_updated"))
+				Expect(entries[0].Event).To(HaveLen(1))
+				Expect(entries[0].Event).To(HaveKeyWithValue("pacing_rate", float64(1000000)))
+				Expect(entries[1].Name).To(Equal("recovery:congestion_metrics_updated"))
+				ev := entries[1].Event
+				Expect(ev).To(HaveKeyWithValue("bandwidth_estimate", float64(800000)))
+				Expect(ev).To(HaveKey("cubic"))
+				cubic := ev["cubic"].(map[string]interface{})
+				Expect(cubic).ToNot(HaveKey("epoch_start"))
+				Expect(cubic).To(HaveKeyWithValue("last_max_congestion_window", float64(24000)))
+				Expect(cubic).To(HaveKeyWithValue("origin_point_congestion_window", float64(24000)))
+				Expect(cubic).To(HaveKeyWithValue("time_to_origin_point", float64(1500)))
+				Expect(cubic).To(HaveKeyWithValue("estimated_reno_congestion_window", float64(20000)))
+			})
+
+			It("only records congestion metrics that changed", func() {
+				m := &logging.CongestionMetrics{PacingRate: 1000000, BandwidthEstimate: 800000}
+				tracer.UpdatedCongestionMetrics(m)
+				tracer.UpdatedCongestionMetrics(m)
+				tracer.UpdatedCongestionMetrics(&logging.CongestionMetrics{PacingRate: 1000000, BandwidthEstimate: 900000})
+				tracer.UpdatedCongestionMetrics(&logging.CongestionMetrics{PacingRate: 1200000, BandwidthEstimate: 900000})
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(4))
+				Expect(entries[0].Name).To(Equal("recovery:metrics_updated"))
+				Expect(entries[1].Name).To(Equal("recovery:congestion_metrics_updated"))
+				Expect(entries[1].Event).ToNot(HaveKey("cubic"))
+				Expect(entries[2].Name).To(Equal("recovery:congestion_metrics_updated"))
+				Expect(entries[2].Event).To(HaveKeyWithValue("bandwidth_estimate", float64(900000)))
+				Expect(entries[3].Name).To(Equal("recovery:metrics_updated"))
+				Expect(entries[3].Event).To(HaveKeyWithValue("pacing_rate", float64(1200000)))
+			})
+
+			It("records congestion parameters", func() {
+				tracer.UpdatedCongestionParameters(&logging.CongestionParameters{
+					InitialCongestionWindow: 12345,
+					MinCongestionWindow:     2400,
+					MaxCongestionWindow:     1234567,
+					LossReductionFactor:     0.5,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:parameters_set"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("initial_congestion_window", float64(12345)))
+				Expect(ev).To(HaveKeyWithValue("minimum_congestion_window", float64(2400)))
+				Expect(ev).To(HaveKeyWithValue("maximum_congestion_window", float64(1234567)))
+				Expect(ev).To(HaveKeyWithValue("loss_reduction_factor", 0.5))
 			})
 
 			It("records PTO changes", func() {
@@ -706,6 +868,15 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Event).To(HaveKeyWithValue("pto_count", float64(42)))
 			})
 
+			It("records flow label updates", func() {
+				tracer.UpdatedFlowLabel(0xabcde, logging.FlowLabelTriggerCongestion)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:flow_label_updated"))
+				Expect(entry.Event).To(HaveKeyWithValue("flow_label", float64(0xabcde)))
+				Expect(entry.Event).To(HaveKeyWithValue("trigger", "congestion"))
+			})
+
 			It("records TLS key updates", func() {
 				tracer.UpdatedKeyFromTLS(protocol.EncryptionHandshake, protocol.PerspectiveClient)
 				entry := exportAndParseSingle()
@@ -318,3 +318,82 @@ func (s congestionState) String() string {
 		return "unknown congestion state"
 	}
 }
+
+type congestionStateTrigger logging.CongestionStateTrigger
+
+func (t congestionStateTrigger) String() string {
+	switch logging.CongestionStateTrigger(t) {
+	case logging.CongestionStateTriggerHybridSlowStart:
+		return "hystart_delay"
+	case logging.CongestionStateTriggerSlowStartThreshold:
+		return "ssthresh_reached"
+	case logging.CongestionStateTriggerPacketLoss:
+		return "packet_loss"
+	case logging.CongestionStateTriggerECN:
+		return "ecn"
+	default:
+		return ""
+	}
+}
+
+type faultAction logging.FaultAction
+
+func (a faultAction) String() string {
+	switch logging.FaultAction(a) {
+	case logging.FaultActionDrop:
+		return "drop"
+	case logging.FaultActionDelay:
+		return "delay"
+	case logging.FaultActionDuplicate:
+		return "duplicate"
+	case logging.FaultActionReorder:
+		return "reorder"
+	case logging.FaultActionBitFlip:
+		return "bit_flip"
+	default:
+		return "unknown fault action"
+	}
+}
+
+type datagramDropReason logging.DatagramDropReason
+
+func (r datagramDropReason) String() string {
+	switch logging.DatagramDropReason(r) {
+	case logging.DatagramDropTooLarge:
+		return "too_large"
+	case logging.DatagramDropReceiveQueueFull:
+		return "receive_queue_full"
+	case logging.DatagramDropExpired:
+		return "expired"
+	default:
+		return "unknown datagram drop reason"
+	}
+}
+
+type flowLabelTrigger logging.FlowLabelTrigger
+
+func (t flowLabelTrigger) String() string {
+	switch logging.FlowLabelTrigger(t) {
+	case logging.FlowLabelTriggerInitial:
+		return "initial"
+	case logging.FlowLabelTriggerPTO:
+		return "pto"
+	case logging.FlowLabelTriggerCongestion:
+		return "congestion"
+	default:
+		return "unknown flow label trigger"
+	}
+}
+
+type faultDirection logging.FaultDirection
+
+func (d faultDirection) String() string {
+	switch logging.FaultDirection(d) {
+	case logging.FaultDirectionOutgoing:
+		return "outgoing"
+	case logging.FaultDirectionIncoming:
+		return "incoming"
+	default:
+		return "unknown fault direction"
+	}
+}
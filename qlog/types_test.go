@@ -127,4 +127,20 @@ var _ = Describe("Types", func() {
 		Expect(congestionState(logging.CongestionStateApplicationLimited).String()).To(Equal("application_limited"))
 		Expect(congestionState(logging.CongestionStateRecovery).String()).To(Equal("recovery"))
 	})
+
+	It("has a string representation for injected faults", func() {
+		Expect(faultAction(logging.FaultActionDrop).String()).To(Equal("drop"))
+		Expect(faultAction(logging.FaultActionDelay).String()).To(Equal("delay"))
+		Expect(faultAction(logging.FaultActionDuplicate).String()).To(Equal("duplicate"))
+		Expect(faultAction(logging.FaultActionReorder).String()).To(Equal("reorder"))
+		Expect(faultAction(logging.FaultActionBitFlip).String()).To(Equal("bit_flip"))
+		Expect(faultDirection(logging.FaultDirectionOutgoing).String()).To(Equal("outgoing"))
+		Expect(faultDirection(logging.FaultDirectionIncoming).String()).To(Equal("incoming"))
+	})
+
+	It("has a string representation for the flow label trigger", func() {
+		Expect(flowLabelTrigger(logging.FlowLabelTriggerInitial).String()).To(Equal("initial"))
+		Expect(flowLabelTrigger(logging.FlowLabelTriggerPTO).String()).To(Equal("pto"))
+		Expect(flowLabelTrigger(logging.FlowLabelTriggerCongestion).String()).To(Equal("congestion"))
+	})
 })
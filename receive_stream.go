@@ -33,9 +33,11 @@ type receiveStream struct {
 	finalOffset protocol.ByteCount
 
 	currentFrame       []byte
+	currentFrameOffset protocol.ByteCount
 	currentFrameDone   func()
 	currentFrameIsLast bool // is the currentFrame the last frame on this stream
 	readPosInFrame     int
+	readOffset         protocol.ByteCount // the number of bytes read by the application
 
 	closeForShutdownErr error
 	cancelReadErr       error
@@ -45,11 +47,17 @@ type receiveStream struct {
 	finRead           bool // set once we read a frame with a Fin
 	canceledRead      bool // set when CancelRead() is called
 	resetRemotely     bool // set when HandleResetStreamFrame() is called
+	// Set when a RESET_STREAM_AT frame is received.
+	// The reset only takes effect once the application has read up to this offset.
+	reliableSize protocol.ByteCount
 
 	readChan chan struct{}
 	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
 	deadline time.Time
 
+	notifyMutex sync.Mutex
+	readNotify  func() // set by SetReadNotify
+
 	flowController flowcontrol.StreamFlowController
 	version        protocol.VersionNumber
 }
@@ -83,6 +91,15 @@ func (s *receiveStream) StreamID() protocol.StreamID {
 
 // Read implements io.Reader. It is not thread safe!
 func (s *receiveStream) Read(p []byte) (int, error) {
+	return s.read(p, true)
+}
+
+// TryRead reads the data that is available, without blocking.
+func (s *receiveStream) TryRead(p []byte) (int, error) {
+	return s.read(p, false)
+}
+
+func (s *receiveStream) read(p []byte, blocking bool) (int, error) {
 	// Concurrent use of Read is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
 	// Make sure that we only execute one call at any given time to avoid hard to debug failures.
@@ -90,23 +107,28 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 	defer func() { <-s.readOnce }()
 
 	s.mutex.Lock()
-	completed, n, err := s.readImpl(p)
+	completed, n, err := s.readImpl(p, blocking)
+	// If the stream was reset reliably, the data after the reliable size will never be read.
+	abandon := completed && s.resetRemotely
 	s.mutex.Unlock()
 
+	if abandon {
+		s.flowController.Abandon()
+	}
 	if completed {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return n, err
 }
 
-func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
+func (s *receiveStream) readImpl(p []byte, blocking bool) (bool /*stream completed */, int, error) {
 	if s.finRead {
 		return false, 0, io.EOF
 	}
 	if s.canceledRead {
 		return false, 0, s.cancelReadErr
 	}
-	if s.resetRemotely {
+	if s.isResetEffective() {
 		return false, 0, s.resetRemotelyErr
 	}
 	if s.closedForShutdown {
@@ -131,11 +153,15 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			if s.canceledRead {
 				return false, bytesRead, s.cancelReadErr
 			}
-			if s.resetRemotely {
+			if s.isResetEffective() {
 				return false, bytesRead, s.resetRemotelyErr
 			}
 
-			deadline := s.deadline
+			// The deadline only applies to blocking reads.
+			var deadline time.Time
+			if blocking {
+				deadline = s.deadline
+			}
 			if !deadline.IsZero() {
 				if !time.Now().Before(deadline) {
 					return false, bytesRead, errDeadline
@@ -150,6 +176,12 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			if s.currentFrame != nil || s.currentFrameIsLast {
 				break
 			}
+			if !blocking {
+				if bytesRead > 0 {
+					return false, bytesRead, nil
+				}
+				return false, 0, ErrWouldBlock
+			}
 
 			s.mutex.Unlock()
 			if deadline.IsZero() {
@@ -176,6 +208,7 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 
 		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
 		s.readPosInFrame += m
+		s.readOffset += protocol.ByteCount(m)
 		bytesRead += m
 
 		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
@@ -183,6 +216,10 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			s.flowController.AddBytesRead(protocol.ByteCount(m))
 		}
 
+		// the application read all the data that was reliably delivered
+		if s.resetRemotely && s.readOffset >= s.reliableSize {
+			return true, bytesRead, s.resetRemotelyErr
+		}
 		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
 			s.finRead = true
 			return true, bytesRead, io.EOF
@@ -191,15 +228,46 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 	return false, bytesRead, nil
 }
 
+// isResetEffective says if the stream was reset by the peer,
+// and all the data that the peer sent reliably was read.
+func (s *receiveStream) isResetEffective() bool {
+	return s.resetRemotely && s.readOffset >= s.reliableSize
+}
+
 func (s *receiveStream) dequeueNextFrame() {
-	var offset protocol.ByteCount
 	// We're done with the last frame. Release the buffer.
 	if s.currentFrameDone != nil {
 		s.currentFrameDone()
 	}
-	offset, s.currentFrame, s.currentFrameDone = s.frameQueue.Pop()
-	s.currentFrameIsLast = offset+protocol.ByteCount(len(s.currentFrame)) >= s.finalOffset
+	s.currentFrameOffset, s.currentFrame, s.currentFrameDone = s.frameQueue.Pop()
+	s.currentFrameIsLast = s.currentFrameOffset+protocol.ByteCount(len(s.currentFrame)) >= s.finalOffset
 	s.readPosInFrame = 0
+	if s.currentFrame != nil && s.resetRemotely {
+		s.truncateCurrentFrame()
+	}
+}
+
+// truncateCurrentFrame cuts off the data after the reliable size of a RESET_STREAM_AT frame.
+func (s *receiveStream) truncateCurrentFrame() {
+	if s.currentFrameOffset+protocol.ByteCount(len(s.currentFrame)) <= s.reliableSize {
+		return
+	}
+	end := int(s.reliableSize - s.currentFrameOffset)
+	if s.reliableSize < s.currentFrameOffset {
+		end = 0
+	}
+	if end < s.readPosInFrame {
+		end = s.readPosInFrame
+	}
+	s.currentFrame = s.currentFrame[:end]
+}
+
+// openForReading says if the application still has to read from the stream,
+// i.e. if it neither read the FIN, nor canceled reading, nor was the stream reset by the peer.
+func (s *receiveStream) openForReading() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !s.finRead && !s.canceledRead && !s.isResetEffective() && !s.closedForShutdown
 }
 
 func (s *receiveStream) CancelRead(errorCode StreamErrorCode) {
@@ -214,7 +282,7 @@ func (s *receiveStream) CancelRead(errorCode StreamErrorCode) {
 }
 
 func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* completed */ {
-	if s.finRead || s.canceledRead || s.resetRemotely {
+	if s.finRead || s.canceledRead || s.isResetEffective() {
 		return false
 	}
 	s.canceledRead = true
@@ -284,6 +352,18 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 
 	// ignore duplicate RESET_STREAM frames for this stream (after checking their final offset)
 	if s.resetRemotely {
+		// A later RESET_STREAM_AT frame may reduce the reliable size, but never increase it.
+		if s.canceledRead || s.isResetEffective() || frame.ReliableSize >= s.reliableSize {
+			return false, nil
+		}
+		s.reliableSize = frame.ReliableSize
+		s.signalRead()
+		if s.isResetEffective() {
+			return true, nil
+		}
+		if s.currentFrame != nil {
+			s.truncateCurrentFrame()
+		}
 		return false, nil
 	}
 	s.resetRemotely = true
@@ -291,6 +371,15 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
 	}
+	if frame.ReliableSize > s.readOffset && !s.canceledRead {
+		// The data up to the reliable size still needs to be delivered to the application.
+		s.reliableSize = frame.ReliableSize
+		if s.currentFrame != nil {
+			s.truncateCurrentFrame()
+		}
+		s.signalRead()
+		return false, nil
+	}
 	s.signalRead()
 	return newlyRcvdFinalOffset, nil
 }
@@ -322,10 +411,27 @@ func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
 
-// signalRead performs a non-blocking send on the readChan
+func (s *receiveStream) SetReadNotify(f func()) {
+	s.notifyMutex.Lock()
+	s.readNotify = f
+	s.notifyMutex.Unlock()
+	// Data might have arrived before the callback was set.
+	if f != nil {
+		f()
+	}
+}
+
+// signalRead performs a non-blocking send on the readChan,
+// and calls the callback set by SetReadNotify
 func (s *receiveStream) signalRead() {
 	select {
 	case s.readChan <- struct{}{}:
 	default:
 	}
+	s.notifyMutex.Lock()
+	f := s.readNotify
+	s.notifyMutex.Unlock()
+	if f != nil {
+		f()
+	}
 }
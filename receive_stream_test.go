@@ -484,6 +484,97 @@ var _ = Describe("Receive Stream", func() {
 		})
 	})
 
+	Context("non-blocking reads", func() {
+		It("returns ErrWouldBlock if no data is available", func() {
+			n, err := str.TryRead(make([]byte, 4))
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeZero())
+		})
+
+		It("reads the data that is available", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("fo")})).To(Succeed())
+			// this frame can't be read yet, since there's a gap
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("ar")})).To(Succeed())
+			b := make([]byte, 6)
+			n, err := str.TryRead(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("fo")))
+			n, err = str.TryRead(b)
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeZero())
+		})
+
+		It("returns EOF", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), Fin: true})).To(Succeed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			b := make([]byte, 10)
+			n, err := str.TryRead(b)
+			Expect(err).To(MatchError(io.EOF))
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+		})
+
+		It("ignores the read deadline", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			str.SetReadDeadline(time.Now().Add(-time.Second))
+			b := make([]byte, 6)
+			n, err := str.TryRead(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+		})
+
+		It("returns errors", func() {
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			_, err := str.TryRead(make([]byte, 4))
+			Expect(err).To(MatchError(testErr))
+		})
+
+		Context("notifications", func() {
+			var notified chan struct{}
+
+			BeforeEach(func() {
+				notified = make(chan struct{}, 10)
+				str.SetReadNotify(func() { notified <- struct{}{} })
+				// the callback is called once when it is set
+				Expect(notified).To(Receive())
+			})
+
+			It("notifies when data arrives", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				Expect(notified).To(Receive())
+				Expect(notified).ToNot(Receive())
+			})
+
+			It("notifies when the stream is reset", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: streamID, FinalSize: 42})).To(Succeed())
+				Expect(notified).To(Receive())
+			})
+
+			It("notifies when the stream is closed for shutdown", func() {
+				str.closeForShutdown(errors.New("test error"))
+				Expect(notified).To(Receive())
+			})
+
+			It("doesn't notify after the callback was removed", func() {
+				str.SetReadNotify(nil)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				Expect(notified).ToNot(Receive())
+			})
+		})
+	})
+
 	Context("stream cancelations", func() {
 		Context("canceling read", func() {
 			It("unblocks Read", func() {
@@ -674,6 +765,126 @@ var _ = Describe("Receive Stream", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("receiving RESET_STREAM_AT frames", func() {
+			streamErr := &StreamError{StreamID: streamID, ErrorCode: 1234}
+
+			It("delivers the data up to the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    10,
+					ReliableSize: 4,
+				})).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 10)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).To(MatchError(streamErr))
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(streamErr))
+			})
+
+			It("waits for data below the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    6,
+					ReliableSize: 3,
+				})).To(Succeed())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					b := make([]byte, 10)
+					n, err := strWithTimeout.Read(b)
+					Expect(err).To(MatchError(streamErr))
+					Expect(b[:n]).To(Equal([]byte("foo")))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("truncates a frame that was partially read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				b := make([]byte, 2)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    6,
+					ReliableSize: 4,
+				})).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b = make([]byte, 10)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).To(MatchError(streamErr))
+				Expect(b[:n]).To(Equal([]byte("ob")))
+			})
+
+			It("resets the stream right away when the data up to the reliable size was already read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				_, err := strWithTimeout.Read(make([]byte, 6))
+				Expect(err).ToNot(HaveOccurred())
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    10,
+					ReliableSize: 4,
+				})).To(Succeed())
+				_, err = strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(streamErr))
+			})
+
+			It("allows reducing the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true).Times(3)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    6,
+					ReliableSize: 2,
+				})).To(Succeed())
+				// the reliable size can't be increased
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ErrorCode:    1234,
+					FinalSize:    6,
+					ReliableSize: 4,
+				})).To(Succeed())
+				Expect(str.reliableSize).To(BeEquivalentTo(2))
+				// a RESET_STREAM frame makes the reset effective immediately
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+					FinalSize: 6,
+				})).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(streamErr))
+			})
+		})
 	})
 
 	Context("flow control", func() {
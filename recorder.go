@@ -0,0 +1,691 @@
+package quic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A recording starts with the recordingMagic and the recording header.
+// It is followed by a sequence of entries.
+// Every entry consists of the entry type (a single byte),
+// the length of the entry (a varint) and the entry payload.
+const (
+	recordingMagic         = "quic-go recording"
+	recordingFormatVersion = 2
+)
+
+type recordEntryType uint8
+
+const (
+	// Entries recorded by the run loop.
+	// They are replayed in the order they were recorded.
+
+	// The run loop handled a received packet.
+	recordEntryPacket recordEntryType = 1 + iota
+	// The run loop was woken up.
+	recordEntryWakeup
+	// The run loop handled the completion of the handshake.
+	recordEntryHandshakeComplete
+	// The run loop processed the packets that became decryptable.
+	recordEntryUndecryptablePackets
+	// The run loop handled timer expirations and sent packets.
+	recordEntryTimersAndSend
+	// The run loop was closed.
+	recordEntryClose
+
+	// Inputs read by the connection while processing one of the entries above.
+
+	// The clock was read.
+	recordEntryNow
+	// Random bytes were read.
+	recordEntryRand
+	// A stateless reset token was generated.
+	recordEntryStatelessResetToken
+	// A token was popped from the token store (client), or generated (server).
+	recordEntryToken
+	// It was checked if there are received packets waiting to be processed.
+	recordEntryQueuedPackets
+	// It was checked if the send queue is blocked.
+	recordEntrySendQueueBlocked
+	// The fault injector handled an outgoing packet.
+	recordEntryFaultInjected
+	// The fault injector was asked for the next packet to send.
+	recordEntryFaultReleased
+)
+
+func (t recordEntryType) String() string {
+	switch t {
+	case recordEntryPacket:
+		return "packet"
+	case recordEntryWakeup:
+		return "wakeup"
+	case recordEntryHandshakeComplete:
+		return "handshake complete"
+	case recordEntryUndecryptablePackets:
+		return "undecryptable packets"
+	case recordEntryTimersAndSend:
+		return "timers and send"
+	case recordEntryClose:
+		return "close"
+	case recordEntryNow:
+		return "now"
+	case recordEntryRand:
+		return "rand"
+	case recordEntryStatelessResetToken:
+		return "stateless reset token"
+	case recordEntryToken:
+		return "token"
+	case recordEntryQueuedPackets:
+		return "queued packets"
+	case recordEntrySendQueueBlocked:
+		return "send queue blocked"
+	case recordEntryFaultInjected:
+		return "fault injected"
+	case recordEntryFaultReleased:
+		return "fault injector released packet"
+	default:
+		return fmt.Sprintf("unknown entry type: %d", t)
+	}
+}
+
+func (t recordEntryType) isRunLoopEntry() bool {
+	return t >= recordEntryPacket && t <= recordEntryClose
+}
+
+// The reason why the run loop was woken up.
+type runLoopWakeup uint8
+
+const (
+	wakeupTimer runLoopWakeup = 1 + iota
+	wakeupSendingScheduled
+	wakeupSendQueueAvailable
+)
+
+// The type of error a connection was closed with.
+const (
+	recordedCloseErrorNil uint8 = iota
+	recordedCloseErrorApplication
+	recordedCloseErrorTransport
+	recordedCloseErrorOther
+)
+
+// The recordingHeader contains everything that's needed to create the connection.
+type recordingHeader struct {
+	startTime   time.Time
+	perspective protocol.Perspective
+	version     protocol.VersionNumber
+	localAddr   net.Addr
+	remoteAddr  net.Addr
+	tracingID   uint64
+
+	destConnID    protocol.ConnectionID
+	srcConnID     protocol.ConnectionID
+	enable0RTT    bool
+	faultInjector bool // if a FaultInjector was configured
+
+	// only used by the server
+	origDestConnID      protocol.ConnectionID
+	retrySrcConnID      *protocol.ConnectionID
+	clientDestConnID    protocol.ConnectionID
+	statelessResetToken protocol.StatelessResetToken
+
+	// only used by the client
+	initialPacketNumber  protocol.PacketNumber
+	hasNegotiatedVersion bool
+	serverName           string
+}
+
+// originalDestConnID is the connection ID used to identify the connection,
+// in the same way as it is passed to the logging.Tracer.
+func (h *recordingHeader) originalDestConnID() protocol.ConnectionID {
+	if h.perspective == protocol.PerspectiveClient {
+		return h.destConnID
+	}
+	if h.origDestConnID.Len() > 0 {
+		return h.origDestConnID
+	}
+	return h.clientDestConnID
+}
+
+func (h *recordingHeader) Write(b *bytes.Buffer) {
+	b.WriteString(recordingMagic)
+	b.WriteByte(recordingFormatVersion)
+	writeRecordedTime(b, h.startTime)
+	b.WriteByte(uint8(h.perspective))
+	quicvarint.Write(b, uint64(h.version))
+	writeRecordedAddr(b, h.localAddr)
+	writeRecordedAddr(b, h.remoteAddr)
+	quicvarint.Write(b, h.tracingID)
+	writeRecordedBytes(b, h.destConnID)
+	writeRecordedBytes(b, h.srcConnID)
+	writeRecordedBool(b, h.enable0RTT)
+	writeRecordedBool(b, h.faultInjector)
+	writeRecordedBool(b, h.origDestConnID != nil)
+	writeRecordedBytes(b, h.origDestConnID)
+	writeRecordedBool(b, h.retrySrcConnID != nil)
+	if h.retrySrcConnID != nil {
+		writeRecordedBytes(b, *h.retrySrcConnID)
+	}
+	writeRecordedBytes(b, h.clientDestConnID)
+	b.Write(h.statelessResetToken[:])
+	quicvarint.Write(b, uint64(h.initialPacketNumber))
+	writeRecordedBool(b, h.hasNegotiatedVersion)
+	writeRecordedBytes(b, []byte(h.serverName))
+}
+
+func parseRecordingHeader(r *bytes.Reader) (*recordingHeader, error) {
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != recordingMagic {
+		return nil, errors.New("not a quic-go recording")
+	}
+	formatVersion, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if formatVersion != recordingFormatVersion {
+		return nil, fmt.Errorf("unsupported recording format version: %d", formatVersion)
+	}
+	h := &recordingHeader{}
+	if h.startTime, err = readRecordedTime(r); err != nil {
+		return nil, err
+	}
+	pers, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	h.perspective = protocol.Perspective(pers)
+	if h.perspective != protocol.PerspectiveClient && h.perspective != protocol.PerspectiveServer {
+		return nil, fmt.Errorf("invalid perspective: %d", pers)
+	}
+	v, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	h.version = protocol.VersionNumber(v)
+	if h.localAddr, err = readRecordedAddr(r); err != nil {
+		return nil, err
+	}
+	if h.remoteAddr, err = readRecordedAddr(r); err != nil {
+		return nil, err
+	}
+	if h.tracingID, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	if h.destConnID, err = readRecordedBytes(r); err != nil {
+		return nil, err
+	}
+	if h.srcConnID, err = readRecordedBytes(r); err != nil {
+		return nil, err
+	}
+	if h.enable0RTT, err = readRecordedBool(r); err != nil {
+		return nil, err
+	}
+	if h.faultInjector, err = readRecordedBool(r); err != nil {
+		return nil, err
+	}
+	hasOrigDestConnID, err := readRecordedBool(r)
+	if err != nil {
+		return nil, err
+	}
+	origDestConnID, err := readRecordedBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if hasOrigDestConnID {
+		h.origDestConnID = protocol.ConnectionID(origDestConnID)
+		if h.origDestConnID == nil {
+			h.origDestConnID = protocol.ConnectionID{}
+		}
+	}
+	hasRetrySrcConnID, err := readRecordedBool(r)
+	if err != nil {
+		return nil, err
+	}
+	if hasRetrySrcConnID {
+		retrySrcConnID, err := readRecordedBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		connID := protocol.ConnectionID(retrySrcConnID)
+		h.retrySrcConnID = &connID
+	}
+	if h.clientDestConnID, err = readRecordedBytes(r); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, h.statelessResetToken[:]); err != nil {
+		return nil, err
+	}
+	pn, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	h.initialPacketNumber = protocol.PacketNumber(pn)
+	if h.hasNegotiatedVersion, err = readRecordedBool(r); err != nil {
+		return nil, err
+	}
+	serverName, err := readRecordedBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	h.serverName = string(serverName)
+	return h, nil
+}
+
+// The connRecorder records all inputs of a connection.
+// It is used as the connection's clock and as its source of randomness.
+// All inputs are recorded in the order they are read.
+// This requires that the connection only reads them from the run loop,
+// or from the handshake Go routine while the run loop is waiting for the handshake.
+type connRecorder struct {
+	mutex sync.Mutex
+
+	w       io.WriteCloser
+	bw      *bufio.Writer
+	payload bytes.Buffer
+	closed  bool
+
+	logger utils.Logger
+}
+
+var (
+	_ utils.Clock = &connRecorder{}
+	_ io.Reader   = &connRecorder{}
+)
+
+func newConnRecorder(w io.WriteCloser, hdr *recordingHeader, logger utils.Logger) *connRecorder {
+	r := &connRecorder{
+		w:      w,
+		bw:     bufio.NewWriter(w),
+		logger: logger,
+	}
+	hdr.Write(&r.payload)
+	r.bw.Write(r.payload.Bytes())
+	return r
+}
+
+// Now returns the current time, and records it.
+func (r *connRecorder) Now() time.Time {
+	now := time.Now()
+	r.record(recordEntryNow, func(b *bytes.Buffer) { writeRecordedTime(b, now) })
+	return now
+}
+
+// Read reads random bytes from crypto/rand, and records them.
+func (r *connRecorder) Read(p []byte) (int, error) {
+	n, err := rand.Read(p)
+	r.record(recordEntryRand, func(b *bytes.Buffer) { b.Write(p[:n]) })
+	return n, err
+}
+
+func (r *connRecorder) RecordPacket(p *receivedPacket) {
+	r.record(recordEntryPacket, func(b *bytes.Buffer) {
+		writeRecordedTime(b, p.rcvTime)
+		writeRecordedAddr(b, p.remoteAddr)
+		b.WriteByte(uint8(p.ecn))
+		b.Write(p.data)
+	})
+}
+
+func (r *connRecorder) RecordWakeup(reason runLoopWakeup) {
+	r.record(recordEntryWakeup, func(b *bytes.Buffer) { b.WriteByte(uint8(reason)) })
+}
+
+func (r *connRecorder) RecordHandshakeComplete() {
+	r.record(recordEntryHandshakeComplete, func(*bytes.Buffer) {})
+}
+
+func (r *connRecorder) RecordUndecryptablePackets() {
+	r.record(recordEntryUndecryptablePackets, func(*bytes.Buffer) {})
+}
+
+func (r *connRecorder) RecordTimersAndSend() {
+	r.record(recordEntryTimersAndSend, func(*bytes.Buffer) {})
+}
+
+func (r *connRecorder) RecordClose(e closeError) {
+	r.record(recordEntryClose, func(b *bytes.Buffer) {
+		writeRecordedBool(b, e.remote)
+		writeRecordedBool(b, e.immediate)
+		var (
+			appErr       *qerr.ApplicationError
+			transportErr *qerr.TransportError
+		)
+		switch {
+		case e.err == nil:
+			b.WriteByte(recordedCloseErrorNil)
+		case errors.As(e.err, &appErr):
+			b.WriteByte(recordedCloseErrorApplication)
+			writeRecordedBool(b, appErr.Remote)
+			quicvarint.Write(b, uint64(appErr.ErrorCode))
+			writeRecordedBytes(b, []byte(appErr.ErrorMessage))
+		case errors.As(e.err, &transportErr):
+			b.WriteByte(recordedCloseErrorTransport)
+			writeRecordedBool(b, transportErr.Remote)
+			quicvarint.Write(b, uint64(transportErr.ErrorCode))
+			quicvarint.Write(b, transportErr.FrameType)
+			writeRecordedBytes(b, []byte(transportErr.ErrorMessage))
+		default:
+			b.WriteByte(recordedCloseErrorOther)
+			writeRecordedBytes(b, []byte(e.err.Error()))
+		}
+	})
+}
+
+func (r *connRecorder) RecordStatelessResetToken(token protocol.StatelessResetToken) {
+	r.record(recordEntryStatelessResetToken, func(b *bytes.Buffer) { b.Write(token[:]) })
+}
+
+func (r *connRecorder) RecordToken(token []byte) {
+	r.record(recordEntryToken, func(b *bytes.Buffer) {
+		writeRecordedBool(b, token != nil)
+		b.Write(token)
+	})
+}
+
+func (r *connRecorder) RecordQueuedPackets(hasQueuedPackets bool) {
+	r.record(recordEntryQueuedPackets, func(b *bytes.Buffer) { writeRecordedBool(b, hasQueuedPackets) })
+}
+
+func (r *connRecorder) RecordSendQueueBlocked(blocked bool) {
+	r.record(recordEntrySendQueueBlocked, func(b *bytes.Buffer) { writeRecordedBool(b, blocked) })
+}
+
+func (r *connRecorder) RecordFaultInjected(action FaultAction) {
+	r.record(recordEntryFaultInjected, func(b *bytes.Buffer) { b.WriteByte(uint8(action)) })
+}
+
+func (r *connRecorder) RecordFaultReleased(released bool) {
+	r.record(recordEntryFaultReleased, func(b *bytes.Buffer) { writeRecordedBool(b, released) })
+}
+
+func (r *connRecorder) record(t recordEntryType, f func(*bytes.Buffer)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.payload.Reset()
+	f(&r.payload)
+	// Errors are sticky, and reported when the recording is closed.
+	r.bw.WriteByte(uint8(t))
+	quicvarint.Write(r.bw, uint64(r.payload.Len()))
+	r.bw.Write(r.payload.Bytes())
+}
+
+// Close flushes the recording and closes the underlying io.WriteCloser.
+func (r *connRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if err := r.bw.Flush(); err != nil {
+		r.logger.Errorf("Error writing recording: %s", err)
+	}
+	return r.w.Close()
+}
+
+// The recordingRunner records the stateless reset tokens returned by the connRunner.
+type recordingRunner struct {
+	connRunner
+	recorder *connRecorder
+}
+
+func (r *recordingRunner) GetStatelessResetToken(connID protocol.ConnectionID) protocol.StatelessResetToken {
+	token := r.connRunner.GetStatelessResetToken(connID)
+	r.recorder.RecordStatelessResetToken(token)
+	return token
+}
+
+// The recordingSender records if the send queue is blocked.
+type recordingSender struct {
+	sender
+	recorder *connRecorder
+}
+
+func (s *recordingSender) WouldBlock() bool {
+	blocked := s.sender.WouldBlock()
+	s.recorder.RecordSendQueueBlocked(blocked)
+	return blocked
+}
+
+// The recordingFaultInjector records the decisions of the fault injector.
+// Since the injector is random, and releases delayed packets from a timer,
+// these decisions can't be reproduced when replaying the connection.
+type recordingFaultInjector struct {
+	*connFaultInjector
+	recorder *connRecorder
+}
+
+func (i *recordingFaultInjector) HandleOutgoing(buf *packetBuffer, packets []*packetContents) FaultAction {
+	action := i.connFaultInjector.HandleOutgoing(buf, packets)
+	i.recorder.RecordFaultInjected(action)
+	return action
+}
+
+func (i *recordingFaultInjector) NextOutgoing() *packetBuffer {
+	buf := i.connFaultInjector.NextOutgoing()
+	i.recorder.RecordFaultReleased(buf != nil)
+	return buf
+}
+
+// The tlsRand is the source of randomness used by crypto/tls when the connection is recorded or replayed.
+// The crypto/ecdsa and crypto/rsa packages call randutil.MaybeReadByte,
+// which reads and discards a single byte with a probability of 50%, to prevent callers from relying on deterministic output.
+// Serving these reads without consuming any recorded randomness makes the reads from the recording deterministic.
+type tlsRand struct {
+	io.Reader
+}
+
+func (r *tlsRand) Read(p []byte) (int, error) {
+	if len(p) == 1 {
+		p[0] = 0
+		return 1, nil
+	}
+	return r.Reader.Read(p)
+}
+
+// setupRecording sets the clock and the source of randomness of the connection.
+// If the connection is recorded or replayed, it returns the runner and TLS config that need to be used,
+// such that all inputs of the connection are recorded or replayed, respectively.
+func (s *connection) setupRecording(runner connRunner, tlsConf *tls.Config, hdr *recordingHeader) (connRunner, *tls.Config) {
+	s.clock = utils.DefaultClock{}
+	if s.config.Clock != nil {
+		s.clock = s.config.Clock
+	}
+	s.appClock = s.clock
+	if r, ok := runner.(*replayRunner); ok {
+		s.replayer = r.replayer
+		s.clock = s.replayer
+		s.rand = s.replayer
+	} else if s.config.Recorder != nil {
+		hdr.startTime = time.Now()
+		hdr.faultInjector = s.config.FaultInjector != nil
+		w := s.config.Recorder(s.perspective, hdr.originalDestConnID())
+		if w == nil {
+			return runner, tlsConf
+		}
+		s.recorder = newConnRecorder(w, hdr, s.logger)
+		s.clock = s.recorder
+		s.rand = s.recorder
+		runner = &recordingRunner{connRunner: runner, recorder: s.recorder}
+	} else {
+		return runner, tlsConf
+	}
+	tlsConf = tlsConf.Clone()
+	tlsConf.Rand = &tlsRand{Reader: s.rand}
+	tlsConf.Time = s.clock.Now
+	return runner, tlsConf
+}
+
+// newFaultInjector creates the fault injector of the connection.
+// It returns nil if no FaultInjector is configured.
+// When replaying a connection, the decisions of the recorded fault injector are replayed.
+func (s *connection) newFaultInjector(tracingID uint64) faultInjector {
+	if s.replayer != nil {
+		if !s.replayer.header.faultInjector {
+			return nil
+		}
+		return &replayFaultInjector{replayer: s.replayer, tracer: s.tracer}
+	}
+	if s.config.FaultInjector == nil {
+		return nil
+	}
+	// The injector reads the clock from its timers, outside of the run loop.
+	i := newConnFaultInjector(s.config.FaultInjector, s.conn, s.srcConnIDLen, tracingID, s.scheduleSending, s.queueReceivedPacket, s.appClock, s.tracer, s.logger)
+	if s.recorder != nil {
+		return &recordingFaultInjector{connFaultInjector: i, recorder: s.recorder}
+	}
+	return i
+}
+
+// newToken generates a token that is sent in a NEW_TOKEN frame.
+// It is only used by the server.
+func (s *connection) newToken() ([]byte, error) {
+	if s.replayer != nil {
+		return s.replayer.Token(), nil
+	}
+	token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+	if err == nil && s.recorder != nil {
+		s.recorder.RecordToken(token)
+	}
+	return token, err
+}
+
+// popToken pops a token from the token store, if one is configured.
+// It is only used by the client.
+func (s *connection) popToken() []byte {
+	if s.replayer != nil {
+		return s.replayer.Token()
+	}
+	var token []byte
+	if s.config.TokenStore != nil {
+		if t := s.config.TokenStore.Pop(s.tokenStoreKey); t != nil {
+			token = t.data
+		}
+	}
+	if s.recorder != nil {
+		s.recorder.RecordToken(token)
+	}
+	return token
+}
+
+// hasQueuedPackets says if there are received packets waiting to be processed by the run loop.
+func (s *connection) hasQueuedPackets() bool {
+	if s.replayer != nil {
+		return s.replayer.QueuedPackets()
+	}
+	hasQueuedPackets := len(s.receivedPackets) > 0
+	if s.recorder != nil {
+		s.recorder.RecordQueuedPackets(hasQueuedPackets)
+	}
+	return hasQueuedPackets
+}
+
+func writeRecordedBytes(b *bytes.Buffer, data []byte) {
+	quicvarint.Write(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func readRecordedBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeRecordedBool(b *bytes.Buffer, v bool) {
+	if v {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+}
+
+func readRecordedBool(r *bytes.Reader) (bool, error) {
+	v, err := r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	return v != 0, nil
+}
+
+func writeRecordedTime(b *bytes.Buffer, t time.Time) {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], uint64(t.UnixNano()))
+	b.Write(data[:])
+}
+
+func readRecordedTime(r *bytes.Reader) (time.Time, error) {
+	var data [8]byte
+	if _, err := io.ReadFull(r, data[:]); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data[:]))), nil
+}
+
+func writeRecordedAddr(b *bytes.Buffer, addr net.Addr) {
+	if addr == nil {
+		writeRecordedBytes(b, nil)
+		writeRecordedBytes(b, nil)
+		return
+	}
+	writeRecordedBytes(b, []byte(addr.Network()))
+	writeRecordedBytes(b, []byte(addr.String()))
+}
+
+func readRecordedAddr(r *bytes.Reader) (net.Addr, error) {
+	network, err := readRecordedBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := readRecordedBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if network == nil && addr == nil {
+		return nil, nil
+	}
+	if string(network) == "udp" {
+		if udpAddr, err := net.ResolveUDPAddr("udp", string(addr)); err == nil {
+			return udpAddr, nil
+		}
+	}
+	return &recordedAddr{network: string(network), addr: string(addr)}, nil
+}
+
+// A recordedAddr is used for addresses that are not UDP addresses.
+type recordedAddr struct {
+	network, addr string
+}
+
+func (a *recordedAddr) Network() string { return a.network }
+func (a *recordedAddr) String() string  { return a.addr }
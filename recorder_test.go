@@ -0,0 +1,176 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type nopWriteCloser struct{ bytes.Buffer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+var _ = Describe("Recorder", func() {
+	var (
+		w   *nopWriteCloser
+		hdr *recordingHeader
+	)
+
+	BeforeEach(func() {
+		w = &nopWriteCloser{}
+		retrySrcConnID := protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		hdr = &recordingHeader{
+			startTime:           time.Unix(1234, 5678),
+			perspective:         protocol.PerspectiveServer,
+			version:             protocol.Version1,
+			localAddr:           &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 443},
+			remoteAddr:          &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 1337},
+			tracingID:           42,
+			destConnID:          protocol.ConnectionID{1, 2, 3, 4},
+			srcConnID:           protocol.ConnectionID{5, 6, 7, 8},
+			enable0RTT:          true,
+			faultInjector:       true,
+			origDestConnID:      protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+			retrySrcConnID:      &retrySrcConnID,
+			clientDestConnID:    protocol.ConnectionID{9, 9, 9, 9},
+			statelessResetToken: protocol.StatelessResetToken{0xf, 0xe, 0xd, 0xc},
+		}
+	})
+
+	It("writes and parses the header", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		Expect(r.Close()).To(Succeed())
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		h := replayer.header
+		Expect(h.startTime.Equal(hdr.startTime)).To(BeTrue())
+		Expect(h.perspective).To(Equal(protocol.PerspectiveServer))
+		Expect(h.version).To(Equal(protocol.Version1))
+		Expect(h.localAddr.String()).To(Equal("192.168.0.1:443"))
+		Expect(h.remoteAddr.String()).To(Equal("192.168.0.2:1337"))
+		Expect(h.tracingID).To(BeEquivalentTo(42))
+		Expect(h.destConnID).To(Equal(hdr.destConnID))
+		Expect(h.srcConnID).To(Equal(hdr.srcConnID))
+		Expect(h.enable0RTT).To(BeTrue())
+		Expect(h.faultInjector).To(BeTrue())
+		Expect(h.origDestConnID).To(Equal(hdr.origDestConnID))
+		Expect(h.retrySrcConnID).To(Equal(hdr.retrySrcConnID))
+		Expect(h.clientDestConnID).To(Equal(hdr.clientDestConnID))
+		Expect(h.statelessResetToken).To(Equal(hdr.statelessResetToken))
+		Expect(h.originalDestConnID()).To(Equal(hdr.origDestConnID))
+	})
+
+	It("rejects data that is not a recording", func() {
+		_, err := newConnReplayer([]byte("foobar"))
+		Expect(err).To(MatchError("not a quic-go recording"))
+	})
+
+	It("replays recorded inputs", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		now := r.Now()
+		b := make([]byte, 16)
+		_, err := r.Read(b)
+		Expect(err).ToNot(HaveOccurred())
+		r.RecordToken([]byte("token"))
+		r.RecordSendQueueBlocked(true)
+		r.RecordWakeup(wakeupTimer)
+		r.RecordClose(closeError{err: &qerr.ApplicationError{ErrorCode: 0x42, ErrorMessage: "foobar"}, remote: true})
+		Expect(r.Close()).To(Succeed())
+
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(replayer.Now().Equal(now)).To(BeTrue())
+		Expect(replayer.CurrentTime().Equal(now)).To(BeTrue())
+		b2 := make([]byte, 16)
+		_, err = replayer.Read(b2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b2).To(Equal(b))
+		Expect(replayer.Token()).To(Equal([]byte("token")))
+		Expect(replayer.SendQueueBlocked()).To(BeTrue())
+		t, _ := replayer.NextRunLoopEntry()
+		Expect(t).To(Equal(recordEntryWakeup))
+		t, data := replayer.NextRunLoopEntry()
+		Expect(t).To(Equal(recordEntryClose))
+		closeErr, err := parseRecordedCloseError(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(closeErr.remote).To(BeTrue())
+		Expect(closeErr.err).To(Equal(&qerr.ApplicationError{ErrorCode: 0x42, ErrorMessage: "foobar"}))
+		Expect(replayer.Finish()).To(Succeed())
+	})
+
+	It("replays the decisions of the fault injector", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		r.RecordFaultInjected(FaultActionDuplicate)
+		r.RecordFaultReleased(true)
+		r.RecordFaultReleased(true)
+		r.RecordFaultReleased(false)
+		r.RecordFaultInjected(0)
+		Expect(r.Close()).To(Succeed())
+
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		injector := &replayFaultInjector{replayer: replayer}
+		packets := []*packetContents{{header: &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: true, Type: protocol.PacketTypeHandshake}}}}
+		Expect(injector.HandleOutgoing(getPacketBuffer(), packets)).To(Equal(FaultActionDuplicate))
+		Expect(injector.NextOutgoing()).ToNot(BeNil())
+		Expect(injector.NextOutgoing()).ToNot(BeNil())
+		Expect(injector.NextOutgoing()).To(BeNil())
+		Expect(injector.HandleOutgoing(getPacketBuffer(), packets)).To(BeZero())
+		Expect(replayer.Finish()).To(Succeed())
+	})
+
+	It("doesn't use recorded randomness for single bytes read by crypto/tls", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		rand := &tlsRand{Reader: r}
+		b := make([]byte, 32)
+		_, err := rand.Read(b)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = rand.Read(make([]byte, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Close()).To(Succeed())
+
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		rand = &tlsRand{Reader: replayer}
+		_, err = rand.Read(make([]byte, 1))
+		Expect(err).ToNot(HaveOccurred())
+		b2 := make([]byte, 32)
+		_, err = rand.Read(b2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b2).To(Equal(b))
+		Expect(replayer.Finish()).To(Succeed())
+	})
+
+	It("detects when a different number of random bytes is read", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		_, err := r.Read(make([]byte, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Close()).To(Succeed())
+
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = replayer.Read(make([]byte, 32))
+		Expect(errors.Is(err, ErrReplayDiverged)).To(BeTrue())
+	})
+
+	It("detects divergence", func() {
+		r := newConnRecorder(w, hdr, utils.DefaultLogger)
+		r.Now()
+		Expect(r.Close()).To(Succeed())
+
+		replayer, err := newConnReplayer(w.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		replayer.Token()
+		Expect(errors.Is(replayer.Err(), ErrReplayDiverged)).To(BeTrue())
+		Expect(errors.Is(replayer.Finish(), ErrReplayDiverged)).To(BeTrue())
+	})
+})
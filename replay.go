@@ -0,0 +1,563 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// ErrReplayDiverged is returned by Replay when the replayed connection diverges from the recording.
+var ErrReplayDiverged = errors.New("replay diverged from the recording")
+
+// When replaying the completion of the handshake, wait at most this long for the handshake Go routine.
+const replayHandshakeTimeout = 5 * time.Second
+
+// Replay replays a connection that was recorded using the Config.Recorder.
+// The connection is run offline: it uses a virtual clock and the randomness from the recording,
+// and all packets it sends are discarded.
+// When used with the same TLS and QUIC configuration, it goes through the exact same sequence of state transitions
+// as the recorded connection. If the Config contains a Tracer, the tracer uses the virtual clock of the connection,
+// such that replaying the same recording multiple times produces the same trace.
+// If the connection was recorded with a FaultInjector, the faults are replayed from the recording,
+// and the FaultInjector of the Config is ignored.
+// Interactions of the application with the connection (e.g. opening streams and writing to them) are not recorded.
+// Replay returns the error that the connection was closed with.
+// If the replayed connection diverges from the recording, it returns an error wrapping ErrReplayDiverged.
+func Replay(recording io.Reader, tlsConf *tls.Config, config *Config) error {
+	data, err := io.ReadAll(recording)
+	if err != nil {
+		return err
+	}
+	r, err := newConnReplayer(data)
+	if err != nil {
+		return err
+	}
+	hdr := r.header
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	if tlsConf == nil {
+		tlsConf = &tls.Config{}
+	} else {
+		tlsConf = tlsConf.Clone()
+	}
+	if hdr.perspective == protocol.PerspectiveServer {
+		config = populateServerConfig(config)
+	} else {
+		config = populateConfig(config)
+		if tlsConf.ServerName == "" {
+			tlsConf.ServerName = hdr.serverName
+		}
+	}
+	// These would interfere with the replay.
+	config.Recorder = nil
+	config.FaultInjector = nil
+	config.TokenStore = nil
+
+	var tracer logging.ConnectionTracer
+	if config.Tracer != nil {
+		ctx := context.WithValue(context.Background(), ConnectionTracingKey, hdr.tracingID)
+		tracer = config.Tracer.TracerForConnection(
+			logging.WithTimeSource(ctx, r.CurrentTime),
+			hdr.perspective,
+			hdr.originalDestConnID(),
+		)
+	}
+	conn := &replayConn{localAddr: hdr.localAddr, remoteAddr: hdr.remoteAddr}
+	runner := &replayRunner{replayer: r}
+	logger := utils.DefaultLogger.WithPrefix("replay")
+	var c quicConn
+	if hdr.perspective == protocol.PerspectiveServer {
+		c = newConnection(
+			conn,
+			runner,
+			hdr.origDestConnID,
+			hdr.retrySrcConnID,
+			hdr.clientDestConnID,
+			hdr.destConnID,
+			hdr.srcConnID,
+			hdr.statelessResetToken,
+			config,
+			tlsConf,
+			nil,
+			hdr.enable0RTT,
+			tracer,
+			hdr.tracingID,
+			logger,
+			hdr.version,
+		)
+	} else {
+		if tracer != nil {
+			tracer.StartedConnection(hdr.localAddr, hdr.remoteAddr, hdr.srcConnID, hdr.destConnID)
+		}
+		c = newClientConnection(
+			conn,
+			runner,
+			hdr.destConnID,
+			hdr.srcConnID,
+			config,
+			tlsConf,
+			hdr.initialPacketNumber,
+			hdr.enable0RTT,
+			hdr.hasNegotiatedVersion,
+			tracer,
+			hdr.tracingID,
+			logger,
+			hdr.version,
+		)
+	}
+	return c.(*connection).runReplay()
+}
+
+// runReplay is the run loop used when replaying a connection.
+// Instead of waiting for events, it performs the steps of the recorded run loop.
+func (s *connection) runReplay() error {
+	defer s.ctxCancel()
+
+	s.startRunLoop()
+
+	var closeErr closeError
+replayLoop:
+	for {
+		t, data := s.replayer.NextRunLoopEntry()
+		switch t {
+		case recordEntryPacket:
+			p, err := parseRecordedPacket(data)
+			if err != nil {
+				s.replayer.SetError(err)
+				break
+			}
+			s.handlePacketImpl(p)
+		case recordEntryWakeup:
+			// Nothing to do here.
+			// If the recorded run loop handled timers or sent packets, this was recorded separately.
+		case recordEntryHandshakeComplete:
+			select {
+			case <-s.handshakeCompleteChan:
+				s.handleHandshakeComplete()
+			case <-time.After(replayHandshakeTimeout):
+				s.replayer.SetError(fmt.Errorf("%w: handshake didn't complete", ErrReplayDiverged))
+			}
+		case recordEntryUndecryptablePackets:
+			queue := s.undecryptablePacketsToProcess
+			s.undecryptablePacketsToProcess = nil
+			for _, p := range queue {
+				s.handlePacketImpl(p)
+				// The recorded run loop stopped processing packets when the connection was closed.
+				if len(s.closeChan) > 0 {
+					break
+				}
+			}
+		case recordEntryTimersAndSend:
+			s.handleTimersAndSendPackets()
+		case recordEntryClose:
+			select {
+			case closeErr = <-s.closeChan:
+			default:
+				// The connection was closed by the application, or by the server.
+				e, err := parseRecordedCloseError(data)
+				if err != nil {
+					s.replayer.SetError(err)
+					break
+				}
+				closeErr = e
+			}
+			break replayLoop
+		}
+		if err := s.replayer.Err(); err != nil {
+			closeErr = closeError{err: err, immediate: true}
+			break
+		}
+	}
+
+	err := s.finishRunLoop(closeErr)
+	if rerr := s.replayer.Finish(); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+type recordEntry struct {
+	typ  recordEntryType
+	data []byte
+}
+
+// The connReplayer replays the inputs of a recorded connection.
+// It is used as the connection's clock and as its source of randomness.
+type connReplayer struct {
+	header *recordingHeader
+
+	mutex   sync.Mutex
+	entries []recordEntry
+	pos     int
+	now     time.Time // the current time of the virtual clock
+	err     error     // set as soon as the replay diverges from the recording
+}
+
+var (
+	_ utils.Clock = &connReplayer{}
+	_ io.Reader   = &connReplayer{}
+)
+
+func newConnReplayer(data []byte) (*connReplayer, error) {
+	r := bytes.NewReader(data)
+	hdr, err := parseRecordingHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []recordEntry
+	for r.Len() > 0 {
+		t, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := readRecordedBytes(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recorded %s entry: %w", recordEntryType(t), err)
+		}
+		entries = append(entries, recordEntry{typ: recordEntryType(t), data: payload})
+	}
+	return &connReplayer{
+		header:  hdr,
+		entries: entries,
+		now:     hdr.startTime,
+	}, nil
+}
+
+// next returns the payload of the next entry.
+// If the next entry is not of type t, the replay diverged.
+func (r *connReplayer) next(t recordEntryType) ([]byte, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return nil, false
+	}
+	if r.pos >= len(r.entries) {
+		r.err = fmt.Errorf("%w: expected %s, but reached the end of the recording", ErrReplayDiverged, t)
+		return nil, false
+	}
+	e := r.entries[r.pos]
+	if e.typ != t {
+		r.err = fmt.Errorf("%w: expected %s, got %s (entry %d)", ErrReplayDiverged, t, e.typ, r.pos)
+		return nil, false
+	}
+	r.pos++
+	return e.data, true
+}
+
+// NextRunLoopEntry returns the next entry recorded by the run loop.
+// If the replay diverged, it returns a close entry.
+func (r *connReplayer) NextRunLoopEntry() (recordEntryType, []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err == nil {
+		if r.pos >= len(r.entries) {
+			r.err = fmt.Errorf("%w: reached the end of the recording", ErrReplayDiverged)
+		} else if e := r.entries[r.pos]; !e.typ.isRunLoopEntry() {
+			r.err = fmt.Errorf("%w: the recorded connection read %s (entry %d)", ErrReplayDiverged, e.typ, r.pos)
+		} else {
+			r.pos++
+			return e.typ, e.data
+		}
+	}
+	return recordEntryClose, nil
+}
+
+// Now returns the recorded time.
+func (r *connReplayer) Now() time.Time {
+	data, ok := r.next(recordEntryNow)
+	if !ok {
+		return r.CurrentTime()
+	}
+	now, err := readRecordedTime(bytes.NewReader(data))
+	if err != nil {
+		r.SetError(err)
+		return r.CurrentTime()
+	}
+	r.mutex.Lock()
+	r.now = now
+	r.mutex.Unlock()
+	return now
+}
+
+// CurrentTime returns the current time of the virtual clock,
+// i.e. the time returned by the last call to Now.
+// Unlike Now, it doesn't consume an entry.
+func (r *connReplayer) CurrentTime() time.Time {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.now
+}
+
+// Read returns the recorded random bytes.
+func (r *connReplayer) Read(p []byte) (int, error) {
+	data, ok := r.next(recordEntryRand)
+	if !ok {
+		return 0, r.Err()
+	}
+	if len(data) != len(p) {
+		err := fmt.Errorf("%w: read %d random bytes, recorded %d", ErrReplayDiverged, len(p), len(data))
+		r.SetError(err)
+		return 0, err
+	}
+	return copy(p, data), nil
+}
+
+func (r *connReplayer) StatelessResetToken() protocol.StatelessResetToken {
+	var token protocol.StatelessResetToken
+	if data, ok := r.next(recordEntryStatelessResetToken); ok {
+		copy(token[:], data)
+	}
+	return token
+}
+
+func (r *connReplayer) Token() []byte {
+	data, ok := r.next(recordEntryToken)
+	if !ok || len(data) == 0 || data[0] == 0 {
+		return nil
+	}
+	return append([]byte{}, data[1:]...)
+}
+
+func (r *connReplayer) QueuedPackets() bool {
+	data, ok := r.next(recordEntryQueuedPackets)
+	return ok && len(data) > 0 && data[0] != 0
+}
+
+func (r *connReplayer) SendQueueBlocked() bool {
+	data, ok := r.next(recordEntrySendQueueBlocked)
+	return ok && len(data) > 0 && data[0] != 0
+}
+
+// FaultInjected returns the action that the recorded fault injector applied to an outgoing packet.
+func (r *connReplayer) FaultInjected() FaultAction {
+	data, ok := r.next(recordEntryFaultInjected)
+	if !ok || len(data) == 0 {
+		return 0
+	}
+	return FaultAction(data[0])
+}
+
+// FaultReleased says if the recorded fault injector released a packet for sending.
+func (r *connReplayer) FaultReleased() bool {
+	data, ok := r.next(recordEntryFaultReleased)
+	return ok && len(data) > 0 && data[0] != 0
+}
+
+func (r *connReplayer) SetError(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *connReplayer) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// Finish is called when the replayed connection is closed.
+// It returns an error if the replay diverged, or if not all entries were replayed.
+func (r *connReplayer) Finish() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if r.pos < len(r.entries) {
+		return fmt.Errorf("%w: %d recorded entries were not replayed", ErrReplayDiverged, len(r.entries)-r.pos)
+	}
+	return nil
+}
+
+func parseRecordedPacket(data []byte) (*receivedPacket, error) {
+	r := bytes.NewReader(data)
+	rcvTime, err := readRecordedTime(r)
+	if err != nil {
+		return nil, err
+	}
+	remoteAddr, err := readRecordedAddr(r)
+	if err != nil {
+		return nil, err
+	}
+	ecn, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	buf := getPacketBuffer()
+	buf.Data = buf.Data[:r.Len()]
+	r.Read(buf.Data)
+	return &receivedPacket{
+		buffer:     buf,
+		remoteAddr: remoteAddr,
+		rcvTime:    rcvTime,
+		data:       buf.Data,
+		ecn:        protocol.ECN(ecn),
+	}, nil
+}
+
+func parseRecordedCloseError(data []byte) (closeError, error) {
+	r := bytes.NewReader(data)
+	var e closeError
+	var err error
+	if e.remote, err = readRecordedBool(r); err != nil {
+		return closeError{}, err
+	}
+	if e.immediate, err = readRecordedBool(r); err != nil {
+		return closeError{}, err
+	}
+	t, err := r.ReadByte()
+	if err != nil {
+		return closeError{}, err
+	}
+	switch t {
+	case recordedCloseErrorNil:
+	case recordedCloseErrorApplication:
+		appErr := &qerr.ApplicationError{}
+		if appErr.Remote, err = readRecordedBool(r); err != nil {
+			return closeError{}, err
+		}
+		code, err := quicvarint.Read(r)
+		if err != nil {
+			return closeError{}, err
+		}
+		appErr.ErrorCode = qerr.ApplicationErrorCode(code)
+		msg, err := readRecordedBytes(r)
+		if err != nil {
+			return closeError{}, err
+		}
+		appErr.ErrorMessage = string(msg)
+		e.err = appErr
+	case recordedCloseErrorTransport:
+		transportErr := &qerr.TransportError{}
+		if transportErr.Remote, err = readRecordedBool(r); err != nil {
+			return closeError{}, err
+		}
+		code, err := quicvarint.Read(r)
+		if err != nil {
+			return closeError{}, err
+		}
+		transportErr.ErrorCode = qerr.TransportErrorCode(code)
+		if transportErr.FrameType, err = quicvarint.Read(r); err != nil {
+			return closeError{}, err
+		}
+		msg, err := readRecordedBytes(r)
+		if err != nil {
+			return closeError{}, err
+		}
+		transportErr.ErrorMessage = string(msg)
+		e.err = transportErr
+	case recordedCloseErrorOther:
+		msg, err := readRecordedBytes(r)
+		if err != nil {
+			return closeError{}, err
+		}
+		e.err = errors.New(string(msg))
+	default:
+		return closeError{}, fmt.Errorf("unknown close error type: %d", t)
+	}
+	return e, nil
+}
+
+// The replayConn discards all packets sent by the replayed connection.
+type replayConn struct {
+	localAddr, remoteAddr net.Addr
+}
+
+var _ sendConn = &replayConn{}
+
+func (c *replayConn) Write([]byte) error   { return nil }
+func (c *replayConn) Close() error         { return nil }
+func (c *replayConn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *replayConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+// The replayRunner returns the recorded stateless reset tokens.
+// Since a replayed connection doesn't receive packets from the network,
+// all other methods are no-ops.
+type replayRunner struct {
+	replayer *connReplayer
+}
+
+var _ connRunner = &replayRunner{}
+
+func (r *replayRunner) Add(protocol.ConnectionID, packetHandler) bool { return true }
+func (r *replayRunner) GetStatelessResetToken(protocol.ConnectionID) protocol.StatelessResetToken {
+	return r.replayer.StatelessResetToken()
+}
+func (r *replayRunner) Retire(protocol.ConnectionID)                           {}
+func (r *replayRunner) Remove(protocol.ConnectionID)                           {}
+func (r *replayRunner) ReplaceWithClosed(protocol.ConnectionID, packetHandler) {}
+func (r *replayRunner) AddResetToken(protocol.StatelessResetToken, packetHandler) {
+}
+func (r *replayRunner) RemoveResetToken(protocol.StatelessResetToken) {}
+
+// The replaySender discards all packets.
+// It reports the send queue as blocked whenever the recorded send queue was blocked.
+type replaySender struct {
+	replayer    *connReplayer
+	closeCalled chan struct{}
+}
+
+var _ sender = &replaySender{}
+
+func newReplaySender(replayer *connReplayer) sender {
+	return &replaySender{replayer: replayer, closeCalled: make(chan struct{})}
+}
+
+func (h *replaySender) Send(p *packetBuffer)       { p.Release() }
+func (h *replaySender) WouldBlock() bool           { return h.replayer.SendQueueBlocked() }
+func (h *replaySender) Available() <-chan struct{} { return nil }
+
+func (h *replaySender) Run() error {
+	<-h.closeCalled
+	return nil
+}
+
+func (h *replaySender) Close() { close(h.closeCalled) }
+
+// The replayFaultInjector replays the decisions of the recorded fault injector.
+// Since all packets are discarded, it only needs to replay when the injector released a packet,
+// and trace the faults that were injected.
+type replayFaultInjector struct {
+	replayer *connReplayer
+	tracer   logging.ConnectionTracer
+}
+
+var _ faultInjector = &replayFaultInjector{}
+
+// HandleIncoming is never called, since the recorded packets were received after the faults were injected.
+func (i *replayFaultInjector) HandleIncoming(*receivedPacket) {}
+
+func (i *replayFaultInjector) HandleOutgoing(buf *packetBuffer, packets []*packetContents) FaultAction {
+	action := i.replayer.FaultInjected()
+	if action != 0 && i.tracer != nil {
+		i.tracer.InjectedFault(FaultDirectionOutgoing, action, logging.PacketTypeFromHeader(&packets[0].header.Header), buf.Len())
+	}
+	buf.Release()
+	return action
+}
+
+func (i *replayFaultInjector) NextOutgoing() *packetBuffer {
+	if !i.replayer.FaultReleased() {
+		return nil
+	}
+	return getPacketBuffer()
+}
+
+func (i *replayFaultInjector) Close() {}
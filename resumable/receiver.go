@@ -0,0 +1,182 @@
+package resumable
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A Receiver receives resumable streams.
+type Receiver struct {
+	config *Config
+}
+
+// NewReceiver creates a new Receiver.
+func NewReceiver(conf *Config) *Receiver {
+	return &Receiver{config: populateConfig(conf)}
+}
+
+// AcceptStream accepts the next resumable stream opened by the sender on conn.
+// All streams opened by the peer on conn are expected to be resumable streams.
+// Streams that use an invalid header are reset.
+func (r *Receiver) AcceptStream(ctx context.Context, conn quic.Connection) (*ReceiveStream, error) {
+	for {
+		str, err := conn.AcceptStream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		hdr, err := parseHeader(quicvarint.NewReader(str))
+		if err != nil || hdr.Type != headerTypeOpen {
+			resetStream(str, ErrCodeInvalidHeader)
+			continue
+		}
+		return &ReceiveStream{
+			id:          hdr.StreamID,
+			ackInterval: uint64(r.config.AckInterval),
+			str:         str,
+		}, nil
+	}
+}
+
+// A ReceiveStream is the receiving side of a resumable stream.
+// When the connection breaks, Read returns an error wrapping ErrInterrupted.
+// The stream can then be resumed on a new connection.
+type ReceiveStream struct {
+	id          uint64
+	ackInterval uint64
+
+	mutex sync.Mutex
+	// The QUIC stream that the data is currently received on.
+	// It is nil if the connection broke, and the stream wasn't resumed yet.
+	str         quic.Stream
+	offset      uint64 // the number of bytes consumed by the application
+	ackedOffset uint64
+	finished    bool
+	canceled    bool
+}
+
+// StreamID returns the ID assigned to the stream by the sender.
+func (s *ReceiveStream) StreamID() uint64 { return s.id }
+
+// Offset returns the number of bytes read from the stream.
+func (s *ReceiveStream) Offset() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.offset
+}
+
+// Read reads data from the stream.
+// It must not be called concurrently with Resume.
+func (s *ReceiveStream) Read(p []byte) (int, error) {
+	s.mutex.Lock()
+	str := s.str
+	finished := s.finished
+	canceled := s.canceled
+	s.mutex.Unlock()
+
+	if canceled {
+		return 0, errCanceled
+	}
+	if finished {
+		return 0, io.EOF
+	}
+	if str == nil {
+		return 0, ErrInterrupted
+	}
+	n, err := str.Read(p)
+
+	s.mutex.Lock()
+	s.offset += uint64(n)
+	if s.str != str { // the stream was canceled in the meantime
+		s.mutex.Unlock()
+		return n, errCanceled
+	}
+	if err == io.EOF {
+		s.finished = true
+		ack := s.encodeAck()
+		s.mutex.Unlock()
+		// Acknowledge all data, and signal the sender that the stream was consumed completely.
+		str.Write(ack)
+		str.Close()
+		return n, io.EOF
+	}
+	if err != nil {
+		s.str = nil
+		s.mutex.Unlock()
+		if isInterruption(err) {
+			resetStream(str, ErrCodeCanceled)
+			return n, fmt.Errorf("%w: %s", ErrInterrupted, err)
+		}
+		return n, err
+	}
+	var ack []byte
+	if s.offset-s.ackedOffset >= s.ackInterval {
+		ack = s.encodeAck()
+	}
+	s.mutex.Unlock()
+	// Writing to the QUIC stream might block, so this is done without holding the mutex.
+	// If this fails, the next call to Read will fail as well.
+	if ack != nil {
+		str.Write(ack)
+	}
+	return n, nil
+}
+
+// Resume resumes the stream on conn.
+// The sender then continues sending at the offset that was read so far.
+// If conn is an EarlyConnection, the request can be sent in 0-RTT data.
+func (s *ReceiveStream) Resume(ctx context.Context, conn quic.Connection) error {
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.canceled {
+		resetStream(str, ErrCodeCanceled)
+		return errCanceled
+	}
+	if s.finished {
+		resetStream(str, ErrCodeCanceled)
+		return fmt.Errorf("stream %d already finished", s.id)
+	}
+	b := &bytes.Buffer{}
+	(&header{Type: headerTypeResume, StreamID: s.id, Offset: s.offset}).Write(b)
+	if _, err := str.Write(b.Bytes()); err != nil {
+		return err
+	}
+	if s.str != nil {
+		resetStream(s.str, ErrCodeCanceled)
+	}
+	s.str = str
+	s.ackedOffset = s.offset
+	return nil
+}
+
+// Cancel aborts receiving the stream.
+// The stream can't be resumed afterwards.
+func (s *ReceiveStream) Cancel() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.str != nil {
+		resetStream(s.str, ErrCodeCanceled)
+		s.str = nil
+	}
+	s.canceled = true
+}
+
+// encodeAck encodes an acknowledgement for all data consumed so far.
+func (s *ReceiveStream) encodeAck() []byte {
+	b := &bytes.Buffer{}
+	quicvarint.Write(b, s.offset)
+	s.ackedOffset = s.offset
+	return b.Bytes()
+}
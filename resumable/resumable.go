@@ -0,0 +1,157 @@
+// Package resumable implements streams that survive the loss of the QUIC connection they were sent on.
+//
+// The Sender assigns an ID to every resumable stream, and retains all data until the receiver
+// has acknowledged consuming it.
+// When the connection breaks, the receiver dials a new connection (ideally using 0-RTT)
+// and requests to continue the stream at the offset it has consumed so far.
+// The sender then resends only the missing data.
+//
+// Every resumable stream is carried by a bidirectional QUIC stream.
+// The opening endpoint first sends a short header, encoded using QUIC variable-length integers:
+//
+//	Open:   version, type (0), stream ID                (sent by the sender)
+//	Resume: version, type (1), stream ID, offset        (sent by the receiver)
+//
+// After the header, the sender sends the stream data, and the receiver sends the offsets
+// it has consumed, every one of them encoded as a variable-length integer.
+package resumable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// The version of the header format.
+const version = 1
+
+// The maximum value that can be encoded as a variable-length integer.
+const maxVarInt = 1<<62 - 1
+
+const (
+	defaultMaxBufferedBytes = 4 << 20 // 4 MB
+	defaultAckInterval      = 64 << 10
+	defaultResumeTimeout    = time.Minute
+)
+
+// Stream error codes used to reset the QUIC streams carrying resumable streams.
+const (
+	// ErrCodeCanceled is used when the stream is canceled by the application.
+	ErrCodeCanceled quic.StreamErrorCode = 0x52530
+	// ErrCodeUnknownStream is used when a stream with an unknown stream ID is resumed.
+	ErrCodeUnknownStream quic.StreamErrorCode = 0x52531
+	// ErrCodeInvalidOffset is used when a stream is resumed at an offset at which the data isn't available any more.
+	ErrCodeInvalidOffset quic.StreamErrorCode = 0x52532
+	// ErrCodeInvalidHeader is used when the header can't be parsed, or uses an unsupported version.
+	ErrCodeInvalidHeader quic.StreamErrorCode = 0x52533
+)
+
+// ErrInterrupted is returned when the connection that a stream is sent on breaks.
+// The stream can be continued by resuming it on a new connection.
+var ErrInterrupted = errors.New("resumable: stream interrupted")
+
+// ErrResumeTimeout is returned by the sender when an interrupted stream wasn't resumed within the ResumeTimeout.
+var ErrResumeTimeout = errors.New("resumable: stream not resumed in time")
+
+// Config configures resumable streams.
+type Config struct {
+	// MaxBufferedBytes is the maximum number of bytes that the sender retains for every stream,
+	// until the receiver acknowledges consuming them.
+	// Once this limit is reached, Write blocks.
+	// If zero, 4 MB is used.
+	MaxBufferedBytes int
+	// AckInterval is the number of bytes that the receiver consumes before acknowledging them.
+	// Smaller values allow the sender to release its buffer sooner, at the cost of more overhead.
+	// If zero, 64 KB is used.
+	AckInterval int
+	// ResumeTimeout is the time that the sender waits for an interrupted stream to be resumed.
+	// Once it expires, the stream fails with ErrResumeTimeout, and its data is released.
+	// If zero, 1 minute is used.
+	ResumeTimeout time.Duration
+}
+
+func populateConfig(conf *Config) *Config {
+	if conf == nil {
+		conf = &Config{}
+	}
+	maxBufferedBytes := conf.MaxBufferedBytes
+	if maxBufferedBytes == 0 {
+		maxBufferedBytes = defaultMaxBufferedBytes
+	}
+	ackInterval := conf.AckInterval
+	if ackInterval == 0 {
+		ackInterval = defaultAckInterval
+	}
+	resumeTimeout := conf.ResumeTimeout
+	if resumeTimeout == 0 {
+		resumeTimeout = defaultResumeTimeout
+	}
+	return &Config{
+		MaxBufferedBytes: maxBufferedBytes,
+		AckInterval:      ackInterval,
+		ResumeTimeout:    resumeTimeout,
+	}
+}
+
+type headerType uint64
+
+const (
+	headerTypeOpen headerType = iota
+	headerTypeResume
+)
+
+type header struct {
+	Type     headerType
+	StreamID uint64
+	Offset   uint64 // only set for headerTypeResume
+}
+
+func (h *header) Write(b *bytes.Buffer) {
+	quicvarint.Write(b, version)
+	quicvarint.Write(b, uint64(h.Type))
+	quicvarint.Write(b, h.StreamID)
+	if h.Type == headerTypeResume {
+		quicvarint.Write(b, h.Offset)
+	}
+}
+
+func parseHeader(r io.ByteReader) (*header, error) {
+	v, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if v != version {
+		return nil, fmt.Errorf("unsupported version: %d", v)
+	}
+	t, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	h := &header{Type: headerType(t)}
+	if h.Type != headerTypeOpen && h.Type != headerTypeResume {
+		return nil, fmt.Errorf("unknown header type: %d", t)
+	}
+	h.StreamID, err = quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if h.Type == headerTypeResume {
+		h.Offset, err = quicvarint.Read(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// isInterruption says if an error returned by a QUIC stream was caused by the connection breaking,
+// as opposed to the stream being reset by the peer.
+func isInterruption(err error) bool {
+	var streamErr *quic.StreamError
+	return !errors.As(err, &streamErr)
+}
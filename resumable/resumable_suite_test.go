@@ -0,0 +1,13 @@
+package resumable
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestResumable(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Resumable Suite")
+}
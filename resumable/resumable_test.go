@@ -0,0 +1,60 @@
+package resumable
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header", func() {
+	It("writes and parses an Open header", func() {
+		b := &bytes.Buffer{}
+		(&header{Type: headerTypeOpen, StreamID: 1337}).Write(b)
+		hdr, err := parseHeader(bytes.NewReader(b.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(headerTypeOpen))
+		Expect(hdr.StreamID).To(BeEquivalentTo(1337))
+	})
+
+	It("writes and parses a Resume header", func() {
+		b := &bytes.Buffer{}
+		(&header{Type: headerTypeResume, StreamID: 1337, Offset: 42}).Write(b)
+		r := bytes.NewReader(b.Bytes())
+		hdr, err := parseHeader(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(headerTypeResume))
+		Expect(hdr.StreamID).To(BeEquivalentTo(1337))
+		Expect(hdr.Offset).To(BeEquivalentTo(42))
+		Expect(r.Len()).To(BeZero())
+	})
+
+	It("errors on unsupported versions", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, version+1)
+		quicvarint.Write(b, uint64(headerTypeOpen))
+		quicvarint.Write(b, 1337)
+		_, err := parseHeader(bytes.NewReader(b.Bytes()))
+		Expect(err).To(MatchError("unsupported version: 2"))
+	})
+
+	It("errors on unknown header types", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, version)
+		quicvarint.Write(b, 42)
+		_, err := parseHeader(bytes.NewReader(b.Bytes()))
+		Expect(err).To(MatchError("unknown header type: 42"))
+	})
+
+	It("errors on EOF", func() {
+		b := &bytes.Buffer{}
+		(&header{Type: headerTypeResume, StreamID: 1337, Offset: 42}).Write(b)
+		data := b.Bytes()
+		for i := range data {
+			_, err := parseHeader(bytes.NewReader(data[:i]))
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
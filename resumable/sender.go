@@ -0,0 +1,459 @@
+package resumable
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// The maximum number of bytes passed to a single Write call on the QUIC stream.
+const maxWriteSize = 16 << 10
+
+// The time that Accept waits for the header of a stream opened by the receiver.
+const headerTimeout = 10 * time.Second
+
+var (
+	errCanceled     = errors.New("resumable: stream canceled")
+	errSenderClosed = errors.New("resumable: sender closed")
+)
+
+// A Sender sends resumable streams.
+// It keeps track of all streams that haven't been completely consumed by the receiver yet,
+// such that they can be resumed on a new connection.
+type Sender struct {
+	config *Config
+
+	mutex   sync.Mutex
+	streams map[uint64]*SendStream
+	closed  bool
+}
+
+// NewSender creates a new Sender.
+func NewSender(conf *Config) *Sender {
+	return &Sender{
+		config:  populateConfig(conf),
+		streams: make(map[uint64]*SendStream),
+	}
+}
+
+// OpenStream opens a new resumable stream on conn.
+func (s *Sender) OpenStream(conn quic.Connection) (*SendStream, error) {
+	str, err := conn.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.newStreamID()
+	if err != nil {
+		resetStream(str, ErrCodeCanceled)
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	(&header{Type: headerTypeOpen, StreamID: id}).Write(b)
+	if _, err := str.Write(b.Bytes()); err != nil {
+		resetStream(str, ErrCodeCanceled)
+		return nil, err
+	}
+	ss := newSendStream(id, s)
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		resetStream(str, ErrCodeCanceled)
+		return nil, errSenderClosed
+	}
+	s.streams[id] = ss
+	s.mutex.Unlock()
+	if err := ss.attach(str, 0); err != nil {
+		return nil, err
+	}
+	go ss.run()
+	return ss, nil
+}
+
+// Accept waits for the receiver to resume a stream on conn.
+// Sending continues at the offset that the receiver requested.
+// All other streams opened by the peer on conn are expected to resume streams.
+// Streams that use an invalid header, or that refer to an unknown stream, are reset,
+// as are streams that don't send their header within the header timeout.
+// The headers are read concurrently, such that a single stream can't block resuming other streams.
+// A stream whose header is read after Accept returned is still resumed.
+func (s *Sender) Accept(ctx context.Context, conn quic.Connection) (*SendStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resumed := make(chan *SendStream)
+	errChan := make(chan error, 1)
+	go func() {
+		for {
+			str, err := conn.AcceptStream(ctx)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			go func() {
+				if ss := s.resume(str); ss != nil {
+					select {
+					case resumed <- ss:
+					case <-ctx.Done():
+					}
+				}
+			}()
+		}
+	}()
+
+	select {
+	case ss := <-resumed:
+		return ss, nil
+	case err := <-errChan:
+		return nil, err
+	}
+}
+
+// resume reads the header of a stream opened by the receiver, and resumes the stream it refers to.
+// If the stream can't be resumed, it is reset, and nil is returned.
+func (s *Sender) resume(str quic.Stream) *SendStream {
+	str.SetReadDeadline(time.Now().Add(headerTimeout))
+	hdr, err := parseHeader(quicvarint.NewReader(str))
+	if err != nil || hdr.Type != headerTypeResume {
+		resetStream(str, ErrCodeInvalidHeader)
+		return nil
+	}
+	str.SetReadDeadline(time.Time{})
+	s.mutex.Lock()
+	ss, ok := s.streams[hdr.StreamID]
+	s.mutex.Unlock()
+	if !ok {
+		resetStream(str, ErrCodeUnknownStream)
+		return nil
+	}
+	if err := ss.attach(str, hdr.Offset); err != nil {
+		resetStream(str, ErrCodeInvalidOffset)
+		return nil
+	}
+	return ss
+}
+
+func (s *Sender) newStreamID() (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return 0, err
+		}
+		id := binary.BigEndian.Uint64(b) & maxVarInt
+		if _, ok := s.streams[id]; !ok {
+			return id, nil
+		}
+	}
+}
+
+func (s *Sender) removeStream(id uint64) {
+	s.mutex.Lock()
+	delete(s.streams, id)
+	s.mutex.Unlock()
+}
+
+// Close cancels all streams that haven't been completely consumed by the receiver yet,
+// and releases their data.
+// Streams can't be opened or resumed afterwards.
+func (s *Sender) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	streams := make([]*SendStream, 0, len(s.streams))
+	for _, str := range s.streams {
+		streams = append(streams, str)
+	}
+	s.mutex.Unlock()
+
+	for _, str := range streams {
+		str.cancel(errSenderClosed)
+	}
+	return nil
+}
+
+// A SendStream is the sending side of a resumable stream.
+type SendStream struct {
+	id            uint64
+	sender        *Sender
+	maxBuffered   int
+	resumeTimeout time.Duration
+
+	mutex sync.Mutex
+	cond  sync.Cond
+
+	// The QUIC stream that the data is currently sent on.
+	// It is nil if the connection broke, and the stream wasn't resumed yet.
+	str quic.Stream
+	// Set while the stream is waiting to be resumed.
+	resumeTimer *time.Timer
+	// The data that hasn't been acknowledged by the receiver yet, starting at ackedOffset.
+	buf         []byte
+	ackedOffset uint64
+	sentOffset  uint64
+	finished    bool // Close was called
+	finSent     bool
+	err         error // set when the stream was canceled, either locally or by the receiver
+	done        chan struct{}
+}
+
+func newSendStream(id uint64, sender *Sender) *SendStream {
+	s := &SendStream{
+		id:            id,
+		sender:        sender,
+		maxBuffered:   sender.config.MaxBufferedBytes,
+		resumeTimeout: sender.config.ResumeTimeout,
+		done:          make(chan struct{}),
+	}
+	s.cond.L = &s.mutex
+	return s
+}
+
+// StreamID returns the ID assigned to the stream.
+// It is different from the ID of the QUIC stream the data is sent on.
+func (s *SendStream) StreamID() uint64 { return s.id }
+
+// Write writes data to the stream.
+// The data is retained until the receiver acknowledges consuming it.
+// Write blocks when MaxBufferedBytes are retained.
+// In particular, it blocks when the connection broke, until the stream is resumed.
+func (s *SendStream) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var n int
+	for n < len(p) {
+		if s.err != nil {
+			return n, s.err
+		}
+		if s.finished {
+			return n, fmt.Errorf("write on closed stream %d", s.id)
+		}
+		space := s.maxBuffered - len(s.buf)
+		if space <= 0 {
+			s.cond.Wait()
+			continue
+		}
+		if space > len(p)-n {
+			space = len(p) - n
+		}
+		s.buf = append(s.buf, p[n:n+space]...)
+		n += space
+		s.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Close closes the stream for writing.
+// Data that was already written is still delivered, even if the stream needs to be resumed.
+func (s *SendStream) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.finished = true
+	s.cond.Broadcast()
+	return nil
+}
+
+// Cancel aborts sending the stream.
+// The stream can't be resumed afterwards.
+func (s *SendStream) Cancel() {
+	s.cancel(errCanceled)
+}
+
+func (s *SendStream) cancel(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.str != nil {
+		resetStream(s.str, ErrCodeCanceled)
+		s.str = nil
+	}
+	s.closeWithError(err)
+}
+
+// Done is closed once the receiver has consumed all data sent on the stream.
+func (s *SendStream) Done() <-chan struct{} { return s.done }
+
+// attach starts sending the data on str, starting at offset.
+// The stream that the data was sent on before is reset.
+func (s *SendStream) attach(str quic.Stream, offset uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if offset < s.ackedOffset || offset > s.ackedOffset+uint64(len(s.buf)) {
+		return fmt.Errorf("invalid offset %d for stream %d", offset, s.id)
+	}
+	// The receiver has consumed all data up to offset.
+	s.onAcked(offset)
+	s.stopResumeTimer()
+	if s.str != nil {
+		// This unblocks the run loop, if it is blocked writing to a broken connection.
+		resetStream(s.str, ErrCodeCanceled)
+	}
+	s.str = str
+	s.sentOffset = offset
+	s.finSent = false
+	s.cond.Broadcast()
+	go s.readAcks(str)
+	return nil
+}
+
+func (s *SendStream) hasDataToSend() bool {
+	if s.str == nil {
+		return false
+	}
+	return s.sentOffset < s.ackedOffset+uint64(len(s.buf)) || (s.finished && !s.finSent)
+}
+
+func (s *SendStream) run() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		for s.err == nil && !s.isDone() && !s.hasDataToSend() {
+			s.cond.Wait()
+		}
+		if s.err != nil || s.isDone() {
+			return
+		}
+		str := s.str
+		if s.sentOffset == s.ackedOffset+uint64(len(s.buf)) {
+			s.finSent = true
+			s.mutex.Unlock()
+			err := str.Close()
+			s.mutex.Lock()
+			if err != nil && s.str == str {
+				s.handleError(err)
+			}
+			continue
+		}
+		data := s.buf[s.sentOffset-s.ackedOffset:]
+		if len(data) > maxWriteSize {
+			data = data[:maxWriteSize]
+		}
+		data = append([]byte{}, data...)
+		s.mutex.Unlock()
+		n, err := str.Write(data)
+		s.mutex.Lock()
+		// The stream might have been resumed on a different QUIC stream in the meantime.
+		if s.str != str {
+			continue
+		}
+		s.sentOffset += uint64(n)
+		if err != nil {
+			s.handleError(err)
+		}
+	}
+}
+
+func (s *SendStream) readAcks(str quic.Stream) {
+	r := quicvarint.NewReader(str)
+	for {
+		offset, err := quicvarint.Read(r)
+		s.mutex.Lock()
+		if s.str != str {
+			s.mutex.Unlock()
+			return
+		}
+		if err != nil {
+			// The receiver closes the stream after it has consumed all data (including the FIN).
+			if err == io.EOF && s.finished && s.ackedOffset+uint64(len(s.buf)) == s.sentOffset {
+				s.str = nil
+				s.sender.removeStream(s.id)
+				close(s.done)
+				s.cond.Broadcast()
+			} else {
+				s.handleError(err)
+			}
+			s.mutex.Unlock()
+			return
+		}
+		s.onAcked(offset)
+		s.mutex.Unlock()
+	}
+}
+
+func (s *SendStream) onAcked(offset uint64) {
+	if offset <= s.ackedOffset || offset > s.ackedOffset+uint64(len(s.buf)) {
+		return
+	}
+	s.buf = s.buf[offset-s.ackedOffset:]
+	s.ackedOffset = offset
+	s.cond.Broadcast()
+}
+
+// handleError handles an error that occurred on the current QUIC stream.
+func (s *SendStream) handleError(err error) {
+	if isInterruption(err) {
+		// Wait for the receiver to resume the stream.
+		resetStream(s.str, ErrCodeCanceled)
+		s.str = nil
+		s.startResumeTimer()
+		return
+	}
+	s.str = nil
+	s.closeWithError(err)
+}
+
+func (s *SendStream) startResumeTimer() {
+	s.stopResumeTimer()
+	var t *time.Timer
+	t = time.AfterFunc(s.resumeTimeout, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		// The stream might have been resumed (and interrupted again) in the meantime.
+		if s.resumeTimer != t {
+			return
+		}
+		s.closeWithError(ErrResumeTimeout)
+	})
+	s.resumeTimer = t
+}
+
+func (s *SendStream) stopResumeTimer() {
+	if s.resumeTimer != nil {
+		s.resumeTimer.Stop()
+		s.resumeTimer = nil
+	}
+}
+
+func (s *SendStream) closeWithError(err error) {
+	if s.err != nil || s.isDone() {
+		return
+	}
+	s.stopResumeTimer()
+	s.err = err
+	s.buf = nil
+	s.sender.removeStream(s.id)
+	s.cond.Broadcast()
+}
+
+func (s *SendStream) isDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func resetStream(str quic.Stream, code quic.StreamErrorCode) {
+	str.CancelWrite(code)
+	str.CancelRead(code)
+}
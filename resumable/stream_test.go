@@ -0,0 +1,288 @@
+package resumable
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resumable Streams", func() {
+	const alpn = "quic-go-resumable-test"
+
+	var (
+		ln    quic.Listener
+		conns chan quic.Connection
+	)
+
+	BeforeEach(func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{alpn}
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		conns = make(chan quic.Connection, 10)
+		go func() {
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				conns <- conn
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	dial := func() (client, server quic.Connection) {
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			&tls.Config{RootCAs: testdata.GetRootCA(), NextProtos: []string{alpn}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(conns).Should(Receive(&server))
+		return conn, server
+	}
+
+	getData := func(l int) []byte {
+		b := make([]byte, l)
+		rand.Read(b)
+		return b
+	}
+
+	It("transfers a stream", func() {
+		data := getData(50 << 10)
+		clientConn, serverConn := dial()
+		defer clientConn.CloseWithError(0, "")
+
+		sender := NewSender(nil)
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		rstr, err := NewReceiver(nil).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rstr.StreamID()).To(Equal(str.StreamID()))
+		b, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal(data))
+		Eventually(str.Done()).Should(BeClosed())
+	})
+
+	It("only retains data that wasn't consumed yet", func() {
+		data := getData(500 << 10)
+		clientConn, serverConn := dial()
+		defer clientConn.CloseWithError(0, "")
+
+		sender := NewSender(&Config{MaxBufferedBytes: 32 << 10})
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		rstr, err := NewReceiver(&Config{AckInterval: 8 << 10}).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal(data))
+		Eventually(str.Done()).Should(BeClosed())
+	})
+
+	It("resumes a stream on a new connection", func() {
+		data := getData(200 << 10)
+		clientConn, serverConn := dial()
+
+		sender := NewSender(nil)
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		rstr, err := NewReceiver(&Config{AckInterval: 1 << 10}).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		received := make([]byte, 50<<10)
+		_, err = io.ReadFull(rstr, received)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rstr.Offset()).To(BeEquivalentTo(50 << 10))
+		// break the connection
+		Expect(clientConn.CloseWithError(0, "")).To(Succeed())
+		_, err = io.ReadAll(rstr)
+		Expect(err).To(MatchError(ErrInterrupted))
+
+		clientConn, serverConn = dial()
+		defer clientConn.CloseWithError(0, "")
+		resumed := make(chan *SendStream, 1)
+		go func() {
+			defer GinkgoRecover()
+			str, err := sender.Accept(context.Background(), serverConn)
+			Expect(err).ToNot(HaveOccurred())
+			resumed <- str
+		}()
+		Expect(rstr.Resume(context.Background(), clientConn)).To(Succeed())
+		rest, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(append(received, rest...)).To(Equal(data))
+		Eventually(resumed).Should(Receive(Equal(str)))
+		Eventually(str.Done()).Should(BeClosed())
+	})
+
+	It("resumes a stream while another stream doesn't send its header", func() {
+		data := getData(10 << 10)
+		clientConn, serverConn := dial()
+
+		sender := NewSender(nil)
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+
+		rstr, err := NewReceiver(nil).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientConn.CloseWithError(0, "")).To(Succeed())
+		received, err := io.ReadAll(rstr)
+		Expect(err).To(MatchError(ErrInterrupted))
+
+		clientConn, serverConn = dial()
+		defer clientConn.CloseWithError(0, "")
+		// This stream only sends the first byte of the header.
+		stalled, err := clientConn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stalled.Write([]byte{version})
+		Expect(err).ToNot(HaveOccurred())
+		resumed := make(chan *SendStream, 1)
+		go func() {
+			defer GinkgoRecover()
+			str, err := sender.Accept(context.Background(), serverConn)
+			Expect(err).ToNot(HaveOccurred())
+			resumed <- str
+		}()
+		// make sure the stalled stream is accepted first
+		time.Sleep(20 * time.Millisecond)
+		Expect(rstr.Resume(context.Background(), clientConn)).To(Succeed())
+		Eventually(resumed).Should(Receive(Equal(str)))
+		rest, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(append(received, rest...)).To(Equal(data))
+	})
+
+	It("rejects resuming unknown streams", func() {
+		clientConn, serverConn := dial()
+		sender := NewSender(nil)
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		rstr, err := NewReceiver(nil).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientConn.CloseWithError(0, "")).To(Succeed())
+		// the sender gives up on the stream
+		str.Cancel()
+
+		clientConn, serverConn = dial()
+		defer clientConn.CloseWithError(0, "")
+		go sender.Accept(context.Background(), serverConn)
+		Expect(rstr.Resume(context.Background(), clientConn)).To(Succeed())
+		_, err = io.ReadAll(rstr)
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(Equal(ErrCodeUnknownStream))
+	})
+
+	numStreams := func(sender *Sender) int {
+		sender.mutex.Lock()
+		defer sender.mutex.Unlock()
+		return len(sender.streams)
+	}
+
+	It("frees streams that aren't resumed in time", func() {
+		clientConn, serverConn := dial()
+		sender := NewSender(&Config{ResumeTimeout: 50 * time.Millisecond})
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = NewReceiver(nil).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(numStreams(sender)).To(Equal(1))
+		// break the connection, and never resume the stream
+		Expect(clientConn.CloseWithError(0, "")).To(Succeed())
+		Eventually(func() int { return numStreams(sender) }).Should(BeZero())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).To(MatchError(ErrResumeTimeout))
+		str.mutex.Lock()
+		defer str.mutex.Unlock()
+		Expect(str.buf).To(BeNil())
+	})
+
+	It("frees all streams when the sender is closed", func() {
+		clientConn, serverConn := dial()
+		defer clientConn.CloseWithError(0, "")
+
+		sender := NewSender(&Config{MaxBufferedBytes: 1000})
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		// The receiver never consumes the data, so this Write blocks.
+		writeErr := make(chan error, 1)
+		go func() {
+			_, err := str.Write(make([]byte, 2000))
+			writeErr <- err
+		}()
+		Consistently(writeErr).ShouldNot(Receive())
+		Expect(sender.Close()).To(Succeed())
+		Eventually(writeErr).Should(Receive(MatchError(errSenderClosed)))
+		Expect(numStreams(sender)).To(BeZero())
+		_, err = sender.OpenStream(serverConn)
+		Expect(err).To(MatchError(errSenderClosed))
+	})
+
+	It("cancels a stream", func() {
+		clientConn, serverConn := dial()
+		defer clientConn.CloseWithError(0, "")
+
+		sender := NewSender(nil)
+		str, err := sender.OpenStream(serverConn)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		rstr, err := NewReceiver(nil).AcceptStream(context.Background(), clientConn)
+		Expect(err).ToNot(HaveOccurred())
+		rstr.Cancel()
+		_, err = rstr.Read([]byte{0})
+		Expect(err).To(MatchError(errCanceled))
+		Eventually(func() error {
+			_, err := str.Write([]byte("foobar"))
+			return err
+		}, time.Second).Should(HaveOccurred())
+	})
+})
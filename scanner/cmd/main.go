@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/scanner"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "output the report in JSON format")
+	alpns := flag.String("alpn", "h3", "comma-separated list of application protocols to probe")
+	serverName := flag.String("sni", "", "server name used for SNI and certificate verification (default: the host part of the address)")
+	caFile := flag.String("ca", "", "PEM file with the root CAs used to verify the certificate (default: the system roots)")
+	handshakeTimeout := flag.Duration("timeout", 5*time.Second, "handshake timeout")
+	probeDuration := flag.Duration("probe-duration", 2*time.Second, "duration of the key update, ECN, payload size and migration probes")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] host:port\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := &scanner.Options{
+		ServerName:       *serverName,
+		ALPNs:            strings.Split(*alpns, ","),
+		HandshakeTimeout: *handshakeTimeout,
+		ProbeDuration:    *probeDuration,
+	}
+	if *caFile != "" {
+		data, err := ioutil.ReadFile(*caFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.RootCAs = x509.NewCertPool()
+		if !opts.RootCAs.AppendCertsFromPEM(data) {
+			log.Fatalf("no certificates found in %s", *caFile)
+		}
+	}
+
+	report, scanErr := scanner.Scan(context.Background(), flag.Arg(0), opts)
+	if report == nil {
+		log.Fatal(scanErr)
+	}
+	var err error
+	if *jsonOutput {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if scanErr != nil {
+		log.Fatal(scanErr)
+	}
+}
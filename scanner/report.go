@@ -0,0 +1,274 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// A Report is the result of a scan.
+// It can be serialized to JSON.
+type Report struct {
+	Address    string `json:"address"`
+	ServerName string `json:"server_name"`
+	// Versions are the versions listed in the Version Negotiation packet.
+	// It is empty if the server didn't respond to a packet with a reserved version.
+	Versions []string `json:"versions,omitempty"`
+	// Version is the version used for the connections.
+	Version string `json:"version,omitempty"`
+	// ALPNs are the probed application protocols that the server accepted.
+	ALPNs               []string             `json:"alpns"`
+	TransportParameters *TransportParameters `json:"transport_parameters,omitempty"`
+	Certificates        []Certificate        `json:"certificates,omitempty"`
+	// CertificateError is set if the certificate chain couldn't be verified.
+	CertificateError string `json:"certificate_error,omitempty"`
+
+	// Retry is set if the server sent a Retry packet.
+	Retry bool `json:"retry"`
+	// Resumption is set if the server resumed the TLS session.
+	Resumption bool `json:"resumption"`
+	// ZeroRTT is set if the server accepted 0-RTT.
+	ZeroRTT bool `json:"zero_rtt"`
+	// Datagrams is set if the server supports the DATAGRAM extension (RFC 9221).
+	Datagrams   bool         `json:"datagrams"`
+	KeyUpdate   *KeyUpdate   `json:"key_update,omitempty"`
+	ECN         *ECN         `json:"ecn,omitempty"`
+	PayloadSize *PayloadSize `json:"payload_size,omitempty"`
+	Migration   *Migration   `json:"migration,omitempty"`
+
+	// Errors contains the errors of the probes that failed, keyed by the name of the probe.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Duration is a time.Duration that is serialized as a string, e.g. "25ms".
+type Duration time.Duration
+
+// MarshalJSON marshals the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// TransportParameters are the transport parameters sent by the server.
+type TransportParameters struct {
+	InitialMaxStreamDataBidiLocal  uint64   `json:"initial_max_stream_data_bidi_local"`
+	InitialMaxStreamDataBidiRemote uint64   `json:"initial_max_stream_data_bidi_remote"`
+	InitialMaxStreamDataUni        uint64   `json:"initial_max_stream_data_uni"`
+	InitialMaxData                 uint64   `json:"initial_max_data"`
+	InitialMaxStreamsBidi          int64    `json:"initial_max_streams_bidi"`
+	InitialMaxStreamsUni           int64    `json:"initial_max_streams_uni"`
+	MaxIdleTimeout                 Duration `json:"max_idle_timeout"`
+	MaxAckDelay                    Duration `json:"max_ack_delay"`
+	AckDelayExponent               uint8    `json:"ack_delay_exponent"`
+	MaxUDPPayloadSize              uint64   `json:"max_udp_payload_size"`
+	DisableActiveMigration         bool     `json:"disable_active_migration"`
+	ActiveConnectionIDLimit        uint64   `json:"active_connection_id_limit"`
+	MaxDatagramFrameSize           uint64   `json:"max_datagram_frame_size,omitempty"`
+	PreferredAddress               string   `json:"preferred_address,omitempty"`
+	StatelessResetToken            bool     `json:"stateless_reset_token"`
+}
+
+func newTransportParameters(tp *logging.TransportParameters) *TransportParameters {
+	p := &TransportParameters{
+		InitialMaxStreamDataBidiLocal:  uint64(tp.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote: uint64(tp.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:        uint64(tp.InitialMaxStreamDataUni),
+		InitialMaxData:                 uint64(tp.InitialMaxData),
+		InitialMaxStreamsBidi:          int64(tp.MaxBidiStreamNum),
+		InitialMaxStreamsUni:           int64(tp.MaxUniStreamNum),
+		MaxIdleTimeout:                 Duration(tp.MaxIdleTimeout),
+		MaxAckDelay:                    Duration(tp.MaxAckDelay),
+		AckDelayExponent:               tp.AckDelayExponent,
+		MaxUDPPayloadSize:              uint64(tp.MaxUDPPayloadSize),
+		DisableActiveMigration:         tp.DisableActiveMigration,
+		ActiveConnectionIDLimit:        tp.ActiveConnectionIDLimit,
+		StatelessResetToken:            tp.StatelessResetToken != nil,
+	}
+	if tp.MaxDatagramFrameSize != protocol.InvalidByteCount {
+		p.MaxDatagramFrameSize = uint64(tp.MaxDatagramFrameSize)
+	}
+	if tp.PreferredAddress != nil {
+		p.PreferredAddress = fmt.Sprintf("%s:%d, [%s]:%d", tp.PreferredAddress.IPv4, tp.PreferredAddress.IPv4Port, tp.PreferredAddress.IPv6, tp.PreferredAddress.IPv6Port)
+	}
+	return p
+}
+
+// A Certificate is a certificate presented by the server.
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// SHA256 is the hex-encoded SHA-256 fingerprint of the certificate.
+	SHA256 string `json:"sha256"`
+}
+
+func newCertificate(cert *x509.Certificate) Certificate {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return Certificate{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		SHA256:    hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// KeyUpdate is the result of the key update probe.
+type KeyUpdate struct {
+	// Supported is set if the keys were updated, and the connection survived the key updates.
+	Supported bool `json:"supported"`
+	// Updates is the number of key phase changes observed on packets received from the server.
+	Updates int `json:"updates"`
+}
+
+// ECN is the result of the ECN probe.
+// The scanner marks outgoing packets with ECT(1), if the platform allows setting the ECN codepoint.
+type ECN struct {
+	// Echoed is set if the server reported ECN counts in its ACK frames.
+	Echoed bool   `json:"echoed"`
+	ECT0   uint64 `json:"ect0"`
+	ECT1   uint64 `json:"ect1"`
+	CE     uint64 `json:"ce"`
+}
+
+// PayloadSize is the result of the maximum UDP payload size probe.
+type PayloadSize struct {
+	// Advertised is the max_udp_payload_size transport parameter.
+	Advertised uint64 `json:"advertised"`
+	// LargestAcknowledged is the size of the largest packet that was acknowledged by the server.
+	// Path MTU discovery increases the packet size, if supported by the path and the server.
+	LargestAcknowledged uint64 `json:"largest_acknowledged"`
+	// LargestReceived is the size of the largest packet received from the server.
+	LargestReceived uint64 `json:"largest_received"`
+}
+
+// Migration is the result of the connection migration probe.
+type Migration struct {
+	// DisableActiveMigration is set if the server sent the disable_active_migration transport parameter.
+	DisableActiveMigration bool `json:"disable_active_migration"`
+	// Tolerated is set if the server sent packets to the new address after a simulated NAT rebinding.
+	Tolerated bool `json:"tolerated"`
+	// PacketsReceived is the number of packets received on the new address.
+	PacketsReceived int `json:"packets_received"`
+}
+
+// WriteJSON writes the report in JSON format.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes a human-readable version of the report.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := &textWriter{w: w}
+	tw.printf("Server:\t\t%s (%s)\n", r.Address, r.ServerName)
+	if len(r.Versions) > 0 {
+		tw.printf("Versions:\t%s\n", strings.Join(r.Versions, ", "))
+	} else {
+		tw.printf("Versions:\tno Version Negotiation packet received\n")
+	}
+	if r.Version != "" {
+		tw.printf("Version used:\t%s\n", r.Version)
+	}
+	tw.printf("ALPNs:\t\t%s\n", strings.Join(r.ALPNs, ", "))
+	if len(r.Certificates) > 0 {
+		tw.printf("Certificates:\n")
+		for _, cert := range r.Certificates {
+			tw.printf("\t%s (issuer: %s)\n", cert.Subject, cert.Issuer)
+			if len(cert.DNSNames) > 0 {
+				tw.printf("\t\tDNS names: %s\n", strings.Join(cert.DNSNames, ", "))
+			}
+			tw.printf("\t\tvalid: %s - %s\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+			tw.printf("\t\tSHA-256: %s\n", cert.SHA256)
+		}
+	}
+	if r.CertificateError != "" {
+		tw.printf("\tverification failed: %s\n", r.CertificateError)
+	} else if len(r.Certificates) > 0 {
+		tw.printf("\tverification succeeded\n")
+	}
+	if tp := r.TransportParameters; tp != nil {
+		tw.printf("Transport Parameters:\n")
+		tw.printf("\tinitial_max_data:\t\t\t%d\n", tp.InitialMaxData)
+		tw.printf("\tinitial_max_stream_data_bidi_local:\t%d\n", tp.InitialMaxStreamDataBidiLocal)
+		tw.printf("\tinitial_max_stream_data_bidi_remote:\t%d\n", tp.InitialMaxStreamDataBidiRemote)
+		tw.printf("\tinitial_max_stream_data_uni:\t\t%d\n", tp.InitialMaxStreamDataUni)
+		tw.printf("\tinitial_max_streams_bidi:\t\t%d\n", tp.InitialMaxStreamsBidi)
+		tw.printf("\tinitial_max_streams_uni:\t\t%d\n", tp.InitialMaxStreamsUni)
+		tw.printf("\tmax_idle_timeout:\t\t\t%s\n", tp.MaxIdleTimeout)
+		tw.printf("\tmax_ack_delay:\t\t\t\t%s\n", tp.MaxAckDelay)
+		tw.printf("\tack_delay_exponent:\t\t\t%d\n", tp.AckDelayExponent)
+		tw.printf("\tmax_udp_payload_size:\t\t\t%d\n", tp.MaxUDPPayloadSize)
+		tw.printf("\tdisable_active_migration:\t\t%t\n", tp.DisableActiveMigration)
+		tw.printf("\tactive_connection_id_limit:\t\t%d\n", tp.ActiveConnectionIDLimit)
+		tw.printf("\tmax_datagram_frame_size:\t\t%d\n", tp.MaxDatagramFrameSize)
+		tw.printf("\tstateless_reset_token:\t\t\t%t\n", tp.StatelessResetToken)
+		if tp.PreferredAddress != "" {
+			tw.printf("\tpreferred_address:\t\t\t%s\n", tp.PreferredAddress)
+		}
+	}
+	tw.printf("Retry:\t\t%s\n", yesNo(r.Retry))
+	tw.printf("Resumption:\t%s\n", yesNo(r.Resumption))
+	tw.printf("0-RTT:\t\t%s\n", yesNo(r.ZeroRTT))
+	tw.printf("Datagrams:\t%s\n", yesNo(r.Datagrams))
+	if r.KeyUpdate != nil {
+		tw.printf("Key Update:\t%s (%d key phase changes)\n", yesNo(r.KeyUpdate.Supported), r.KeyUpdate.Updates)
+	}
+	if r.ECN != nil {
+		tw.printf("ECN echoed:\t%s (ECT(0): %d, ECT(1): %d, CE: %d)\n", yesNo(r.ECN.Echoed), r.ECN.ECT0, r.ECN.ECT1, r.ECN.CE)
+	}
+	if r.PayloadSize != nil {
+		tw.printf("Payload Size:\tadvertised: %d, largest acknowledged: %d, largest received: %d\n", r.PayloadSize.Advertised, r.PayloadSize.LargestAcknowledged, r.PayloadSize.LargestReceived)
+	}
+	if r.Migration != nil {
+		tw.printf("Migration:\t%s (%d packets received after rebinding, disable_active_migration: %t)\n", yesNo(r.Migration.Tolerated), r.Migration.PacketsReceived, r.Migration.DisableActiveMigration)
+	}
+	if len(r.Errors) > 0 {
+		probes := make([]string, 0, len(r.Errors))
+		for probe := range r.Errors {
+			probes = append(probes, probe)
+		}
+		sort.Strings(probes)
+		tw.printf("Errors:\n")
+		for _, probe := range probes {
+			tw.printf("\t%s: %s\n", probe, r.Errors[probe])
+		}
+	}
+	return tw.err
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// textWriter remembers the first error that occurred while writing.
+type textWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *textWriter) printf(format string, a ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, a...)
+}
@@ -0,0 +1,373 @@
+// Package scanner probes the capabilities of a QUIC server.
+// It uses the quic-go client to establish a number of connections to the server,
+// and reports the supported versions, the transport parameters, the negotiated application protocols and the certificate,
+// as well as whether the server supports Retry, 0-RTT, key updates, DATAGRAM frames, ECN, large packets and connection migration.
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testnet"
+)
+
+// noApplicationProtocol is the CRYPTO_ERROR for the TLS no_application_protocol alert.
+const noApplicationProtocol = quic.TransportErrorCode(0x100 + 120)
+
+const (
+	defaultHandshakeTimeout = 5 * time.Second
+	defaultProbeDuration    = 2 * time.Second
+)
+
+// Options configure the scan.
+type Options struct {
+	// ServerName is used for SNI and for the verification of the certificate.
+	// If empty, the host part of the address is used.
+	ServerName string
+	// ALPNs are the application protocols that are probed.
+	// At least one application protocol is required.
+	ALPNs []string
+	// RootCAs are used to verify the certificate chain presented by the server.
+	// If nil, the host's root CA set is used.
+	// The scan continues even if the certificate can't be verified.
+	RootCAs *x509.CertPool
+	// HandshakeTimeout is the maximum time to wait for a handshake to complete,
+	// and for the response to the version negotiation probe.
+	// If zero, a timeout of 5 seconds is used.
+	HandshakeTimeout time.Duration
+	// ProbeDuration is the time that connections are kept open for the probes that require ongoing traffic,
+	// i.e. the key update, ECN, payload size and connection migration probes.
+	// If zero, 2 seconds are used.
+	ProbeDuration time.Duration
+}
+
+func (o *Options) handshakeTimeout() time.Duration {
+	if o.HandshakeTimeout == 0 {
+		return defaultHandshakeTimeout
+	}
+	return o.HandshakeTimeout
+}
+
+func (o *Options) probeDuration() time.Duration {
+	if o.ProbeDuration == 0 {
+		return defaultProbeDuration
+	}
+	return o.ProbeDuration
+}
+
+type scan struct {
+	opts       *Options
+	addr       *net.UDPAddr
+	serverName string
+	report     *Report
+
+	alpn         string
+	sessionCache *sessionCache
+}
+
+// Scan probes the QUIC server at addr.
+// An error is only returned if the server can't be reached at all.
+// Failures of individual probes are recorded in the Errors field of the report.
+//
+// The key update probe configures its connection to update keys as frequently as possible.
+// This only applies to that connection, other QUIC connections in the process are not affected.
+func Scan(ctx context.Context, addr string, opts *Options) (*Report, error) {
+	if opts == nil || len(opts.ALPNs) == 0 {
+		return nil, errors.New("scanner: at least one ALPN is required")
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	serverName := opts.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+	s := &scan{
+		opts:       opts,
+		addr:       udpAddr,
+		serverName: serverName,
+		report: &Report{
+			Address:    udpAddr.String(),
+			ServerName: serverName,
+		},
+	}
+	s.probeVersionNegotiation(ctx)
+	tracer, err := s.probeALPNs(ctx)
+	if err != nil {
+		return s.report, err
+	}
+	s.report.Retry = tracer.receivedRetry()
+	s.probeResumption(ctx)
+	s.probeKeyUpdate(ctx)
+	s.probeMigration(ctx)
+	return s.report, nil
+}
+
+func (s *scan) tlsConfig(alpn string, cache tls.ClientSessionCache) *tls.Config {
+	return &tls.Config{
+		ServerName: s.serverName,
+		NextProtos: []string{alpn},
+		// The certificate chain is verified after the handshake,
+		// such that the scan can continue if the certificate is invalid.
+		InsecureSkipVerify: true,
+		ClientSessionCache: cache,
+	}
+}
+
+func (s *scan) quicConfig(t *connTracer) *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout: s.opts.handshakeTimeout(),
+		EnableDatagrams:      true,
+		Tracer:               &tracer{connTracer: t},
+	}
+}
+
+func (s *scan) addError(probe string, err error) {
+	if s.report.Errors == nil {
+		s.report.Errors = make(map[string]string)
+	}
+	s.report.Errors[probe] = err.Error()
+}
+
+func (s *scan) dial(ctx context.Context, conn net.PacketConn, tlsConf *tls.Config, conf *quic.Config) (quic.Connection, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.handshakeTimeout())
+	defer cancel()
+	if conn == nil {
+		return quic.DialAddrContext(ctx, s.addr.String(), tlsConf, conf)
+	}
+	return quic.DialContext(ctx, conn, s.addr, s.serverName, tlsConf, conf)
+}
+
+// probeALPNs performs one handshake for every ALPN.
+// The first successful connection is used to record the transport parameters, the certificate and the negotiated version.
+func (s *scan) probeALPNs(ctx context.Context) (*connTracer, error) {
+	var firstTracer *connTracer
+	var lastErr error
+	for _, alpn := range s.opts.ALPNs {
+		t := newConnTracer()
+		// Use a separate session cache for every ALPN,
+		// such that the resumption probe uses a session ticket issued for the first ALPN.
+		cache := newSessionCache()
+		conn, err := s.dial(ctx, nil, s.tlsConfig(alpn, cache), s.quicConfig(t))
+		if err != nil {
+			var transportErr *quic.TransportError
+			if !errors.As(err, &transportErr) || transportErr.ErrorCode != noApplicationProtocol {
+				s.addError("alpn "+alpn, err)
+			}
+			lastErr = err
+			continue
+		}
+		s.report.ALPNs = append(s.report.ALPNs, alpn)
+		if firstTracer == nil {
+			firstTracer = t
+			s.alpn = alpn
+			s.sessionCache = cache
+			s.recordConnection(conn, t)
+			// wait for the session ticket, so it can be used for the resumption probe
+			select {
+			case <-s.sessionCache.stored:
+			case <-time.After(s.opts.handshakeTimeout()):
+			case <-ctx.Done():
+			}
+		}
+		conn.CloseWithError(0, "")
+	}
+	if firstTracer == nil {
+		return nil, fmt.Errorf("scanner: handshake failed: %w", lastErr)
+	}
+	return firstTracer, nil
+}
+
+func (s *scan) recordConnection(conn quic.Connection, t *connTracer) {
+	state := conn.ConnectionState()
+	s.report.Version = t.version().String()
+	if tp := t.transportParameters(); tp != nil {
+		s.report.TransportParameters = newTransportParameters(tp)
+		// A max_datagram_frame_size of 0 means that DATAGRAM frames are not supported, see section 3 of RFC 9221.
+		s.report.Datagrams = s.report.TransportParameters.MaxDatagramFrameSize > 0
+	}
+	certs := state.TLS.PeerCertificates
+	for _, cert := range certs {
+		s.report.Certificates = append(s.report.Certificates, newCertificate(cert))
+	}
+	if len(certs) == 0 {
+		s.report.CertificateError = "no certificate"
+		return
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       s.serverName,
+		Roots:         s.opts.RootCAs,
+		Intermediates: intermediates,
+	}); err != nil {
+		s.report.CertificateError = err.Error()
+	}
+}
+
+// probeResumption resumes the session established by the ALPN probe, and attempts to use 0-RTT.
+func (s *scan) probeResumption(ctx context.Context) {
+	if s.sessionCache == nil || !s.sessionCache.hasSession() {
+		s.addError("resumption", errors.New("no session ticket received"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.opts.handshakeTimeout())
+	defer cancel()
+	conn, err := quic.DialAddrEarlyContext(ctx, s.addr.String(), s.tlsConfig(s.alpn, s.sessionCache), s.quicConfig(newConnTracer()))
+	if err != nil {
+		s.addError("resumption", err)
+		return
+	}
+	defer conn.CloseWithError(0, "")
+	select {
+	case <-conn.HandshakeComplete().Done():
+	case <-ctx.Done():
+		s.addError("resumption", ctx.Err())
+		return
+	}
+	state := conn.ConnectionState()
+	s.report.Resumption = state.TLS.DidResume
+	s.report.ZeroRTT = state.TLS.Used0RTT
+}
+
+// probeKeyUpdate keeps a connection open for the probe duration, and updates keys as frequently as possible.
+// It also records whether the server echoes ECN markings, and the size of the packets exchanged on the connection.
+func (s *scan) probeKeyUpdate(ctx context.Context) {
+	// Use a *net.UDPConn, such that outgoing packets can be marked for ECN.
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		s.addError("key update", err)
+		return
+	}
+	defer udpConn.Close()
+
+	t := newConnTracer()
+	conf := s.quicConfig(t)
+	conf.EnableL4S = true
+	conf.KeepAlivePeriod = s.opts.probeDuration() / 20
+	conf.KeyUpdateInterval = 1 // update keys as frequently as possible
+
+	conn, err := s.dial(ctx, udpConn, s.tlsConfig(s.alpn, nil), conf)
+	if err != nil {
+		s.addError("key update", err)
+		return
+	}
+
+	closed := s.wait(ctx, conn)
+	if tp := t.transportParameters(); tp != nil {
+		s.report.PayloadSize = &PayloadSize{Advertised: uint64(tp.MaxUDPPayloadSize)}
+	} else {
+		s.report.PayloadSize = &PayloadSize{}
+	}
+	s.report.PayloadSize.LargestAcknowledged, s.report.PayloadSize.LargestReceived = t.packetSizes()
+	s.report.ECN = t.ecn()
+	updates := t.keyUpdates()
+	s.report.KeyUpdate = &KeyUpdate{Updates: updates, Supported: updates > 0 && closed == nil}
+	if closed != nil {
+		s.addError("key update", closed)
+		return
+	}
+	conn.CloseWithError(0, "")
+}
+
+// probeMigration moves a connection to a new local address, simulating a NAT rebinding.
+func (s *scan) probeMigration(ctx context.Context) {
+	conn, err := testnet.NewRebindingConn(nil)
+	if err != nil {
+		s.addError("migration", err)
+		return
+	}
+	defer conn.Close()
+
+	t := newConnTracer()
+	conf := s.quicConfig(t)
+	conf.KeepAlivePeriod = s.opts.probeDuration() / 20
+	qconn, err := s.dial(ctx, conn, s.tlsConfig(s.alpn, nil), conf)
+	if err != nil {
+		s.addError("migration", err)
+		return
+	}
+	defer qconn.CloseWithError(0, "")
+	var disableActiveMigration bool
+	if tp := t.transportParameters(); tp != nil {
+		disableActiveMigration = tp.DisableActiveMigration
+	}
+	if err := conn.Rebind(); err != nil {
+		s.addError("migration", err)
+		return
+	}
+	s.wait(ctx, qconn)
+	received := conn.ReceivedAfterRebind()
+	s.report.Migration = &Migration{
+		DisableActiveMigration: disableActiveMigration,
+		Tolerated:              received > 0,
+		PacketsReceived:        received,
+	}
+}
+
+// wait waits for the probe duration.
+// It returns the error that closed the connection, if the connection was closed in the meantime.
+func (s *scan) wait(ctx context.Context, conn quic.Connection) error {
+	timer := time.NewTimer(s.opts.probeDuration())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-conn.Context().Done():
+		_, err := conn.AcceptStream(context.Background()) // returns the error that closed the connection
+		return err
+	}
+}
+
+type sessionCache struct {
+	tls.ClientSessionCache
+
+	mutex   sync.Mutex
+	session bool
+	stored  chan struct{}
+}
+
+var _ tls.ClientSessionCache = &sessionCache{}
+
+func newSessionCache() *sessionCache {
+	return &sessionCache{
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		stored:             make(chan struct{}, 1),
+	}
+}
+
+func (c *sessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	c.ClientSessionCache.Put(sessionKey, cs)
+	if cs == nil {
+		return
+	}
+	c.mutex.Lock()
+	c.session = true
+	c.mutex.Unlock()
+	select {
+	case c.stored <- struct{}{}:
+	default:
+	}
+}
+
+func (c *sessionCache) hasSession() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.session
+}
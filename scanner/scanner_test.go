@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const alpn = "scanner"
+
+var _ = Describe("Scanner", func() {
+	// runServer runs a server that accepts connections and keeps them open until the server is closed.
+	runServer := func(early bool, conf *quic.Config) (addr string, closeFn func()) {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{alpn}
+		var ln interface {
+			Addr() net.Addr
+			Close() error
+		}
+		var accept func() (quic.Connection, error)
+		if early {
+			eln, err := quic.ListenAddrEarly("localhost:0", tlsConf, conf)
+			Expect(err).ToNot(HaveOccurred())
+			ln = eln
+			accept = func() (quic.Connection, error) { return eln.Accept(context.Background()) }
+		} else {
+			l, err := quic.ListenAddr("localhost:0", tlsConf, conf)
+			Expect(err).ToNot(HaveOccurred())
+			ln = l
+			accept = func() (quic.Connection, error) { return l.Accept(context.Background()) }
+		}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			var conns []quic.Connection
+			for {
+				conn, err := accept()
+				if err != nil {
+					for _, conn := range conns {
+						conn.CloseWithError(0, "")
+					}
+					return
+				}
+				conns = append(conns, conn)
+			}
+		}()
+		return fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port), func() {
+			ln.Close()
+			Eventually(done).Should(BeClosed())
+		}
+	}
+
+	scanOpts := func(alpns ...string) *Options {
+		return &Options{
+			ALPNs:         alpns,
+			RootCAs:       testdata.GetRootCA(),
+			ProbeDuration: 500 * time.Millisecond,
+		}
+	}
+
+	It("requires at least one ALPN", func() {
+		_, err := Scan(context.Background(), "localhost:443", &Options{})
+		Expect(err).To(MatchError("scanner: at least one ALPN is required"))
+	})
+
+	It("scans a server", func() {
+		addr, closeFn := runServer(true, &quic.Config{
+			AcceptToken:     func(net.Addr, *quic.Token) bool { return true },
+			EnableDatagrams: true,
+		})
+		defer closeFn()
+
+		report, err := Scan(context.Background(), addr, scanOpts("foobar", alpn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Errors).To(BeEmpty())
+		Expect(report.ServerName).To(Equal("localhost"))
+		Expect(report.Versions).To(ContainElements("v1", "v2", "draft-29", ContainSubstring("(reserved)")))
+		Expect(report.Versions).To(HaveLen(len(protocol.SupportedVersions) + 1))
+		Expect(report.Version).To(Equal("v1"))
+		Expect(report.ALPNs).To(Equal([]string{alpn}))
+		Expect(report.Certificates).ToNot(BeEmpty())
+		Expect(report.Certificates[0].DNSNames).To(Equal([]string{"localhost"}))
+		Expect(report.Certificates[0].SHA256).To(HaveLen(64))
+		Expect(report.CertificateError).To(BeEmpty())
+		Expect(report.TransportParameters).ToNot(BeNil())
+		Expect(report.TransportParameters.InitialMaxData).ToNot(BeZero())
+		Expect(report.TransportParameters.MaxDatagramFrameSize).ToNot(BeZero())
+		Expect(report.TransportParameters.StatelessResetToken).To(BeTrue())
+		Expect(report.Retry).To(BeFalse())
+		Expect(report.Resumption).To(BeTrue())
+		Expect(report.ZeroRTT).To(BeTrue())
+		Expect(report.Datagrams).To(BeTrue())
+		Expect(report.KeyUpdate.Supported).To(BeTrue())
+		Expect(report.KeyUpdate.Updates).To(BeNumerically(">", 0))
+		Expect(report.PayloadSize.Advertised).To(Equal(report.TransportParameters.MaxUDPPayloadSize))
+		Expect(report.PayloadSize.LargestAcknowledged).To(BeNumerically(">=", protocol.MinInitialPacketSize))
+		Expect(report.PayloadSize.LargestReceived).ToNot(BeZero())
+		if runtime.GOOS == "linux" {
+			Expect(report.ECN.Echoed).To(BeTrue())
+			Expect(report.ECN.ECT1).ToNot(BeZero())
+		}
+		// quic-go doesn't support connection migration
+		Expect(report.Migration.DisableActiveMigration).To(BeTrue())
+		Expect(report.Migration.Tolerated).To(BeFalse())
+	})
+
+	It("detects Retry, and servers that don't accept 0-RTT and datagrams", func() {
+		// by default, quic-go performs a Retry if the client doesn't present a token
+		addr, closeFn := runServer(false, nil)
+		defer closeFn()
+
+		report, err := Scan(context.Background(), addr, scanOpts(alpn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Retry).To(BeTrue())
+		Expect(report.Resumption).To(BeTrue())
+		Expect(report.ZeroRTT).To(BeFalse())
+		Expect(report.Datagrams).To(BeFalse())
+		Expect(report.TransportParameters.MaxDatagramFrameSize).To(BeZero())
+	})
+
+	It("reports certificate verification errors", func() {
+		addr, closeFn := runServer(false, nil)
+		defer closeFn()
+
+		opts := scanOpts(alpn)
+		opts.RootCAs = nil
+		opts.ServerName = "quic-go.net"
+		report, err := Scan(context.Background(), addr, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.ServerName).To(Equal("quic-go.net"))
+		Expect(report.Certificates).ToNot(BeEmpty())
+		Expect(report.CertificateError).ToNot(BeEmpty())
+	})
+
+	It("errors if the server doesn't support any of the ALPNs", func() {
+		addr, closeFn := runServer(false, nil)
+		defer closeFn()
+
+		report, err := Scan(context.Background(), addr, scanOpts("foo", "bar"))
+		Expect(err).To(HaveOccurred())
+		Expect(report.ALPNs).To(BeEmpty())
+		Expect(report.Versions).ToNot(BeEmpty())
+	})
+
+	It("errors if the server is not reachable", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		addr := conn.LocalAddr().String()
+		Expect(conn.Close()).To(Succeed())
+
+		opts := scanOpts(alpn)
+		opts.HandshakeTimeout = 250 * time.Millisecond
+		report, err := Scan(context.Background(), addr, opts)
+		Expect(err).To(HaveOccurred())
+		Expect(report.Errors).To(HaveKey("version negotiation"))
+	})
+
+	Context("output", func() {
+		report := &Report{
+			Address:             "127.0.0.1:443",
+			ServerName:          "quic-go.net",
+			Versions:            []string{"v1", "0x1a2a3a4a (reserved)"},
+			Version:             "v1",
+			ALPNs:               []string{"h3"},
+			TransportParameters: &TransportParameters{MaxIdleTimeout: Duration(30 * time.Second), MaxUDPPayloadSize: 1452},
+			ZeroRTT:             true,
+			KeyUpdate:           &KeyUpdate{Supported: true, Updates: 3},
+			Migration:           &Migration{DisableActiveMigration: true},
+			Errors:              map[string]string{"resumption": "no session ticket received"},
+		}
+
+		It("writes JSON", func() {
+			var buf bytes.Buffer
+			Expect(report.WriteJSON(&buf)).To(Succeed())
+			var m map[string]interface{}
+			Expect(json.Unmarshal(buf.Bytes(), &m)).To(Succeed())
+			Expect(m).To(HaveKeyWithValue("server_name", "quic-go.net"))
+			Expect(m).To(HaveKeyWithValue("zero_rtt", true))
+			Expect(m).To(HaveKeyWithValue("alpns", []interface{}{"h3"}))
+			Expect(m).To(HaveKey("transport_parameters"))
+			Expect(m["transport_parameters"]).To(HaveKeyWithValue("max_idle_timeout", "30s"))
+			Expect(m["key_update"]).To(HaveKeyWithValue("updates", 3.0))
+			Expect(m["errors"]).To(HaveKeyWithValue("resumption", "no session ticket received"))
+			Expect(m).ToNot(HaveKey("ecn"))
+		})
+
+		It("writes text", func() {
+			var buf bytes.Buffer
+			Expect(report.WriteText(&buf)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("v1, 0x1a2a3a4a (reserved)"))
+			Expect(buf.String()).To(ContainSubstring("max_idle_timeout:\t\t\t30s"))
+			Expect(buf.String()).To(ContainSubstring("0-RTT:\t\tyes"))
+			Expect(buf.String()).To(ContainSubstring("Key Update:\tyes (3 key phase changes)"))
+			Expect(buf.String()).To(ContainSubstring("resumption: no session ticket received"))
+		})
+	})
+})
@@ -0,0 +1,198 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+type tracer struct {
+	connTracer *connTracer
+}
+
+var _ logging.Tracer = &tracer{}
+
+func (t *tracer) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return t.connTracer
+}
+func (t *tracer) SentPacket(net.Addr, *logging.Header, logging.ByteCount, []logging.Frame) {}
+func (t *tracer) DroppedPacket(net.Addr, logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+
+// The connTracer records the events that are evaluated by the probes.
+type connTracer struct {
+	mutex sync.Mutex
+
+	tp     *logging.TransportParameters
+	retry  bool
+	vers   logging.VersionNumber
+	ecnRes ECN
+
+	sentSizes       map[logging.PacketNumber]logging.ByteCount // sizes of the 1-RTT packets sent
+	largestAcked    logging.ByteCount
+	largestReceived logging.ByteCount
+
+	received1RTT    bool
+	keyPhase        logging.KeyPhaseBit // key phase of the last 1-RTT packet received
+	keyPhaseChanges int
+}
+
+var _ logging.ConnectionTracer = &connTracer{}
+
+func newConnTracer() *connTracer {
+	return &connTracer{sentSizes: make(map[logging.PacketNumber]logging.ByteCount)}
+}
+
+func (t *connTracer) transportParameters() *logging.TransportParameters {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.tp
+}
+
+func (t *connTracer) receivedRetry() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.retry
+}
+
+func (t *connTracer) version() logging.VersionNumber {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.vers
+}
+
+func (t *connTracer) ecn() *ECN {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	res := t.ecnRes
+	res.Echoed = res.ECT0+res.ECT1+res.CE > 0
+	return &res
+}
+
+// packetSizes returns the size of the largest acknowledged 1-RTT packet, and of the largest 1-RTT packet received.
+func (t *connTracer) packetSizes() (acked, received uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return uint64(t.largestAcked), uint64(t.largestReceived)
+}
+
+// keyUpdates returns the number of key phase changes observed on received 1-RTT packets.
+func (t *connTracer) keyUpdates() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.keyPhaseChanges
+}
+
+func (t *connTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+}
+func (t *connTracer) StartedHandshake(context.Context, *logging.HandshakeInfo) {}
+func (t *connTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
+}
+func (t *connTracer) ClosedConnection(error)                                   {}
+func (t *connTracer) SentTransportParameters(*logging.TransportParameters)     {}
+func (t *connTracer) RestoredTransportParameters(*logging.TransportParameters) {}
+
+func (t *connTracer) ReceivedTransportParameters(tp *logging.TransportParameters) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tp = tp
+}
+
+func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sentSizes[hdr.PacketNumber] = size
+}
+
+func (t *connTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {}
+
+func (t *connTracer) ReceivedRetry(*logging.Header) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.retry = true
+}
+
+func (t *connTracer) ReceivedPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, frames []logging.Frame) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, f := range frames {
+		if ack, ok := f.(*logging.AckFrame); ok {
+			if ack.ECT0 > t.ecnRes.ECT0 {
+				t.ecnRes.ECT0 = ack.ECT0
+			}
+			if ack.ECT1 > t.ecnRes.ECT1 {
+				t.ecnRes.ECT1 = ack.ECT1
+			}
+			if ack.ECNCE > t.ecnRes.CE {
+				t.ecnRes.CE = ack.ECNCE
+			}
+		}
+	}
+	if hdr.IsLongHeader {
+		t.vers = hdr.Version
+		return
+	}
+	if size > t.largestReceived {
+		t.largestReceived = size
+	}
+	if t.received1RTT && hdr.KeyPhase != t.keyPhase {
+		t.keyPhaseChanges++
+	}
+	t.received1RTT = true
+	t.keyPhase = hdr.KeyPhase
+}
+
+func (t *connTracer) AcknowledgedPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber) {
+	if encLevel != logging.Encryption1RTT {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if size, ok := t.sentSizes[pn]; ok {
+		if size > t.largestAcked {
+			t.largestAcked = size
+		}
+		delete(t.sentSizes, pn)
+	}
+}
+
+func (t *connTracer) LostPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber, _ logging.PacketLossReason) {
+	if encLevel != logging.Encryption1RTT {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.sentSizes, pn)
+}
+
+func (t *connTracer) BufferedPacket(logging.PacketType)                                             {}
+func (t *connTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {}
+func (t *connTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+}
+func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                     {}
+func (t *connTracer) UpdatedCongestionParameters(*logging.CongestionParameters)          {}
+func (t *connTracer) UpdatedCongestionMetrics(*logging.CongestionMetrics)                {}
+func (t *connTracer) UpdatedPTOCount(value uint32)                                       {}
+func (t *connTracer) UpdatedFlowLabel(uint32, logging.FlowLabelTrigger)                  {}
+func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
+func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
+func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
+func (t *connTracer) DroppedKey(logging.KeyPhase)                                        {}
+func (t *connTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
+func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
+func (t *connTracer) LossTimerCanceled()                                                 {}
+func (t *connTracer) InjectedFault(logging.FaultDirection, logging.FaultAction, logging.PacketType, logging.ByteCount) {
+}
+func (t *connTracer) QueuedDatagram(logging.ByteCount)                              {}
+func (t *connTracer) SentDatagram(logging.ByteCount)                                {}
+func (t *connTracer) DroppedDatagram(logging.ByteCount, logging.DatagramDropReason) {}
+func (t *connTracer) ReceivedDatagram(logging.ByteCount)                            {}
+func (t *connTracer) LostDatagram(logging.ByteCount)                                {}
+func (t *connTracer) Close()                                                        {}
+func (t *connTracer) Debug(string, string)                                          {}
@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// reservedVersion is a version reserved for triggering version negotiation (see section 15 of RFC 9000).
+const reservedVersion protocol.VersionNumber = 0x1a2a3a4a
+
+// composeVersionNegotiationProbe composes a long header packet using the reserved version.
+// It is padded, such that it is large enough to trigger a Version Negotiation packet.
+func composeVersionNegotiationProbe(destConnID, srcConnID protocol.ConnectionID) []byte {
+	b := make([]byte, 0, int(protocol.MinInitialPacketSize))
+	b = append(b, 0xc0)
+	b = append(b, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(b[1:], uint32(reservedVersion))
+	b = append(b, uint8(destConnID.Len()))
+	b = append(b, destConnID.Bytes()...)
+	b = append(b, uint8(srcConnID.Len()))
+	b = append(b, srcConnID.Bytes()...)
+	return append(b, make([]byte, int(protocol.MinInitialPacketSize)-len(b))...)
+}
+
+// probeVersionNegotiation sends a packet with a reserved version, and lists the versions in the Version Negotiation packet.
+func (s *scan) probeVersionNegotiation(ctx context.Context) {
+	versions, err := s.negotiateVersion(ctx)
+	if err != nil {
+		s.addError("version negotiation", err)
+		return
+	}
+	for _, v := range versions {
+		if v&0x0f0f0f0f == 0x0a0a0a0a {
+			s.report.Versions = append(s.report.Versions, v.String()+" (reserved)")
+			continue
+		}
+		s.report.Versions = append(s.report.Versions, v.String())
+	}
+}
+
+func (s *scan) negotiateVersion(ctx context.Context) ([]protocol.VersionNumber, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	destConnID, err := protocol.GenerateConnectionIDForInitial()
+	if err != nil {
+		return nil, err
+	}
+	srcConnID, err := protocol.GenerateConnectionID(8)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(s.opts.handshakeTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(composeVersionNegotiationProbe(destConnID, srcConnID), s.addr); err != nil {
+		return nil, err
+	}
+	b := make([]byte, protocol.MaxPacketBufferSize)
+	for {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				return nil, errors.New("no Version Negotiation packet received")
+			}
+			return nil, err
+		}
+		if !wire.IsVersionNegotiationPacket(b[:n]) {
+			continue
+		}
+		hdr, versions, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(b[:n]))
+		if err != nil {
+			return nil, err
+		}
+		// the connection IDs are echoed by the server, see section 17.2.1 of RFC 9000
+		if !hdr.DestConnectionID.Equal(srcConnID) || !hdr.SrcConnectionID.Equal(destConnID) {
+			continue
+		}
+		return versions, nil
+	}
+}
@@ -1,7 +1,12 @@
 package quic
 
 import (
+	"errors"
 	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
@@ -12,15 +17,126 @@ type sendConn interface {
 	RemoteAddr() net.Addr
 }
 
+// An ecnSetter is a sendConn that can set the ECN codepoint of outgoing packets.
+type ecnSetter interface {
+	// SetECN sets the ECN codepoint used for all packets sent after this call.
+	// It returns false if the underlying connection doesn't allow setting the codepoint.
+	SetECN(protocol.ECN) bool
+}
+
+// A flowLabelSetter is a sendConn that can set the IPv6 flow label of outgoing packets.
+type flowLabelSetter interface {
+	// RotateFlowLabel chooses a new random flow label, which is used for all packets sent after this call.
+	// The flow label used before is released.
+	RotateFlowLabel() (uint32, error)
+	// ReleaseFlowLabel releases the flow label.
+	// Packets sent after this call don't carry a flow label.
+	ReleaseFlowLabel()
+}
+
+var errFlowLabelsNotSupported = errors.New("setting the flow label is not supported by this connection")
+
+// sendOptions are the options that are set on every outgoing packet using a control message.
+type sendOptions struct {
+	mutex     sync.Mutex
+	ecn       protocol.ECN
+	flowLabel uint32
+
+	oob atomic.Value // []byte, the oob including the control messages, nil if no options are set
+}
+
+func (o *sendOptions) OOB() []byte {
+	oob, _ := o.oob.Load().([]byte)
+	return oob
+}
+
+func (o *sendOptions) SetECN(base []byte, remoteAddr net.Addr, ecn protocol.ECN) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	oldECN := o.ecn
+	o.ecn = ecn
+	if !o.update(base, remoteAddr) {
+		o.ecn = oldECN
+		return false
+	}
+	return true
+}
+
+func (o *sendOptions) RotateFlowLabel(c interface{}, base []byte, remoteAddr net.Addr) (uint32, error) {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok || udpAddr.IP.To4() != nil {
+		return 0, errors.New("flow labels can only be used with IPv6")
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	label, err := leaseFlowLabel(c, udpAddr)
+	if err != nil {
+		return 0, err
+	}
+	oldLabel := o.flowLabel
+	o.flowLabel = label
+	if !o.update(base, remoteAddr) {
+		o.flowLabel = oldLabel
+		releaseFlowLabel(c, label)
+		return 0, errFlowLabelsNotSupported
+	}
+	if oldLabel != 0 {
+		releaseFlowLabel(c, oldLabel)
+	}
+	return label, nil
+}
+
+func (o *sendOptions) ReleaseFlowLabel(c interface{}, base []byte, remoteAddr net.Addr) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.flowLabel == 0 {
+		return
+	}
+	label := o.flowLabel
+	o.flowLabel = 0
+	// Removing the flow label from the control message can't fail.
+	o.update(base, remoteAddr)
+	releaseFlowLabel(c, label)
+}
+
+// update must be called with the mutex held.
+func (o *sendOptions) update(base []byte, remoteAddr net.Addr) bool {
+	if o.ecn == protocol.ECNNon && o.flowLabel == 0 {
+		o.oob.Store([]byte(nil))
+		return true
+	}
+	oob := append([]byte{}, base...)
+	if o.ecn != protocol.ECNNon {
+		if oob = appendECNOOB(oob, remoteAddr, o.ecn); oob == nil {
+			return false
+		}
+	}
+	if o.flowLabel != 0 {
+		if oob = appendFlowLabelOOB(oob, o.flowLabel); oob == nil {
+			return false
+		}
+	}
+	o.oob.Store(oob)
+	return true
+}
+
 type sconn struct {
 	rawConn
 
 	remoteAddr net.Addr
 	info       *packetInfo
 	oob        []byte
+	options    sendOptions
 }
 
-var _ sendConn = &sconn{}
+var (
+	_ sendConn        = &sconn{}
+	_ ecnSetter       = &sconn{}
+	_ flowLabelSetter = &sconn{}
+)
 
 func newSendConn(c rawConn, remote net.Addr, info *packetInfo) sendConn {
 	return &sconn{
@@ -32,10 +148,34 @@ func newSendConn(c rawConn, remote net.Addr, info *packetInfo) sendConn {
 }
 
 func (c *sconn) Write(p []byte) error {
-	_, err := c.WritePacket(p, c.remoteAddr, c.oob)
+	oob := c.oob
+	if optionsOOB := c.options.OOB(); optionsOOB != nil {
+		oob = optionsOOB
+	}
+	_, err := c.WritePacket(p, c.remoteAddr, oob)
 	return err
 }
 
+func (c *sconn) SetECN(ecn protocol.ECN) bool {
+	// The basicConn doesn't use the oob.
+	if _, ok := c.rawConn.(*basicConn); ok && ecn != protocol.ECNNon {
+		return false
+	}
+	return c.options.SetECN(c.oob, c.remoteAddr, ecn)
+}
+
+func (c *sconn) RotateFlowLabel() (uint32, error) {
+	// The basicConn doesn't use the oob.
+	if _, ok := c.rawConn.(*basicConn); ok {
+		return 0, errFlowLabelsNotSupported
+	}
+	return c.options.RotateFlowLabel(c.rawConn, c.oob, c.remoteAddr)
+}
+
+func (c *sconn) ReleaseFlowLabel() {
+	c.options.ReleaseFlowLabel(c.rawConn, c.oob, c.remoteAddr)
+}
+
 func (c *sconn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
@@ -56,19 +196,46 @@ type spconn struct {
 	net.PacketConn
 
 	remoteAddr net.Addr
+	options    sendOptions
 }
 
-var _ sendConn = &spconn{}
+var (
+	_ sendConn        = &spconn{}
+	_ ecnSetter       = &spconn{}
+	_ flowLabelSetter = &spconn{}
+)
 
 func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
 	return &spconn{PacketConn: c, remoteAddr: remote}
 }
 
 func (c *spconn) Write(p []byte) error {
+	if oob := c.options.OOB(); oob != nil {
+		_, _, err := c.PacketConn.(OOBCapablePacketConn).WriteMsgUDP(p, oob, c.remoteAddr.(*net.UDPAddr))
+		return err
+	}
 	_, err := c.WriteTo(p, c.remoteAddr)
 	return err
 }
 
+func (c *spconn) SetECN(ecn protocol.ECN) bool {
+	if _, ok := c.PacketConn.(OOBCapablePacketConn); !ok && ecn != protocol.ECNNon {
+		return false
+	}
+	return c.options.SetECN(nil, c.remoteAddr, ecn)
+}
+
+func (c *spconn) RotateFlowLabel() (uint32, error) {
+	if _, ok := c.PacketConn.(OOBCapablePacketConn); !ok {
+		return 0, errFlowLabelsNotSupported
+	}
+	return c.options.RotateFlowLabel(c.PacketConn, nil, c.remoteAddr)
+}
+
+func (c *spconn) ReleaseFlowLabel() {
+	c.options.ReleaseFlowLabel(c.PacketConn, nil, c.remoteAddr)
+}
+
 func (c *spconn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
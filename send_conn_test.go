@@ -3,6 +3,8 @@ package quic
 import (
 	"net"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -25,6 +27,12 @@ var _ = Describe("Connection (for sending packets)", func() {
 		Expect(c.Write([]byte("foobar"))).To(Succeed())
 	})
 
+	It("doesn't set the ECN codepoint if the connection doesn't support OOB", func() {
+		Expect(c.(ecnSetter).SetECN(protocol.ECT1)).To(BeFalse())
+		packetConn.EXPECT().WriteTo([]byte("foobar"), addr)
+		Expect(c.Write([]byte("foobar"))).To(Succeed())
+	})
+
 	It("gets the remote address", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
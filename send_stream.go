@@ -53,6 +53,21 @@ type sendStream struct {
 	writeOnce chan struct{}
 	deadline  time.Time
 
+	notifyMutex sync.Mutex
+	writeNotify func() // set by SetWriteNotify
+
+	corkDelay time.Duration   // set by SetCorkDelay, or by Config.CorkDelay
+	corkTimer utils.FuncTimer // running while buffered data is held back
+
+	expiry          time.Time // set by SetExpiry
+	expiryErrorCode qerr.StreamErrorCode
+	expiryTimer     utils.FuncTimer
+
+	clock utils.Clock // used by the cork and the expiry timer
+	// Set when the stream was reset using a RESET_STREAM_AT frame.
+	// Data up to this offset is still retransmitted.
+	reliableSize protocol.ByteCount
+
 	flowController flowcontrol.StreamFlowController
 
 	version protocol.VersionNumber
@@ -75,6 +90,7 @@ func newSendStream(
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
 		writeOnce:      make(chan struct{}, 1), // cap: 1, to protect against concurrent use of Write
+		clock:          utils.DefaultClock{},
 		version:        version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
@@ -107,6 +123,9 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
 		return 0, errDeadline
 	}
+	if s.isExpired() {
+		return 0, ErrExpired
+	}
 	if len(p) == 0 {
 		return 0, nil
 	}
@@ -162,8 +181,10 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			}
 		}
 
+		// Small amounts of data are held back if the stream is corked.
+		corked := s.maybeCork()
 		s.mutex.Unlock()
-		if !notifiedSender {
+		if !notifiedSender && !corked {
 			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
 			notifiedSender = true
 		}
@@ -194,6 +215,165 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	return bytesWritten, nil
 }
 
+// TryWrite copies as much of p as fits into the send buffer, without blocking.
+func (s *sendStream) TryWrite(p []byte) (int, error) {
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	s.mutex.Lock()
+	if s.finishedWriting {
+		s.mutex.Unlock()
+		return 0, fmt.Errorf("write on closed stream %d", s.streamID)
+	}
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return 0, s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return 0, s.closeForShutdownErr
+	}
+	if s.isExpired() {
+		s.mutex.Unlock()
+		return 0, ErrExpired
+	}
+	if len(p) == 0 {
+		s.mutex.Unlock()
+		return 0, nil
+	}
+	var buffered protocol.ByteCount
+	if s.nextFrame != nil {
+		buffered = s.nextFrame.DataLen()
+	}
+	n := utils.MinByteCount(protocol.ByteCount(len(p)), protocol.MaxPacketBufferSize-buffered)
+	if n == 0 {
+		s.mutex.Unlock()
+		return 0, ErrWouldBlock
+	}
+	if s.nextFrame == nil {
+		f := wire.GetStreamFrame()
+		f.Offset = s.writeOffset
+		f.StreamID = s.streamID
+		f.DataLenPresent = true
+		f.Data = f.Data[:0]
+		s.nextFrame = f
+	}
+	s.nextFrame.Data = append(s.nextFrame.Data, p[:n]...)
+	corked := s.maybeCork()
+	s.mutex.Unlock()
+
+	if !corked {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+	return int(n), nil
+}
+
+// maybeCork decides if the buffered data is held back.
+// Data is held back if the stream is corked, and less than a full packet of data is buffered.
+// The data is then sent when the cork timer fires, at most corkDelay after the first byte was held back.
+// must be called after locking the mutex
+func (s *sendStream) maybeCork() bool {
+	if s.corkDelay == 0 || s.dataForWriting != nil || s.nextFrame == nil || s.nextFrame.DataLen() >= protocol.CorkedDataThreshold {
+		s.stopCorkTimer()
+		return false
+	}
+	if s.corkTimer == nil {
+		s.corkTimer = utils.AfterFunc(s.clock, s.corkDelay, s.uncork)
+	}
+	return true
+}
+
+// uncork is called when the cork timer fires.
+func (s *sendStream) uncork() {
+	s.mutex.Lock()
+	s.corkTimer = nil
+	s.mutex.Unlock()
+	s.sender.onHasStreamData(s.streamID)
+}
+
+// must be called after locking the mutex
+func (s *sendStream) stopCorkTimer() bool {
+	if s.corkTimer == nil {
+		return false
+	}
+	s.corkTimer.Stop()
+	s.corkTimer = nil
+	return true
+}
+
+func (s *sendStream) SetCorkDelay(d time.Duration) {
+	d = utils.MinDuration(utils.MaxDuration(d, 0), protocol.MaxCorkDelay)
+	s.mutex.Lock()
+	s.corkDelay = d
+	// When uncorking, send the data that is currently held back.
+	wasCorked := d == 0 && s.stopCorkTimer()
+	s.mutex.Unlock()
+
+	if wasCorked {
+		s.sender.onHasStreamData(s.streamID)
+	}
+}
+
+func (s *sendStream) Flush() error {
+	s.mutex.Lock()
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
+	}
+	wasCorked := s.stopCorkTimer()
+	s.mutex.Unlock()
+
+	if wasCorked {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+	return nil
+}
+
+func (s *sendStream) SetExpiry(t time.Time, errorCode StreamErrorCode) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expiry = t
+	s.expiryErrorCode = errorCode
+	s.stopExpiryTimer()
+	if t.IsZero() || s.canceledWrite || s.finSent || s.closedForShutdown {
+		return
+	}
+	s.expiryTimer = utils.AfterFunc(s.clock, t.Sub(s.clock.Now()), s.expire)
+}
+
+// expire is called when the expiry timer fires.
+// If there's still data that hasn't been sent, the stream is reset.
+func (s *sendStream) expire() {
+	s.mutex.Lock()
+	s.expiryTimer = nil
+	if s.canceledWrite || s.closedForShutdown || (s.dataForWriting == nil && s.nextFrame == nil) {
+		s.mutex.Unlock()
+		return
+	}
+	errorCode := s.expiryErrorCode
+	s.mutex.Unlock()
+
+	s.cancelWriteImpl(errorCode, true, ErrExpired)
+}
+
+// must be called after locking the mutex
+func (s *sendStream) isExpired() bool {
+	return !s.expiry.IsZero() && !s.clock.Now().Before(s.expiry)
+}
+
+// must be called after locking the mutex
+func (s *sendStream) stopExpiryTimer() {
+	if s.expiryTimer != nil {
+		s.expiryTimer.Stop()
+		s.expiryTimer = nil
+	}
+}
+
 func (s *sendStream) canBufferStreamFrame() bool {
 	var l protocol.ByteCount
 	if s.nextFrame != nil {
@@ -219,7 +399,12 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Fr
 }
 
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more data to send */) {
-	if s.canceledWrite || s.closeForShutdownErr != nil {
+	if (s.canceledWrite && s.reliableSize == 0) || s.closeForShutdownErr != nil {
+		return nil, false
+	}
+	// While the stream is corked, nothing is sent, not even retransmissions.
+	// The sender is notified when the cork timer fires.
+	if s.corkTimer != nil {
 		return nil, false
 	}
 
@@ -234,6 +419,10 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 			return f, true
 		}
 	}
+	// After a RESET_STREAM_AT, only data below the reliable size is retransmitted.
+	if s.canceledWrite {
+		return nil, false
+	}
 
 	if len(s.dataForWriting) == 0 && s.nextFrame == nil {
 		if s.finishedWriting && !s.finSent {
@@ -286,6 +475,9 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 			s.nextFrame.DataLenPresent = true
 			copy(s.nextFrame.Data, nextFrame.Data[maxDataLen:])
 			nextFrame.Data = nextFrame.Data[:maxDataLen]
+			// Space in the send buffer became available.
+			// Don't signal the writeChan, since Write only continues once the whole STREAM frame was popped.
+			s.notifyWrite()
 		} else {
 			s.signalWrite()
 		}
@@ -354,7 +546,7 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 	f.(*wire.StreamFrame).PutBack()
 
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
@@ -383,7 +575,7 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
@@ -409,18 +601,29 @@ func (s *sendStream) Close() error {
 	}
 	s.ctxCancel()
 	s.finishedWriting = true
+	s.stopCorkTimer()
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID) // need to send the FIN, must be called without holding the mutex
 	return nil
 }
 
+// openForWriting says if the application might still write to the stream,
+// i.e. if it was neither closed nor canceled.
+func (s *sendStream) openForWriting() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !s.finishedWriting && !s.canceledWrite && !s.closedForShutdown
+}
+
 func (s *sendStream) CancelWrite(errorCode StreamErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, false, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
 }
 
-// must be called after locking the mutex
-func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr error) {
+// cancelWriteImpl resets the stream.
+// If reliable is set, and both endpoints support RESET_STREAM_AT,
+// all data that was sent so far is still delivered reliably.
+func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, reliable bool, writeErr error) {
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
@@ -429,16 +632,25 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr er
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
-	s.numOutstandingFrames = 0
-	s.retransmissionQueue = nil
+	s.stopCorkTimer()
+	s.stopExpiryTimer()
+	if reliable && s.writeOffset > 0 && s.sender.supportsReliableReset() {
+		s.reliableSize = s.writeOffset
+	} else {
+		s.numOutstandingFrames = 0
+		s.retransmissionQueue = nil
+	}
 	newlyCompleted := s.isNewlyCompleted()
+	finalSize := s.writeOffset
+	reliableSize := s.reliableSize
 	s.mutex.Unlock()
 
 	s.signalWrite()
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
-		StreamID:  s.streamID,
-		FinalSize: s.writeOffset,
-		ErrorCode: errorCode,
+		StreamID:     s.streamID,
+		FinalSize:    finalSize,
+		ErrorCode:    errorCode,
+		ReliableSize: reliableSize,
 	})
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
@@ -457,7 +669,7 @@ func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 }
 
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
-	s.cancelWriteImpl(frame.ErrorCode, &StreamError{
+	s.cancelWriteImpl(frame.ErrorCode, false, &StreamError{
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
 	})
@@ -483,14 +695,37 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.stopCorkTimer()
+	s.stopExpiryTimer()
 	s.mutex.Unlock()
 	s.signalWrite()
 }
 
-// signalWrite performs a non-blocking send on the writeChan
+func (s *sendStream) SetWriteNotify(f func()) {
+	s.notifyMutex.Lock()
+	s.writeNotify = f
+	s.notifyMutex.Unlock()
+	// Space in the send buffer might have become available before the callback was set.
+	if f != nil {
+		f()
+	}
+}
+
+// signalWrite performs a non-blocking send on the writeChan,
+// and calls the callback set by SetWriteNotify
 func (s *sendStream) signalWrite() {
 	select {
 	case s.writeChan <- struct{}{}:
 	default:
 	}
+	s.notifyWrite()
+}
+
+func (s *sendStream) notifyWrite() {
+	s.notifyMutex.Lock()
+	f := s.writeNotify
+	s.notifyMutex.Unlock()
+	if f != nil {
+		f()
+	}
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -649,6 +650,360 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("non-blocking writes", func() {
+		It("buffers the data", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := str.TryWrite([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(hasMoreData).To(BeFalse())
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(f.Offset).To(BeZero())
+		})
+
+		It("bundles small writes", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := str.TryWrite([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.TryWrite([]byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("only writes as much data as fits into the send buffer", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			data := getData(protocol.MaxPacketBufferSize + 100)
+			n, err := str.TryWrite(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+			n, err = str.TryWrite(data[n:])
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeZero())
+			// popping a STREAM frame frees up space in the send buffer
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(gomock.Any())
+			frame, hasMoreData := str.popStreamFrame(500)
+			Expect(hasMoreData).To(BeTrue())
+			dataLen := frame.Frame.(*wire.StreamFrame).DataLen()
+			Expect(dataLen).To(BeNumerically(">", 400))
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err = str.TryWrite(data[protocol.MaxPacketBufferSize:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(100))
+			Expect(str.nextFrame.Data).To(Equal(data[dataLen:]))
+		})
+
+		It("ignores the write deadline", func() {
+			str.SetWriteDeadline(time.Now().Add(-time.Second))
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := str.TryWrite([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+		})
+
+		It("returns errors", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			_, err := str.TryWrite([]byte("foobar"))
+			Expect(err).To(MatchError("write on closed stream 1337"))
+		})
+
+		Context("notifications", func() {
+			var notified chan struct{}
+
+			BeforeEach(func() {
+				notified = make(chan struct{}, 10)
+				str.SetWriteNotify(func() { notified <- struct{}{} })
+				// the callback is called once when it is set
+				Expect(notified).To(Receive())
+			})
+
+			It("notifies when a STREAM frame is popped", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.TryWrite(getData(protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(notified).ToNot(Receive())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+				str.popStreamFrame(500)
+				Expect(notified).To(Receive())
+				str.popStreamFrame(protocol.MaxByteCount)
+				Expect(notified).To(Receive())
+			})
+
+			It("notifies when the stream is canceled", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Expect(notified).To(Receive())
+			})
+
+			It("notifies when the stream is closed for shutdown", func() {
+				str.closeForShutdown(errors.New("test error"))
+				Expect(notified).To(Receive())
+			})
+		})
+	})
+
+	Context("corking", func() {
+		var corkDelay time.Duration
+
+		BeforeEach(func() {
+			corkDelay = scaleDuration(50 * time.Millisecond)
+			str.SetCorkDelay(corkDelay)
+		})
+
+		It("holds back small writes", func() {
+			called := make(chan time.Time, 1)
+			mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { called <- time.Now() })
+			start := time.Now()
+			_, err := str.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+			var t time.Time
+			Eventually(called).Should(Receive(&t))
+			Expect(t.Sub(start)).To(BeNumerically(">=", corkDelay))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("holds back small non-blocking writes", func() {
+			called := make(chan struct{}, 1)
+			mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(called) })
+			_, err := str.TryWrite([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(called, corkDelay/2).ShouldNot(BeClosed())
+			Eventually(called).Should(BeClosed())
+		})
+
+		It("sends the data once a full packet is buffered", func() {
+			_, err := str.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err = str.Write(getData(protocol.CorkedDataThreshold))
+			Expect(err).ToNot(HaveOccurred())
+			// make sure the cork timer was stopped
+			time.Sleep(2 * corkDelay)
+		})
+
+		It("sends the data when flushed", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Flush()).To(Succeed())
+			// make sure the cork timer was stopped
+			time.Sleep(2 * corkDelay)
+		})
+
+		It("doesn't do anything when flushing a stream that has no data held back", func() {
+			Expect(str.Flush()).To(Succeed())
+		})
+
+		It("sends the data when uncorked", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			str.SetCorkDelay(0)
+			// make sure the cork timer was stopped
+			time.Sleep(2 * corkDelay)
+			// writes are now sent right away
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("limits the cork delay", func() {
+			str.SetCorkDelay(time.Hour)
+			Expect(str.corkDelay).To(Equal(protocol.MaxCorkDelay))
+			str.SetCorkDelay(-time.Second)
+			Expect(str.corkDelay).To(BeZero())
+		})
+
+		It("stops the cork timer when the stream is closed", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			time.Sleep(2 * corkDelay)
+		})
+
+		It("uses the clock of the connection", func() {
+			clock := utils.NewVirtualClock(time.Now())
+			str.clock = clock
+			called := make(chan struct{})
+			mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(called) })
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(called, 2*corkDelay).ShouldNot(BeClosed())
+			clock.Advance(corkDelay)
+			Eventually(called).Should(BeClosed())
+		})
+
+		It("holds back retransmissions", func() {
+			str.SetCorkDelay(0)
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			str.SetCorkDelay(corkDelay)
+			_, err = str.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			frame.OnLost(frame.Frame)
+			f, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(f).To(BeNil())
+			Expect(hasMoreData).To(BeFalse())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Flush()).To(Succeed())
+			f, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("returns an error when flushing a canceled stream", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.Flush()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+		})
+	})
+
+	Context("expiry", func() {
+		It("returns an error when Write is called after the expiry", func() {
+			str.SetExpiry(time.Now().Add(-time.Second), 1234)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).To(MatchError(ErrExpired))
+			_, err = str.TryWrite([]byte("foobar"))
+			Expect(err).To(MatchError(ErrExpired))
+		})
+
+		It("resets the stream and unblocks Write when data wasn't sent before the expiry", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := str.Write(getData(5000))
+				Expect(err).To(MatchError(ErrExpired))
+			}()
+			waitForWrite()
+			gomock.InOrder(
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: streamID, ErrorCode: 1234}),
+				mockSender.EXPECT().onStreamCompleted(streamID),
+			)
+			str.SetExpiry(time.Now().Add(scaleDuration(20*time.Millisecond)), 1234)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("resets the stream reliably, if data was already sent", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+			reset := make(chan struct{})
+			mockSender.EXPECT().supportsReliableReset().Return(true)
+			mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+				StreamID:     streamID,
+				ErrorCode:    1234,
+				FinalSize:    3,
+				ReliableSize: 3,
+			}).Do(func(wire.Frame) { close(reset) })
+			str.SetExpiry(time.Now().Add(scaleDuration(20*time.Millisecond)), 1234)
+			Eventually(reset).Should(BeClosed())
+
+			// data up to the reliable size is still retransmitted
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frame.OnLost(frame.Frame)
+			retransmission, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(retransmission).ToNot(BeNil())
+			Expect(retransmission.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+			// but no new data is sent
+			f, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(f).To(BeNil())
+			Expect(hasMoreData).To(BeFalse())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			retransmission.OnAcked(retransmission.Frame)
+		})
+
+		It("uses a regular RESET_STREAM frame if the peer doesn't support reliable resets", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(frame).ToNot(BeNil())
+			completed := make(chan struct{})
+			mockSender.EXPECT().supportsReliableReset().Return(false)
+			gomock.InOrder(
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: streamID, ErrorCode: 1234, FinalSize: 3}),
+				mockSender.EXPECT().onStreamCompleted(streamID).Do(func(protocol.StreamID) { close(completed) }),
+			)
+			str.SetExpiry(time.Now().Add(scaleDuration(20*time.Millisecond)), 1234)
+			Eventually(completed).Should(BeClosed())
+		})
+
+		It("doesn't reset the stream if all data was sent before the expiry", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			expiry := scaleDuration(20 * time.Millisecond)
+			str.SetExpiry(time.Now().Add(expiry), 1234)
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			// don't EXPECT any calls to queueControlFrame
+			time.Sleep(2 * expiry)
+		})
+
+		It("uses the clock of the connection", func() {
+			clock := utils.NewVirtualClock(time.Now())
+			str.clock = clock
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			completed := make(chan struct{})
+			gomock.InOrder(
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: streamID, ErrorCode: 1234}),
+				mockSender.EXPECT().onStreamCompleted(streamID).Do(func(protocol.StreamID) { close(completed) }),
+			)
+			str.SetExpiry(clock.Now().Add(time.Hour), 1234)
+			Consistently(completed, scaleDuration(20*time.Millisecond)).ShouldNot(BeClosed())
+			clock.Advance(time.Hour)
+			Eventually(completed).Should(BeClosed())
+			_, err = str.TryWrite([]byte("foobar"))
+			Expect(err).To(MatchError(ErrExpired))
+		})
+
+		It("doesn't reset the stream if the expiry is removed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			expiry := scaleDuration(20 * time.Millisecond)
+			str.SetExpiry(time.Now().Add(expiry), 1234)
+			str.SetExpiry(time.Time{}, 0)
+			// don't EXPECT any calls to queueControlFrame
+			time.Sleep(2 * expiry)
+		})
+	})
+
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
@@ -42,6 +42,7 @@ type packetHandlerManager interface {
 	connRunner
 	SetServer(unknownPacketHandler)
 	CloseServer()
+	Stats() EndpointStats
 }
 
 type quicConn interface {
@@ -103,6 +104,8 @@ type baseServer struct {
 	connQueue    chan quicConn
 	connQueueLen int32 // to be used as an atomic
 
+	counters *endpointCounters
+
 	logger utils.Logger
 }
 
@@ -160,9 +163,12 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 // The PacketConn can be used for simultaneous calls to Dial. QUIC connection
 // IDs are used for demultiplexing the different connections. The tls.Config
 // must not be nil and must contain a certificate configuration. The
-// tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites. Furthermore,
-// it must define an application control (using NextProtos). The quic.Config may
-// be nil, in that case the default values will be used.
+// tls.Config.CipherSuites allows restricting the TLS 1.3 cipher suites. It must
+// contain at least one TLS 1.3 cipher suite. The server selects the first suite
+// in this list that is also offered by the client, so the order of the list
+// takes precedence over the client's preferences. Furthermore, it must define
+// an application control (using NextProtos). The quic.Config may be nil, in that
+// case the default values will be used.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
 	return listen(conn, tlsConf, config, false)
 }
@@ -177,8 +183,8 @@ func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Earl
 }
 
 func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
-	if tlsConf == nil {
-		return nil, errors.New("quic: tls.Config not set")
+	if err := validateTLSConfig(tlsConf); err != nil {
+		return nil, err
 	}
 	if err := validateConfig(config); err != nil {
 		return nil, err
@@ -213,6 +219,7 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		running:          make(chan struct{}),
 		receivedPackets:  make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
 		newConn:          newConnection,
+		counters:         &endpointCounters{},
 		logger:           utils.DefaultLogger.WithPrefix("server"),
 		acceptEarlyConns: acceptEarly,
 	}
@@ -320,32 +327,41 @@ func (s *baseServer) Addr() net.Addr {
 	return s.conn.LocalAddr()
 }
 
+// Stats returns statistics about the server
+func (s *baseServer) Stats() EndpointStats {
+	stats := s.connHandler.Stats()
+	s.counters.addTo(&stats)
+	stats.AcceptQueueLen = int(atomic.LoadInt32(&s.connQueueLen))
+	return stats
+}
+
+func (s *baseServer) droppedPacket(p *receivedPacket, pt logging.PacketType, reason logging.PacketDropReason) {
+	s.counters.droppedPacket(reason)
+	if s.config.Tracer != nil {
+		s.config.Tracer.DroppedPacket(p.remoteAddr, pt, p.Size(), reason)
+	}
+}
+
 func (s *baseServer) handlePacket(p *receivedPacket) {
 	select {
 	case s.receivedPackets <- p:
 	default:
 		s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", p.remoteAddr, p.Size())
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
-		}
+		s.droppedPacket(p, logging.PacketTypeNotDetermined, logging.PacketDropDOSPrevention)
 	}
 }
 
 func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer still in use? */ {
 	if wire.IsVersionNegotiationPacket(p.data) {
 		s.logger.Debugf("Dropping Version Negotiation packet.")
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeVersionNegotiation, p.Size(), logging.PacketDropUnexpectedPacket)
-		}
+		s.droppedPacket(p, logging.PacketTypeVersionNegotiation, logging.PacketDropUnexpectedPacket)
 		return false
 	}
 	// If we're creating a new connection, the packet will be passed to the connection.
 	// The header will then be parsed again.
 	hdr, _, _, err := wire.ParsePacket(p.data, s.config.ConnectionIDLength)
 	if err != nil && err != wire.ErrUnsupportedVersion {
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropHeaderParseError)
-		}
+		s.droppedPacket(p, logging.PacketTypeNotDetermined, logging.PacketDropHeaderParseError)
 		s.logger.Debugf("Error parsing packet: %s", err)
 		return false
 	}
@@ -355,18 +371,14 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 	}
 	if hdr.Type == protocol.PacketTypeInitial && p.Size() < protocol.MinInitialPacketSize {
 		s.logger.Debugf("Dropping a packet that is too small to be a valid Initial (%d bytes)", p.Size())
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropUnexpectedPacket)
-		}
+		s.droppedPacket(p, logging.PacketTypeInitial, logging.PacketDropUnexpectedPacket)
 		return false
 	}
 	// send a Version Negotiation Packet if the client is speaking a different protocol version
 	if !protocol.IsSupportedVersion(s.config.Versions, hdr.Version) {
 		if p.Size() < protocol.MinUnknownVersionPacketSize {
 			s.logger.Debugf("Dropping a packet with an unknown version that is too small (%d bytes)", p.Size())
-			if s.config.Tracer != nil {
-				s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropUnexpectedPacket)
-			}
+			s.droppedPacket(p, logging.PacketTypeNotDetermined, logging.PacketDropUnexpectedPacket)
 			return false
 		}
 		if !s.config.DisableVersionNegotiationPackets {
@@ -379,9 +391,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 		// There's little point in sending a Stateless Reset, since the client
 		// might not have received the token yet.
 		s.logger.Debugf("Dropping long header packet of type %s (%d bytes)", hdr.Type, len(p.data))
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeFromHeader(hdr), p.Size(), logging.PacketDropUnexpectedPacket)
-		}
+		s.droppedPacket(p, logging.PacketTypeFromHeader(hdr), logging.PacketDropUnexpectedPacket)
 		return false
 	}
 
@@ -398,9 +408,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) error {
 	if len(hdr.Token) == 0 && hdr.DestConnectionID.Len() < protocol.MinConnectionIDLenInitial {
 		p.buffer.Release()
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropUnexpectedPacket)
-		}
+		s.droppedPacket(p, logging.PacketTypeInitial, logging.PacketDropUnexpectedPacket)
 		return errors.New("too short connection ID")
 	}
 
@@ -568,8 +576,11 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header, info *pack
 	if s.config.Tracer != nil {
 		s.config.Tracer.SentPacket(remoteAddr, &replyHdr.Header, protocol.ByteCount(buf.Len()), nil)
 	}
-	_, err = s.conn.WritePacket(buf.Bytes(), remoteAddr, info.OOB())
-	return err
+	if _, err := s.conn.WritePacket(buf.Bytes(), remoteAddr, info.OOB()); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.counters.retriesSent, 1)
+	return nil
 }
 
 func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header) error {
@@ -579,18 +590,14 @@ func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header)
 	data := p.data[:hdr.ParsedLen()+hdr.Length]
 	extHdr, err := unpackHeader(opener, hdr, data, hdr.Version)
 	if err != nil {
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropHeaderParseError)
-		}
+		s.droppedPacket(p, logging.PacketTypeInitial, logging.PacketDropHeaderParseError)
 		// don't return the error here. Just drop the packet.
 		return nil
 	}
 	hdrLen := extHdr.ParsedLen()
 	if _, err := opener.Open(data[hdrLen:hdrLen], data[hdrLen:], extHdr.PacketNumber, data[:hdrLen]); err != nil {
 		// don't return the error here. Just drop the packet.
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropPayloadDecryptError)
-		}
+		s.droppedPacket(p, logging.PacketTypeInitial, logging.PacketDropPayloadDecryptError)
 		return nil
 	}
 	if s.logger.Debug() {
@@ -666,5 +673,7 @@ func (s *baseServer) sendVersionNegotiationPacket(p *receivedPacket, hdr *wire.H
 	}
 	if _, err := s.conn.WritePacket(data, p.remoteAddr, p.info.OOB()); err != nil {
 		s.logger.Debugf("Error sending Version Negotiation: %s", err)
+		return
 	}
+	atomic.AddUint64(&s.counters.versionNegotiationSent, 1)
 }
@@ -113,6 +113,13 @@ var _ = Describe("Server", func() {
 		Expect(err.Error()).To(ContainSubstring("quic: tls.Config not set"))
 	})
 
+	It("errors when the tls.Config doesn't contain any TLS 1.3 cipher suite", func() {
+		conf := tlsConf.Clone()
+		conf.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+		_, err := ListenAddr("localhost:0", conf, nil)
+		Expect(err).To(MatchError("quic: tls.Config.CipherSuites doesn't contain any TLS 1.3 cipher suite"))
+	})
+
 	It("errors when the Config contains an invalid version", func() {
 		version := protocol.VersionNumber(0x1234)
 		_, err := Listen(nil, tlsConf, &Config{Versions: []protocol.VersionNumber{version}})
@@ -237,6 +244,20 @@ var _ = Describe("Server", func() {
 				serv.handlePacket(p)
 				// make sure there are no Write calls on the packet conn
 				time.Sleep(50 * time.Millisecond)
+				// the server's counters are added to the counters of the packet handler map
+				phm.EXPECT().Stats().Return(EndpointStats{
+					PacketsReceived: 10,
+					PacketsDropped: map[logging.PacketDropReason]uint64{
+						logging.PacketDropHeaderParseError: 2,
+						logging.PacketDropUnexpectedPacket: 3,
+					},
+				})
+				stats := serv.Stats()
+				Expect(stats.PacketsReceived).To(BeEquivalentTo(10))
+				Expect(stats.PacketsDropped).To(Equal(map[logging.PacketDropReason]uint64{
+					logging.PacketDropHeaderParseError: 2,
+					logging.PacketDropUnexpectedPacket: 4,
+				}))
 			})
 
 			It("decodes the token from the Token field", func() {
@@ -404,6 +425,8 @@ var _ = Describe("Server", func() {
 				})
 				serv.handlePacket(packet)
 				Eventually(done).Should(BeClosed())
+				phm.EXPECT().Stats().AnyTimes()
+				Eventually(func() uint64 { return serv.Stats().VersionNegotiationPacketsSent }).Should(BeEquivalentTo(1))
 			})
 
 			It("doesn't send a Version Negotiation packets if sending them is disabled", func() {
@@ -500,6 +523,8 @@ var _ = Describe("Server", func() {
 				})
 				serv.handlePacket(packet)
 				Eventually(done).Should(BeClosed())
+				phm.EXPECT().Stats().AnyTimes()
+				Eventually(func() uint64 { return serv.Stats().RetriesSent }).Should(BeEquivalentTo(1))
 			})
 
 			It("sends an INVALID_TOKEN error, if an invalid retry token is received", func() {
@@ -540,7 +565,7 @@ var _ = Describe("Server", func() {
 					Expect(err).ToNot(HaveOccurred())
 					data, err := opener.Open(nil, b[extHdr.ParsedLen():], extHdr.PacketNumber, b[:extHdr.ParsedLen()])
 					Expect(err).ToNot(HaveOccurred())
-					f, err := wire.NewFrameParser(false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
+					f, err := wire.NewFrameParser(false, false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 					ccf := f.(*wire.ConnectionCloseFrame)
@@ -1028,6 +1053,7 @@ var _ = Describe("Server", func() {
 				conn.EXPECT().run().Do(func() {})
 				conn.EXPECT().earlyConnReady().Return(ready)
 				conn.EXPECT().Context().Return(context.Background())
+				conn.EXPECT().HandshakeComplete().Return(context.Background())
 				return conn
 			}
 			phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
@@ -1073,6 +1099,7 @@ var _ = Describe("Server", func() {
 				conn.EXPECT().run()
 				conn.EXPECT().earlyConnReady().Return(ready)
 				conn.EXPECT().Context().Return(context.Background())
+				conn.EXPECT().HandshakeComplete().Return(context.Background())
 				return conn
 			}
 
@@ -1134,6 +1161,7 @@ var _ = Describe("Server", func() {
 				conn.EXPECT().run()
 				conn.EXPECT().earlyConnReady()
 				conn.EXPECT().Context().Return(ctx)
+				conn.EXPECT().HandshakeComplete().Return(context.Background())
 				close(connCreated)
 				return conn
 			}
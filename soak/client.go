@@ -0,0 +1,372 @@
+package soak
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// maxDatagramSize is the maximum size of the datagrams sent by the client.
+// It is small enough for datagrams to fit into a packet with the minimum QUIC packet size.
+const maxDatagramSize = 900
+
+// The client repeatedly dials connections, and verifies that the server echoes all data sent on them.
+type client struct {
+	conn       rebindableConn
+	serverAddr net.Addr
+	tlsConf    *tls.Config
+	quicConf   *quic.Config
+	opts       *Options
+	stats      *stats
+
+	randMutex sync.Mutex
+	rand      *rand.Rand
+}
+
+func newClient(
+	conn rebindableConn,
+	serverAddr net.Addr,
+	tlsConf *tls.Config,
+	quicConf *quic.Config,
+	opts *Options,
+	seed int64,
+	s *stats,
+) *client {
+	tlsConf = tlsConf.Clone()
+	tlsConf.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	return &client{
+		conn:       conn,
+		serverAddr: serverAddr,
+		tlsConf:    tlsConf,
+		quicConf:   quicConf,
+		opts:       opts,
+		stats:      s,
+		rand:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (c *client) randInt63() int64 {
+	c.randMutex.Lock()
+	defer c.randMutex.Unlock()
+	return c.rand.Int63()
+}
+
+// randIntn returns a random value in [0, n).
+func (c *client) randIntn(n int) int {
+	c.randMutex.Lock()
+	defer c.randMutex.Unlock()
+	return c.rand.Intn(n)
+}
+
+// run dials connections until the context is canceled.
+func (c *client) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := c.runConnection(ctx); err != nil && ctx.Err() == nil {
+			c.stats.addError("", err)
+			// don't busy-loop if the server is unreachable
+			select {
+			case <-ctx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// runConnection dials a connection, and uses it until the end of its lifetime.
+// It only returns the error that occurred when dialing.
+// All other errors are recorded in the stats.
+func (c *client) runConnection(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	conn, err := quic.DialEarlyContext(dialCtx, c.conn, c.serverAddr, serverName, c.tlsConf, c.quicConf)
+	cancel()
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&c.stats.openConnections, 1)
+	defer atomic.AddInt64(&c.stats.openConnections, -1)
+
+	cc := &clientConn{
+		client:  c,
+		early:   conn,
+		current: conn,
+	}
+	lifetimeCtx, cancel := context.WithTimeout(ctx, c.opts.ConnectionLifetime)
+	defer cancel()
+	cc.run(ctx, lifetimeCtx)
+	return nil
+}
+
+// A clientConn is a single connection dialed by the client.
+type clientConn struct {
+	*client
+
+	early quic.EarlyConnection
+
+	mutex    sync.Mutex
+	current  quic.Connection // replaced when 0-RTT is rejected
+	rejected bool
+
+	closing uint32 // set when the connection reached the end of its lifetime, to be accessed atomically
+}
+
+func (cc *clientConn) currentConn() quic.Connection {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	return cc.current
+}
+
+// handle0RTTRejected switches to the connection that is used after 0-RTT was rejected.
+// It blocks until the handshake completes.
+func (cc *clientConn) handle0RTTRejected() {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	if cc.rejected {
+		return
+	}
+	cc.rejected = true
+	atomic.AddUint64(&cc.stats.zeroRTTRejected, 1)
+	cc.current = cc.early.NextConnection()
+}
+
+// addError records an error, unless it was caused by closing the connection.
+func (cc *clientConn) addError(err error) {
+	if atomic.LoadUint32(&cc.closing) == 1 {
+		return
+	}
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && !appErr.Remote && appErr.ErrorCode == 0 {
+		return
+	}
+	cc.stats.addError("", err)
+}
+
+// run runs streams until lifetimeCtx is done.
+// In-flight streams are then given some time to complete, before the connection is closed.
+// If ctx is canceled, the connection is closed immediately.
+func (cc *clientConn) run(ctx, lifetimeCtx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cc.runHandshake(lifetimeCtx)
+	}()
+
+	var streamsWG sync.WaitGroup
+	streamsWG.Add(cc.opts.StreamsPerConnection)
+	for i := 0; i < cc.opts.StreamsPerConnection; i++ {
+		go func() {
+			defer streamsWG.Done()
+			for lifetimeCtx.Err() == nil && cc.early.Context().Err() == nil {
+				cc.runStream()
+			}
+		}()
+	}
+
+	streamsDone := make(chan struct{})
+	go func() {
+		streamsWG.Wait()
+		close(streamsDone)
+	}()
+	select {
+	case <-streamsDone:
+	case <-ctx.Done():
+	case <-time.After(cc.opts.ConnectionLifetime + drainTimeout):
+	}
+	atomic.StoreUint32(&cc.closing, 1)
+	cc.early.CloseWithError(0, "")
+	<-streamsDone
+	wg.Wait()
+}
+
+// runHandshake waits for the handshake to complete, and then starts sending datagrams and migrating.
+func (cc *clientConn) runHandshake(lifetimeCtx context.Context) {
+	select {
+	case <-cc.early.HandshakeComplete().Done():
+	case <-cc.early.Context().Done():
+		return
+	}
+	if cc.early.Context().Err() != nil {
+		return
+	}
+	atomic.AddUint64(&cc.stats.connections, 1)
+	state := cc.early.ConnectionState().TLS
+	if state.DidResume {
+		atomic.AddUint64(&cc.stats.resumed, 1)
+	}
+	if state.Used0RTT {
+		atomic.AddUint64(&cc.stats.zeroRTTAccepted, 1)
+	}
+
+	var wg sync.WaitGroup
+	if cc.opts.DatagramInterval > 0 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cc.sendDatagrams(lifetimeCtx)
+		}()
+		go func() {
+			defer wg.Done()
+			cc.receiveDatagrams()
+		}()
+	}
+	if cc.opts.MigrationInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cc.migrate(lifetimeCtx)
+		}()
+	}
+	wg.Wait()
+}
+
+// runStream sends a random amount of data on a new stream, and verifies that it is echoed by the server.
+func (cc *clientConn) runStream() {
+	conn := cc.currentConn()
+	str, err := conn.OpenStreamSync(cc.early.Context())
+	if err != nil {
+		cc.handleStreamError(err)
+		return
+	}
+	str.SetDeadline(time.Now().Add(streamTimeout))
+	size := 1 + cc.randIntn(cc.opts.MaxStreamSize)
+	seed := cc.randInt63()
+
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(str, io.LimitReader(rand.New(rand.NewSource(seed)), int64(size)))
+		if err == nil {
+			err = str.Close()
+		}
+		writeErr <- err
+	}()
+	if err := verify(str, rand.New(rand.NewSource(seed)), size); err != nil {
+		str.CancelRead(0)
+		str.CancelWrite(0)
+		<-writeErr
+		cc.handleStreamError(err)
+		return
+	}
+	if err := <-writeErr; err != nil {
+		cc.handleStreamError(err)
+		return
+	}
+	atomic.AddUint64(&cc.stats.streams, 1)
+	atomic.AddUint64(&cc.stats.bytesVerified, uint64(size))
+}
+
+func (cc *clientConn) handleStreamError(err error) {
+	if errors.Is(err, quic.Err0RTTRejected) {
+		cc.handle0RTTRejected()
+		return
+	}
+	cc.addError(err)
+}
+
+// verify reads from r, and checks that it returns exactly size bytes generated by expected.
+func verify(r io.Reader, expected io.Reader, size int) error {
+	buf := make([]byte, 4096)
+	exp := make([]byte, len(buf))
+	var read int
+	for {
+		n, err := r.Read(buf)
+		if read+n > size {
+			return errCorrupted
+		}
+		if n > 0 {
+			if _, err := io.ReadFull(expected, exp[:n]); err != nil {
+				return err
+			}
+			if !bytes.Equal(buf[:n], exp[:n]) {
+				return errCorrupted
+			}
+			read += n
+		}
+		if err == io.EOF {
+			if read < size {
+				return errTruncated
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// A datagram consists of an 8 byte sequence number, random data, and a CRC32 checksum of the preceding bytes.
+func (cc *clientConn) sendDatagrams(lifetimeCtx context.Context) {
+	ticker := time.NewTicker(cc.opts.DatagramInterval)
+	defer ticker.Stop()
+	var seq uint64
+	for {
+		select {
+		case <-ticker.C:
+		case <-lifetimeCtx.Done():
+			return
+		case <-cc.early.Context().Done():
+			return
+		}
+		b := make([]byte, 8+4+cc.randIntn(maxDatagramSize-8-4+1))
+		binary.BigEndian.PutUint64(b, seq)
+		seq++
+		rand.New(rand.NewSource(cc.randInt63())).Read(b[8 : len(b)-4])
+		binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+		if err := cc.early.SendMessage(b); err != nil {
+			cc.addError(err)
+			return
+		}
+		atomic.AddUint64(&cc.stats.datagramsSent, 1)
+	}
+}
+
+func (cc *clientConn) receiveDatagrams() {
+	for {
+		b, err := cc.early.ReceiveMessage()
+		if err != nil {
+			return
+		}
+		if len(b) < 8+4 || binary.BigEndian.Uint32(b[len(b)-4:]) != crc32.ChecksumIEEE(b[:len(b)-4]) {
+			cc.stats.addError("", errCorrupted)
+			continue
+		}
+		atomic.AddUint64(&cc.stats.datagramsVerified, 1)
+	}
+}
+
+// migrate moves the client to a new address, in random intervals of 0.5 to 1.5 times the migration interval.
+func (cc *clientConn) migrate(lifetimeCtx context.Context) {
+	for {
+		interval := cc.opts.MigrationInterval/2 + time.Duration(cc.randInt63()%int64(cc.opts.MigrationInterval+1))
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-lifetimeCtx.Done():
+			timer.Stop()
+			return
+		case <-cc.early.Context().Done():
+			timer.Stop()
+			return
+		}
+		if err := cc.conn.Rebind(); err != nil {
+			cc.addError(err)
+			return
+		}
+		atomic.AddUint64(&cc.stats.migrations, 1)
+	}
+}
+
+// Close closes the underlying conn.
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/soak"
+)
+
+func main() {
+	network := flag.String("network", "loopback", "network to run the soak test on (loopback or simulated)")
+	connections := flag.Int("connections", 16, "number of concurrent connections")
+	duration := flag.Duration("duration", 0, "duration of the soak test (default: run until interrupted)")
+	lifetime := flag.Duration("lifetime", 10*time.Second, "time after which a connection is closed and a new one is dialed")
+	streams := flag.Int("streams", 4, "number of concurrent streams per connection")
+	maxStreamSize := flag.Int("max-stream-size", 256<<10, "maximum number of bytes sent on a stream")
+	datagramInterval := flag.Duration("datagram-interval", 20*time.Millisecond, "interval at which datagrams are sent (negative to disable datagrams)")
+	migrationInterval := flag.Duration("migration-interval", 5*time.Second, "average interval at which clients migrate (negative to disable migration)")
+	keyUpdateInterval := flag.Uint64("key-update-interval", 1000, "number of packets after which the keys are updated")
+	loss := flag.Float64("loss", 0, "probability that a packet is dropped")
+	duplicate := flag.Float64("duplicate", 0, "probability that a packet is duplicated")
+	delay := flag.Duration("delay", 0, "delay added to every packet")
+	jitter := flag.Duration("jitter", 0, "maximum random delay added to every packet, in addition to -delay")
+	reportInterval := flag.Duration("report-interval", 10*time.Second, "interval at which reports are printed")
+	seed := flag.Int64("seed", 0, "seed for the random number generator (default: random)")
+	jsonOutput := flag.Bool("json", false, "output the reports in JSON format")
+	flag.Parse()
+
+	var nw soak.Network
+	switch *network {
+	case "loopback":
+		nw = soak.NetworkLoopback
+	case "simulated":
+		nw = soak.NetworkSimulated
+	default:
+		log.Fatalf("unknown network: %s", *network)
+	}
+
+	writeReport := func(r *soak.Report) {
+		var err error
+		if *jsonOutput {
+			err = r.WriteJSON(os.Stdout)
+		} else {
+			err = r.WriteText(os.Stdout)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	report, err := soak.Run(ctx, &soak.Options{
+		Network:              nw,
+		Connections:          *connections,
+		Duration:             *duration,
+		ConnectionLifetime:   *lifetime,
+		StreamsPerConnection: *streams,
+		MaxStreamSize:        *maxStreamSize,
+		DatagramInterval:     *datagramInterval,
+		MigrationInterval:    *migrationInterval,
+		KeyUpdateInterval:    *keyUpdateInterval,
+		Loss:                 *loss,
+		Duplicate:            *duplicate,
+		Delay:                *delay,
+		Jitter:               *jitter,
+		ReportInterval:       *reportInterval,
+		OnReport:             writeReport,
+		Seed:                 *seed,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !*jsonOutput {
+		fmt.Println("final report:")
+	}
+	writeReport(report)
+	if report.Corruptions > 0 {
+		log.Fatalf("detected %d corruptions", report.Corruptions)
+	}
+}
@@ -0,0 +1,72 @@
+package soak
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/testnet"
+)
+
+// A rebindableConn is a net.PacketConn that can be moved to a new address, simulating a NAT rebinding.
+// The local address doesn't change.
+type rebindableConn interface {
+	net.PacketConn
+	Rebind() error
+}
+
+type network interface {
+	// Listen creates the conn used by the server.
+	Listen() (net.PacketConn, error)
+	// NewConn creates a conn used by a client.
+	NewConn() (rebindableConn, error)
+}
+
+// The loopbackNetwork uses UDP sockets on the loopback interface.
+type loopbackNetwork struct{}
+
+var _ network = &loopbackNetwork{}
+
+func (n *loopbackNetwork) Listen() (net.PacketConn, error) {
+	return net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+}
+
+func (n *loopbackNetwork) NewConn() (rebindableConn, error) {
+	return testnet.NewRebindingConn(net.IPv4(127, 0, 0, 1))
+}
+
+func newSimAddr() *net.UDPAddr {
+	return &net.UDPAddr{IP: testnet.NewIP(), Port: 443}
+}
+
+// The simNetwork is an in-memory network.
+// Packets are delivered immediately. Impairments are injected by the quic.FaultInjector.
+type simNetwork struct {
+	network *testnet.Network
+}
+
+var _ network = &simNetwork{}
+
+func newSimNetwork() *simNetwork {
+	return &simNetwork{network: testnet.NewNetwork(testnet.NetworkOptions{})}
+}
+
+func (n *simNetwork) Listen() (net.PacketConn, error) {
+	return n.newConn(), nil
+}
+
+func (n *simNetwork) NewConn() (rebindableConn, error) {
+	return n.newConn(), nil
+}
+
+func (n *simNetwork) newConn() *simConn {
+	return &simConn{Conn: n.network.NewConn(newSimAddr())}
+}
+
+// A simConn is a net.PacketConn on a simNetwork.
+// On rebinding, it is moved to a new address, and packets sent to the old address are dropped.
+type simConn struct {
+	*testnet.Conn
+}
+
+var _ rebindableConn = &simConn{}
+
+func (c *simConn) Rebind() error { return c.Conn.Rebind(newSimAddr()) }
@@ -0,0 +1,271 @@
+package soak
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+var (
+	// errCorrupted is used when data received doesn't match the data that was sent.
+	errCorrupted = errors.New("data corrupted")
+	// errTruncated is used when a stream ended before all data was received.
+	errTruncated = errors.New("data truncated")
+)
+
+// Error categories that indicate that data was corrupted.
+const (
+	categoryCorrupted = "data_corrupted"
+	categoryTruncated = "data_truncated"
+)
+
+// stats are the counters updated while the soak test is running.
+type stats struct {
+	connections     uint64 // connections that completed the handshake
+	openConnections int64
+	resumed         uint64
+	zeroRTTAccepted uint64
+	zeroRTTRejected uint64
+	migrations      uint64
+
+	streams       uint64 // streams that were completely verified
+	bytesVerified uint64
+
+	datagramsSent     uint64
+	datagramsVerified uint64
+
+	keyUpdates  uint64
+	packetsSent uint64
+	packetsLost uint64
+
+	mutex  sync.Mutex
+	errors map[string]uint64
+}
+
+func newStats() *stats {
+	return &stats{errors: make(map[string]uint64)}
+}
+
+func (s *stats) addError(prefix string, err error) {
+	category := prefix + classifyError(err)
+	s.mutex.Lock()
+	s.errors[category]++
+	s.mutex.Unlock()
+}
+
+// classifyError assigns an error to a category.
+func classifyError(err error) string {
+	var (
+		transportErr        *quic.TransportError
+		applicationErr      *quic.ApplicationError
+		streamErr           *quic.StreamError
+		idleTimeoutErr      *quic.IdleTimeoutError
+		handshakeTimeoutErr *quic.HandshakeTimeoutError
+		statelessResetErr   *quic.StatelessResetError
+		versionNegErr       *quic.VersionNegotiationError
+		netErr              net.Error
+	)
+	switch {
+	case errors.Is(err, errCorrupted):
+		return categoryCorrupted
+	case errors.Is(err, errTruncated):
+		return categoryTruncated
+	case errors.Is(err, quic.Err0RTTRejected):
+		return "0rtt_rejected"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &idleTimeoutErr):
+		return "idle_timeout"
+	case errors.As(err, &handshakeTimeoutErr):
+		return "handshake_timeout"
+	case errors.As(err, &statelessResetErr):
+		return "stateless_reset"
+	case errors.As(err, &versionNegErr):
+		return "version_negotiation"
+	case errors.As(err, &transportErr):
+		if transportErr.Remote {
+			return "remote_transport_error:" + transportErr.ErrorCode.String()
+		}
+		return "local_transport_error:" + transportErr.ErrorCode.String()
+	case errors.As(err, &applicationErr):
+		if applicationErr.Remote {
+			return "remote_application_error"
+		}
+		return "local_application_error"
+	case errors.As(err, &streamErr):
+		return "stream_reset"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, net.ErrClosed):
+		return "conn_closed"
+	default:
+		return "other"
+	}
+}
+
+// Duration is a time.Duration that is serialized as a string, e.g. "25ms".
+type Duration time.Duration
+
+// MarshalJSON marshals the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// A Report reports the progress of the soak test.
+// It can be serialized to JSON.
+type Report struct {
+	Elapsed Duration `json:"elapsed"`
+
+	// Connections is the number of connections that completed the handshake.
+	Connections     uint64 `json:"connections"`
+	OpenConnections int64  `json:"open_connections"`
+	// Resumed is the number of connections that resumed a TLS session.
+	Resumed         uint64 `json:"resumed"`
+	ZeroRTTAccepted uint64 `json:"zero_rtt_accepted"`
+	ZeroRTTRejected uint64 `json:"zero_rtt_rejected"`
+	Migrations      uint64 `json:"migrations"`
+	KeyUpdates      uint64 `json:"key_updates"`
+	PacketsSent     uint64 `json:"packets_sent"`
+	PacketsLost     uint64 `json:"packets_lost"`
+
+	// Streams is the number of streams on which all data was echoed and verified.
+	Streams uint64 `json:"streams"`
+	// BytesVerified is the number of stream bytes that were echoed and verified.
+	BytesVerified uint64 `json:"bytes_verified"`
+	// Throughput is the number of bytes verified per second since the last report.
+	Throughput float64 `json:"throughput"`
+
+	DatagramsSent uint64 `json:"datagrams_sent"`
+	// DatagramsVerified is the number of datagrams that were echoed and verified.
+	DatagramsVerified uint64 `json:"datagrams_verified"`
+
+	// Errors counts the errors encountered, by category.
+	// Categories of errors encountered by the server are prefixed with "server/".
+	Errors map[string]uint64 `json:"errors,omitempty"`
+	// Corruptions is the number of streams and datagrams on which the data received didn't match the data sent.
+	Corruptions uint64 `json:"corruptions"`
+
+	Goroutines int `json:"goroutines"`
+	// GoroutineGrowth is the change in the number of goroutines since the soak test was started.
+	GoroutineGrowth int    `json:"goroutine_growth"`
+	HeapAlloc       uint64 `json:"heap_alloc"`
+	// HeapGrowth is the change in allocated heap memory since the soak test was started.
+	HeapGrowth int64  `json:"heap_growth"`
+	NumGC      uint32 `json:"num_gc"`
+}
+
+// WriteJSON writes the report in JSON format.
+func (r *Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteText writes the report in a human-readable format.
+func (r *Report) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[%s] connections: %d (open: %d, resumed: %d, 0-RTT accepted: %d, rejected: %d), migrations: %d, key updates: %d\n",
+		r.Elapsed, r.Connections, r.OpenConnections, r.Resumed, r.ZeroRTTAccepted, r.ZeroRTTRejected, r.Migrations, r.KeyUpdates)
+	fmt.Fprintf(b, "  streams: %d, verified: %d bytes (%.2f MB/s), datagrams: %d sent, %d verified, packets: %d sent, %d lost\n",
+		r.Streams, r.BytesVerified, r.Throughput/1e6, r.DatagramsSent, r.DatagramsVerified, r.PacketsSent, r.PacketsLost)
+	fmt.Fprintf(b, "  goroutines: %d (%+d), heap: %d KB (%+d KB), GCs: %d, corruptions: %d\n",
+		r.Goroutines, r.GoroutineGrowth, r.HeapAlloc>>10, r.HeapGrowth>>10, r.NumGC, r.Corruptions)
+	categories := make([]string, 0, len(r.Errors))
+	for c := range r.Errors {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		fmt.Fprintf(b, "  error %s: %d\n", c, r.Errors[c])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// The reporter generates reports from the stats.
+type reporter struct {
+	stats *stats
+
+	start          time.Time
+	baseGoroutines int
+	baseHeapAlloc  uint64
+
+	lastReport        time.Time
+	lastBytesVerified uint64
+}
+
+func newReporter(s *stats) *reporter {
+	now := time.Now()
+	return &reporter{
+		stats:          s,
+		start:          now,
+		lastReport:     now,
+		baseGoroutines: runtime.NumGoroutine(),
+		baseHeapAlloc:  readMemStats().HeapAlloc,
+	}
+}
+
+func readMemStats() *runtime.MemStats {
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+	return ms
+}
+
+func (r *reporter) report() *Report {
+	now := time.Now()
+	s := r.stats
+	rep := &Report{
+		Elapsed:           Duration(now.Sub(r.start).Truncate(time.Millisecond)),
+		Connections:       atomic.LoadUint64(&s.connections),
+		OpenConnections:   atomic.LoadInt64(&s.openConnections),
+		Resumed:           atomic.LoadUint64(&s.resumed),
+		ZeroRTTAccepted:   atomic.LoadUint64(&s.zeroRTTAccepted),
+		ZeroRTTRejected:   atomic.LoadUint64(&s.zeroRTTRejected),
+		Migrations:        atomic.LoadUint64(&s.migrations),
+		KeyUpdates:        atomic.LoadUint64(&s.keyUpdates),
+		PacketsSent:       atomic.LoadUint64(&s.packetsSent),
+		PacketsLost:       atomic.LoadUint64(&s.packetsLost),
+		Streams:           atomic.LoadUint64(&s.streams),
+		BytesVerified:     atomic.LoadUint64(&s.bytesVerified),
+		DatagramsSent:     atomic.LoadUint64(&s.datagramsSent),
+		DatagramsVerified: atomic.LoadUint64(&s.datagramsVerified),
+	}
+	s.mutex.Lock()
+	if len(s.errors) > 0 {
+		rep.Errors = make(map[string]uint64, len(s.errors))
+		for c, n := range s.errors {
+			rep.Errors[c] = n
+			if strings.HasSuffix(c, categoryCorrupted) || strings.HasSuffix(c, categoryTruncated) {
+				rep.Corruptions += n
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	if d := now.Sub(r.lastReport); d > 0 {
+		rep.Throughput = float64(rep.BytesVerified-r.lastBytesVerified) / d.Seconds()
+	}
+	r.lastReport = now
+	r.lastBytesVerified = rep.BytesVerified
+
+	ms := readMemStats()
+	rep.Goroutines = runtime.NumGoroutine()
+	rep.GoroutineGrowth = rep.Goroutines - r.baseGoroutines
+	rep.HeapAlloc = ms.HeapAlloc
+	rep.HeapGrowth = int64(ms.HeapAlloc) - int64(r.baseHeapAlloc)
+	rep.NumGC = ms.NumGC
+	return rep
+}
@@ -0,0 +1,129 @@
+package soak
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// The server echoes all data received on bidirectional streams, and all datagrams.
+type server struct {
+	ln    quic.EarlyListener
+	stats *stats
+
+	closing uint32 // set when Close is called, to be accessed atomically
+	wg      sync.WaitGroup
+
+	mutex sync.Mutex
+	conns map[quic.Connection]struct{}
+}
+
+func newServer(ln quic.EarlyListener, st *stats) *server {
+	s := &server{
+		ln:    ln,
+		stats: st,
+		conns: make(map[quic.Connection]struct{}),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run()
+	}()
+	return s
+}
+
+func (s *server) run() {
+	for {
+		conn, err := s.ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		if atomic.LoadUint32(&s.closing) == 1 {
+			s.mutex.Unlock()
+			conn.CloseWithError(0, "")
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mutex.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+		}()
+	}
+}
+
+func (s *server) handleConn(conn quic.EarlyConnection) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			b, err := conn.ReceiveMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.SendMessage(b); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		str, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			s.addError(err)
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.echo(str)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *server) echo(str quic.Stream) {
+	str.SetDeadline(time.Now().Add(streamTimeout))
+	if _, err := io.Copy(str, str); err != nil {
+		s.addError(err)
+		str.CancelRead(0)
+		str.CancelWrite(0)
+		return
+	}
+	str.Close()
+}
+
+// addError records an error, unless it was caused by the client closing the connection,
+// or by the server shutting down.
+func (s *server) addError(err error) {
+	if atomic.LoadUint32(&s.closing) == 1 || errors.Is(err, quic.Err0RTTRejected) {
+		return
+	}
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.Remote && appErr.ErrorCode == 0 {
+		return
+	}
+	s.stats.addError("server/", err)
+}
+
+// Close closes the listener and all connections, and waits for all go routines to return.
+func (s *server) Close() {
+	s.mutex.Lock()
+	atomic.StoreUint32(&s.closing, 1)
+	s.ln.Close()
+	for conn := range s.conns {
+		conn.CloseWithError(0, "")
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
@@ -0,0 +1,291 @@
+// Package soak implements a long-running soak test of quic-go.
+//
+// The soak test runs a quic-go server and a number of concurrent quic-go clients,
+// either over UDP sockets on the loopback interface, or over an in-memory network.
+// Every client repeatedly dials a connection, resuming the TLS session and sending 0-RTT data when possible.
+// On every connection, it transfers data on a number of concurrent streams, sends datagrams,
+// and periodically migrates to a new address, simulating a NAT rebinding.
+// Keys are updated frequently, and packets are randomly dropped, duplicated, delayed and reordered.
+//
+// The server echoes all stream data and datagrams, and the clients verify every byte they receive.
+// Periodic reports contain the throughput, a taxonomy of the errors encountered,
+// as well as the number of goroutines and the memory usage.
+package soak
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+const (
+	defaultConnections          = 16
+	defaultConnectionLifetime   = 10 * time.Second
+	defaultStreamsPerConnection = 4
+	defaultMaxStreamSize        = 256 << 10 // 256 KB
+	defaultDatagramInterval     = 20 * time.Millisecond
+	defaultMigrationInterval    = 5 * time.Second
+	defaultKeyUpdateInterval    = 1000
+	defaultReportInterval       = 10 * time.Second
+)
+
+const (
+	// handshakeTimeout is the time after which dialing a connection is aborted.
+	handshakeTimeout = 10 * time.Second
+	// streamTimeout is the time after which a stream that hasn't completed is considered stalled.
+	streamTimeout = time.Minute
+	// drainTimeout is the time that in-flight streams are given to complete when a connection reaches the end of its lifetime.
+	drainTimeout = 30 * time.Second
+)
+
+// Network is the network that the soak test is run on.
+type Network uint8
+
+const (
+	// NetworkLoopback uses UDP sockets on the loopback interface.
+	NetworkLoopback Network = iota
+	// NetworkSimulated uses an in-memory network, without using any sockets.
+	NetworkSimulated
+)
+
+func (n Network) String() string {
+	switch n {
+	case NetworkLoopback:
+		return "loopback"
+	case NetworkSimulated:
+		return "simulated"
+	default:
+		return "unknown network"
+	}
+}
+
+// Options configure the soak test.
+type Options struct {
+	// Network is the network that the soak test is run on.
+	Network Network
+	// Connections is the number of concurrent client connections.
+	// If zero, 16 connections are used.
+	Connections int
+	// Duration is the duration of the soak test.
+	// If zero, the test runs until the context is canceled.
+	Duration time.Duration
+	// ConnectionLifetime is the time after which a client closes its connection, and dials a new one.
+	// New connections resume the TLS session, and send 0-RTT data.
+	// If zero, connections are closed after 10 seconds.
+	ConnectionLifetime time.Duration
+	// StreamsPerConnection is the number of concurrent streams on every connection.
+	// If zero, 4 streams are used.
+	StreamsPerConnection int
+	// MaxStreamSize is the maximum number of bytes sent on a stream.
+	// The size of every stream is chosen randomly.
+	// If zero, up to 256 KB are sent.
+	MaxStreamSize int
+	// DatagramInterval is the interval at which datagrams are sent on every connection.
+	// If zero, a datagram is sent every 20ms. If negative, no datagrams are sent.
+	DatagramInterval time.Duration
+	// MigrationInterval is the average interval at which clients migrate to a new address.
+	// If zero, clients migrate every 5 seconds. If negative, clients don't migrate.
+	MigrationInterval time.Duration
+	// KeyUpdateInterval is the number of packets after which the keys are updated.
+	// If zero, keys are updated every 1000 packets.
+	KeyUpdateInterval uint64
+	// Loss and Duplicate are the probabilities that a packet is dropped or duplicated, respectively.
+	Loss, Duplicate float64
+	// Every packet is delayed by Delay plus a random value in [0, Jitter).
+	// A non-zero Jitter reorders packets.
+	// Impairments are injected by a quic.FaultInjector on both endpoints,
+	// which applies at most one fault to every packet: dropped and duplicated packets are not delayed.
+	Delay, Jitter time.Duration
+	// ReportInterval is the interval at which OnReport is called.
+	// If zero, a report is generated every 10 seconds.
+	ReportInterval time.Duration
+	// OnReport is called with a report of the progress so far.
+	OnReport func(*Report)
+	// Seed seeds the random number generator used to size streams and datagrams.
+	// If zero, a random seed is used.
+	Seed int64
+}
+
+func populateOptions(opts *Options) *Options {
+	if opts == nil {
+		opts = &Options{}
+	}
+	o := *opts
+	if o.Connections == 0 {
+		o.Connections = defaultConnections
+	}
+	if o.ConnectionLifetime == 0 {
+		o.ConnectionLifetime = defaultConnectionLifetime
+	}
+	if o.StreamsPerConnection == 0 {
+		o.StreamsPerConnection = defaultStreamsPerConnection
+	}
+	if o.MaxStreamSize == 0 {
+		o.MaxStreamSize = defaultMaxStreamSize
+	}
+	if o.DatagramInterval == 0 {
+		o.DatagramInterval = defaultDatagramInterval
+	}
+	if o.MigrationInterval == 0 {
+		o.MigrationInterval = defaultMigrationInterval
+	}
+	if o.KeyUpdateInterval == 0 {
+		o.KeyUpdateInterval = defaultKeyUpdateInterval
+	}
+	if o.ReportInterval == 0 {
+		o.ReportInterval = defaultReportInterval
+	}
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+	return &o
+}
+
+// The number of delay rules used to approximate a uniformly distributed jitter.
+const jitterSteps = 8
+
+// faultRules translates the impairments of the network into rules for the quic.FaultInjector.
+// Faults are only injected into outgoing packets, so that every packet is impaired once.
+func faultRules(o *Options) []quic.FaultRule {
+	rules := []quic.FaultRule{
+		{Action: quic.FaultActionDrop, Probability: o.Loss, Direction: quic.FaultDirectionOutgoing},
+		{Action: quic.FaultActionDuplicate, Probability: o.Duplicate, Direction: quic.FaultDirectionOutgoing},
+	}
+	if o.Jitter <= 0 {
+		if o.Delay > 0 {
+			rules = append(rules, quic.FaultRule{Action: quic.FaultActionDelay, Probability: 1, Delay: o.Delay, Direction: quic.FaultDirectionOutgoing})
+		}
+		return rules
+	}
+	// Rules are evaluated in order, so the i-th step applies with a probability of 1/(jitterSteps-i)
+	// to the packets that didn't match any of the previous steps.
+	// That way, every step applies to the same fraction of packets.
+	for i := 0; i < jitterSteps; i++ {
+		rules = append(rules, quic.FaultRule{
+			Action:      quic.FaultActionDelay,
+			Probability: 1 / float64(jitterSteps-i),
+			Delay:       o.Delay + o.Jitter*time.Duration(2*i+1)/(2*jitterSteps),
+			Direction:   quic.FaultDirectionOutgoing,
+		})
+	}
+	return rules
+}
+
+// Run runs the soak test, until the context is canceled, or the configured duration has passed.
+// It returns the final report.
+// An error is only returned if the soak test couldn't be started.
+func Run(ctx context.Context, opts *Options) (*Report, error) {
+	o := populateOptions(opts)
+	if o.Network != NetworkLoopback && o.Network != NetworkSimulated {
+		return nil, errors.New("soak: unknown network")
+	}
+	if o.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Duration)
+		defer cancel()
+	}
+
+	serverTLSConf, clientTLSConf, err := generateTLSConfigs()
+	if err != nil {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(o.Seed))
+	faultInjector := quic.NewFaultInjector()
+	if err := faultInjector.SetRules(faultRules(o)...); err != nil {
+		return nil, err
+	}
+	var nw network
+	if o.Network == NetworkSimulated {
+		nw = newSimNetwork()
+	} else {
+		nw = &loopbackNetwork{}
+	}
+
+	st := newStats()
+	r := newReporter(st)
+	tracer := &tracer{stats: st}
+	serverConn, err := nw.Listen()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := quic.ListenEarly(
+		serverConn,
+		serverTLSConf,
+		&quic.Config{
+			EnableDatagrams:       true,
+			MaxIncomingStreams:    int64(2 * o.StreamsPerConnection),
+			MaxIncomingUniStreams: -1,
+			KeyUpdateInterval:     o.KeyUpdateInterval,
+			FaultInjector:         faultInjector,
+			Tracer:                tracer,
+		},
+	)
+	if err != nil {
+		serverConn.Close()
+		return nil, err
+	}
+	srv := newServer(ln, st)
+
+	clientConf := &quic.Config{
+		EnableDatagrams:      true,
+		HandshakeIdleTimeout: handshakeTimeout,
+		KeyUpdateInterval:    o.KeyUpdateInterval,
+		FaultInjector:        faultInjector,
+		Tracer:               tracer,
+	}
+	clients := make([]*client, 0, o.Connections)
+	for i := 0; i < o.Connections; i++ {
+		conn, err := nw.NewConn()
+		if err != nil {
+			for _, c := range clients {
+				c.Close()
+			}
+			srv.Close()
+			serverConn.Close()
+			return nil, err
+		}
+		clients = append(clients, newClient(
+			conn,
+			serverConn.LocalAddr(),
+			clientTLSConf,
+			clientConf,
+			o,
+			rnd.Int63(),
+			st,
+		))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(clients))
+	for _, c := range clients {
+		go func(c *client) {
+			defer wg.Done()
+			c.run(ctx)
+		}(c)
+	}
+
+	ticker := time.NewTicker(o.ReportInterval)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			if o.OnReport != nil {
+				o.OnReport(r.report())
+			}
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	wg.Wait()
+	for _, c := range clients {
+		c.Close()
+	}
+	srv.Close()
+	serverConn.Close()
+	return r.report(), nil
+}
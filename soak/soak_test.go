@@ -0,0 +1,214 @@
+package soak
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Soak", func() {
+	for _, n := range []Network{NetworkLoopback, NetworkSimulated} {
+		nw := n
+
+		Context(fmt.Sprintf("on the %s network", nw), func() {
+			It("transfers and verifies data", func() {
+				var reports []*Report
+				report, err := Run(context.Background(), &Options{
+					Network:              nw,
+					Connections:          4,
+					Duration:             4 * time.Second,
+					ConnectionLifetime:   time.Second,
+					StreamsPerConnection: 2,
+					MaxStreamSize:        32 << 10,
+					DatagramInterval:     10 * time.Millisecond,
+					MigrationInterval:    300 * time.Millisecond,
+					KeyUpdateInterval:    100,
+					ReportInterval:       time.Second,
+					OnReport:             func(r *Report) { reports = append(reports, r) },
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reports).ToNot(BeEmpty())
+				Expect(report.Connections).To(BeNumerically(">", 4))
+				Expect(report.Resumed).ToNot(BeZero())
+				Expect(report.ZeroRTTAccepted).ToNot(BeZero())
+				Expect(report.Streams).ToNot(BeZero())
+				Expect(report.BytesVerified).ToNot(BeZero())
+				Expect(report.DatagramsSent).ToNot(BeZero())
+				Expect(report.DatagramsVerified).ToNot(BeZero())
+				Expect(report.Migrations).ToNot(BeZero())
+				Expect(report.KeyUpdates).ToNot(BeZero())
+				Expect(report.PacketsSent).ToNot(BeZero())
+				Expect(report.Corruptions).To(BeZero())
+				Expect(report.OpenConnections).To(BeZero())
+			})
+
+			It("transfers and verifies data on an impaired network", func() {
+				report, err := Run(context.Background(), &Options{
+					Network:              nw,
+					Connections:          4,
+					Duration:             4 * time.Second,
+					ConnectionLifetime:   time.Second,
+					StreamsPerConnection: 2,
+					MaxStreamSize:        32 << 10,
+					MigrationInterval:    500 * time.Millisecond,
+					Loss:                 0.05,
+					Duplicate:            0.05,
+					Delay:                2 * time.Millisecond,
+					Jitter:               5 * time.Millisecond,
+					Seed:                 GinkgoRandomSeed(),
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(report.Streams).ToNot(BeZero())
+				Expect(report.BytesVerified).ToNot(BeZero())
+				Expect(report.PacketsLost).ToNot(BeZero())
+				Expect(report.Corruptions).To(BeZero())
+			})
+		})
+	}
+
+	It("stops when the context is canceled", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		report, err := Run(ctx, &Options{Network: NetworkSimulated, Connections: 2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(report.OpenConnections).To(BeZero())
+	})
+
+	It("rejects unknown networks", func() {
+		_, err := Run(context.Background(), &Options{Network: 42})
+		Expect(err).To(MatchError("soak: unknown network"))
+	})
+
+	Context("verifying data", func() {
+		data := func(seed int64, n int) []byte {
+			b := make([]byte, n)
+			rand.New(rand.NewSource(seed)).Read(b)
+			return b
+		}
+
+		It("accepts matching data", func() {
+			Expect(verify(bytes.NewReader(data(1, 10000)), rand.New(rand.NewSource(1)), 10000)).To(Succeed())
+		})
+
+		It("detects corrupted data", func() {
+			b := data(1, 10000)
+			b[5000] ^= 0xff
+			Expect(verify(bytes.NewReader(b), rand.New(rand.NewSource(1)), 10000)).To(MatchError(errCorrupted))
+		})
+
+		It("detects additional data", func() {
+			Expect(verify(bytes.NewReader(data(1, 10001)), rand.New(rand.NewSource(1)), 10000)).To(MatchError(errCorrupted))
+		})
+
+		It("detects truncated data", func() {
+			Expect(verify(bytes.NewReader(data(1, 9999)), rand.New(rand.NewSource(1)), 10000)).To(MatchError(errTruncated))
+		})
+	})
+
+	It("classifies errors", func() {
+		Expect(classifyError(errCorrupted)).To(Equal(categoryCorrupted))
+		Expect(classifyError(fmt.Errorf("stream 4: %w", errTruncated))).To(Equal(categoryTruncated))
+		Expect(classifyError(quic.Err0RTTRejected)).To(Equal("0rtt_rejected"))
+		Expect(classifyError(context.DeadlineExceeded)).To(Equal("timeout"))
+		Expect(classifyError(&quic.IdleTimeoutError{})).To(Equal("idle_timeout"))
+		Expect(classifyError(&quic.HandshakeTimeoutError{})).To(Equal("handshake_timeout"))
+		Expect(classifyError(&quic.TransportError{Remote: true, ErrorCode: quic.FlowControlError})).To(Equal("remote_transport_error:FLOW_CONTROL_ERROR"))
+		Expect(classifyError(&quic.ApplicationError{ErrorCode: 42})).To(Equal("local_application_error"))
+		Expect(classifyError(&quic.StreamError{StreamID: 4, ErrorCode: 1})).To(Equal("stream_reset"))
+		Expect(classifyError(net.ErrClosed)).To(Equal("conn_closed"))
+		Expect(classifyError(fmt.Errorf("foobar"))).To(Equal("other"))
+	})
+
+	It("counts corruptions in the report", func() {
+		st := newStats()
+		r := newReporter(st)
+		st.addError("", errCorrupted)
+		st.addError("server/", errTruncated)
+		st.addError("", &quic.IdleTimeoutError{})
+		st.bytesVerified = 1000
+		report := r.report()
+		Expect(report.Errors).To(Equal(map[string]uint64{
+			"data_corrupted":        1,
+			"server/data_truncated": 1,
+			"idle_timeout":          1,
+		}))
+		Expect(report.Corruptions).To(BeEquivalentTo(2))
+		Expect(report.Throughput).To(BeNumerically(">", 0))
+
+		buf := &bytes.Buffer{}
+		Expect(report.WriteText(buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("corruptions: 2"))
+		Expect(buf.String()).To(ContainSubstring("error server/data_truncated: 1"))
+
+		buf.Reset()
+		Expect(report.WriteJSON(buf)).To(Succeed())
+		var m map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &m)).To(Succeed())
+		Expect(m).To(HaveKeyWithValue("corruptions", BeEquivalentTo(2)))
+		Expect(m).To(HaveKey("elapsed"))
+		Expect(m["elapsed"]).To(BeAssignableToTypeOf(""))
+	})
+
+	Context("simulated network", func() {
+		It("changes the address seen by the peer on rebinding", func() {
+			n := newSimNetwork()
+			c1 := n.newConn()
+			c2 := n.newConn()
+			Expect(c1.Rebind()).To(Succeed())
+			_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 100)
+			_, addr, err := c2.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).ToNot(Equal(c1.LocalAddr()))
+			// packets sent to the old address are dropped
+			_, err = c2.WriteTo([]byte("foo"), c1.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c2.WriteTo([]byte("bar"), addr)
+			Expect(err).ToNot(HaveOccurred())
+			l, _, err := c1.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:l]).To(Equal([]byte("bar")))
+		})
+	})
+
+	It("translates the impairments into fault rules", func() {
+		rules := faultRules(&Options{Loss: 0.1, Duplicate: 0.2, Delay: 10 * time.Millisecond, Jitter: 8 * time.Millisecond})
+		Expect(rules).To(HaveLen(2 + jitterSteps))
+		Expect(rules[0].Action).To(Equal(quic.FaultActionDrop))
+		Expect(rules[0].Probability).To(Equal(0.1))
+		Expect(rules[1].Action).To(Equal(quic.FaultActionDuplicate))
+		Expect(rules[1].Probability).To(Equal(0.2))
+		for _, r := range rules[2:] {
+			Expect(r.Action).To(Equal(quic.FaultActionDelay))
+			Expect(r.Delay).To(And(
+				BeNumerically(">=", 10*time.Millisecond),
+				BeNumerically("<", 18*time.Millisecond),
+			))
+		}
+		Expect(rules[len(rules)-1].Probability).To(Equal(1.0))
+		for _, r := range rules {
+			Expect(r.Direction).To(Equal(quic.FaultDirectionOutgoing))
+		}
+		Expect(quic.NewFaultInjector().SetRules(rules...)).To(Succeed())
+	})
+
+	It("doesn't delay packets if no delay is configured", func() {
+		rules := faultRules(&Options{})
+		Expect(rules).To(HaveLen(2))
+		for _, r := range rules {
+			Expect(r.Probability).To(BeZero())
+		}
+	})
+})
@@ -0,0 +1,90 @@
+package soak
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// The tracer is shared by the server and all clients.
+// quic-go requires all connections using the same net.PacketConn to use the same tracer.
+type tracer struct {
+	stats *stats
+}
+
+var _ logging.Tracer = &tracer{}
+
+func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, _ logging.ConnectionID) logging.ConnectionTracer {
+	return &connTracer{stats: t.stats, perspective: p}
+}
+func (t *tracer) SentPacket(net.Addr, *logging.Header, logging.ByteCount, []logging.Frame) {}
+func (t *tracer) DroppedPacket(net.Addr, logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+}
+
+// The connTracer counts packets and key updates.
+// Packets are counted for both endpoints, key updates only for the client.
+type connTracer struct {
+	stats       *stats
+	perspective logging.Perspective
+}
+
+var _ logging.ConnectionTracer = &connTracer{}
+
+func (t *connTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+}
+func (t *connTracer) StartedHandshake(context.Context, *logging.HandshakeInfo) {}
+func (t *connTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
+}
+func (t *connTracer) ClosedConnection(error)                                      {}
+func (t *connTracer) SentTransportParameters(*logging.TransportParameters)        {}
+func (t *connTracer) ReceivedTransportParameters(tp *logging.TransportParameters) {}
+func (t *connTracer) RestoredTransportParameters(*logging.TransportParameters)    {}
+
+func (t *connTracer) SentPacket(*logging.ExtendedHeader, logging.ByteCount, *logging.AckFrame, []logging.Frame) {
+	atomic.AddUint64(&t.stats.packetsSent, 1)
+}
+
+func (t *connTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {}
+func (t *connTracer) ReceivedRetry(*logging.Header)                                             {}
+func (t *connTracer) ReceivedPacket(*logging.ExtendedHeader, logging.ByteCount, []logging.Frame) {
+}
+func (t *connTracer) BufferedPacket(logging.PacketType)                                             {}
+func (t *connTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {}
+func (t *connTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+}
+func (t *connTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
+
+func (t *connTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
+	atomic.AddUint64(&t.stats.packetsLost, 1)
+}
+
+func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                 {}
+func (t *connTracer) UpdatedCongestionParameters(*logging.CongestionParameters)      {}
+func (t *connTracer) UpdatedCongestionMetrics(*logging.CongestionMetrics)            {}
+func (t *connTracer) UpdatedPTOCount(value uint32)                                   {}
+func (t *connTracer) UpdatedFlowLabel(uint32, logging.FlowLabelTrigger)              {}
+func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective) {}
+
+func (t *connTracer) UpdatedKey(logging.KeyPhase, bool) {
+	if t.perspective == logging.PerspectiveClient {
+		atomic.AddUint64(&t.stats.keyUpdates, 1)
+	}
+}
+
+func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
+func (t *connTracer) DroppedKey(logging.KeyPhase)                                        {}
+func (t *connTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
+func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
+func (t *connTracer) LossTimerCanceled()                                                 {}
+func (t *connTracer) InjectedFault(logging.FaultDirection, logging.FaultAction, logging.PacketType, logging.ByteCount) {
+}
+func (t *connTracer) QueuedDatagram(logging.ByteCount)                              {}
+func (t *connTracer) SentDatagram(logging.ByteCount)                                {}
+func (t *connTracer) DroppedDatagram(logging.ByteCount, logging.DatagramDropReason) {}
+func (t *connTracer) ReceivedDatagram(logging.ByteCount)                            {}
+func (t *connTracer) LostDatagram(logging.ByteCount)                                {}
+func (t *connTracer) Close()                                                        {}
+func (t *connTracer) Debug(string, string)                                          {}
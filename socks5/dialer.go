@@ -0,0 +1,86 @@
+package socks5
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Dialer establishes QUIC connections through a SOCKS5 proxy.
+// Every connection uses its own UDP association, which is terminated when the QUIC connection is closed.
+//
+// The DialEarly method can be used as the Dial function of the http3.RoundTripper:
+//
+//	d := &socks5.Dialer{ProxyAddr: "proxy.example.com:1080"}
+//	rt := &http3.RoundTripper{Dial: d.DialEarly}
+type Dialer struct {
+	// ProxyAddr is the address of the SOCKS5 proxy.
+	ProxyAddr string
+	// Auth contains the credentials used to authenticate with the proxy.
+	// If nil, no authentication is performed.
+	Auth *Auth
+}
+
+// Dial establishes a new QUIC connection to addr through the SOCKS5 proxy.
+// The host name in addr is resolved by the proxy.
+// The hostname for SNI is taken from addr, unless it is set in the tls.Config.
+func (d *Dialer) Dial(ctx context.Context, addr string, tlsConf *tls.Config, config *quic.Config) (quic.Connection, error) {
+	pconn, remoteAddr, host, err := d.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.DialContext(ctx, pconn, remoteAddr, host, tlsConf, config)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	go closeWhenDone(conn, pconn)
+	return conn, nil
+}
+
+// DialEarly establishes a new 0-RTT QUIC connection to addr through the SOCKS5 proxy.
+// See Dial for details.
+func (d *Dialer) DialEarly(ctx context.Context, addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlyConnection, error) {
+	pconn, remoteAddr, host, err := d.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.DialEarlyContext(ctx, pconn, remoteAddr, host, tlsConf, config)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	go closeWhenDone(conn, pconn)
+	return conn, nil
+}
+
+func (d *Dialer) dial(ctx context.Context, addr string) (*PacketConn, net.Addr, string, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var remoteAddr net.Addr
+	if ip := net.ParseIP(host); ip != nil {
+		remoteAddr = &net.UDPAddr{IP: ip, Port: int(port)}
+	} else {
+		remoteAddr = &Addr{Host: host, Port: int(port)}
+	}
+	pconn, err := DialContext(ctx, d.ProxyAddr, d.Auth)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return pconn, remoteAddr, addr, nil
+}
+
+// closeWhenDone terminates the UDP association when the QUIC connection is closed.
+func closeWhenDone(conn quic.Connection, pconn *PacketConn) {
+	<-conn.Context().Done()
+	pconn.Close()
+}
@@ -0,0 +1,104 @@
+package socks5
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dialer", func() {
+	var (
+		server *testServer
+		auth   = &Auth{Username: "user", Password: "secret"}
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(auth)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("establishes a QUIC connection through the proxy", func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"quic-go-test"}
+		ln, err := quic.ListenAddr("127.0.0.1:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			str.Close()
+		}()
+
+		d := &Dialer{ProxyAddr: server.Addr(), Auth: auth}
+		conn, err := d.Dial(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			&tls.Config{RootCAs: testdata.GetRootCA(), NextProtos: []string{"quic-go-test"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.NumAssociations()).To(Equal(1))
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		// the host name was resolved by the proxy
+		Expect(server.Destinations()).To(ContainElement(fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port)))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+	})
+
+	It("errors when the proxy rejects the credentials", func() {
+		d := &Dialer{ProxyAddr: server.Addr(), Auth: &Auth{Username: "user", Password: "wrong"}}
+		_, err := d.Dial(context.Background(), "localhost:443", &tls.Config{}, nil)
+		Expect(err).To(MatchError(ErrAuthenticationFailed))
+	})
+
+	It("is usable with the http3.RoundTripper", func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Hello, World!\n"))
+		})
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		s := &http3.Server{Handler: mux, TLSConfig: testdata.GetTLSConfig()}
+		go s.Serve(udpConn)
+		defer s.Close()
+
+		d := &Dialer{ProxyAddr: server.Addr(), Auth: auth}
+		rt := &http3.RoundTripper{
+			TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()},
+			Dial:            d.DialEarly,
+		}
+		defer rt.Close()
+		cl := &http.Client{Transport: rt}
+		resp, err := cl.Get(fmt.Sprintf("https://localhost:%d/hello", udpConn.LocalAddr().(*net.UDPAddr).Port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("Hello, World!\n"))
+		Expect(server.NumAssociations()).To(Equal(1))
+	})
+})
@@ -0,0 +1,134 @@
+package socks5
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The maximum length of the SOCKS5 UDP request header:
+// RSV (2), FRAG (1), ATYP (1), a domain name (1 + 255), DST.PORT (2).
+const maxHeaderLen = 2 + 1 + 1 + 1 + 255 + 2
+
+// The maximum size of a UDP datagram.
+const maxDatagramSize = 1<<16 - 1
+
+// A PacketConn sends and receives datagrams through a SOCKS5 UDP relay.
+// Every datagram is encapsulated in a SOCKS5 UDP request header.
+// The association is terminated when the PacketConn is closed,
+// or when the proxy closes the TCP control connection.
+type PacketConn struct {
+	tcpConn   net.Conn
+	udpConn   *net.UDPConn
+	relayAddr *net.UDPAddr
+
+	readMutex sync.Mutex
+	readBuf   []byte
+
+	writeMutex sync.Mutex
+	writeBuf   []byte
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var _ net.PacketConn = &PacketConn{}
+
+func newPacketConn(tcpConn net.Conn, udpConn *net.UDPConn, relayAddr *net.UDPAddr) *PacketConn {
+	c := &PacketConn{
+		tcpConn:   tcpConn,
+		udpConn:   udpConn,
+		relayAddr: relayAddr,
+		readBuf:   make([]byte, maxDatagramSize),
+	}
+	go c.watchControlConn()
+	return c
+}
+
+// watchControlConn closes the PacketConn when the proxy closes the TCP control connection.
+// The proxy doesn't send any data on the control connection after the handshake.
+func (c *PacketConn) watchControlConn() {
+	io.Copy(io.Discard, c.tcpConn)
+	c.Close()
+}
+
+// ReadFrom reads a datagram from the relay, and strips the SOCKS5 UDP request header.
+// It returns the address of the peer that sent the datagram.
+// Datagrams that are not sent by the relay, fragmented datagrams and malformed datagrams are dropped.
+func (c *PacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	c.readMutex.Lock()
+	defer c.readMutex.Unlock()
+
+	for {
+		n, from, err := c.udpConn.ReadFromUDP(c.readBuf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !from.IP.Equal(c.relayAddr.IP) || from.Port != c.relayAddr.Port {
+			continue
+		}
+		addr, data, err := parseDatagram(c.readBuf[:n])
+		if err != nil {
+			continue
+		}
+		return copy(p, data), addr, nil
+	}
+}
+
+// WriteTo encapsulates p in a SOCKS5 UDP request header, and sends it to the relay.
+// If addr is not a *net.UDPAddr, and its host is not an IP address, it is resolved by the proxy.
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if len(p) > maxDatagramSize-maxHeaderLen {
+		return 0, errors.New("socks5: datagram too large")
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.writeBuf = append(c.writeBuf[:0], 0, 0, 0) // RSV and FRAG
+	c.writeBuf = appendAddr(c.writeBuf, addr)
+	c.writeBuf = append(c.writeBuf, p...)
+	if _, err := c.udpConn.WriteToUDP(c.writeBuf, c.relayAddr); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close terminates the UDP association.
+func (c *PacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		err1 := c.udpConn.Close()
+		err2 := c.tcpConn.Close()
+		if err1 != nil {
+			c.closeErr = err1
+		} else {
+			c.closeErr = err2
+		}
+	})
+	return c.closeErr
+}
+
+// LocalAddr returns the local address of the UDP socket used to communicate with the relay.
+func (c *PacketConn) LocalAddr() net.Addr { return c.udpConn.LocalAddr() }
+
+// RelayAddr returns the address of the proxy's UDP relay.
+func (c *PacketConn) RelayAddr() net.Addr { return c.relayAddr }
+
+// SetDeadline sets the read and write deadlines.
+func (c *PacketConn) SetDeadline(t time.Time) error { return c.udpConn.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline.
+func (c *PacketConn) SetReadDeadline(t time.Time) error { return c.udpConn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline.
+func (c *PacketConn) SetWriteDeadline(t time.Time) error { return c.udpConn.SetWriteDeadline(t) }
+
+// SetReadBuffer sets the size of the receive buffer of the UDP socket.
+func (c *PacketConn) SetReadBuffer(bytes int) error { return c.udpConn.SetReadBuffer(bytes) }
+
+// SyscallConn returns a raw network connection of the UDP socket.
+// It allows quic-go to inspect the size of the receive buffer.
+func (c *PacketConn) SyscallConn() (syscall.RawConn, error) { return c.udpConn.SyscallConn() }
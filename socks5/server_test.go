@@ -0,0 +1,186 @@
+package socks5
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	. "github.com/onsi/gomega"
+)
+
+// testServer is a minimal SOCKS5 server that only supports the UDP ASSOCIATE command.
+type testServer struct {
+	ln   net.Listener
+	auth *Auth // if set, username / password authentication is required
+
+	replyCode uint8 // if non-zero, the UDP ASSOCIATE request is rejected with this code
+
+	mutex           sync.Mutex
+	numAssociations int
+	destinations    []string // the destinations of the relayed datagrams
+	controlConns    []net.Conn
+}
+
+func newTestServer(auth *Auth) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	s := &testServer{ln: ln, auth: auth}
+	go s.run()
+	return s
+}
+
+func (s *testServer) Addr() string { return s.ln.Addr().String() }
+
+func (s *testServer) Close() {
+	s.ln.Close()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, c := range s.controlConns {
+		c.Close()
+	}
+}
+
+func (s *testServer) NumAssociations() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.numAssociations
+}
+
+func (s *testServer) Destinations() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.destinations...)
+}
+
+// CloseControlConns closes all TCP control connections.
+func (s *testServer) CloseControlConns() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, c := range s.controlConns {
+		c.Close()
+	}
+}
+
+func (s *testServer) run() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.controlConns = append(s.controlConns, conn)
+		s.mutex.Unlock()
+		go s.handleConn(conn)
+	}
+}
+
+func (s *testServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	var hdr [2]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil || hdr[0] != socksVersion {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	method := uint8(methodNoAcceptable)
+	for _, m := range methods {
+		if (s.auth == nil && m == methodNoAuth) || (s.auth != nil && m == methodUserPass) {
+			method = m
+		}
+	}
+	conn.Write([]byte{socksVersion, method})
+	switch method {
+	case methodNoAcceptable:
+		return
+	case methodUserPass:
+		if !s.authenticate(conn) {
+			return
+		}
+	}
+
+	var req [3]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return
+	}
+	if _, err := readAddr(conn); err != nil {
+		return
+	}
+	if req[1] != cmdUDPAssociate {
+		conn.Write(appendAddr([]byte{socksVersion, 7, 0}, &net.UDPAddr{IP: net.IPv4zero}))
+		return
+	}
+	if s.replyCode != 0 {
+		conn.Write(appendAddr([]byte{socksVersion, s.replyCode, 0}, &net.UDPAddr{IP: net.IPv4zero}))
+		return
+	}
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return
+	}
+	defer relay.Close()
+	s.mutex.Lock()
+	s.numAssociations++
+	s.mutex.Unlock()
+	// Return an unspecified address, the client is expected to use the address of the proxy.
+	relayAddr := &net.UDPAddr{IP: net.IPv4zero, Port: relay.LocalAddr().(*net.UDPAddr).Port}
+	conn.Write(appendAddr([]byte{socksVersion, 0, 0}, relayAddr))
+
+	go s.relay(relay)
+	// The association is terminated when the control connection is closed.
+	io.Copy(io.Discard, conn)
+}
+
+func (s *testServer) authenticate(conn net.Conn) bool {
+	var b [2]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil || b[0] != userPassVersion {
+		return false
+	}
+	username := make([]byte, b[1])
+	if _, err := io.ReadFull(conn, username); err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(conn, b[:1]); err != nil {
+		return false
+	}
+	password := make([]byte, b[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return false
+	}
+	if string(username) != s.auth.Username || string(password) != s.auth.Password {
+		conn.Write([]byte{userPassVersion, 1})
+		return false
+	}
+	conn.Write([]byte{userPassVersion, 0})
+	return true
+}
+
+func (s *testServer) relay(relay *net.UDPConn) {
+	var clientAddr *net.UDPAddr
+	b := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := relay.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		if clientAddr == nil || (from.IP.Equal(clientAddr.IP) && from.Port == clientAddr.Port) {
+			clientAddr = from
+			addr, data, err := parseDatagram(b[:n])
+			if err != nil {
+				continue
+			}
+			s.mutex.Lock()
+			s.destinations = append(s.destinations, addr.String())
+			s.mutex.Unlock()
+			dest, err := net.ResolveUDPAddr("udp", addr.String())
+			if err != nil {
+				continue
+			}
+			relay.WriteToUDP(data, dest)
+			continue
+		}
+		relay.WriteToUDP(append(appendAddr([]byte{0, 0, 0}, from), b[:n]...), clientAddr)
+	}
+}
@@ -0,0 +1,349 @@
+// Package socks5 implements a net.PacketConn that sends and receives datagrams
+// through a SOCKS5 proxy, using the UDP ASSOCIATE command (RFC 1928).
+// It allows establishing QUIC connections from networks where direct UDP traffic is not possible.
+package socks5
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const socksVersion = 5
+
+const (
+	methodNoAuth       = 0x00
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xff
+
+	// the version of the username / password subnegotiation (RFC 1929)
+	userPassVersion = 1
+)
+
+const cmdUDPAssociate = 3
+
+const (
+	addrTypeIPv4   = 1
+	addrTypeDomain = 3
+	addrTypeIPv6   = 4
+)
+
+// handshakeTimeout is used if the context passed to DialContext doesn't have a deadline.
+const handshakeTimeout = 10 * time.Second
+
+// Auth contains the credentials used to authenticate with the proxy,
+// using the username / password authentication method (RFC 1929).
+type Auth struct {
+	Username string
+	Password string
+}
+
+// A ReplyError is returned when the proxy rejects the UDP ASSOCIATE request.
+type ReplyError struct {
+	Code uint8
+}
+
+func (e *ReplyError) Error() string {
+	var msg string
+	switch e.Code {
+	case 1:
+		msg = "general SOCKS server failure"
+	case 2:
+		msg = "connection not allowed by ruleset"
+	case 3:
+		msg = "network unreachable"
+	case 4:
+		msg = "host unreachable"
+	case 5:
+		msg = "connection refused"
+	case 6:
+		msg = "TTL expired"
+	case 7:
+		msg = "command not supported"
+	case 8:
+		msg = "address type not supported"
+	default:
+		msg = "unknown error"
+	}
+	return fmt.Sprintf("socks5: %s (reply code %d)", msg, e.Code)
+}
+
+// ErrAuthenticationFailed is returned when the proxy rejects the credentials.
+var ErrAuthenticationFailed = errors.New("socks5: authentication failed")
+
+// ErrNoAcceptableMethod is returned when the proxy doesn't support any of the offered authentication methods.
+var ErrNoAcceptableMethod = errors.New("socks5: no acceptable authentication method")
+
+// Dial connects to the SOCKS5 proxy at proxyAddr and establishes a UDP ASSOCIATE session.
+// If auth is non-nil, username / password authentication is offered to the proxy.
+// The returned PacketConn can be used with quic.Dial.
+func Dial(proxyAddr string, auth *Auth) (*PacketConn, error) {
+	return DialContext(context.Background(), proxyAddr, auth)
+}
+
+// DialContext connects to the SOCKS5 proxy at proxyAddr and establishes a UDP ASSOCIATE session.
+// The context is used for connecting to the proxy and for the SOCKS5 handshake.
+// See Dial for details.
+func DialContext(ctx context.Context, proxyAddr string, auth *Auth) (*PacketConn, error) {
+	var d net.Dialer
+	tcpConn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	relayAddr, err := handshake(ctx, tcpConn, auth)
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+	// The proxy might return an unspecified address (0.0.0.0 or ::),
+	// meaning that the relay is reachable at the address of the proxy.
+	proxyTCPAddr := tcpConn.RemoteAddr().(*net.TCPAddr)
+	if relayAddr.IP.IsUnspecified() {
+		relayAddr.IP = proxyTCPAddr.IP
+	}
+	localTCPAddr := tcpConn.LocalAddr().(*net.TCPAddr)
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: localTCPAddr.IP, Zone: localTCPAddr.Zone})
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+	return newPacketConn(tcpConn, udpConn, relayAddr), nil
+}
+
+// handshake performs the method negotiation, the authentication, and sends the UDP ASSOCIATE request.
+// It returns the address of the UDP relay.
+func handshake(ctx context.Context, conn net.Conn, auth *Auth) (*net.UDPAddr, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+			return nil, err
+		}
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblock all reads and writes
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	relayAddr, err := handshakeImpl(conn, auth)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	// The TCP connection is kept open for the lifetime of the association.
+	return relayAddr, conn.SetDeadline(time.Time{})
+}
+
+func handshakeImpl(conn net.Conn, auth *Auth) (*net.UDPAddr, error) {
+	methods := []byte{methodNoAuth}
+	if auth != nil {
+		methods = append(methods, methodUserPass)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, uint8(len(methods))}, methods...)); err != nil {
+		return nil, err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return nil, err
+	}
+	if resp[0] != socksVersion {
+		return nil, fmt.Errorf("socks5: unexpected version: %d", resp[0])
+	}
+	switch resp[1] {
+	case methodNoAuth:
+	case methodUserPass:
+		if auth == nil {
+			return nil, fmt.Errorf("socks5: proxy selected an unoffered authentication method: %d", resp[1])
+		}
+		if err := authenticate(conn, auth); err != nil {
+			return nil, err
+		}
+	case methodNoAcceptable:
+		return nil, ErrNoAcceptableMethod
+	default:
+		return nil, fmt.Errorf("socks5: proxy selected an unoffered authentication method: %d", resp[1])
+	}
+
+	// The client doesn't know the address it will send datagrams from (as seen by the proxy),
+	// so it uses an all-zeros address (see Section 7 of RFC 1928).
+	req := []byte{socksVersion, cmdUDPAssociate, 0}
+	req = appendAddr(req, &net.UDPAddr{IP: net.IPv4zero})
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	var hdr [3]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != socksVersion {
+		return nil, fmt.Errorf("socks5: unexpected version: %d", hdr[0])
+	}
+	if hdr[1] != 0 {
+		return nil, &ReplyError{Code: hdr[1]}
+	}
+	addr, err := readAddr(conn)
+	if err != nil {
+		return nil, err
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		// The relay address is a domain name. Resolve it.
+		return net.ResolveUDPAddr("udp", addr.String())
+	}
+	return udpAddr, nil
+}
+
+func authenticate(conn net.Conn, auth *Auth) error {
+	if len(auth.Username) == 0 || len(auth.Username) > 255 {
+		return errors.New("socks5: username must be between 1 and 255 bytes long")
+	}
+	if len(auth.Password) == 0 || len(auth.Password) > 255 {
+		return errors.New("socks5: password must be between 1 and 255 bytes long")
+	}
+	req := make([]byte, 0, 3+len(auth.Username)+len(auth.Password))
+	req = append(req, userPassVersion, uint8(len(auth.Username)))
+	req = append(req, auth.Username...)
+	req = append(req, uint8(len(auth.Password)))
+	req = append(req, auth.Password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return err
+	}
+	if resp[0] != userPassVersion {
+		return fmt.Errorf("socks5: unexpected authentication version: %d", resp[0])
+	}
+	if resp[1] != 0 {
+		return ErrAuthenticationFailed
+	}
+	return nil
+}
+
+// appendAddr appends the SOCKS5 encoding of addr (ATYP, DST.ADDR and DST.PORT) to b.
+// If addr is not a *net.UDPAddr, and its host is not an IP address,
+// it is sent as a domain name, and resolved by the proxy.
+func appendAddr(b []byte, addr net.Addr) []byte {
+	var (
+		ip     net.IP
+		domain string
+		port   int
+	)
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		ip = udpAddr.IP
+		port = udpAddr.Port
+	} else {
+		host, portStr, err := net.SplitHostPort(addr.String())
+		if err == nil {
+			port, err = strconv.Atoi(portStr)
+		}
+		if err != nil {
+			// This will be rejected by the proxy.
+			domain = addr.String()
+		} else if ip = net.ParseIP(host); ip == nil {
+			domain = host
+		}
+	}
+	switch {
+	case domain != "":
+		if len(domain) > 255 {
+			domain = domain[:255]
+		}
+		b = append(b, addrTypeDomain, uint8(len(domain)))
+		b = append(b, domain...)
+	case ip.To4() != nil:
+		b = append(b, addrTypeIPv4)
+		b = append(b, ip.To4()...)
+	default:
+		b = append(b, addrTypeIPv6)
+		b = append(b, ip.To16()...)
+	}
+	return append(b, uint8(port>>8), uint8(port))
+}
+
+// readAddr reads a SOCKS5 address (ATYP, ADDR and PORT).
+// It returns a *net.UDPAddr for IP addresses, and an Addr for domain names.
+func readAddr(r io.Reader) (net.Addr, error) {
+	var addrType [1]byte
+	if _, err := io.ReadFull(r, addrType[:]); err != nil {
+		return nil, err
+	}
+	var domain string
+	var ip net.IP
+	switch addrType[0] {
+	case addrTypeIPv4:
+		ip = make(net.IP, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, err
+		}
+	case addrTypeIPv6:
+		ip = make(net.IP, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, err
+		}
+	case addrTypeDomain:
+		var l [1]byte
+		if _, err := io.ReadFull(r, l[:]); err != nil {
+			return nil, err
+		}
+		b := make([]byte, l[0])
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		domain = string(b)
+	default:
+		return nil, fmt.Errorf("socks5: unknown address type: %d", addrType[0])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return nil, err
+	}
+	p := int(binary.BigEndian.Uint16(port[:]))
+	if ip != nil {
+		return &net.UDPAddr{IP: ip, Port: p}, nil
+	}
+	return &Addr{Host: domain, Port: p}, nil
+}
+
+// parseDatagram parses the SOCKS5 UDP request header.
+// It returns the address contained in the header, and the payload.
+func parseDatagram(b []byte) (net.Addr, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, io.EOF
+	}
+	if b[2] != 0 {
+		return nil, nil, errors.New("socks5: fragmented datagrams are not supported")
+	}
+	r := bytes.NewReader(b[3:])
+	addr, err := readAddr(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addr, b[len(b)-r.Len():], nil
+}
+
+// An Addr is a domain name address, as used in the SOCKS5 protocol.
+type Addr struct {
+	Host string
+	Port int
+}
+
+var _ net.Addr = &Addr{}
+
+// Network returns "udp".
+func (a *Addr) Network() string { return "udp" }
+
+func (a *Addr) String() string { return net.JoinHostPort(a.Host, strconv.Itoa(a.Port)) }
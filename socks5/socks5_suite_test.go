@@ -0,0 +1,13 @@
+package socks5
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSOCKS5(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SOCKS5 Suite")
+}
@@ -0,0 +1,205 @@
+package socks5
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SOCKS5", func() {
+	Context("addresses", func() {
+		It("encodes and decodes IPv4 addresses", func() {
+			b := appendAddr(nil, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337})
+			Expect(b).To(Equal([]byte{addrTypeIPv4, 1, 2, 3, 4, 0x5, 0x39}))
+			addr, err := readAddr(bytes.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal("1.2.3.4:1337"))
+		})
+
+		It("encodes and decodes IPv6 addresses", func() {
+			b := appendAddr(nil, &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443})
+			Expect(b).To(HaveLen(1 + 16 + 2))
+			Expect(b[0]).To(BeEquivalentTo(addrTypeIPv6))
+			addr, err := readAddr(bytes.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(BeAssignableToTypeOf(&net.UDPAddr{}))
+			Expect(addr.String()).To(Equal("[2001:db8::1]:443"))
+		})
+
+		It("encodes and decodes domain names", func() {
+			b := appendAddr(nil, &Addr{Host: "quic.clemente.io", Port: 443})
+			Expect(b[:2]).To(Equal([]byte{addrTypeDomain, uint8(len("quic.clemente.io"))}))
+			addr, err := readAddr(bytes.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(&Addr{Host: "quic.clemente.io", Port: 443}))
+		})
+
+		It("encodes addresses that are not UDP addresses, but contain an IP", func() {
+			b := appendAddr(nil, &Addr{Host: "127.0.0.1", Port: 443})
+			Expect(b).To(Equal([]byte{addrTypeIPv4, 127, 0, 0, 1, 0x1, 0xbb}))
+		})
+
+		It("errors on unknown address types", func() {
+			_, err := readAddr(bytes.NewReader([]byte{42, 1, 2, 3, 4, 0, 1}))
+			Expect(err).To(MatchError("socks5: unknown address type: 42"))
+		})
+
+		It("rejects fragmented datagrams", func() {
+			b := appendAddr([]byte{0, 0, 1}, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337})
+			_, _, err := parseDatagram(append(b, []byte("foobar")...))
+			Expect(err).To(MatchError("socks5: fragmented datagrams are not supported"))
+		})
+
+		It("parses datagrams", func() {
+			b := appendAddr([]byte{0, 0, 0}, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337})
+			addr, data, err := parseDatagram(append(b, []byte("foobar")...))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal("1.2.3.4:1337"))
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+	})
+
+	Context("handshake", func() {
+		var server *testServer
+
+		BeforeEach(func() { server = nil })
+
+		AfterEach(func() {
+			if server != nil {
+				server.Close()
+			}
+		})
+
+		It("establishes an association without authentication", func() {
+			server = newTestServer(nil)
+			conn, err := Dial(server.Addr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(server.NumAssociations()).To(Equal(1))
+			// the server returned 0.0.0.0 as the relay address
+			Expect(conn.RelayAddr().(*net.UDPAddr).IP.Equal(net.IPv4(127, 0, 0, 1))).To(BeTrue())
+		})
+
+		It("authenticates", func() {
+			server = newTestServer(&Auth{Username: "user", Password: "secret"})
+			conn, err := Dial(server.Addr(), &Auth{Username: "user", Password: "secret"})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(server.NumAssociations()).To(Equal(1))
+		})
+
+		It("errors when the credentials are wrong", func() {
+			server = newTestServer(&Auth{Username: "user", Password: "secret"})
+			_, err := Dial(server.Addr(), &Auth{Username: "user", Password: "wrong"})
+			Expect(err).To(MatchError(ErrAuthenticationFailed))
+			Expect(server.NumAssociations()).To(BeZero())
+		})
+
+		It("errors when the proxy requires authentication, but no credentials are configured", func() {
+			server = newTestServer(&Auth{Username: "user", Password: "secret"})
+			_, err := Dial(server.Addr(), nil)
+			Expect(err).To(MatchError(ErrNoAcceptableMethod))
+		})
+
+		It("rejects invalid credentials", func() {
+			server = newTestServer(&Auth{Username: "user", Password: "secret"})
+			_, err := Dial(server.Addr(), &Auth{Username: "user"})
+			Expect(err).To(MatchError("socks5: password must be between 1 and 255 bytes long"))
+		})
+
+		It("errors when the proxy rejects the request", func() {
+			server = newTestServer(nil)
+			server.replyCode = 2
+			_, err := Dial(server.Addr(), nil)
+			Expect(err).To(MatchError(&ReplyError{Code: 2}))
+			Expect(err.Error()).To(ContainSubstring("connection not allowed by ruleset"))
+		})
+
+		It("respects the context", func() {
+			// a TCP listener that accepts connections, but never responds
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = DialContext(ctx, ln.Addr().String(), nil)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
+	Context("sending and receiving datagrams", func() {
+		var (
+			server *testServer
+			peer   *net.UDPConn
+			conn   *PacketConn
+		)
+
+		BeforeEach(func() {
+			server = newTestServer(nil)
+			var err error
+			peer, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			conn, err = Dial(server.Addr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			conn.Close()
+			peer.Close()
+			server.Close()
+		})
+
+		It("sends and receives datagrams", func() {
+			_, err := conn.WriteTo([]byte("foobar"), peer.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 100)
+			peer.SetReadDeadline(time.Now().Add(time.Second))
+			n, relayAddr, err := peer.ReadFromUDP(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+
+			_, err = peer.WriteToUDP([]byte("raboof"), relayAddr)
+			Expect(err).ToNot(HaveOccurred())
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, addr, err := conn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("raboof")))
+			Expect(addr.String()).To(Equal(peer.LocalAddr().String()))
+		})
+
+		It("lets the proxy resolve domain names", func() {
+			port := peer.LocalAddr().(*net.UDPAddr).Port
+			_, err := conn.WriteTo([]byte("foobar"), &Addr{Host: "localhost", Port: port})
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(server.Destinations).Should(ContainElement(net.JoinHostPort("localhost", strconv.Itoa(port))))
+		})
+
+		It("drops datagrams that were not sent by the relay", func() {
+			other, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer other.Close()
+			_, err = other.WriteToUDP([]byte("foobar"), conn.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			_, _, err = conn.ReadFrom(make([]byte, 100))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(net.Error).Timeout()).To(BeTrue())
+		})
+
+		It("closes when the proxy closes the control connection", func() {
+			errChan := make(chan error, 1)
+			go func() {
+				_, _, err := conn.ReadFrom(make([]byte, 100))
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			server.CloseControlConns()
+			Eventually(errChan).Should(Receive(HaveOccurred()))
+		})
+	})
+})
@@ -27,6 +27,8 @@ type streamSender interface {
 	onHasStreamData(protocol.StreamID)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
+	// says if both endpoints support RESET_STREAM_AT
+	supportsReliableReset() bool
 }
 
 // Each of the both stream halves gets its own uniStreamSender.
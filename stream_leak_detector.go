@@ -0,0 +1,410 @@
+package quic
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A StreamLeakReason is the reason why a stream was reported as leaked.
+type StreamLeakReason uint8
+
+const (
+	// StreamLeakUnreachable means that the application dropped all references to the stream,
+	// while the stream was still open.
+	StreamLeakUnreachable StreamLeakReason = iota + 1
+	// StreamLeakIdle means that the stream was open, but the application didn't read from
+	// or write to it for longer than StreamLeakDetector.IdleTimeout.
+	StreamLeakIdle
+)
+
+func (r StreamLeakReason) String() string {
+	switch r {
+	case StreamLeakUnreachable:
+		return "unreachable"
+	case StreamLeakIdle:
+		return "idle"
+	default:
+		return fmt.Sprintf("unknown reason: %d", r)
+	}
+}
+
+// A LeakedStream is a stream that was reported by the StreamLeakDetector.
+type LeakedStream struct {
+	StreamID StreamID
+	Reason   StreamLeakReason
+	// ConnectionTracingID is the value of ConnectionTracingKey on the connection's context.
+	ConnectionTracingID uint64
+	// Accepted is true if the stream was opened by the peer and accepted by the application,
+	// and false if it was opened by the application.
+	Accepted bool
+	// SendOpen is true if the send side of the stream was neither closed nor canceled.
+	SendOpen bool
+	// ReceiveOpen is true if the receive side of the stream was neither read until io.EOF,
+	// nor canceled, nor reset by the peer.
+	ReceiveOpen bool
+	// Idle is the time since the application last read from or wrote to the stream.
+	// If it never did, it is the time since the stream was opened or accepted.
+	Idle time.Duration
+	// Stack is the stack trace of the call that opened or accepted the stream.
+	Stack string
+	// Reset is true if the stream was reset, because StreamLeakDetector.ResetLeakedStreams is set.
+	Reset bool
+}
+
+func (s *LeakedStream) String() string {
+	verb := "opened"
+	if s.Accepted {
+		verb = "accepted"
+	}
+	var open []string
+	if s.SendOpen {
+		open = append(open, "send")
+	}
+	if s.ReceiveOpen {
+		open = append(open, "receive")
+	}
+	return fmt.Sprintf("leaked stream %d (%s, %s side open, idle for %s), %s at:\n%s", s.StreamID, s.Reason, strings.Join(open, " and "), s.Idle, verb, s.Stack)
+}
+
+// A StreamLeakDetector detects streams that the application abandoned without closing them.
+// A stream is abandoned if the application neither closed nor canceled the send side,
+// or neither read the receive side until io.EOF nor canceled it.
+// Such streams leak flow control credit and count against the peer's stream limit.
+// Leaks are detected when a stream becomes unreachable (using a finalizer),
+// and optionally when a stream is idle for too long.
+// Every leaked stream is only reported once.
+// It is intended for debugging, and comes with a performance cost.
+type StreamLeakDetector struct {
+	// IdleTimeout is the duration after which an open stream that wasn't read from or written to is reported.
+	// A stream is not considered idle while a Read or Write call is blocked.
+	// If zero, streams are only reported when they become unreachable.
+	IdleTimeout time.Duration
+	// ResetLeakedStreams resets the open sides of a leaked stream using ResetErrorCode,
+	// such that its resources are released.
+	ResetLeakedStreams bool
+	ResetErrorCode     StreamErrorCode
+	// OnLeak is called for every leaked stream.
+	// It may be called concurrently from different goroutines, and must not block.
+	// If not set, leaked streams are logged at error level (see the QUIC_GO_LOG_LEVEL environment variable).
+	OnLeak func(*LeakedStream)
+}
+
+const maxLeakStackDepth = 32
+
+// The streamLeakDetector tracks the streams of a single connection.
+type streamLeakDetector struct {
+	config    *StreamLeakDetector
+	tracingID uint64
+
+	mutex          sync.Mutex
+	closed         bool
+	streams        map[*trackedStreamState]struct{} // only used if IdleTimeout is set
+	idleCheckStart sync.Once
+	done           chan struct{}
+}
+
+func newStreamLeakDetector(config *StreamLeakDetector, tracingID uint64) *streamLeakDetector {
+	return &streamLeakDetector{
+		config:    config,
+		tracingID: tracingID,
+		streams:   make(map[*trackedStreamState]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// trackedStreamState is the state of a stream handed out to the application.
+// It must not reference the stream wrapper, otherwise the finalizer would never run.
+type trackedStreamState struct {
+	detector *streamLeakDetector
+	id       StreamID
+	send     SendStream    // nil for receive streams
+	receive  ReceiveStream // nil for send streams
+	accepted bool
+	stack    []uintptr
+
+	lastActive int64 // UnixNano, accessed atomically
+	activeOps  int32 // number of ongoing (possibly blocked) calls, accessed atomically
+
+	reported bool // protected by the detector's mutex
+}
+
+func (s *trackedStreamState) startOp() {
+	atomic.AddInt32(&s.activeOps, 1)
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+}
+
+func (s *trackedStreamState) endOp() {
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+	atomic.AddInt32(&s.activeOps, -1)
+}
+
+func (s *trackedStreamState) openSides() (sendOpen, receiveOpen bool) {
+	if s.send != nil {
+		sendOpen = true
+		if str, ok := s.send.(interface{ openForWriting() bool }); ok {
+			sendOpen = str.openForWriting()
+		}
+	}
+	if s.receive != nil {
+		receiveOpen = true
+		if str, ok := s.receive.(interface{ openForReading() bool }); ok {
+			receiveOpen = str.openForReading()
+		}
+	}
+	return
+}
+
+func (s *trackedStreamState) isOpen() bool {
+	sendOpen, receiveOpen := s.openSides()
+	return sendOpen || receiveOpen
+}
+
+func (s *trackedStreamState) formatStack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(s.stack)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+func (d *streamLeakDetector) newState(id StreamID, send SendStream, receive ReceiveStream, accepted bool) *trackedStreamState {
+	s := &trackedStreamState{
+		detector:   d,
+		id:         id,
+		send:       send,
+		receive:    receive,
+		accepted:   accepted,
+		stack:      make([]uintptr, maxLeakStackDepth),
+		lastActive: time.Now().UnixNano(),
+	}
+	// skip runtime.Callers, newState, the track function, and the connection method
+	s.stack = s.stack[:runtime.Callers(4, s.stack)]
+	if d.config.IdleTimeout > 0 {
+		d.mutex.Lock()
+		if !d.closed {
+			d.streams[s] = struct{}{}
+		}
+		d.mutex.Unlock()
+		d.idleCheckStart.Do(func() { go d.runIdleCheck() })
+	}
+	return s
+}
+
+func (d *streamLeakDetector) trackStream(str Stream, accepted bool) Stream {
+	t := &trackedStream{Stream: str, state: d.newState(str.StreamID(), str, str, accepted)}
+	runtime.SetFinalizer(t, func(t *trackedStream) { d.onUnreachable(t.state) })
+	return t
+}
+
+func (d *streamLeakDetector) trackReceiveStream(str ReceiveStream) ReceiveStream {
+	t := &trackedReceiveStream{ReceiveStream: str, state: d.newState(str.StreamID(), nil, str, true)}
+	runtime.SetFinalizer(t, func(t *trackedReceiveStream) { d.onUnreachable(t.state) })
+	return t
+}
+
+func (d *streamLeakDetector) trackSendStream(str SendStream) SendStream {
+	t := &trackedSendStream{SendStream: str, state: d.newState(str.StreamID(), str, nil, false)}
+	runtime.SetFinalizer(t, func(t *trackedSendStream) { d.onUnreachable(t.state) })
+	return t
+}
+
+func (d *streamLeakDetector) onUnreachable(s *trackedStreamState) {
+	d.mutex.Lock()
+	delete(d.streams, s)
+	ignore := d.closed || s.reported
+	s.reported = true
+	d.mutex.Unlock()
+	if ignore || !s.isOpen() {
+		return
+	}
+	d.report(s, StreamLeakUnreachable, time.Now())
+}
+
+func (d *streamLeakDetector) runIdleCheck() {
+	interval := d.config.IdleTimeout / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			d.checkIdle(now)
+		}
+	}
+}
+
+func (d *streamLeakDetector) checkIdle(now time.Time) {
+	var leaked []*trackedStreamState
+	d.mutex.Lock()
+	for s := range d.streams {
+		if !s.isOpen() {
+			delete(d.streams, s)
+			continue
+		}
+		if atomic.LoadInt32(&s.activeOps) > 0 || now.Sub(time.Unix(0, atomic.LoadInt64(&s.lastActive))) < d.config.IdleTimeout {
+			continue
+		}
+		delete(d.streams, s)
+		s.reported = true
+		leaked = append(leaked, s)
+	}
+	d.mutex.Unlock()
+
+	for _, s := range leaked {
+		d.report(s, StreamLeakIdle, now)
+	}
+}
+
+func (d *streamLeakDetector) report(s *trackedStreamState, reason StreamLeakReason, now time.Time) {
+	sendOpen, receiveOpen := s.openSides()
+	l := &LeakedStream{
+		StreamID:            s.id,
+		Reason:              reason,
+		ConnectionTracingID: d.tracingID,
+		Accepted:            s.accepted,
+		SendOpen:            sendOpen,
+		ReceiveOpen:         receiveOpen,
+		Idle:                now.Sub(time.Unix(0, atomic.LoadInt64(&s.lastActive))),
+		Stack:               s.formatStack(),
+	}
+	if d.config.ResetLeakedStreams {
+		if sendOpen {
+			s.send.CancelWrite(d.config.ResetErrorCode)
+		}
+		if receiveOpen {
+			s.receive.CancelRead(d.config.ResetErrorCode)
+		}
+		l.Reset = true
+	}
+	if d.config.OnLeak != nil {
+		d.config.OnLeak(l)
+		return
+	}
+	utils.DefaultLogger.Errorf("%s", l)
+}
+
+// close is called when the connection is closed.
+// All streams are closed with the connection, so no more leaks are reported.
+func (d *streamLeakDetector) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	d.streams = nil
+	close(d.done)
+}
+
+// The tracked stream types are handed out to the application instead of the stream.
+// They record when the stream was last used, and carry the finalizer.
+type trackedStream struct {
+	Stream
+	state *trackedStreamState
+}
+
+func (s *trackedStream) Read(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.Stream.Read(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedStream) TryRead(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.Stream.TryRead(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedStream) Write(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.Stream.Write(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedStream) TryWrite(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.Stream.TryWrite(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedStream) Flush() error {
+	s.state.startOp()
+	defer s.state.endOp()
+	err := s.Stream.Flush()
+	runtime.KeepAlive(s)
+	return err
+}
+
+type trackedReceiveStream struct {
+	ReceiveStream
+	state *trackedStreamState
+}
+
+func (s *trackedReceiveStream) Read(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.ReceiveStream.Read(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedReceiveStream) TryRead(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.ReceiveStream.TryRead(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+type trackedSendStream struct {
+	SendStream
+	state *trackedStreamState
+}
+
+func (s *trackedSendStream) Write(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.SendStream.Write(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedSendStream) TryWrite(p []byte) (int, error) {
+	s.state.startOp()
+	defer s.state.endOp()
+	n, err := s.SendStream.TryWrite(p)
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+func (s *trackedSendStream) Flush() error {
+	s.state.startOp()
+	defer s.state.endOp()
+	err := s.SendStream.Flush()
+	runtime.KeepAlive(s)
+	return err
+}
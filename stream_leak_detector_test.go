@@ -0,0 +1,231 @@
+package quic
+
+import (
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Leak Detector", func() {
+	var (
+		mockSender *MockStreamSender
+		mockFC     *mocks.MockStreamFlowController
+		leaks      chan *LeakedStream
+		detectors  []*streamLeakDetector
+	)
+
+	BeforeEach(func() {
+		mockSender = NewMockStreamSender(mockCtrl)
+		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
+		leaks = make(chan *LeakedStream, 10)
+	})
+
+	AfterEach(func() {
+		for _, d := range detectors {
+			d.close()
+		}
+		detectors = nil
+	})
+
+	newDetector := func(conf *StreamLeakDetector) *streamLeakDetector {
+		conf.OnLeak = func(l *LeakedStream) { leaks <- l }
+		d := newStreamLeakDetector(conf, 42)
+		detectors = append(detectors, d)
+		return d
+	}
+
+	// expectLeak runs the garbage collector until a leak is reported
+	expectLeak := func() *LeakedStream {
+		var l *LeakedStream
+		Eventually(func() bool {
+			runtime.GC()
+			select {
+			case l = <-leaks:
+				return true
+			default:
+				return false
+			}
+		}).Should(BeTrue())
+		return l
+	}
+
+	expectNoLeak := func() {
+		Consistently(func() chan *LeakedStream {
+			runtime.GC()
+			return leaks
+		}, scaleDuration(100*time.Millisecond)).ShouldNot(Receive())
+	}
+
+	Context("unreachable streams", func() {
+		It("reports streams", func() {
+			d := newDetector(&StreamLeakDetector{})
+			func() { d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), true) }()
+			l := expectLeak()
+			Expect(l.StreamID).To(Equal(protocol.StreamID(4)))
+			Expect(l.Reason).To(Equal(StreamLeakUnreachable))
+			Expect(l.ConnectionTracingID).To(BeEquivalentTo(42))
+			Expect(l.Accepted).To(BeTrue())
+			Expect(l.SendOpen).To(BeTrue())
+			Expect(l.ReceiveOpen).To(BeTrue())
+			Expect(l.Reset).To(BeFalse())
+			Expect(l.Stack).To(ContainSubstring("stream_leak_detector_test.go"))
+			Expect(l.String()).To(ContainSubstring("leaked stream 4 (unreachable, send and receive side open"))
+			Expect(l.String()).To(ContainSubstring("accepted at:\n"))
+		})
+
+		It("reports send streams", func() {
+			d := newDetector(&StreamLeakDetector{})
+			func() { d.trackSendStream(newSendStream(2, mockSender, mockFC, protocol.Version1)) }()
+			l := expectLeak()
+			Expect(l.StreamID).To(Equal(protocol.StreamID(2)))
+			Expect(l.Accepted).To(BeFalse())
+			Expect(l.SendOpen).To(BeTrue())
+			Expect(l.ReceiveOpen).To(BeFalse())
+			Expect(l.String()).To(ContainSubstring("send side open"))
+			Expect(l.String()).To(ContainSubstring("opened at:\n"))
+		})
+
+		It("reports receive streams", func() {
+			d := newDetector(&StreamLeakDetector{})
+			func() { d.trackReceiveStream(newReceiveStream(3, mockSender, mockFC, protocol.Version1)) }()
+			l := expectLeak()
+			Expect(l.StreamID).To(Equal(protocol.StreamID(3)))
+			Expect(l.Accepted).To(BeTrue())
+			Expect(l.SendOpen).To(BeFalse())
+			Expect(l.ReceiveOpen).To(BeTrue())
+		})
+
+		It("doesn't report streams that were closed and canceled", func() {
+			mockSender.EXPECT().onHasStreamData(gomock.Any())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			d := newDetector(&StreamLeakDetector{})
+			func() {
+				str := d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), false)
+				Expect(str.Close()).To(Succeed())
+				str.CancelRead(1234)
+			}()
+			expectNoLeak()
+		})
+
+		It("doesn't report receive streams that were read until the end", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			mockFC.EXPECT().Abandon().AnyTimes()
+			mockSender.EXPECT().onStreamCompleted(gomock.Any())
+			d := newDetector(&StreamLeakDetector{})
+			func() {
+				str := newReceiveStream(3, mockSender, mockFC, protocol.Version1)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{StreamID: 3, Fin: true})).To(Succeed())
+				_, err := d.trackReceiveStream(str).Read(make([]byte, 10))
+				Expect(err).To(MatchError("EOF"))
+			}()
+			expectNoLeak()
+		})
+
+		It("doesn't report streams that were reset by the peer", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true)
+			mockFC.EXPECT().Abandon()
+			mockSender.EXPECT().onStreamCompleted(gomock.Any())
+			d := newDetector(&StreamLeakDetector{})
+			func() {
+				str := newReceiveStream(3, mockSender, mockFC, protocol.Version1)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: 3, FinalSize: 10})).To(Succeed())
+				d.trackReceiveStream(str)
+			}()
+			expectNoLeak()
+		})
+
+		It("doesn't report streams after the connection was closed", func() {
+			d := newDetector(&StreamLeakDetector{})
+			func() { d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), false) }()
+			d.close()
+			d.close() // closing twice is fine
+			expectNoLeak()
+		})
+	})
+
+	Context("idle streams", func() {
+		It("reports streams once", func() {
+			d := newDetector(&StreamLeakDetector{IdleTimeout: scaleDuration(50 * time.Millisecond)})
+			str := func() Stream { return d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), false) }()
+			l := expectLeak()
+			Expect(l.StreamID).To(Equal(protocol.StreamID(4)))
+			Expect(l.Reason).To(Equal(StreamLeakIdle))
+			Expect(l.Idle).To(BeNumerically(">=", scaleDuration(50*time.Millisecond)))
+			Expect(l.Stack).To(ContainSubstring("stream_leak_detector_test.go"))
+			expectNoLeak()
+			runtime.KeepAlive(str)
+		})
+
+		It("doesn't report streams while Read is blocked", func() {
+			d := newDetector(&StreamLeakDetector{IdleTimeout: scaleDuration(20 * time.Millisecond)})
+			inner := newStream(4, mockSender, mockFC, protocol.Version1)
+			str := d.trackStream(inner, false)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := str.Read(make([]byte, 10))
+				Expect(err).To(MatchError("shutdown"))
+			}()
+			Consistently(leaks, scaleDuration(100*time.Millisecond)).ShouldNot(Receive())
+			inner.closeForShutdown(errors.New("shutdown"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't report streams that are being used", func() {
+			idleTimeout := scaleDuration(50 * time.Millisecond)
+			d := newDetector(&StreamLeakDetector{IdleTimeout: idleTimeout})
+			str := d.trackReceiveStream(newReceiveStream(3, mockSender, mockFC, protocol.Version1))
+			for i := 0; i < 10; i++ {
+				Expect(str.SetReadDeadline(time.Now().Add(idleTimeout / 5))).To(Succeed())
+				_, err := str.Read(make([]byte, 10))
+				Expect(err).To(MatchError(errDeadline))
+			}
+			Expect(leaks).ToNot(Receive())
+		})
+
+		It("doesn't report streams that are only used with TryRead and TryWrite", func() {
+			mockSender.EXPECT().onHasStreamData(protocol.StreamID(4)).AnyTimes()
+			idleTimeout := scaleDuration(50 * time.Millisecond)
+			d := newDetector(&StreamLeakDetector{IdleTimeout: idleTimeout})
+			str := d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), false)
+			for i := 0; i < 10; i++ {
+				time.Sleep(idleTimeout / 5)
+				_, err := str.TryRead(make([]byte, 10))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				n, err := str.TryWrite([]byte("f"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(1))
+			}
+			Expect(leaks).ToNot(Receive())
+		})
+
+		It("resets leaked streams", func() {
+			var frames []wire.Frame
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) { frames = append(frames, f) }).Times(2)
+			d := newDetector(&StreamLeakDetector{
+				IdleTimeout:        scaleDuration(20 * time.Millisecond),
+				ResetLeakedStreams: true,
+				ResetErrorCode:     1337,
+			})
+			str := d.trackStream(newStream(4, mockSender, mockFC, protocol.Version1), false)
+			l := expectLeak()
+			Expect(l.Reset).To(BeTrue())
+			Expect(l.SendOpen).To(BeTrue())
+			Expect(l.ReceiveOpen).To(BeTrue())
+			Expect(frames).To(ContainElement(&wire.ResetStreamFrame{StreamID: 4, ErrorCode: 1337}))
+			Expect(frames).To(ContainElement(&wire.StopSendingFrame{StreamID: 4, ErrorCode: 1337}))
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 type streamError struct {
 	message string
 	nums    []protocol.StreamNum
+	// the error code used when the peer caused the error, if it's not a STREAM_STATE_ERROR
+	errorCode qerr.TransportErrorCode
 }
 
 func (e streamError) Error() string {
@@ -31,6 +35,12 @@ func convertStreamError(err error, stype protocol.StreamType, pers protocol.Pers
 	for i, num := range strError.nums {
 		ids[i] = num.StreamID(stype, pers)
 	}
+	if strError.errorCode != 0 {
+		return &qerr.TransportError{
+			ErrorCode:    strError.errorCode,
+			ErrorMessage: fmt.Sprintf(strError.Error(), ids...),
+		}
+	}
 	return fmt.Errorf(strError.Error(), ids...)
 }
 
@@ -50,6 +60,8 @@ type streamsMap struct {
 
 	maxIncomingBidiStreams uint64
 	maxIncomingUniStreams  uint64
+	corkDelay              time.Duration // the initial cork delay of send streams
+	clock                  utils.Clock   // the clock used by the expiry and cork timers of send streams
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
@@ -69,6 +81,8 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	corkDelay time.Duration,
+	clock utils.Clock,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
@@ -77,6 +91,8 @@ func newStreamsMap(
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
+		corkDelay:              corkDelay,
+		clock:                  clock,
 		sender:                 sender,
 		version:                version,
 	}
@@ -88,14 +104,20 @@ func (m *streamsMap) initMaps() {
 	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newStream(id, m.sender, m.newFlowController(id), m.version)
+			str.corkDelay = m.corkDelay
+			str.clock = m.clock
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
 	m.incomingBidiStreams = newIncomingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newStream(id, m.sender, m.newFlowController(id), m.version)
+			str.corkDelay = m.corkDelay
+			str.clock = m.clock
+			return str
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
@@ -103,7 +125,10 @@ func (m *streamsMap) initMaps() {
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newSendStream(id, m.sender, m.newFlowController(id), m.version)
+			str.corkDelay = m.corkDelay
+			str.clock = m.clock
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
@@ -209,6 +234,9 @@ func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
 func (m *streamsMap) GetOrOpenReceiveStream(id protocol.StreamID) (receiveStreamI, error) {
 	str, err := m.getOrOpenReceiveStream(id)
 	if err != nil {
+		if _, ok := err.(*qerr.TransportError); ok {
+			return nil, err
+		}
 		return nil, &qerr.TransportError{
 			ErrorCode:    qerr.StreamStateError,
 			ErrorMessage: err.Error(),
@@ -243,6 +271,9 @@ func (m *streamsMap) getOrOpenReceiveStream(id protocol.StreamID) (receiveStream
 func (m *streamsMap) GetOrOpenSendStream(id protocol.StreamID) (sendStreamI, error) {
 	str, err := m.getOrOpenSendStream(id)
 	if err != nil {
+		if _, ok := err.(*qerr.TransportError); ok {
+			return nil, err
+		}
 		return nil, &qerr.TransportError{
 			ErrorCode:    qerr.StreamStateError,
 			ErrorMessage: err.Error(),
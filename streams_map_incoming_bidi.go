@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -100,8 +101,9 @@ func (m *incomingBidiStreamsMap) GetOrOpenStream(num protocol.StreamNum) (stream
 	if num > m.maxStream {
 		m.mutex.RUnlock()
 		return nil, streamError{
-			message: "peer tried to open stream %d (current limit: %d)",
-			nums:    []protocol.StreamNum{num, m.maxStream},
+			message:   "peer tried to open stream %d (current limit: %d)",
+			nums:      []protocol.StreamNum{num, m.maxStream},
+			errorCode: qerr.StreamLimitError,
 		}
 	}
 	// if the num is smaller than the highest we accepted
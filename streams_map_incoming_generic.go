@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -98,8 +99,9 @@ func (m *incomingItemsMap) GetOrOpenStream(num protocol.StreamNum) (item, error)
 	if num > m.maxStream {
 		m.mutex.RUnlock()
 		return nil, streamError{
-			message: "peer tried to open stream %d (current limit: %d)",
-			nums:    []protocol.StreamNum{num, m.maxStream},
+			message:   "peer tried to open stream %d (current limit: %d)",
+			nums:      []protocol.StreamNum{num, m.maxStream},
+			errorCode: qerr.StreamLimitError,
 		}
 	}
 	// if the num is smaller than the highest we accepted
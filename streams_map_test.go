@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -88,7 +90,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -115,6 +117,17 @@ var _ = Describe("Streams Map", func() {
 					Expect(str).To(BeAssignableToTypeOf(&sendStream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstOutgoingUniStream + 4))
 				})
+
+				It("sets the cork delay", func() {
+					allowUnlimitedStreams()
+					m.corkDelay = time.Millisecond
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.(*stream).corkDelay).To(Equal(time.Millisecond))
+					ustr, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ustr.(*sendStream).corkDelay).To(Equal(time.Millisecond))
+				})
 			})
 
 			Context("accepting", func() {
@@ -325,6 +338,15 @@ var _ = Describe("Streams Map", func() {
 						Expect(str.StreamID()).To(Equal(id))
 					})
 
+					It("errors when the peer exceeds the stream limit", func() {
+						id := ids.firstIncomingUniStream + 4*MaxUniStreamNum
+						_, err := m.GetOrOpenReceiveStream(id)
+						Expect(err).To(MatchError(&qerr.TransportError{
+							ErrorCode:    qerr.StreamLimitError,
+							ErrorMessage: fmt.Sprintf("peer tried to open stream %d (current limit: %d)", id, id-4),
+						}))
+					})
+
 					It("errors when trying to get an outgoing unidirectional stream", func() {
 						id := ids.firstOutgoingUniStream
 						_, err := m.GetOrOpenReceiveStream(id)
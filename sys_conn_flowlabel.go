@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import "net"
+
+func leaseFlowLabel(interface{}, *net.UDPAddr) (uint32, error) { return 0, errFlowLabelsNotSupported }
+
+func releaseFlowLabel(interface{}, uint32) {}
+
+func appendFlowLabelOOB([]byte, uint32) []byte { return nil }
@@ -0,0 +1,117 @@
+//go:build linux
+// +build linux
+
+package quic
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// These constants are not defined in golang.org/x/sys/unix.
+// See include/uapi/linux/in6.h.
+const (
+	ipv6FlowInfo     = 11 // IPV6_FLOWINFO
+	ipv6FlowLabelMgr = 32 // IPV6_FLOWLABEL_MGR
+
+	ipv6FlowLabelMask = 0x000fffff
+	// Flow labels with this bit set are reserved for stateless flow labels (see net.ipv6.flowlabel_state_ranges).
+	ipv6FlowLabelStatelessFlag = 0x00080000
+
+	ipv6FlowLabelActionGet = 0 // IPV6_FL_A_GET
+	ipv6FlowLabelActionPut = 1 // IPV6_FL_A_PUT
+	ipv6FlowLabelShareExcl = 1 // IPV6_FL_S_EXCL
+	ipv6FlowLabelCreate    = 1 // IPV6_FL_F_CREATE
+	ipv6FlowLabelExcl      = 2 // IPV6_FL_F_EXCL
+)
+
+// The kernel only accepts flow labels on outgoing packets that the socket leased before.
+// If a randomly chosen flow label is already in use, we try a different one.
+const maxFlowLabelLeaseAttempts = 8
+
+// flowLabelRequest marshals a struct in6_flowlabel_req:
+//
+//	struct in6_flowlabel_req {
+//		struct in6_addr flr_dst;
+//		__be32          flr_label;
+//		__u8            flr_action;
+//		__u8            flr_share;
+//		__u16           flr_flags;
+//		__u16           flr_expires;
+//		__u16           flr_linger;
+//		__u32           __flr_pad;
+//	};
+func flowLabelRequest(dst net.IP, label uint32, action, share uint8, flags uint16) string {
+	b := make([]byte, 32)
+	copy(b[:16], dst.To16())
+	binary.BigEndian.PutUint32(b[16:20], label)
+	b[20] = action
+	b[21] = share
+	*(*uint16)(unsafe.Pointer(&b[22])) = flags
+	return string(b)
+}
+
+func flowLabelManager(c interface{}, req string) error {
+	conn, ok := c.(interface {
+		SyscallConn() (syscall.RawConn, error)
+	})
+	if !ok {
+		return errFlowLabelsNotSupported
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		serr = unix.SetsockoptString(int(fd), unix.IPPROTO_IPV6, ipv6FlowLabelMgr, req)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// leaseFlowLabel leases a new random flow label for packets sent to the remote address.
+func leaseFlowLabel(c interface{}, remoteAddr *net.UDPAddr) (uint32, error) {
+	for i := 0; i < maxFlowLabelLeaseAttempts; i++ {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		label := binary.BigEndian.Uint32(b[:]) & ipv6FlowLabelMask &^ ipv6FlowLabelStatelessFlag
+		if label == 0 {
+			continue
+		}
+		err := flowLabelManager(c, flowLabelRequest(remoteAddr.IP, label, ipv6FlowLabelActionGet, ipv6FlowLabelShareExcl, ipv6FlowLabelCreate|ipv6FlowLabelExcl))
+		if errors.Is(err, unix.EEXIST) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return label, nil
+	}
+	return 0, errors.New("failed to lease a flow label")
+}
+
+func releaseFlowLabel(c interface{}, label uint32) {
+	_ = flowLabelManager(c, flowLabelRequest(net.IPv6zero, label, ipv6FlowLabelActionPut, 0, 0))
+}
+
+// appendFlowLabelOOB appends a control message to oob that sets the flow label of outgoing IPv6 packets.
+func appendFlowLabelOOB(oob []byte, label uint32) []byte {
+	startLen := len(oob)
+	oob = append(oob, make([]byte, unix.CmsgSpace(4))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[startLen]))
+	h.Level = unix.IPPROTO_IPV6
+	h.Type = ipv6FlowInfo
+	h.SetLen(unix.CmsgLen(4))
+	binary.BigEndian.PutUint32(oob[startLen+unix.CmsgLen(0):], label&ipv6FlowLabelMask)
+	return oob
+}
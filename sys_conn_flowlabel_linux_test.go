@@ -0,0 +1,99 @@
+//go:build linux
+// +build linux
+
+package quic
+
+import (
+	"encoding/binary"
+	"net"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flow Labels", func() {
+	// runReceiver runs a UDP socket that reports the flow label of received packets.
+	runReceiver := func() (*net.UDPConn, <-chan uint32) {
+		conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+		Expect(err).ToNot(HaveOccurred())
+		rawConn, err := conn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		var serr error
+		Expect(rawConn.Control(func(fd uintptr) {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FlowInfo, 1)
+		})).To(Succeed())
+		Expect(serr).ToNot(HaveOccurred())
+
+		labelChan := make(chan uint32, 10)
+		go func() {
+			defer GinkgoRecover()
+			for {
+				b := make([]byte, 100)
+				oob := make([]byte, 100)
+				_, oobn, _, _, err := conn.ReadMsgUDP(b, oob)
+				if err != nil {
+					return
+				}
+				msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+				Expect(err).ToNot(HaveOccurred())
+				for _, msg := range msgs {
+					if msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == ipv6FlowInfo {
+						labelChan <- binary.BigEndian.Uint32(msg.Data) & ipv6FlowLabelMask
+					}
+				}
+			}
+		}()
+		return conn, labelChan
+	}
+
+	It("sets and rotates the flow label", func() {
+		receiver, labelChan := runReceiver()
+		defer receiver.Close()
+		udpConn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		oobConn, err := newConn(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, c := range []sendConn{
+			newSendConn(oobConn, receiver.LocalAddr(), nil),
+			newSendPconn(udpConn, receiver.LocalAddr()),
+		} {
+			label1, err := c.(flowLabelSetter).RotateFlowLabel()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(label1).ToNot(BeZero())
+			Expect(label1 & ipv6FlowLabelStatelessFlag).To(BeZero())
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(labelChan).Should(Receive(Equal(label1)))
+
+			// the flow label is kept when setting the ECN codepoint
+			Expect(c.(ecnSetter).SetECN(protocol.ECT1)).To(BeTrue())
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(labelChan).Should(Receive(Equal(label1)))
+
+			label2, err := c.(flowLabelSetter).RotateFlowLabel()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(label2).ToNot(Equal(label1))
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(labelChan).Should(Receive(Equal(label2)))
+
+			c.(flowLabelSetter).ReleaseFlowLabel()
+			Expect(c.(ecnSetter).SetECN(protocol.ECNNon)).To(BeTrue())
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(labelChan).Should(Receive())
+		}
+	})
+
+	It("doesn't set flow labels for IPv4", func() {
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		c := newSendPconn(udpConn, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234})
+		_, err = c.(flowLabelSetter).RotateFlowLabel()
+		Expect(err).To(MatchError("flow labels can only be used with IPv6"))
+	})
+})
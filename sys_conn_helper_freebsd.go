@@ -9,6 +9,9 @@ const (
 	msgTypeIPTOS = unix.IP_RECVTOS
 )
 
+// The IP_TOS control message used to set the ECN bits when sending is a single byte.
+const ecnIPv4DataLen = 1
+
 const (
 	ipv4RECVPKTINFO = 0x7
 	ipv6RECVPKTINFO = 0x24
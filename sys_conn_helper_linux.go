@@ -7,6 +7,9 @@ import "golang.org/x/sys/unix"
 
 const msgTypeIPTOS = unix.IP_TOS
 
+// The IP_TOS control message used to set the ECN bits when sending is an int.
+const ecnIPv4DataLen = 4
+
 const (
 	ipv4RECVPKTINFO = unix.IP_PKTINFO
 	ipv6RECVPKTINFO = unix.IPV6_RECVPKTINFO
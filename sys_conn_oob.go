@@ -10,6 +10,7 @@ import (
 	"net"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -255,3 +256,29 @@ func (info *packetInfo) OOB() []byte {
 	}
 	return nil
 }
+
+// appendECNOOB appends a control message to oob that sets the ECN codepoint of outgoing packets.
+// The IP version is determined by the address of the peer.
+func appendECNOOB(oob []byte, remoteAddr net.Addr, ecn protocol.ECN) []byte {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	level, typ, dataLen := unix.IPPROTO_IPV6, unix.IPV6_TCLASS, 4
+	if udpAddr.IP.To4() != nil {
+		level, typ, dataLen = unix.IPPROTO_IP, unix.IP_TOS, ecnIPv4DataLen
+	}
+	startLen := len(oob)
+	oob = append(oob, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[startLen]))
+	h.Level = int32(level)
+	h.Type = int32(typ)
+	h.SetLen(unix.CmsgLen(dataLen))
+	data := oob[startLen+unix.CmsgLen(0):]
+	if dataLen == 1 {
+		data[0] = uint8(ecn)
+	} else {
+		*(*int32)(unsafe.Pointer(&data[0])) = int32(ecn)
+	}
+	return oob
+}
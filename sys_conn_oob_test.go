@@ -129,6 +129,47 @@ var _ = Describe("OOB Conn Test", func() {
 			Expect(utils.IsIPv4(p.remoteAddr.(*net.UDPAddr).IP)).To(BeFalse())
 			Expect(p.ecn).To(Equal(protocol.ECT1))
 		})
+
+		for _, v := range []struct {
+			network, address string
+		}{
+			{network: "udp4", address: "localhost:0"},
+			{network: "udp6", address: "[::1]:0"},
+		} {
+			network := v.network
+			address := v.address
+
+			It(fmt.Sprintf("sets ECN flags when sending, using %s", network), func() {
+				conn, packetChan := runServer(network, address)
+				defer conn.Close()
+
+				addr, err := net.ResolveUDPAddr(network, address)
+				Expect(err).ToNot(HaveOccurred())
+				udpConn, err := net.ListenUDP(network, addr)
+				Expect(err).ToNot(HaveOccurred())
+				defer udpConn.Close()
+				oobConn, err := newConn(udpConn)
+				Expect(err).ToNot(HaveOccurred())
+
+				for _, c := range []sendConn{
+					newSendConn(oobConn, conn.LocalAddr(), nil),
+					newSendPconn(udpConn, conn.LocalAddr()),
+				} {
+					Expect(c.(ecnSetter).SetECN(protocol.ECT1)).To(BeTrue())
+					Expect(c.Write([]byte("foobar"))).To(Succeed())
+					var p *receivedPacket
+					Eventually(packetChan).Should(Receive(&p))
+					Expect(p.data).To(Equal([]byte("foobar")))
+					Expect(p.ecn).To(Equal(protocol.ECT1))
+
+					Expect(c.(ecnSetter).SetECN(protocol.ECNNon)).To(BeTrue())
+					Expect(c.Write([]byte("raboof"))).To(Succeed())
+					Eventually(packetChan).Should(Receive(&p))
+					Expect(p.data).To(Equal([]byte("raboof")))
+					Expect(p.ecn).To(Equal(protocol.ECNNon))
+				}
+			})
+		}
 	})
 
 	Context("Packet Info conn", func() {
@@ -10,6 +10,8 @@ import (
 	"syscall"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 func newConn(c OOBCapablePacketConn) (rawConn, error) {
@@ -38,3 +40,5 @@ func inspectReadBuffer(c net.PacketConn) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func appendECNOOB([]byte, net.Addr, protocol.ECN) []byte { return nil }